@@ -0,0 +1,456 @@
+package artmodel
+
+import (
+	"math/big"
+
+	"website/chain"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ******** Art reference model **********
+
+// the state machine of ArtToken.sol written as plain Go, without storage layout, events or gas
+// every operation either fails with the revert reason the contract would give or applies completely
+// rules are checked in the order of the contract's require statements, so reasons match when several fail
+
+var (
+	delegationTypeHash = crypto.Keccak256Hash([]byte("Delegation(address delegatee,uint256 nonce,uint256 expiry)"))
+	permitTypeHash     = crypto.Keccak256Hash([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+
+	MaxUint96  = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+	MaxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// constants of the contract
+var (
+	InitialSupply = new(big.Int).Mul(big.NewInt(1_000_000_000), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+
+	// seconds between two mints
+	MinimumTimeBetweenMints uint64 = 365 * 24 * 60 * 60
+
+	// percentage of the total supply one mint may add
+	MintCap int64 = 2
+)
+
+// Revert is the failure of an operation, the reason is the contract's require message
+type Revert struct {
+	Reason string
+}
+
+// formatted like node errors of eth_call and eth_estimateGas
+func (r *Revert) Error() string {
+	if r.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + r.Reason
+}
+
+// Block is the context an operation executes in
+type Block struct {
+	Number uint64
+	Time   uint64
+}
+
+// Model is the token state, the zero value is not usable, start with New
+type Model struct {
+	domain              signing.Domain
+	totalSupply         *big.Int
+	minter              common.Address
+	mintingAllowedAfter *big.Int
+	balances            map[common.Address]*big.Int
+	allowances          map[common.Address]map[common.Address]*big.Int
+	delegates           map[common.Address]common.Address
+	checkpoints         map[common.Address][]chain.Checkpoint
+	nonces              map[common.Address]*big.Int
+}
+
+// New deploys the token at address, account receives the initial supply
+func New(address common.Address, chainId *big.Int, account common.Address, minter common.Address, mintingAllowedAfter *big.Int, b Block) (*Model, error) {
+
+	if mintingAllowedAfter.Cmp(new(big.Int).SetUint64(b.Time)) < 0 {
+		return nil, &Revert{"Art::constructor: minting can only begin after deployment"}
+	}
+
+	m := &Model{
+		domain:              signing.Domain{Name: "ArtToken", ChainId: new(big.Int).Set(chainId), VerifyingContract: address},
+		totalSupply:         new(big.Int).Set(InitialSupply),
+		minter:              minter,
+		mintingAllowedAfter: new(big.Int).Set(mintingAllowedAfter),
+		balances:            map[common.Address]*big.Int{account: new(big.Int).Set(InitialSupply)},
+		allowances:          make(map[common.Address]map[common.Address]*big.Int),
+		delegates:           make(map[common.Address]common.Address),
+		checkpoints:         make(map[common.Address][]chain.Checkpoint),
+		nonces:              make(map[common.Address]*big.Int),
+	}
+	return m, nil
+}
+
+// deep copy, operations run on a copy that replaces the model on success
+// amounts are never modified in place, so they are shared
+func (m *Model) clone() *Model {
+
+	c := *m
+	c.balances = make(map[common.Address]*big.Int, len(m.balances))
+	for k, v := range m.balances {
+		c.balances[k] = v
+	}
+	c.allowances = make(map[common.Address]map[common.Address]*big.Int, len(m.allowances))
+	for owner, spenders := range m.allowances {
+		c.allowances[owner] = make(map[common.Address]*big.Int, len(spenders))
+		for k, v := range spenders {
+			c.allowances[owner][k] = v
+		}
+	}
+	c.delegates = make(map[common.Address]common.Address, len(m.delegates))
+	for k, v := range m.delegates {
+		c.delegates[k] = v
+	}
+	c.checkpoints = make(map[common.Address][]chain.Checkpoint, len(m.checkpoints))
+	for k, v := range m.checkpoints {
+		c.checkpoints[k] = append([]chain.Checkpoint(nil), v...)
+	}
+	c.nonces = make(map[common.Address]*big.Int, len(m.nonces))
+	for k, v := range m.nonces {
+		c.nonces[k] = v
+	}
+	return &c
+}
+
+// runs op on a copy and keeps the result only if op succeeds, like a transaction
+func (m *Model) apply(op func(next *Model) error) error {
+	next := m.clone()
+	if err := op(next); err != nil {
+		return err
+	}
+	*m = *next
+	return nil
+}
+
+// ******** Views **********
+
+func (m *Model) TotalSupply() *big.Int { return m.totalSupply }
+
+func (m *Model) Minter() common.Address { return m.minter }
+
+func (m *Model) MintingAllowedAfter() *big.Int { return m.mintingAllowedAfter }
+
+func (m *Model) BalanceOf(account common.Address) *big.Int {
+	if b, ok := m.balances[account]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+func (m *Model) Allowance(owner common.Address, spender common.Address) *big.Int {
+	if a, ok := m.allowances[owner][spender]; ok {
+		return a
+	}
+	return new(big.Int)
+}
+
+func (m *Model) Delegates(account common.Address) common.Address { return m.delegates[account] }
+
+func (m *Model) Nonce(account common.Address) *big.Int {
+	if n, ok := m.nonces[account]; ok {
+		return n
+	}
+	return new(big.Int)
+}
+
+// Checkpoints of a delegate, oldest first
+func (m *Model) Checkpoints(account common.Address) []chain.Checkpoint {
+	return append([]chain.Checkpoint(nil), m.checkpoints[account]...)
+}
+
+func (m *Model) CurrentVotes(account common.Address) *big.Int {
+	cps := m.checkpoints[account]
+	if len(cps) == 0 {
+		return new(big.Int)
+	}
+	return cps[len(cps)-1].Votes
+}
+
+// PriorVotes are the votes at the end of a block before b
+// the latest checkpoint at or below the block counts, looked up like the checkpoints replica does
+func (m *Model) PriorVotes(account common.Address, blockNumber uint64, b Block) (*big.Int, error) {
+
+	if blockNumber >= b.Number {
+		return nil, &Revert{"Art::getPriorVotes: not yet determined"}
+	}
+	return chain.VotesAt(m.checkpoints[account], blockNumber), nil
+}
+
+// ******** Operations **********
+
+func (m *Model) SetMinter(sender common.Address, minter common.Address) error {
+	return m.apply(func(next *Model) error {
+		if sender != next.minter {
+			return &Revert{"Art::setMinter: only the minter can change the minter address"}
+		}
+		next.minter = minter
+		return nil
+	})
+}
+
+// Mint adds at most mint_cap percent of the supply, once per minimum_time_between_mints
+func (m *Model) Mint(sender common.Address, dst common.Address, amount *big.Int, b Block) error {
+	return m.apply(func(next *Model) error {
+
+		now := new(big.Int).SetUint64(b.Time)
+		if sender != next.minter {
+			return &Revert{"Art::mint: only the minter can mint"}
+		}
+		if now.Cmp(next.mintingAllowedAfter) < 0 {
+			return &Revert{"Art::mint: minting not allowed yet"}
+		}
+		if dst == (common.Address{}) {
+			return &Revert{"Art::mint: cannot transfer to the zero address"}
+		}
+		next.mintingAllowedAfter = new(big.Int).Add(now, new(big.Int).SetUint64(MinimumTimeBetweenMints))
+
+		if amount.Cmp(MaxUint96) > 0 {
+			return &Revert{"Art::mint: amount exceeds 96 bits"}
+		}
+		limit := new(big.Int).Div(new(big.Int).Mul(next.totalSupply, big.NewInt(MintCap)), big.NewInt(100))
+		if amount.Cmp(limit) > 0 {
+			return &Revert{"Art::mint: exceeded mint cap"}
+		}
+		supply := new(big.Int).Add(next.totalSupply, amount)
+		if supply.Cmp(MaxUint96) > 0 {
+			return &Revert{"Art::mint: totalSupply exceeds 96 bits"}
+		}
+		next.totalSupply = supply
+
+		balance, err := add96(next.BalanceOf(dst), amount)
+		if err != nil {
+			return err
+		}
+		next.balances[dst] = balance
+		return next.moveVotes(common.Address{}, next.delegates[dst], amount, b)
+	})
+}
+
+// amount of an approval, the uint256 maximum means unlimited and is stored as the uint96 maximum
+func approval(amount *big.Int, reason string) (*big.Int, error) {
+	if amount.Cmp(MaxUint256) == 0 {
+		return MaxUint96, nil
+	}
+	if amount.Cmp(MaxUint96) > 0 {
+		return nil, &Revert{reason}
+	}
+	return amount, nil
+}
+
+func (m *Model) setAllowance(owner common.Address, spender common.Address, amount *big.Int) {
+	if m.allowances[owner] == nil {
+		m.allowances[owner] = make(map[common.Address]*big.Int)
+	}
+	m.allowances[owner][spender] = amount
+}
+
+func (m *Model) Approve(sender common.Address, spender common.Address, amount *big.Int) error {
+	return m.apply(func(next *Model) error {
+		a, err := approval(amount, "Art::approve: amount exceeds 96 bits")
+		if err != nil {
+			return err
+		}
+		next.setAllowance(sender, spender, a)
+		return nil
+	})
+}
+
+// Permit approves with the owner's signature, the nonce is consumed whatever the signature recovers to
+func (m *Model) Permit(owner common.Address, spender common.Address, amount *big.Int, deadline *big.Int, v uint8, r [32]byte, s [32]byte, b Block) error {
+	return m.apply(func(next *Model) error {
+
+		a, err := approval(amount, "Art::permit: amount exceeds 96 bits")
+		if err != nil {
+			return err
+		}
+		nonce := next.useNonce(owner)
+		structHash := crypto.Keccak256Hash(permitTypeHash[:], addressWord(owner), addressWord(spender), word(amount), word(nonce), word(deadline))
+		signatory := ecrecover(next.domain.Digest(structHash), v, r, s)
+		if signatory == (common.Address{}) {
+			return &Revert{"Art::permit: invalid signature"}
+		}
+		if signatory != owner {
+			return &Revert{"Art::permit: unauthorized"}
+		}
+		if new(big.Int).SetUint64(b.Time).Cmp(deadline) > 0 {
+			return &Revert{"Art::permit: signature expired"}
+		}
+		next.setAllowance(owner, spender, a)
+		return nil
+	})
+}
+
+func (m *Model) Transfer(sender common.Address, dst common.Address, amount *big.Int, b Block) error {
+	return m.apply(func(next *Model) error {
+		if amount.Cmp(MaxUint96) > 0 {
+			return &Revert{"Art::transfer: amount exceeds 96 bits"}
+		}
+		return next.transfer(sender, dst, amount, b)
+	})
+}
+
+// TransferFrom spends the sender's allowance, unlimited allowances and the owner itself spend none
+func (m *Model) TransferFrom(sender common.Address, src common.Address, dst common.Address, amount *big.Int, b Block) error {
+	return m.apply(func(next *Model) error {
+
+		// the contract reuses approve's message here
+		if amount.Cmp(MaxUint96) > 0 {
+			return &Revert{"Art::approve: amount exceeds 96 bits"}
+		}
+		allowance := next.Allowance(src, sender)
+		if sender != src && allowance.Cmp(MaxUint96) != 0 {
+			if amount.Cmp(allowance) > 0 {
+				return &Revert{"Art::transferFrom: transfer amount exceeds spender allowance"}
+			}
+			next.setAllowance(src, sender, new(big.Int).Sub(allowance, amount))
+		}
+		return next.transfer(src, dst, amount, b)
+	})
+}
+
+func (m *Model) Delegate(sender common.Address, delegatee common.Address, b Block) error {
+	return m.apply(func(next *Model) error {
+		return next.delegate(sender, delegatee, b)
+	})
+}
+
+// DelegateBySig delegates the votes of whoever signed, the recovered signer's nonce must match
+func (m *Model) DelegateBySig(delegatee common.Address, nonce *big.Int, expiry *big.Int, v uint8, r [32]byte, s [32]byte, b Block) error {
+	return m.apply(func(next *Model) error {
+
+		structHash := crypto.Keccak256Hash(delegationTypeHash[:], addressWord(delegatee), word(nonce), word(expiry))
+		signatory := ecrecover(next.domain.Digest(structHash), v, r, s)
+		if signatory == (common.Address{}) {
+			return &Revert{"Art::delegateBySig: invalid signature"}
+		}
+		if nonce.Cmp(next.useNonce(signatory)) != 0 {
+			return &Revert{"Art::delegateBySig: invalid nonce"}
+		}
+		if new(big.Int).SetUint64(b.Time).Cmp(expiry) > 0 {
+			return &Revert{"Art::delegateBySig: signature expired"}
+		}
+		return next.delegate(signatory, delegatee, b)
+	})
+}
+
+// ******** Internal transitions, called on the copy **********
+
+// nonces[account]++, returns the value before the increment
+func (m *Model) useNonce(account common.Address) *big.Int {
+	n := m.Nonce(account)
+	m.nonces[account] = new(big.Int).Add(n, big.NewInt(1))
+	return n
+}
+
+func (m *Model) delegate(delegator common.Address, delegatee common.Address, b Block) error {
+	current := m.delegates[delegator]
+	m.delegates[delegator] = delegatee
+	return m.moveVotes(current, delegatee, m.BalanceOf(delegator), b)
+}
+
+func (m *Model) transfer(src common.Address, dst common.Address, amount *big.Int, b Block) error {
+
+	if src == (common.Address{}) {
+		return &Revert{"Art::_transferTokens: cannot transfer from the zero address"}
+	}
+	if dst == (common.Address{}) {
+		return &Revert{"Art::_transferTokens: cannot transfer to the zero address"}
+	}
+	if amount.Cmp(m.BalanceOf(src)) > 0 {
+		return &Revert{"Art::_transferTokens: transfer amount exceeds balance"}
+	}
+	m.balances[src] = new(big.Int).Sub(m.BalanceOf(src), amount)
+	balance, err := add96(m.BalanceOf(dst), amount)
+	if err != nil {
+		return err
+	}
+	m.balances[dst] = balance
+	return m.moveVotes(m.delegates[src], m.delegates[dst], amount, b)
+}
+
+// moves votes between delegates, the zero address holds no votes
+func (m *Model) moveVotes(src common.Address, dst common.Address, amount *big.Int, b Block) error {
+
+	if src == dst || amount.Sign() == 0 {
+		return nil
+	}
+	if src != (common.Address{}) {
+		old := m.CurrentVotes(src)
+		if amount.Cmp(old) > 0 {
+			return &Revert{"Art::_moveVotes: vote amount underflows"}
+		}
+		if err := m.checkpoint(src, new(big.Int).Sub(old, amount), b); err != nil {
+			return err
+		}
+	}
+	if dst != (common.Address{}) {
+		votes, err := add96(m.CurrentVotes(dst), amount)
+		if err != nil {
+			return err
+		}
+		if err := m.checkpoint(dst, votes, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// records votes at the block, a second change within the block overwrites the first
+func (m *Model) checkpoint(delegatee common.Address, votes *big.Int, b Block) error {
+
+	if b.Number >= 1<<32 {
+		return &Revert{"Art::_writeCheckpoint: block number exceeds 32 bits"}
+	}
+	cps := m.checkpoints[delegatee]
+	if n := len(cps); n > 0 && uint64(cps[n-1].FromBlock) == b.Number {
+		cps[n-1].Votes = votes
+		return nil
+	}
+	m.checkpoints[delegatee] = append(cps, chain.Checkpoint{FromBlock: uint32(b.Number), Votes: votes})
+	return nil
+}
+
+// uint96 addition, overflows are solidity 0.8 panics rather than the contract's messages
+// a panic carries no reason string, so the revert has none either
+// unreachable while balances and votes add up to the total supply
+func add96(a *big.Int, b *big.Int) (*big.Int, error) {
+	c := new(big.Int).Add(a, b)
+	if c.Cmp(MaxUint96) > 0 {
+		return nil, &Revert{}
+	}
+	return c, nil
+}
+
+// ecrecover precompile, malformed signatures recover the zero address, high s values are accepted
+func ecrecover(digest common.Hash, v uint8, r [32]byte, s [32]byte) common.Address {
+
+	if v != 27 && v != 28 {
+		return common.Address{}
+	}
+	if !crypto.ValidateSignatureValues(v-27, new(big.Int).SetBytes(r[:]), new(big.Int).SetBytes(s[:]), false) {
+		return common.Address{}
+	}
+	sig := append(append(append([]byte{}, r[:]...), s[:]...), v-27)
+	pub, err := crypto.SigToPub(digest[:], sig)
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+// abi encoded uint256
+func word(n *big.Int) []byte {
+	return common.LeftPadBytes(n.Bytes(), 32)
+}
+
+// abi encoded address
+func addressWord(a common.Address) []byte {
+	return common.LeftPadBytes(a[:], 32)
+}
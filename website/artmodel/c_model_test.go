@@ -0,0 +1,519 @@
+package artmodel
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var seed = flag.Int64("seed", 0, "runs only this seed of the property test, e.g. to reproduce a failure")
+
+// backend the compiled contract runs on, go-ethereum's EVM behind chain.SimulatedBackend
+// blocks are sealed and time moved by the harness, it only sends transactions and calls through the abi
+// and never looks at the backend's state
+type backend interface {
+	chain.Backend
+	PendingCallContract(ctx context.Context, call ethereum.CallMsg) ([]byte, error)
+	Commit() common.Hash
+	AdjustTime(d time.Duration) error
+}
+
+// operation sent as a transaction and applied to the model
+type op struct {
+	desc  string
+	from  chaintest.Account
+	data  []byte
+	model func(m *Model, b Block) error
+}
+
+// sent operation with the contract's dry run result
+type sent struct {
+	op
+	tx     *types.Transaction
+	dryRun error
+}
+
+type harness struct {
+	t       *testing.T
+	ctx     context.Context
+	rng     *rand.Rand
+	backend backend
+	art     *chain.Art
+	domain  signing.Domain
+	model   *Model
+	actors  []chaintest.Account
+	head    Block
+	history []string
+}
+
+// deploys the compiled contract and the model with actor 0 holding the supply and minting
+// the test fails when the contract cannot be deployed
+func newHarness(t *testing.T, seed int64) *harness {
+
+	h := &harness{t: t, ctx: context.Background(), rng: rand.New(rand.NewSource(seed))}
+	for i := 0; i < 4; i++ {
+		h.actors = append(h.actors, chaintest.NewAccount(t))
+	}
+
+	sim := chaintest.NewBackend(t)
+	h.backend = sim
+	genesis, _ := sim.HeaderByNumber(h.ctx, nil)
+	after := new(big.Int).SetUint64(genesis.Time + 30*24*3600)
+	h.art = chaintest.DeployArtOn(t, sim, h.actors[0], h.actors[0].Addr, after).Art()
+	address := h.art.Address()
+	h.head = h.header()
+
+	h.domain = signing.Domain{Name: "ArtToken", ChainId: chaintest.ChainId, VerifyingContract: address}
+	model, err := New(address, chaintest.ChainId, h.actors[0].Addr, h.actors[0].Addr, after, h.head)
+	if err != nil {
+		t.Fatalf("New failed, error: %v.", err)
+	}
+	h.model = model
+	return h
+}
+
+// seals the pending block and remembers it as head
+func (h *harness) seal() {
+	h.backend.Commit()
+	h.head = h.header()
+}
+
+func (h *harness) header() Block {
+	header, err := h.backend.HeaderByNumber(h.ctx, nil)
+	if err != nil {
+		h.t.Fatalf("HeaderByNumber failed, error: %v.", err)
+	}
+	return Block{Number: header.Number.Uint64(), Time: header.Time}
+}
+
+func (h *harness) fail(format string, a ...interface{}) {
+	history := h.history
+	if len(history) > 20 {
+		history = history[len(history)-20:]
+	}
+	h.t.Fatalf("block %d: %s\nlast operations:\n  %s", h.head.Number, fmt.Sprintf(format, a...), strings.Join(history, "\n  "))
+}
+
+// ******** Generators **********
+
+func (h *harness) actor() chaintest.Account {
+	return h.actors[h.rng.Intn(len(h.actors))]
+}
+
+// an actor, sometimes the zero address
+func (h *harness) address() common.Address {
+	if h.rng.Intn(8) == 0 {
+		return common.Address{}
+	}
+	return h.actor().Addr
+}
+
+// amounts around the balance of holder and the contract's limits
+func (h *harness) amount(holder common.Address) *big.Int {
+
+	balance := h.model.BalanceOf(holder)
+	limit := new(big.Int).Div(new(big.Int).Mul(h.model.TotalSupply(), big.NewInt(MintCap)), big.NewInt(100))
+	switch h.rng.Intn(10) {
+	case 0:
+		return new(big.Int)
+	case 1:
+		return balance
+	case 2:
+		return new(big.Int).Add(balance, big.NewInt(1))
+	case 3:
+		return MaxUint96
+	case 4:
+		return new(big.Int).Add(MaxUint96, big.NewInt(1))
+	case 5:
+		return MaxUint256
+	case 6:
+		return limit
+	case 7:
+		return new(big.Int).Add(limit, big.NewInt(1))
+	case 8:
+		return big.NewInt(h.rng.Int63n(1000) + 1)
+	default:
+		if balance.Sign() == 0 {
+			return big.NewInt(1)
+		}
+		return new(big.Int).Rand(h.rng, balance)
+	}
+}
+
+// deadlines around the time of the next block, never before the genesis block of 1970
+func (h *harness) deadline() *big.Int {
+	d := int64(h.head.Time) + h.rng.Int63n(60) - 20
+	if d < 0 {
+		d = 0
+	}
+	return big.NewInt(d)
+}
+
+// the signer's next nonce, sometimes off by one
+func (h *harness) nonce(signer common.Address) *big.Int {
+	n := h.model.Nonce(signer)
+	if h.rng.Intn(6) == 0 {
+		return new(big.Int).Add(n, big.NewInt(1))
+	}
+	return n
+}
+
+// signature over a struct hash, sometimes with an invalid recovery id
+func (h *harness) sign(a chaintest.Account, structHash common.Hash) (uint8, [32]byte, [32]byte) {
+	digest := h.domain.Digest(structHash)
+	sig, err := crypto.Sign(digest[:], a.Key)
+	if err != nil {
+		h.t.Fatalf("Sign failed, error: %v.", err)
+	}
+	var r, s [32]byte
+	copy(r[:], sig[:32])
+	copy(s[:], sig[32:64])
+	v := sig[64] + 27
+	if h.rng.Intn(10) == 0 {
+		v += 2
+	}
+	return v, r, s
+}
+
+func (h *harness) pack(method string, args ...interface{}) []byte {
+	data, err := h.art.Pack(method, args...)
+	if err != nil {
+		h.t.Fatalf("Pack failed, error: %v.", err)
+	}
+	return data
+}
+
+// a random operation, weighted towards the common ones
+func (h *harness) op() op {
+
+	from := h.actor()
+	switch h.rng.Intn(12) {
+
+	case 0, 1, 2:
+		dst, amount := h.address(), h.amount(from.Addr)
+		return op{
+			desc:  fmt.Sprintf("%s transfer(%s, %s)", h.name(from.Addr), h.name(dst), amount),
+			from:  from,
+			data:  h.pack("transfer", dst, amount),
+			model: func(m *Model, b Block) error { return m.Transfer(from.Addr, dst, amount, b) },
+		}
+
+	case 3:
+		spender, amount := h.address(), h.amount(from.Addr)
+		return op{
+			desc:  fmt.Sprintf("%s approve(%s, %s)", h.name(from.Addr), h.name(spender), amount),
+			from:  from,
+			data:  h.pack("approve", spender, amount),
+			model: func(m *Model, b Block) error { return m.Approve(from.Addr, spender, amount) },
+		}
+
+	case 4, 5:
+		src, dst := h.actor().Addr, h.address()
+		amount := h.amount(src)
+		if h.rng.Intn(2) == 0 {
+			amount = h.model.Allowance(src, from.Addr)
+		}
+		return op{
+			desc:  fmt.Sprintf("%s transferFrom(%s, %s, %s)", h.name(from.Addr), h.name(src), h.name(dst), amount),
+			from:  from,
+			data:  h.pack("transferFrom", src, dst, amount),
+			model: func(m *Model, b Block) error { return m.TransferFrom(from.Addr, src, dst, amount, b) },
+		}
+
+	case 6, 7:
+		delegatee := h.address()
+		return op{
+			desc:  fmt.Sprintf("%s delegate(%s)", h.name(from.Addr), h.name(delegatee)),
+			from:  from,
+			data:  h.pack("delegate", delegatee),
+			model: func(m *Model, b Block) error { return m.Delegate(from.Addr, delegatee, b) },
+		}
+
+	case 8:
+		signer, delegatee := h.actor(), h.address()
+		nonce, expiry := h.nonce(signer.Addr), h.deadline()
+		v, r, s := h.sign(signer, crypto.Keccak256Hash(delegationTypeHash[:], addressWord(delegatee), word(nonce), word(expiry)))
+		return op{
+			desc:  fmt.Sprintf("%s delegateBySig(%s, %s, %s) signed by %s, v %d", h.name(from.Addr), h.name(delegatee), nonce, expiry, h.name(signer.Addr), v),
+			from:  from,
+			data:  h.pack("delegateBySig", delegatee, nonce, expiry, v, r, s),
+			model: func(m *Model, b Block) error { return m.DelegateBySig(delegatee, nonce, expiry, v, r, s, b) },
+		}
+
+	case 9:
+		owner, signer, spender := h.actor(), h.actor(), h.address()
+		if h.rng.Intn(3) > 0 {
+			signer = owner
+		}
+		amount, nonce, deadline := h.amount(owner.Addr), h.nonce(owner.Addr), h.deadline()
+		v, r, s := h.sign(signer, crypto.Keccak256Hash(permitTypeHash[:], addressWord(owner.Addr), addressWord(spender), word(amount), word(nonce), word(deadline)))
+		return op{
+			desc:  fmt.Sprintf("%s permit(%s, %s, %s, %s) nonce %s signed by %s, v %d", h.name(from.Addr), h.name(owner.Addr), h.name(spender), amount, deadline, nonce, h.name(signer.Addr), v),
+			from:  from,
+			data:  h.pack("permit", owner.Addr, spender, amount, deadline, v, r, s),
+			model: func(m *Model, b Block) error { return m.Permit(owner.Addr, spender, amount, deadline, v, r, s, b) },
+		}
+
+	case 10:
+		if minter := h.model.Minter(); h.rng.Intn(4) > 0 {
+			for _, a := range h.actors {
+				if a.Addr == minter {
+					from = a
+				}
+			}
+		}
+		dst, amount := h.address(), h.amount(common.Address{})
+		return op{
+			desc:  fmt.Sprintf("%s mint(%s, %s)", h.name(from.Addr), h.name(dst), amount),
+			from:  from,
+			data:  h.pack("mint", dst, amount),
+			model: func(m *Model, b Block) error { return m.Mint(from.Addr, dst, amount, b) },
+		}
+
+	default:
+		if minter := h.model.Minter(); h.rng.Intn(2) > 0 {
+			for _, a := range h.actors {
+				if a.Addr == minter {
+					from = a
+				}
+			}
+		}
+		minter := h.actor().Addr
+		return op{
+			desc:  fmt.Sprintf("%s setMinter(%s)", h.name(from.Addr), h.name(minter)),
+			from:  from,
+			data:  h.pack("setMinter", minter),
+			model: func(m *Model, b Block) error { return m.SetMinter(from.Addr, minter) },
+		}
+	}
+}
+
+// short names keep failure output readable
+func (h *harness) name(a common.Address) string {
+	for i, actor := range h.actors {
+		if actor.Addr == a {
+			return fmt.Sprintf("actor%d", i)
+		}
+	}
+	if a == (common.Address{}) {
+		return "zero"
+	}
+	return a.Hex()
+}
+
+// ******** Execution and comparison **********
+
+// dry runs the operation for its revert reason, then sends it with enough gas to be included even if it reverts
+func (h *harness) send(o op) sent {
+
+	to := h.art.Address()
+	_, dryRun := h.backend.PendingCallContract(h.ctx, ethereum.CallMsg{From: o.from.Addr, To: &to, Data: o.data})
+
+	nonce, err := h.backend.PendingNonceAt(h.ctx, o.from.Addr)
+	if err != nil {
+		h.t.Fatalf("PendingNonceAt failed, error: %v.", err)
+	}
+	tx, err := types.SignNewTx(o.from.Key, types.LatestSignerForChainID(chaintest.ChainId), &types.DynamicFeeTx{
+		ChainID:   chaintest.ChainId,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1000000000),
+		GasFeeCap: big.NewInt(3000000000),
+		Gas:       500000,
+		To:        &to,
+		Data:      o.data,
+	})
+	if err != nil {
+		h.t.Fatalf("SignNewTx failed, error: %v.", err)
+	}
+	if err := h.backend.SendTransaction(h.ctx, tx); err != nil {
+		h.t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	return sent{op: o, tx: tx, dryRun: dryRun}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "success"
+	}
+	return err.Error()
+}
+
+// applies the block's operations to the model, outcomes must match the receipts and dry runs
+func (h *harness) check(block []sent) {
+
+	for _, s := range block {
+		err := s.model(h.model, h.head)
+		h.history = append(h.history, fmt.Sprintf("%s: %s", s.desc, errString(err)))
+
+		receipt, rerr := h.backend.TransactionReceipt(h.ctx, s.tx.Hash())
+		if rerr != nil {
+			h.fail("TransactionReceipt failed, error: %v", rerr)
+		}
+		if (receipt.Status == types.ReceiptStatusSuccessful) != (err == nil) {
+			h.fail("%s: receipt status %d, model %s", s.desc, receipt.Status, errString(err))
+		}
+		if errString(s.dryRun) != errString(err) {
+			h.fail("%s: contract %s, model %s", s.desc, errString(s.dryRun), errString(err))
+		}
+	}
+}
+
+// compares every view of the contract at the head with the model
+func (h *harness) compare() {
+
+	opts := &bind.CallOpts{Context: h.ctx, BlockNumber: new(big.Int).SetUint64(h.head.Number)}
+	equal := func(what string, contract interface{}, err error, model interface{}) {
+		if err != nil {
+			h.fail("%s failed, error: %v", what, err)
+		}
+		if fmt.Sprint(contract) != fmt.Sprint(model) {
+			h.fail("%s: contract %v, model %v", what, contract, model)
+		}
+	}
+
+	supply, err := h.art.TotalSupply(opts)
+	equal("totalSupply", supply, err, h.model.TotalSupply())
+	minter, err := h.art.Minter(opts)
+	equal("minter", minter, err, h.model.Minter())
+	after, err := h.art.MintingAllowedAfter(opts)
+	equal("mintingAllowedAfter", after, err, h.model.MintingAllowedAfter())
+
+	sum := new(big.Int)
+	for _, a := range append(h.actors, chaintest.Account{}) {
+		name := h.name(a.Addr)
+		balance, err := h.art.BalanceOf(opts, a.Addr)
+		equal(name+" balanceOf", balance, err, h.model.BalanceOf(a.Addr))
+		sum.Add(sum, h.model.BalanceOf(a.Addr))
+		nonce, err := h.art.Nonces(opts, a.Addr)
+		equal(name+" nonces", nonce, err, h.model.Nonce(a.Addr))
+		delegate, err := h.art.Delegates(opts, a.Addr)
+		equal(name+" delegates", delegate, err, h.model.Delegates(a.Addr))
+		votes, err := h.art.GetCurrentVotes(opts, a.Addr)
+		equal(name+" getCurrentVotes", votes, err, h.model.CurrentVotes(a.Addr))
+
+		cps := h.model.Checkpoints(a.Addr)
+		n, err := h.art.NumCheckpoints(opts, a.Addr)
+		equal(name+" numCheckpoints", n, err, len(cps))
+		for i := range cps {
+			cp, err := h.art.Checkpoints(opts, a.Addr, uint32(i))
+			equal(fmt.Sprintf("%s checkpoints %d", name, i), cp, err, cps[i])
+		}
+
+		block := uint64(h.rng.Int63n(int64(h.head.Number) + 1))
+		prior, err := h.art.GetPriorVotes(opts, a.Addr, new(big.Int).SetUint64(block))
+		expected, merr := h.model.PriorVotes(a.Addr, block, h.head)
+		if errString(err) != errString(merr) || (err == nil && prior.Cmp(expected) != 0) {
+			h.fail("%s getPriorVotes(%d): contract %v %s, model %v %s", name, block, prior, errString(err), expected, errString(merr))
+		}
+
+		for _, spender := range h.actors {
+			allowance, err := h.art.Allowance(opts, a.Addr, spender.Addr)
+			equal(fmt.Sprintf("%s allowance for %s", name, h.name(spender.Addr)), allowance, err, h.model.Allowance(a.Addr, spender.Addr))
+		}
+	}
+
+	// tokens only move between the actors
+	if sum.Cmp(h.model.TotalSupply()) != 0 {
+		h.fail("balances add up to %v, total supply %v", sum, h.model.TotalSupply())
+	}
+}
+
+// random blocks of one to three operations, a quarter year passes now and then so that mints become possible
+func (h *harness) run(blocks int) {
+
+	for i := 0; i < blocks; i++ {
+		if h.rng.Intn(8) == 0 {
+			if err := h.backend.AdjustTime(time.Duration(MinimumTimeBetweenMints/4) * time.Second); err != nil {
+				h.t.Fatalf("AdjustTime failed, error: %v.", err)
+			}
+		}
+		var block []sent
+		for n := 1 + h.rng.Intn(3); n > 0; n-- {
+			block = append(block, h.send(h.op()))
+		}
+		h.seal()
+		h.check(block)
+		h.compare()
+	}
+}
+
+// random operation sequences give the same results on the contract and the model
+func TestModelMatchesContract(t *testing.T) {
+
+	seeds := []int64{1, 2, 3, 4, 5, 6, 7, 8}
+	blocks := 150
+	if testing.Short() {
+		seeds, blocks = seeds[:2], 50
+	}
+	if *seed != 0 {
+		seeds = []int64{*seed}
+	}
+
+	for _, s := range seeds {
+		t.Run(fmt.Sprintf("seed %d", s), func(t *testing.T) {
+			newHarness(t, s).run(blocks)
+		})
+	}
+}
+
+// the limits that keep surprising: yearly mints of at most 2% and uint96 amounts
+func TestMintRules(t *testing.T) {
+
+	minter, holder := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	start := Block{Number: 1, Time: 1000}
+	m, err := New(common.HexToAddress("0xa1"), chaintest.ChainId, holder, minter, big.NewInt(2000), start)
+	if err != nil {
+		t.Fatalf("New failed, error: %v.", err)
+	}
+	reason := func(err error) string {
+		if r, ok := err.(*Revert); ok {
+			return r.Reason
+		}
+		return errString(err)
+	}
+
+	limit := new(big.Int).Div(InitialSupply, big.NewInt(50))
+	cases := []struct {
+		name   string
+		sender common.Address
+		amount *big.Int
+		block  Block
+		reason string
+	}{
+		{"before minting is allowed", minter, big.NewInt(1), Block{2, 1999}, "Art::mint: minting not allowed yet"},
+		{"not the minter", holder, big.NewInt(1), Block{3, 2000}, "Art::mint: only the minter can mint"},
+		{"above 96 bits", minter, new(big.Int).Add(MaxUint96, big.NewInt(1)), Block{4, 2000}, "Art::mint: amount exceeds 96 bits"},
+		{"above the cap", minter, new(big.Int).Add(limit, big.NewInt(1)), Block{5, 2000}, "Art::mint: exceeded mint cap"},
+		{"the full cap", minter, limit, Block{6, 2000}, "success"},
+		{"a second mint within the year", minter, big.NewInt(1), Block{7, 2000 + MinimumTimeBetweenMints - 1}, "Art::mint: minting not allowed yet"},
+		{"the cap grows with the supply", minter, new(big.Int).Div(new(big.Int).Add(InitialSupply, limit), big.NewInt(50)), Block{8, 2000 + MinimumTimeBetweenMints}, "success"},
+	}
+	for _, c := range cases {
+		if got := reason(m.Mint(c.sender, holder, c.amount, c.block)); got != c.reason {
+			t.Errorf("%s: Mint returned %q, expected %q.", c.name, got, c.reason)
+		}
+	}
+
+	// failed mints leave no trace, the last one moved the next mint a year on
+	if after := m.MintingAllowedAfter().Uint64(); after != 2000+2*MinimumTimeBetweenMints {
+		t.Errorf("minting allowed after %d, expected %d.", after, 2000+2*MinimumTimeBetweenMints)
+	}
+	if m.TotalSupply().Cmp(m.BalanceOf(holder)) != 0 {
+		t.Errorf("total supply %v, holder balance %v, expected equal.", m.TotalSupply(), m.BalanceOf(holder))
+	}
+}
@@ -0,0 +1,275 @@
+package auction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"website/collection"
+	"website/signing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAuction),
+		errors.Is(err, ErrInvalidAmount),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidCommitment),
+		errors.Is(err, ErrCommitmentMismatch):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrWalletNotLinked),
+		errors.Is(err, ErrNotOwner),
+		errors.Is(err, ErrNotOperator):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrAuctionNotFound),
+		errors.Is(err, collection.ErrCollectionNotFound),
+		errors.Is(err, ErrNoCommitment):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrAuctionNotLive),
+		errors.Is(err, ErrBidTooLow),
+		errors.Is(err, ErrAlreadyLeading),
+		errors.Is(err, ErrWalletMismatch),
+		errors.Is(err, ErrWrongMode),
+		errors.Is(err, ErrNotEnded),
+		errors.Is(err, ErrNotRevealing),
+		errors.Is(err, ErrAlreadyCommitted),
+		errors.Is(err, ErrAlreadyRevealed):
+		return http.StatusConflict
+
+	default:
+		return signing.ErrorStatusCode(err)
+	}
+}
+
+// decode create auction
+func decodeCreateAuction(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req CreateAuctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Auction); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode auction identifier from route
+func decodeReadAuction(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadAuctionRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode optional collection filter
+func decodeListAuctions(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListAuctionsRequest{CollectionId: r.URL.Query().Get("collection")}, nil
+}
+
+// decode bid
+func decodePlaceBid(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := PlaceBidRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode auction identifier for the bid ledger
+func decodeReadBids(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadBidsRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode auction identifier and optional RFC 3339 time for the price
+func decodeReadPrice(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := ReadPriceRequest{Id: mux.Vars(r)["id"]}
+	if s := r.URL.Query().Get("at"); s != "" {
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		req.At = at
+	}
+
+	return req, nil
+}
+
+// decode auction identifier to settle
+func decodeSettle(_ context.Context, r *http.Request) (interface{}, error) {
+	return SettleRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode sealed bid commitment
+func decodeCommitBid(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := CommitBidRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode sealed bid reveal
+func decodeRevealBid(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := RevealBidRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode auction identifier for the audit log
+func decodeReadAudit(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadAuditRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach after session routes, bids need the session identifier set by the session middleware
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching auction handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	createAuctionHandler := httptransport.NewServer(
+		e.CreateAuction,
+		decodeCreateAuction,
+		encodeResponse,
+		options...,
+	)
+
+	readAuctionHandler := httptransport.NewServer(
+		e.ReadAuction,
+		decodeReadAuction,
+		encodeResponse,
+		options...,
+	)
+
+	listAuctionsHandler := httptransport.NewServer(
+		e.ListAuctions,
+		decodeListAuctions,
+		encodeResponse,
+		options...,
+	)
+
+	placeBidHandler := httptransport.NewServer(
+		e.PlaceBid,
+		decodePlaceBid,
+		encodeResponse,
+		options...,
+	)
+
+	readBidsHandler := httptransport.NewServer(
+		e.ReadBids,
+		decodeReadBids,
+		encodeResponse,
+		options...,
+	)
+
+	readPriceHandler := httptransport.NewServer(
+		e.ReadPrice,
+		decodeReadPrice,
+		encodeResponse,
+		options...,
+	)
+
+	settleHandler := httptransport.NewServer(
+		e.Settle,
+		decodeSettle,
+		encodeResponse,
+		options...,
+	)
+
+	commitBidHandler := httptransport.NewServer(
+		e.CommitBid,
+		decodeCommitBid,
+		encodeResponse,
+		options...,
+	)
+
+	revealBidHandler := httptransport.NewServer(
+		e.RevealBid,
+		decodeRevealBid,
+		encodeResponse,
+		options...,
+	)
+
+	readAuditHandler := httptransport.NewServer(
+		e.ReadAudit,
+		decodeReadAudit,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/auctions", createAuctionHandler).Methods("POST")
+	router.Handle("/auctions", listAuctionsHandler).Methods("GET")
+	router.Handle("/auctions/{id}", readAuctionHandler).Methods("GET")
+	router.Handle("/auctions/{id}/bids", placeBidHandler).Methods("POST")
+	router.Handle("/auctions/{id}/bids", readBidsHandler).Methods("GET")
+	router.Handle("/auctions/{id}/price", readPriceHandler).Methods("GET")
+	router.Handle("/auctions/{id}/settle", settleHandler).Methods("POST")
+	router.Handle("/auctions/{id}/commitments", commitBidHandler).Methods("POST")
+	router.Handle("/auctions/{id}/reveals", revealBidHandler).Methods("POST")
+	router.Handle("/auctions/{id}/audit", readAuditHandler).Methods("GET")
+
+	return router
+}
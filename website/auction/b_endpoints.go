@@ -0,0 +1,311 @@
+package auction
+
+import (
+	"context"
+	"time"
+
+	"website/signing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type CreateAuctionRequest struct {
+	Auction Auction
+}
+
+type ReadAuctionRequest struct {
+	Id string
+}
+
+type ListAuctionsRequest struct {
+	CollectionId string
+}
+
+type PlaceBidRequest struct {
+	Id      string `json:"-"`
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+	signing.Proof
+}
+
+type ReadBidsRequest struct {
+	Id string
+}
+
+type ReadPriceRequest struct {
+	Id string
+	At time.Time
+}
+
+type SettleRequest struct {
+	Id string
+}
+
+type PriceResponse struct {
+	Data Price `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have PriceResponse follow the customError interface defined in a_transport.go
+func (r PriceResponse) error() error { return r.Err }
+
+type CommitBidRequest struct {
+	Id         string `json:"-"`
+	Address    string `json:"address"`
+	Commitment string `json:"commitment"`
+}
+
+type RevealBidRequest struct {
+	Id     string `json:"-"`
+	Amount string `json:"amount"`
+	Salt   string `json:"salt"`
+}
+
+type ReadAuditRequest struct {
+	Id string
+}
+
+type AuditResponse struct {
+	Data []AuditEntry `json:"data"`
+	Err  error        `json:"errors"`
+}
+
+// have AuditResponse follow the customError interface defined in a_transport.go
+func (r AuditResponse) error() error { return r.Err }
+
+type AuctionResponse struct {
+	Data Auction `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have AuctionResponse follow the customError interface defined in a_transport.go
+func (r AuctionResponse) error() error { return r.Err }
+
+type AuctionsResponse struct {
+	Data []Auction `json:"data"`
+	Err  error     `json:"errors"`
+}
+
+// have AuctionsResponse follow the customError interface defined in a_transport.go
+func (r AuctionsResponse) error() error { return r.Err }
+
+type BidsResponse struct {
+	Data []Bid `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have BidsResponse follow the customError interface defined in a_transport.go
+func (r BidsResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	CreateAuction endpoint.Endpoint
+	ReadAuction   endpoint.Endpoint
+	ListAuctions  endpoint.Endpoint
+	PlaceBid      endpoint.Endpoint
+	ReadBids      endpoint.Endpoint
+	ReadPrice     endpoint.Endpoint
+	Settle        endpoint.Endpoint
+	CommitBid     endpoint.Endpoint
+	RevealBid     endpoint.Endpoint
+	ReadAudit     endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		CreateAuction: epCreateAuction(s),
+		ReadAuction:   epReadAuction(s),
+		ListAuctions:  epListAuctions(s),
+		PlaceBid:      epPlaceBid(s),
+		ReadBids:      epReadBids(s),
+		ReadPrice:     epReadPrice(s),
+		Settle:        epSettle(s),
+		CommitBid:     epCommitBid(s),
+		RevealBid:     epRevealBid(s),
+		ReadAudit:     epReadAudit(s),
+	}
+}
+
+// create auction endpoint
+func epCreateAuction(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(CreateAuctionRequest)
+
+		// call service method
+		a, err := s.CreateAuction(ctx, req.Auction)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// read auction endpoint
+func epReadAuction(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadAuctionRequest)
+
+		// call service method
+		a, err := s.ReadAuction(ctx, req.Id)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// list auctions endpoint
+func epListAuctions(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListAuctionsRequest)
+
+		// call service method
+		auctions, err := s.ListAuctions(ctx, req.CollectionId)
+		if err != nil {
+			return AuctionsResponse{Err: err}, err
+		}
+
+		return AuctionsResponse{Data: auctions, Err: nil}, nil
+	}
+}
+
+// place bid endpoint
+func epPlaceBid(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(PlaceBidRequest)
+
+		// call service method
+		a, err := s.PlaceBid(ctx, req.Id, req.Address, req.Amount, req.Proof)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// bid ledger endpoint
+func epReadBids(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadBidsRequest)
+
+		// call service method
+		bids, err := s.ReadBids(ctx, req.Id)
+		if err != nil {
+			return BidsResponse{Err: err}, err
+		}
+
+		return BidsResponse{Data: bids, Err: nil}, nil
+	}
+}
+
+// dutch auction price endpoint
+func epReadPrice(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadPriceRequest)
+
+		// call service method
+		price, err := s.ReadPrice(ctx, req.Id, req.At)
+		if err != nil {
+			return PriceResponse{Err: err}, err
+		}
+
+		return PriceResponse{Data: price, Err: nil}, nil
+	}
+}
+
+// settle auction endpoint
+func epSettle(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(SettleRequest)
+
+		// call service method
+		a, err := s.Settle(ctx, req.Id)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// sealed bid commitment endpoint
+func epCommitBid(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(CommitBidRequest)
+
+		// call service method
+		a, err := s.CommitBid(ctx, req.Id, req.Address, req.Commitment)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// sealed bid reveal endpoint
+func epRevealBid(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(RevealBidRequest)
+
+		// call service method
+		a, err := s.RevealBid(ctx, req.Id, req.Amount, req.Salt)
+		if err != nil {
+			return AuctionResponse{Err: err}, err
+		}
+
+		return AuctionResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// sealed bid audit log endpoint
+func epReadAudit(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadAuditRequest)
+
+		// call service method
+		entries, err := s.ReadAudit(ctx, req.Id)
+		if err != nil {
+			return AuditResponse{Err: err}, err
+		}
+
+		return AuditResponse{Data: entries, Err: nil}, nil
+	}
+}
@@ -0,0 +1,161 @@
+package auction
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"time"
+)
+
+// ******** Dutch auction **********
+
+type Curve string
+
+const (
+
+	// price falls continuously from start to floor price over the auction
+	CurveLinear Curve = "linear"
+
+	// price falls in equal steps every step_interval seconds, reaching the floor price at the end
+	CurveStepwise Curve = "stepwise"
+
+	// distance between price and floor price halves every half_life seconds
+	CurveExponential Curve = "exponential"
+)
+
+// price curve and supply of a dutch auction
+// step_interval and half_life are seconds and only used by their curve
+type Dutch struct {
+	StartPrice   string `json:"start_price"`
+	FloorPrice   string `json:"floor_price"`
+	Curve        Curve  `json:"curve"`
+	StepInterval int    `json:"step_interval,omitempty"`
+	HalfLife     int    `json:"half_life,omitempty"`
+	Quantity     int    `json:"quantity"`
+}
+
+// price at a point in time, the price at the start before it and at the end after it
+type Price struct {
+	Price string    `json:"price"`
+	At    time.Time `json:"at"`
+}
+
+// validates the curve for an auction of the given duration
+func (d *Dutch) validate(duration time.Duration) error {
+
+	start, err := parseAmount(d.StartPrice, false)
+	if err != nil {
+		return errors.New("invalid start price")
+	}
+	floor, err := parseAmount(d.FloorPrice, true)
+	if err != nil || floor.Cmp(start) > 0 {
+		return errors.New("floor price must not exceed the start price")
+	}
+	if d.Quantity < 1 {
+		return errors.New("quantity must be positive")
+	}
+
+	switch d.Curve {
+	case CurveLinear:
+	case CurveStepwise:
+		if d.StepInterval <= 0 || time.Duration(d.StepInterval)*time.Second > duration {
+			return errors.New("step interval must be positive and within the auction")
+		}
+	case CurveExponential:
+		if d.HalfLife <= 0 {
+			return errors.New("half life must be positive")
+		}
+	default:
+		return errors.New("unknown price curve")
+	}
+
+	return nil
+}
+
+// Price of one unit at a point in time, never below the floor price
+func (d *Dutch) Price(startsAt, endsAt, now time.Time) (*big.Int, error) {
+
+	start, err := parseAmount(d.StartPrice, false)
+	if err != nil {
+		return nil, err
+	}
+	floor, err := parseAmount(d.FloorPrice, true)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := endsAt.Sub(startsAt)
+	elapsed := now.Sub(startsAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > duration {
+		elapsed = duration
+	}
+
+	span := new(big.Int).Sub(start, floor)
+	drop := new(big.Int)
+
+	switch d.Curve {
+
+	case CurveLinear:
+		drop.Mul(span, big.NewInt(int64(elapsed)))
+		drop.Quo(drop, big.NewInt(int64(duration)))
+
+	case CurveStepwise:
+		interval := time.Duration(d.StepInterval) * time.Second
+		steps := int64(elapsed / interval)
+		total := int64(duration / interval)
+		drop.Mul(span, big.NewInt(steps))
+		drop.Quo(drop, big.NewInt(total))
+
+	case CurveExponential:
+		factor := math.Exp2(-elapsed.Seconds() / float64(d.HalfLife))
+		remaining, _ := new(big.Float).Mul(new(big.Float).SetInt(span), big.NewFloat(factor)).Int(nil)
+		drop.Sub(span, remaining)
+
+	default:
+		return nil, ErrInvalidAuction
+	}
+
+	price := start.Sub(start, drop)
+	if price.Cmp(floor) < 0 {
+		price.Set(floor)
+	}
+
+	return price, nil
+}
+
+// buys one unit at the current price
+// amount is the highest price the buyer accepts, the ledger records the price paid
+// callers must serialise calls per auction
+func placeDutchBid(a *Auction, userId, address string, amount *big.Int, now time.Time) (Bid, error) {
+
+	if a.status(now) != StatusLive {
+		return Bid{}, ErrAuctionNotLive
+	}
+
+	if err := checkBidderWallet(a, userId, address); err != nil {
+		return Bid{}, err
+	}
+
+	price, err := a.Dutch.Price(a.StartsAt, a.EndsAt, now)
+	if err != nil {
+		return Bid{}, err
+	}
+	if amount.Cmp(price) < 0 {
+		return Bid{}, ErrBidTooLow
+	}
+
+	bid := Bid{
+		Seq:      len(a.Bids) + 1,
+		UserId:   userId,
+		Address:  address,
+		Amount:   price.String(),
+		PlacedAt: now,
+		EndsAt:   a.EndsAt,
+	}
+	a.Bids = append(a.Bids, bid)
+
+	return bid, nil
+}
@@ -0,0 +1,88 @@
+package auction
+
+import (
+	"testing"
+	"time"
+
+	"website/signing"
+)
+
+func TestDutchPrice(t *testing.T) {
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Minute)
+
+	for _, tc := range []struct {
+		dutch    Dutch
+		elapsed  time.Duration
+		expected int64
+	}{
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveLinear}, -time.Minute, 1000},
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveLinear}, 25 * time.Minute, 800},
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveLinear}, 200 * time.Minute, 200},
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveStepwise, StepInterval: 1200}, 39 * time.Minute, 840},
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveStepwise, StepInterval: 1200}, 100 * time.Minute, 200},
+		{Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveExponential, HalfLife: 600}, 20 * time.Minute, 400},
+	} {
+		price, err := tc.dutch.Price(start, end, start.Add(tc.elapsed))
+		if err != nil {
+			t.Fatalf("Price failed, error: %v.", err)
+		}
+		if price.Int64() != tc.expected {
+			t.Errorf("%s price after %v is %s, expected %d.", tc.dutch.Curve, tc.elapsed, price, tc.expected)
+		}
+	}
+}
+
+func TestDutchSettlement(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("operator")
+
+	a, err := s.CreateAuction(ctx, Auction{
+		CollectionId: "c",
+		Mode:         ModeDutch,
+		StartsAt:     now,
+		EndsAt:       now.Add(100 * time.Minute),
+		Dutch:        &Dutch{StartPrice: "1000", FloorPrice: "200", Curve: CurveLinear, Quantity: 2},
+	})
+	if err != nil {
+		t.Fatalf("CreateAuction failed, error: %v.", err)
+	}
+
+	// price above the buyer's limit
+	if _, err := s.PlaceBid(withSession("alice"), a.Id, walletA, "999", signing.Proof{}); err != ErrBidTooLow {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrBidTooLow)
+	}
+	if _, err := s.PlaceBid(withSession("alice"), a.Id, walletA, "1000", signing.Proof{}); err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+
+	if _, err := s.Settle(ctx, a.Id); err != ErrNotEnded {
+		t.Errorf("Settle returned %v, expected %v.", err, ErrNotEnded)
+	}
+
+	// second unit sells for less and ends the auction
+	now = now.Add(50 * time.Minute)
+	price, _ := s.ReadPrice(ctx, a.Id, time.Time{})
+	if price.Price != "600" {
+		t.Errorf("ReadPrice returned %s, expected 600.", price.Price)
+	}
+	a, err = s.PlaceBid(withSession("bob"), a.Id, walletB, "1000", signing.Proof{})
+	if err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+	if a.Status != StatusEnded || a.Bids[1].Amount != "600" {
+		t.Errorf("auction %s after sale at %s, expected ended after sale at 600.", a.Status, a.Bids[1].Amount)
+	}
+
+	a, err = s.Settle(ctx, a.Id)
+	if err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	st := a.Settlement
+	if st.ClearingPrice != "600" || len(st.Winners) != 2 || st.Winners[0].Refund != "400" || st.Winners[1].Refund != "0" {
+		t.Errorf("unexpected settlement %+v.", st)
+	}
+}
@@ -0,0 +1,95 @@
+package auction
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+// ******** English auction **********
+
+var ErrInvalidAmount = errors.New("Invalid amount")
+
+var ErrAuctionNotLive = errors.New("Auction is not accepting bids")
+
+var ErrBidTooLow = errors.New("Bid is below the minimum bid")
+
+var ErrAlreadyLeading = errors.New("Bidder already holds the highest bid")
+
+var ErrWalletMismatch = errors.New("Bidders must use one wallet per auction and wallets cannot be shared")
+
+// lowest acceptable next bid
+// the reserve price for the first bid, the highest bid plus the minimum increment afterwards
+func minimumBid(a *Auction) (*big.Int, error) {
+
+	leader := a.leader()
+	if leader == nil {
+		return parseAmount(a.ReservePrice, true)
+	}
+
+	highest, err := parseAmount(leader.Amount, true)
+	if err != nil {
+		return nil, err
+	}
+	increment, err := parseAmount(a.MinIncrement, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return highest.Add(highest, increment), nil
+}
+
+// validates a bid against the auction state and appends it to the ledger
+// callers must serialise calls per auction
+func placeEnglishBid(a *Auction, userId, address string, amount *big.Int, now time.Time) (Bid, error) {
+
+	if a.status(now) != StatusLive {
+		return Bid{}, ErrAuctionNotLive
+	}
+
+	if err := checkBidderWallet(a, userId, address); err != nil {
+		return Bid{}, err
+	}
+
+	if leader := a.leader(); leader != nil && leader.UserId == userId {
+		return Bid{}, ErrAlreadyLeading
+	}
+
+	min, err := minimumBid(a)
+	if err != nil {
+		return Bid{}, err
+	}
+	if amount.Cmp(min) < 0 {
+		return Bid{}, ErrBidTooLow
+	}
+
+	// soft close, bids in the final window extend the auction
+	window := time.Duration(a.ExtensionWindow) * time.Second
+	if a.EndsAt.Sub(now) <= window {
+		if end := now.Add(time.Duration(a.Extension) * time.Second); end.After(a.EndsAt) {
+			a.EndsAt = end
+		}
+	}
+
+	bid := Bid{
+		Seq:      len(a.Bids) + 1,
+		UserId:   userId,
+		Address:  address,
+		Amount:   amount.String(),
+		PlacedAt: now,
+		EndsAt:   a.EndsAt,
+	}
+	a.Bids = append(a.Bids, bid)
+
+	return bid, nil
+}
+
+// one wallet per bidder and one bidder per wallet, settlement pays out to a single address
+func checkBidderWallet(a *Auction, userId, address string) error {
+	for _, b := range a.Bids {
+		if (b.UserId == userId) != (b.Address == address) {
+			return ErrWalletMismatch
+		}
+	}
+	return nil
+}
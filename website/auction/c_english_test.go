@@ -0,0 +1,202 @@
+package auction
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"website/collection"
+	"website/session"
+	"website/signing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// sessions of fixed users, the session identifier is the file hash
+type staticSessions map[string]time.Time
+
+func (ss staticSessions) ReadSession(sessionId string) (session.Session, error) {
+	return session.Session{FileHash: sessionId, ExpiresAt: ss[sessionId]}, nil
+}
+
+// context of a request with a session cookie
+func withSession(sessionId string) context.Context {
+	return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), sessionId)
+}
+
+// collections by identifier
+type staticCollections map[string]collection.Collection
+
+func (cs staticCollections) ReadCollection(ctx context.Context, id string) (collection.Collection, error) {
+	c, ok := cs[id]
+	if !ok {
+		return collection.Collection{}, collection.ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+// service with a temporary store and a controllable clock
+// collection c belongs to the artist, the operator settles
+func testService(t *testing.T, now *time.Time) *service {
+	store := NewAuctionStore(AuctionStoreConfig{AuctionsPath: t.TempDir()}, log.NewNopLogger())
+	sessions := staticSessions{"alice": now.Add(24 * time.Hour), "bob": now.Add(24 * time.Hour), "eve": now.Add(-time.Hour), "artist": now.Add(24 * time.Hour), "operator": now.Add(24 * time.Hour)}
+	collections := staticCollections{"c": {Id: "c", Owner: session.Session{FileHash: "artist"}.UserId()}}
+	operators := []string{session.Session{FileHash: "operator"}.UserId()}
+	s := NewService(store, collections, sessions, nil, nil, operators, log.NewNopLogger()).(*service)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+const (
+	walletA = "0x000000000000000000000000000000000000000a"
+	walletB = "0x000000000000000000000000000000000000000b"
+)
+
+func TestEnglishAuction(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+
+	a, err := s.CreateAuction(withSession("artist"), Auction{
+		CollectionId:    "c",
+		ReservePrice:    "100",
+		MinIncrement:    "10",
+		StartsAt:        now,
+		EndsAt:          now.Add(time.Hour),
+		ExtensionWindow: 300,
+		Extension:       600,
+	})
+	if err != nil {
+		t.Fatalf("CreateAuction failed, error: %v.", err)
+	}
+
+	alice, bob := withSession("alice"), withSession("bob")
+
+	if _, err := s.PlaceBid(context.Background(), a.Id, walletA, "100", signing.Proof{}); err != ErrUnauthorized {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := s.PlaceBid(withSession("eve"), a.Id, walletA, "100", signing.Proof{}); err != ErrUnauthorized {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := s.PlaceBid(alice, a.Id, walletA, "99", signing.Proof{}); err != ErrBidTooLow {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrBidTooLow)
+	}
+	if _, err := s.PlaceBid(alice, a.Id, walletA, "100", signing.Proof{}); err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+	if _, err := s.PlaceBid(alice, a.Id, walletA, "200", signing.Proof{}); err != ErrAlreadyLeading {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrAlreadyLeading)
+	}
+	if _, err := s.PlaceBid(bob, a.Id, walletA, "110", signing.Proof{}); err != ErrWalletMismatch {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrWalletMismatch)
+	}
+	if _, err := s.PlaceBid(bob, a.Id, walletB, "109", signing.Proof{}); err != ErrBidTooLow {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrBidTooLow)
+	}
+
+	// bid in the final minutes extends the auction
+	now = now.Add(58 * time.Minute)
+	a, err = s.PlaceBid(bob, a.Id, walletB, "110", signing.Proof{})
+	if err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+	if !a.EndsAt.Equal(now.Add(10*time.Minute)) || a.MinimumBid != "120" {
+		t.Errorf("auction ends at %v with minimum bid %s, expected %v and 120.", a.EndsAt, a.MinimumBid, now.Add(10*time.Minute))
+	}
+	if a.Bids[1].UserId != "" {
+		t.Errorf("public bids expose user identifiers.")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := s.PlaceBid(alice, a.Id, walletA, "500", signing.Proof{}); err != ErrAuctionNotLive {
+		t.Errorf("PlaceBid returned %v, expected %v.", err, ErrAuctionNotLive)
+	}
+}
+
+func TestConcurrentBids(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+
+	a, _ := s.CreateAuction(withSession("artist"), Auction{
+		CollectionId: "c",
+		ReservePrice: "0",
+		MinIncrement: "1",
+		StartsAt:     now,
+		EndsAt:       now.Add(time.Hour),
+	})
+
+	// both bidders race with the same amounts, each amount is accepted at most once
+	var wg sync.WaitGroup
+	for _, bidder := range []struct{ session, wallet string }{{"alice", walletA}, {"bob", walletB}} {
+		wg.Add(1)
+		go func(sessionId, wallet string) {
+			defer wg.Done()
+			for amount := 1; amount <= 50; amount++ {
+				s.PlaceBid(withSession(sessionId), a.Id, wallet, strconv.Itoa(amount), signing.Proof{})
+			}
+		}(bidder.session, bidder.wallet)
+	}
+	wg.Wait()
+
+	bids, err := s.ReadBids(context.Background(), a.Id)
+	if err != nil {
+		t.Fatalf("ReadBids failed, error: %v.", err)
+	}
+	if len(bids) == 0 {
+		t.Fatalf("no bids accepted.")
+	}
+	for i := 1; i < len(bids); i++ {
+		prev, _ := strconv.Atoi(bids[i-1].Amount)
+		amount, _ := strconv.Atoi(bids[i].Amount)
+		if bids[i].Seq != i+1 || bids[i].Address == bids[i-1].Address || amount <= prev {
+			t.Fatalf("inconsistent ledger at bid %d: %+v.", i, bids)
+		}
+	}
+}
+
+// only the collection's owner or an operator creates auctions, only operators settle
+func TestAuctionAuthorization(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	input := Auction{CollectionId: "c", ReservePrice: "0", MinIncrement: "1", StartsAt: now, EndsAt: now.Add(time.Hour)}
+
+	for _, c := range []struct {
+		ctx          context.Context
+		collectionId string
+		expected     error
+	}{
+		{context.Background(), "c", ErrUnauthorized},
+		{withSession("eve"), "c", ErrUnauthorized},
+		{withSession("alice"), "c", ErrNotOwner},
+		{withSession("artist"), "missing", collection.ErrCollectionNotFound},
+	} {
+		a := input
+		a.CollectionId = c.collectionId
+		if _, err := s.CreateAuction(c.ctx, a); err != c.expected {
+			t.Errorf("CreateAuction of %s returned %v, expected %v.", c.collectionId, err, c.expected)
+		}
+	}
+
+	if _, err := s.CreateAuction(withSession("operator"), input); err != nil {
+		t.Fatalf("CreateAuction by an operator failed, error: %v.", err)
+	}
+	a, err := s.CreateAuction(withSession("artist"), input)
+	if err != nil {
+		t.Fatalf("CreateAuction by the owner failed, error: %v.", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := s.Settle(context.Background(), a.Id); err != ErrUnauthorized {
+		t.Errorf("Settle without a session returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := s.Settle(withSession("artist"), a.Id); err != ErrNotOperator {
+		t.Errorf("Settle by the owner returned %v, expected %v.", err, ErrNotOperator)
+	}
+	if a, err = s.Settle(withSession("operator"), a.Id); err != nil || a.Settlement == nil {
+		t.Errorf("Settle by an operator returned %+v, error: %v.", a.Settlement, err)
+	}
+}
@@ -0,0 +1,133 @@
+package auction
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Auction events **********
+
+type EventType string
+
+const (
+	EventBid      EventType = "bid"
+	EventExtended EventType = "extended"
+	EventStatus   EventType = "status"
+	EventSettled  EventType = "settled"
+	EventCommit   EventType = "commit"
+	EventReveal   EventType = "reveal"
+)
+
+// emitted to subscribers after a change has been stored
+// the auction is the public view at the time of the event
+type Event struct {
+	Type    EventType `json:"type"`
+	Auction Auction   `json:"auction"`
+	Bid     *Bid      `json:"bid,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// called synchronously for every emitted event
+type EventHandler func(Event)
+
+// service struct advance method
+// emits a status event for every auction whose derived status changed since the last call
+// auctions seen for the first time are recorded without an event
+func (s *service) Advance(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Advance")
+
+	auctions, err := s.auctionStore.ReadAuctions()
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuctions:", err)
+		return err
+	}
+
+	now := s.now()
+	var events []Event
+
+	s.statusesMu.Lock()
+	for _, a := range auctions {
+		status := a.status(now)
+		last, seen := s.statuses[a.Id]
+		s.statuses[a.Id] = status
+		if seen && last != status {
+			events = append(events, Event{Type: EventStatus, Auction: a.Public(now), At: now})
+		}
+	}
+	s.statusesMu.Unlock()
+
+	for _, e := range events {
+		s.emit(e)
+	}
+
+	return nil
+}
+
+// registers a handler which is called for every auction event
+func (s *service) Subscribe(h EventHandler) {
+	s.handlersMu.Lock()
+	s.handlers = append(s.handlers, h)
+	s.handlersMu.Unlock()
+}
+
+// emits an event to all subscribers
+func (s *service) emit(event Event) {
+
+	s.handlersMu.RLock()
+	handlers := s.handlers
+	s.handlersMu.RUnlock()
+
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// remembers the status of an auction so that Advance only reports later changes
+func (s *service) track(a Auction, now time.Time) {
+	s.statusesMu.Lock()
+	s.statuses[a.Id] = a.status(now)
+	s.statusesMu.Unlock()
+}
+
+// Scheduler periodically emits status events of auctions that started, ended or finished revealing
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and checks auction statuses every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+
+		if err := sc.service.Advance(ctx); err != nil {
+			level.Error(logger).Log("sc.service.Advance:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
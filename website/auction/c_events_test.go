@@ -0,0 +1,71 @@
+package auction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"website/signing"
+)
+
+func TestAuctionEvents(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+
+	var events []Event
+	s.Subscribe(func(e Event) { events = append(events, e) })
+
+	a, err := s.CreateAuction(withSession("artist"), Auction{
+		CollectionId:    "c",
+		ReservePrice:    "100",
+		MinIncrement:    "10",
+		StartsAt:        now.Add(time.Minute),
+		EndsAt:          now.Add(time.Hour),
+		ExtensionWindow: 300,
+		Extension:       600,
+	})
+	if err != nil {
+		t.Fatalf("CreateAuction failed, error: %v.", err)
+	}
+
+	// no change yet
+	if err := s.Advance(context.Background()); err != nil || len(events) != 0 {
+		t.Fatalf("Advance emitted %d events, error: %v.", len(events), err)
+	}
+
+	now = now.Add(time.Minute)
+	s.Advance(context.Background())
+	if len(events) != 1 || events[0].Type != EventStatus || events[0].Auction.Status != StatusLive {
+		t.Fatalf("Advance emitted %+v, expected a live status event.", events)
+	}
+
+	if _, err := s.PlaceBid(withSession("alice"), a.Id, walletA, "100", signing.Proof{}); err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+	if len(events) != 2 || events[1].Type != EventBid || events[1].Bid.Amount != "100" || events[1].Bid.UserId != "" {
+		t.Fatalf("PlaceBid emitted %+v, expected a public bid event.", events[1:])
+	}
+
+	// a bid in the extension window also announces the new end
+	now = a.EndsAt.Add(-time.Minute)
+	if _, err := s.PlaceBid(withSession("bob"), a.Id, walletB, "110", signing.Proof{}); err != nil {
+		t.Fatalf("PlaceBid failed, error: %v.", err)
+	}
+	if len(events) != 4 || events[2].Type != EventBid || events[3].Type != EventExtended || !events[3].Auction.EndsAt.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("PlaceBid emitted %+v, expected bid and extended events.", events[2:])
+	}
+
+	now = now.Add(10 * time.Minute)
+	s.Advance(context.Background())
+	if len(events) != 5 || events[4].Auction.Status != StatusEnded {
+		t.Fatalf("Advance emitted %+v, expected an ended status event.", events[4:])
+	}
+
+	// settling twice emits once
+	s.Settle(withSession("operator"), a.Id)
+	s.Settle(withSession("operator"), a.Id)
+	if len(events) != 6 || events[5].Type != EventSettled || events[5].Auction.Settlement.Winners[0].Address != walletB {
+		t.Errorf("Settle emitted %+v, expected one settled event.", events[5:])
+	}
+}
@@ -0,0 +1,248 @@
+package auction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ******** Sealed-bid second-price auction **********
+
+// domain separation of bid commitments
+const sealedVersion = "art-token-sealed-bid-v1"
+
+var ErrAlreadyCommitted = errors.New("Bidder already committed a bid")
+
+var ErrNoCommitment = errors.New("Bidder has no commitment in this auction")
+
+var ErrAlreadyRevealed = errors.New("Bid already revealed")
+
+var ErrCommitmentMismatch = errors.New("Bid and salt do not match the commitment")
+
+var ErrNotRevealing = errors.New("Auction is not in its reveal window")
+
+var ErrInvalidCommitment = errors.New("Invalid commitment")
+
+// hex encoded sha256 digest
+var commitmentPattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// salts are hex strings of at least 16 bytes
+var saltPattern = regexp.MustCompile("^[0-9a-f]{32,128}$")
+
+// reveal window of a sealed-bid auction, bids are committed between starts_at and ends_at
+type Sealed struct {
+	RevealEndsAt time.Time `json:"reveal_ends_at"`
+}
+
+// a committed bid, amount and salt are set once revealed
+type SealedBid struct {
+	Seq         int        `json:"seq"`
+	UserId      string     `json:"user_id,omitempty"`
+	Address     string     `json:"address"`
+	Commitment  string     `json:"commitment"`
+	CommittedAt time.Time  `json:"committed_at"`
+	Amount      string     `json:"amount,omitempty"`
+	Salt        string     `json:"salt,omitempty"`
+	RevealedAt  *time.Time `json:"revealed_at,omitempty"`
+}
+
+type AuditEvent string
+
+const (
+	AuditCommit  AuditEvent = "commit"
+	AuditReveal  AuditEvent = "reveal"
+	AuditForfeit AuditEvent = "forfeit"
+	AuditSettle  AuditEvent = "settle"
+)
+
+// append-only log of a sealed-bid auction
+type AuditEntry struct {
+	Seq        int        `json:"seq"`
+	Event      AuditEvent `json:"event"`
+	Address    string     `json:"address,omitempty"`
+	Commitment string     `json:"commitment,omitempty"`
+	Amount     string     `json:"amount,omitempty"`
+	Salt       string     `json:"salt,omitempty"`
+	At         time.Time  `json:"at"`
+}
+
+// Commitment of a sealed bid, sha256 over version, auction, bidder address, amount in wei and salt
+// binding auction and address prevents copying another bidder's commitment
+func Commitment(auctionId, address, amount, salt string) string {
+	h := sha256.New()
+	for _, part := range []string{sealedVersion, auctionId, strings.ToLower(address), amount, strings.ToLower(salt)} {
+		h.Write([]byte(part))
+		h.Write([]byte{'|'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// records a bid commitment during the bidding window
+// callers must serialise calls per auction
+func commitBid(a *Auction, userId, address, commitment string, now time.Time) error {
+
+	if a.Mode != ModeSealed {
+		return ErrWrongMode
+	}
+	if a.status(now) != StatusLive {
+		return ErrAuctionNotLive
+	}
+
+	commitment = strings.ToLower(commitment)
+	if !commitmentPattern.MatchString(commitment) {
+		return ErrInvalidCommitment
+	}
+
+	for _, c := range a.Commits {
+		if c.UserId == userId || c.Address == address {
+			return ErrAlreadyCommitted
+		}
+	}
+
+	a.Commits = append(a.Commits, SealedBid{
+		Seq:         len(a.Commits) + 1,
+		UserId:      userId,
+		Address:     address,
+		Commitment:  commitment,
+		CommittedAt: now,
+	})
+	a.audit(AuditEntry{Event: AuditCommit, Address: address, Commitment: commitment, At: now})
+
+	return nil
+}
+
+// opens the commitment of a bidder during the reveal window
+// callers must serialise calls per auction
+func revealBid(a *Auction, userId string, amount *big.Int, salt string, now time.Time) error {
+
+	if a.Mode != ModeSealed {
+		return ErrWrongMode
+	}
+	if a.status(now) != StatusRevealing {
+		return ErrNotRevealing
+	}
+
+	salt = strings.ToLower(salt)
+	if !saltPattern.MatchString(salt) {
+		return ErrCommitmentMismatch
+	}
+
+	for i := range a.Commits {
+		c := &a.Commits[i]
+		if c.UserId != userId {
+			continue
+		}
+		if c.RevealedAt != nil {
+			return ErrAlreadyRevealed
+		}
+		if Commitment(a.Id, c.Address, amount.String(), salt) != c.Commitment {
+			return ErrCommitmentMismatch
+		}
+
+		c.Amount = amount.String()
+		c.Salt = salt
+		c.RevealedAt = &now
+		a.audit(AuditEntry{Event: AuditReveal, Address: c.Address, Commitment: c.Commitment, Amount: c.Amount, Salt: salt, At: now})
+
+		return nil
+	}
+
+	return ErrNoCommitment
+}
+
+// settles a sealed-bid auction after the reveal window
+// unrevealed bids are forfeited, bids below the reserve price lose
+// the highest bid wins and pays the second highest valid bid, or the reserve price without competition
+// ties go to the earlier commitment
+func settleSealed(a *Auction, settlement *Settlement, now time.Time) error {
+
+	reserve, err := parseAmount(a.ReservePrice, true)
+	if err != nil {
+		return err
+	}
+
+	type valid struct {
+		bid    SealedBid
+		amount *big.Int
+	}
+	var bids []valid
+
+	for _, c := range a.Commits {
+		if c.RevealedAt == nil {
+			settlement.Forfeited = append(settlement.Forfeited, c.Address)
+			a.audit(AuditEntry{Event: AuditForfeit, Address: c.Address, Commitment: c.Commitment, At: now})
+			continue
+		}
+		amount, err := parseAmount(c.Amount, true)
+		if err != nil {
+			return err
+		}
+		if amount.Cmp(reserve) >= 0 {
+			bids = append(bids, valid{bid: c, amount: amount})
+		}
+	}
+
+	sort.SliceStable(bids, func(i, j int) bool {
+		return bids[i].amount.Cmp(bids[j].amount) > 0
+	})
+
+	if len(bids) > 0 {
+		price := reserve
+		if len(bids) > 1 {
+			price = bids[1].amount
+		}
+		winner := bids[0]
+		settlement.ClearingPrice = price.String()
+		settlement.Winners = append(settlement.Winners, Winner{
+			Seq:     winner.bid.Seq,
+			UserId:  winner.bid.UserId,
+			Address: winner.bid.Address,
+			Paid:    winner.amount.String(),
+			Price:   price.String(),
+			Refund:  new(big.Int).Sub(winner.amount, price).String(),
+		})
+	}
+
+	a.audit(AuditEntry{Event: AuditSettle, Amount: settlement.ClearingPrice, At: now})
+
+	return nil
+}
+
+// appends an entry to the audit log
+func (a *Auction) audit(e AuditEntry) {
+	e.Seq = len(a.Audit) + 1
+	a.Audit = append(a.Audit, e)
+}
+
+// sealed bids for clients, amounts and salts stay hidden until the reveal window closes
+// so that bidders cannot decide whether to reveal based on other bids
+func publicCommits(commits []SealedBid, hidden bool) []SealedBid {
+	public := make([]SealedBid, len(commits))
+	for i, c := range commits {
+		c.UserId = ""
+		if hidden {
+			c.Amount = ""
+			c.Salt = ""
+		}
+		public[i] = c
+	}
+	return public
+}
+
+// audit log for clients, reveal details stay hidden until the reveal window closes
+func publicAudit(log []AuditEntry, hidden bool) []AuditEntry {
+	public := make([]AuditEntry, len(log))
+	for i, e := range log {
+		if hidden {
+			e.Amount = ""
+			e.Salt = ""
+		}
+		public[i] = e
+	}
+	return public
+}
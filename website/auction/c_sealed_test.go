@@ -0,0 +1,90 @@
+package auction
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSealedAuction(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	s.sessions = staticSessions{"alice": now.Add(24 * time.Hour), "bob": now.Add(24 * time.Hour), "carol": now.Add(24 * time.Hour), "operator": now.Add(24 * time.Hour)}
+	ctx := withSession("operator")
+
+	a, err := s.CreateAuction(ctx, Auction{
+		CollectionId: "c",
+		Mode:         ModeSealed,
+		ReservePrice: "100",
+		StartsAt:     now,
+		EndsAt:       now.Add(time.Hour),
+		Sealed:       &Sealed{RevealEndsAt: now.Add(2 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("CreateAuction failed, error: %v.", err)
+	}
+
+	const walletC = "0x000000000000000000000000000000000000000c"
+	salt := "00112233445566778899aabbccddeeff"
+	bids := []struct{ session, wallet, amount string }{
+		{"alice", walletA, "500"},
+		{"bob", walletB, "300"},
+		{"carol", walletC, "900"},
+	}
+	for _, b := range bids {
+		if _, err := s.CommitBid(withSession(b.session), a.Id, b.wallet, Commitment(a.Id, b.wallet, b.amount, salt)); err != nil {
+			t.Fatalf("CommitBid failed, error: %v.", err)
+		}
+	}
+	if _, err := s.CommitBid(withSession("alice"), a.Id, walletA, Commitment(a.Id, walletA, "1", salt)); err != ErrAlreadyCommitted {
+		t.Errorf("CommitBid returned %v, expected %v.", err, ErrAlreadyCommitted)
+	}
+
+	// reveals only after bidding closed
+	if _, err := s.RevealBid(withSession("alice"), a.Id, "500", salt); err != ErrNotRevealing {
+		t.Errorf("RevealBid returned %v, expected %v.", err, ErrNotRevealing)
+	}
+
+	now = now.Add(90 * time.Minute)
+	if _, err := s.RevealBid(withSession("alice"), a.Id, "501", salt); err != ErrCommitmentMismatch {
+		t.Errorf("RevealBid returned %v, expected %v.", err, ErrCommitmentMismatch)
+	}
+	if _, err := s.RevealBid(withSession("alice"), a.Id, "500", salt); err != nil {
+		t.Fatalf("RevealBid failed, error: %v.", err)
+	}
+	a, err = s.RevealBid(withSession("bob"), a.Id, "300", salt)
+	if err != nil {
+		t.Fatalf("RevealBid failed, error: %v.", err)
+	}
+	if a.Commits[0].Amount != "" {
+		t.Errorf("revealed amounts visible during the reveal window.")
+	}
+
+	// carol does not reveal and forfeits, alice wins at bob's price
+	if _, err := s.Settle(ctx, a.Id); err != ErrNotEnded {
+		t.Errorf("Settle returned %v, expected %v.", err, ErrNotEnded)
+	}
+	now = now.Add(time.Hour)
+	a, err = s.Settle(ctx, a.Id)
+	if err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	st := a.Settlement
+	if len(st.Winners) != 1 || st.Winners[0].Address != walletA || st.Winners[0].Price != "300" || st.Winners[0].Refund != "200" {
+		t.Errorf("unexpected settlement %+v.", st)
+	}
+	if len(st.Forfeited) != 1 || st.Forfeited[0] != walletC {
+		t.Errorf("forfeited %v, expected %s.", st.Forfeited, walletC)
+	}
+
+	audit, _ := s.ReadAudit(ctx, a.Id)
+	events := []AuditEvent{AuditCommit, AuditCommit, AuditCommit, AuditReveal, AuditReveal, AuditForfeit, AuditSettle}
+	if len(audit) != len(events) {
+		t.Fatalf("audit log has %d entries, expected %d.", len(audit), len(events))
+	}
+	for i, e := range events {
+		if audit[i].Event != e || audit[i].Seq != i+1 {
+			t.Errorf("audit entry %d is %+v, expected %s.", i, audit[i], e)
+		}
+	}
+}
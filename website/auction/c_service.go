@@ -0,0 +1,570 @@
+package auction
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"website/collection"
+	"website/session"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrWalletNotLinked = errors.New("Wallet is not linked to the user")
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrWrongMode = errors.New("Operation not supported by the auction mode")
+
+var ErrNotOwner = errors.New("Only the owner of the collection or an operator can create auctions")
+
+var ErrNotOperator = errors.New("Only operators can settle auctions")
+
+// hex encoded 20 byte ethereum address
+var addressPattern = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// Collections reads collections, implemented by the collection service
+type Collections interface {
+	ReadCollection(ctx context.Context, id string) (collection.Collection, error)
+}
+
+// Wallets checks whether a wallet address belongs to a user
+type Wallets interface {
+	IsLinked(ctx context.Context, userId string, address string) (bool, error)
+}
+
+// BidVerifier checks wallet signatures of bids, implemented by the signing service
+type BidVerifier interface {
+	VerifyBid(ctx context.Context, b signing.Bid, signature string) error
+}
+
+// service interface defining all required methods
+type Service interface {
+	CreateAuction(ctx context.Context, a Auction) (Auction, error)
+	ReadAuction(ctx context.Context, id string) (Auction, error)
+	ListAuctions(ctx context.Context, collectionId string) ([]Auction, error)
+	PlaceBid(ctx context.Context, id string, address string, amount string, proof signing.Proof) (Auction, error)
+	ReadBids(ctx context.Context, id string) ([]Bid, error)
+	ReadPrice(ctx context.Context, id string, at time.Time) (Price, error)
+	Settle(ctx context.Context, id string) (Auction, error)
+	Finalize(ctx context.Context, id string) (Auction, error)
+	CommitBid(ctx context.Context, id string, address string, commitment string) (Auction, error)
+	RevealBid(ctx context.Context, id string, amount string, salt string) (Auction, error)
+	ReadAudit(ctx context.Context, id string) ([]AuditEntry, error)
+	Advance(ctx context.Context) error
+	Subscribe(h EventHandler)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// one lock per auction, bids on an auction are applied one at a time
+	locks   map[string]*sync.Mutex
+	locksMu sync.Mutex
+
+	// last known status per auction, see Advance
+	statuses   map[string]Status
+	statusesMu sync.Mutex
+
+	handlers   []EventHandler
+	handlersMu sync.RWMutex
+
+	auctionStore AuctionStore
+	collections  Collections
+	sessions     Sessions
+	wallets      Wallets
+	verifier     BidVerifier
+	operators    map[string]bool
+	now          func() time.Time
+	logger       log.Logger
+}
+
+// service struct create auction method
+// only the owner of an existing collection or an operator puts its tokens up for auction
+func (s *service) CreateAuction(ctx context.Context, a Auction) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "CreateAuction")
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	if a.Mode == "" {
+		a.Mode = ModeEnglish
+	}
+	if err := a.Validate(); err != nil {
+		return Auction{}, err
+	}
+
+	c, err := s.collections.ReadCollection(ctx, a.CollectionId)
+	if err != nil {
+		level.Error(logger).Log("s.collections.ReadCollection:", err)
+		return Auction{}, err
+	}
+	if c.Owner != userId && !s.operators[userId] {
+		return Auction{}, ErrNotOwner
+	}
+
+	id, err := newId()
+	if err != nil {
+		level.Error(logger).Log("newId:", err)
+		return Auction{}, err
+	}
+
+	now := s.now()
+	a.Id = id
+	a.Bids = []Bid{}
+	a.CreatedAt = now
+	a.UpdatedAt = now
+
+	if err := s.auctionStore.WriteAuction(a); err != nil {
+		level.Error(logger).Log("s.auctionStore.WriteAuction:", err)
+		return Auction{}, err
+	}
+	s.track(a, now)
+
+	return a.Public(now), nil
+}
+
+// service struct read auction method
+func (s *service) ReadAuction(ctx context.Context, id string) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadAuction")
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Auction{}, err
+	}
+
+	return a.Public(s.now()), nil
+}
+
+// service struct list auctions method
+// lists all auctions, or the auctions of one collection
+func (s *service) ListAuctions(ctx context.Context, collectionId string) ([]Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ListAuctions")
+
+	auctions, err := s.auctionStore.ReadAuctions()
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuctions:", err)
+		return nil, err
+	}
+
+	now := s.now()
+	list := make([]Auction, 0, len(auctions))
+	for _, a := range auctions {
+		if collectionId == "" || a.CollectionId == collectionId {
+			list = append(list, a.Public(now))
+		}
+	}
+
+	return list, nil
+}
+
+// service struct place bid method
+// the bidder is the user of the request's session, the wallet must be linked to that user
+// with a verifier, the bid must be signed by that wallet, see signing.Bid
+// a valid signature consumes its nonce even if the bid is rejected afterwards
+func (s *service) PlaceBid(ctx context.Context, id string, address string, amount string, proof signing.Proof) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "PlaceBid")
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	value, err := parseAmount(amount, false)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	if err := s.checkWallet(ctx, userId, address); err != nil {
+		return Auction{}, err
+	}
+
+	if s.verifier != nil {
+		signed := signing.Bid{
+			Auction:  id,
+			Bidder:   common.HexToAddress(address),
+			Amount:   value,
+			Nonce:    proof.Nonce,
+			Deadline: proof.Deadline,
+		}
+		if err := s.verifier.VerifyBid(ctx, signed, proof.Signature); err != nil {
+			return Auction{}, err
+		}
+	}
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Auction{}, err
+	}
+
+	now := s.now()
+	endsAt := a.EndsAt
+	var bid Bid
+	switch a.Mode {
+	case ModeEnglish:
+		bid, err = placeEnglishBid(&a, userId, address, value, now)
+	case ModeDutch:
+		bid, err = placeDutchBid(&a, userId, address, value, now)
+	default:
+		err = ErrWrongMode
+	}
+	if err != nil {
+		return Auction{}, err
+	}
+	a.UpdatedAt = now
+
+	if err := s.auctionStore.WriteAuction(a); err != nil {
+		level.Error(logger).Log("s.auctionStore.WriteAuction:", err)
+		return Auction{}, err
+	}
+
+	level.Info(logger).Log("auction", a.Id, "seq", bid.Seq, "amount", bid.Amount, "ends_at", bid.EndsAt)
+
+	public := a.Public(now)
+	bid.UserId = ""
+	s.emit(Event{Type: EventBid, Auction: public, Bid: &bid, At: now})
+	if !a.EndsAt.Equal(endsAt) {
+		s.emit(Event{Type: EventExtended, Auction: public, At: now})
+	}
+
+	return public, nil
+}
+
+// service struct read bids method
+// returns the bid ledger in placement order
+func (s *service) ReadBids(ctx context.Context, id string) ([]Bid, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadBids")
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return nil, err
+	}
+
+	return publicBids(a.Bids), nil
+}
+
+// service struct read price method
+// returns the dutch auction price at a point in time, the current price for a zero time
+func (s *service) ReadPrice(ctx context.Context, id string, at time.Time) (Price, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadPrice")
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Price{}, err
+	}
+
+	if a.Mode != ModeDutch {
+		return Price{}, ErrWrongMode
+	}
+
+	if at.IsZero() {
+		at = s.now()
+	}
+
+	price, err := a.Dutch.Price(a.StartsAt, a.EndsAt, at)
+	if err != nil {
+		return Price{}, err
+	}
+
+	return Price{Price: price.String(), At: at}, nil
+}
+
+// service struct settle method
+// settles an ended auction on request of an operator, see Finalize
+func (s *service) Settle(ctx context.Context, id string) (Auction, error) {
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Auction{}, err
+	}
+	if !s.operators[userId] {
+		return Auction{}, ErrNotOperator
+	}
+
+	return s.Finalize(ctx, id)
+}
+
+// service struct finalize method
+// computes winners, prices and refunds of an ended auction, repeated calls return the stored settlement
+// not routed, the ledger's settlement jobs call it without a session
+func (s *service) Finalize(ctx context.Context, id string) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Finalize")
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Auction{}, err
+	}
+
+	now := s.now()
+	if a.Settlement != nil {
+		return a.Public(now), nil
+	}
+
+	if err := settle(&a, now); err != nil {
+		return Auction{}, err
+	}
+	a.UpdatedAt = now
+
+	if err := s.auctionStore.WriteAuction(a); err != nil {
+		level.Error(logger).Log("s.auctionStore.WriteAuction:", err)
+		return Auction{}, err
+	}
+
+	public := a.Public(now)
+	s.emit(Event{Type: EventSettled, Auction: public, At: now})
+
+	return public, nil
+}
+
+// service struct commit bid method
+// records the sealed bid commitment of the session's user, see Commitment for its construction
+func (s *service) CommitBid(ctx context.Context, id string, address string, commitment string) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "CommitBid")
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	if err := s.checkWallet(ctx, userId, address); err != nil {
+		return Auction{}, err
+	}
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Auction{}, err
+	}
+
+	now := s.now()
+	if err := commitBid(&a, userId, address, commitment, now); err != nil {
+		return Auction{}, err
+	}
+	a.UpdatedAt = now
+
+	if err := s.auctionStore.WriteAuction(a); err != nil {
+		level.Error(logger).Log("s.auctionStore.WriteAuction:", err)
+		return Auction{}, err
+	}
+
+	public := a.Public(now)
+	s.emit(Event{Type: EventCommit, Auction: public, At: now})
+
+	return public, nil
+}
+
+// service struct reveal bid method
+// opens the commitment of the session's user with bid amount and salt
+func (s *service) RevealBid(ctx context.Context, id string, amount string, salt string) (Auction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "RevealBid")
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	value, err := parseAmount(amount, true)
+	if err != nil {
+		return Auction{}, err
+	}
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return Auction{}, err
+	}
+
+	now := s.now()
+	if err := revealBid(&a, userId, value, salt, now); err != nil {
+		return Auction{}, err
+	}
+	a.UpdatedAt = now
+
+	if err := s.auctionStore.WriteAuction(a); err != nil {
+		level.Error(logger).Log("s.auctionStore.WriteAuction:", err)
+		return Auction{}, err
+	}
+
+	public := a.Public(now)
+	s.emit(Event{Type: EventReveal, Auction: public, At: now})
+
+	return public, nil
+}
+
+// service struct read audit method
+// returns the commit and reveal log of a sealed-bid auction, reveal details once the reveal window closed
+func (s *service) ReadAudit(ctx context.Context, id string) ([]AuditEntry, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadAudit")
+
+	a, err := s.auctionStore.ReadAuction(id)
+	if err != nil {
+		level.Error(logger).Log("s.auctionStore.ReadAuction:", err)
+		return nil, err
+	}
+
+	if a.Mode != ModeSealed {
+		return nil, ErrWrongMode
+	}
+
+	return a.Public(s.now()).Audit, nil
+}
+
+// user identifier of the active session attached to the request context
+func (s *service) user(ctx context.Context) (string, error) {
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok || s.sessions == nil {
+		return "", ErrUnauthorized
+	}
+
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	return sess.UserId(), nil
+}
+
+// checks that the wallet belongs to the user if wallet links are available
+func (s *service) checkWallet(ctx context.Context, userId, address string) error {
+
+	if s.wallets == nil {
+		return nil
+	}
+
+	linked, err := s.wallets.IsLinked(ctx, userId, address)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return ErrWalletNotLinked
+	}
+
+	return nil
+}
+
+// locks an auction and returns the unlock function
+func (s *service) lock(id string) func() {
+
+	s.locksMu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+	s.locksMu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// initialization function to return service struct
+// without wallets, bids are only checked against the wallets used in the same auction
+// without a verifier, bids are accepted without wallet signatures
+// operators are the user identifiers allowed to settle and to auction any collection, see session.Session.UserId
+// this function should is called in main.go
+func NewService(auctionStore AuctionStore, collections Collections, sessions Sessions, wallets Wallets, verifier BidVerifier, operators []string, logger log.Logger) Service {
+
+	ops := make(map[string]bool, len(operators))
+	for _, o := range operators {
+		ops[o] = true
+	}
+
+	return &service{
+		locks:        make(map[string]*sync.Mutex),
+		statuses:     make(map[string]Status),
+		auctionStore: auctionStore,
+		collections:  collections,
+		sessions:     sessions,
+		wallets:      wallets,
+		verifier:     verifier,
+		operators:    ops,
+		now:          time.Now,
+		logger:       logger,
+	}
+}
+
+// ******* utils functions ********
+
+// random auction identifier
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validates and lowercases an ethereum address
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !addressPattern.MatchString(address) {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(address), nil
+}
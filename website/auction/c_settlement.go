@@ -0,0 +1,126 @@
+package auction
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+// ******** Settlement **********
+
+var ErrNotEnded = errors.New("Auction has not ended")
+
+// a winning bid with the amount due
+// paid is the bid amount, price is what the winner owes, refund is the difference returned to the winner
+type Winner struct {
+	Seq     int    `json:"seq"`
+	UserId  string `json:"user_id,omitempty"`
+	Address string `json:"address"`
+	Paid    string `json:"paid"`
+	Price   string `json:"price"`
+	Refund  string `json:"refund"`
+}
+
+// result of an ended auction
+// english auctions have at most one winner paying the highest bid
+// in dutch auctions every buyer wins and pays the clearing price, the lowest accepted price
+// sealed-bid auctions list the addresses of unrevealed, forfeited bids
+type Settlement struct {
+	ClearingPrice string    `json:"clearing_price,omitempty"`
+	Winners       []Winner  `json:"winners"`
+	Forfeited     []string  `json:"forfeited,omitempty"`
+	SettledAt     time.Time `json:"settled_at"`
+}
+
+// settles an ended auction, settling twice keeps the first settlement
+func settle(a *Auction, now time.Time) error {
+
+	if a.status(now) != StatusEnded {
+		return ErrNotEnded
+	}
+	if a.Settlement != nil {
+		return nil
+	}
+
+	settlement := Settlement{Winners: []Winner{}, SettledAt: now}
+
+	switch a.Mode {
+
+	case ModeEnglish:
+		if leader := a.leader(); leader != nil {
+			settlement.ClearingPrice = leader.Amount
+			settlement.Winners = append(settlement.Winners, Winner{
+				Seq:     leader.Seq,
+				UserId:  leader.UserId,
+				Address: leader.Address,
+				Paid:    leader.Amount,
+				Price:   leader.Amount,
+				Refund:  "0",
+			})
+		}
+
+	case ModeDutch:
+		clearing, err := clearingPrice(a.Bids)
+		if err != nil {
+			return err
+		}
+		if clearing == nil {
+			break
+		}
+		settlement.ClearingPrice = clearing.String()
+
+		for _, b := range a.Bids {
+			paid, err := parseAmount(b.Amount, true)
+			if err != nil {
+				return err
+			}
+			settlement.Winners = append(settlement.Winners, Winner{
+				Seq:     b.Seq,
+				UserId:  b.UserId,
+				Address: b.Address,
+				Paid:    b.Amount,
+				Price:   clearing.String(),
+				Refund:  paid.Sub(paid, clearing).String(),
+			})
+		}
+
+	case ModeSealed:
+		if err := settleSealed(a, &settlement, now); err != nil {
+			return err
+		}
+
+	default:
+		return ErrWrongMode
+	}
+
+	a.Settlement = &settlement
+
+	return nil
+}
+
+// lowest price paid, nil without bids
+func clearingPrice(bids []Bid) (*big.Int, error) {
+
+	var lowest *big.Int
+	for _, b := range bids {
+		amount, err := parseAmount(b.Amount, true)
+		if err != nil {
+			return nil, err
+		}
+		if lowest == nil || amount.Cmp(lowest) < 0 {
+			lowest = amount
+		}
+	}
+
+	return lowest, nil
+}
+
+// winners without user identifiers
+func publicWinners(winners []Winner) []Winner {
+	public := make([]Winner, len(winners))
+	for i, w := range winners {
+		w.UserId = ""
+		public[i] = w
+	}
+	return public
+}
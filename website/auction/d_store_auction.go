@@ -0,0 +1,358 @@
+package auction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Auction struct **********
+
+type Mode string
+
+const (
+
+	// ascending price, highest bid at the end wins
+	ModeEnglish Mode = "english"
+
+	// descending price, buyers accept the current price until all units are sold
+	ModeDutch Mode = "dutch"
+
+	// sealed bids are committed, then revealed, the highest bidder pays the second highest bid
+	ModeSealed Mode = "sealed"
+)
+
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusLive      Status = "live"
+	StatusRevealing Status = "revealing"
+	StatusEnded     Status = "ended"
+)
+
+var ErrInvalidAuction = errors.New("Invalid auction")
+
+// a bid of the ledger, seq numbers are consecutive starting at 1
+// ends_at records the auction end after the bid, later than before if the bid extended the auction
+type Bid struct {
+	Seq      int       `json:"seq"`
+	UserId   string    `json:"user_id,omitempty"`
+	Address  string    `json:"address"`
+	Amount   string    `json:"amount"`
+	PlacedAt time.Time `json:"placed_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// auction of a single token of a collection, or of a number of units in dutch mode
+// amounts are decimal strings in wei
+// english: a bid placed less than extension_window seconds before the end moves the end to extension seconds after the bid
+// dutch: the price curve is configured in dutch, reserve price and increments are not used
+// sealed: bids are committed until ends_at and revealed until sealed.reveal_ends_at, the reserve price applies
+type Auction struct {
+	Id              string       `json:"id"`
+	CollectionId    string       `json:"collection_id"`
+	TokenId         int          `json:"token_id"`
+	Mode            Mode         `json:"mode"`
+	ReservePrice    string       `json:"reserve_price"`
+	MinIncrement    string       `json:"min_increment"`
+	StartsAt        time.Time    `json:"starts_at"`
+	EndsAt          time.Time    `json:"ends_at"`
+	ExtensionWindow int          `json:"extension_window"`
+	Extension       int          `json:"extension"`
+	Dutch           *Dutch       `json:"dutch,omitempty"`
+	Sealed          *Sealed      `json:"sealed,omitempty"`
+	Bids            []Bid        `json:"bids"`
+	Commits         []SealedBid  `json:"commits,omitempty"`
+	Audit           []AuditEntry `json:"audit,omitempty"`
+	Settlement      *Settlement  `json:"settlement,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// derived on read, not persisted
+	Status     Status `json:"status,omitempty"`
+	MinimumBid string `json:"minimum_bid,omitempty"`
+}
+
+// Validate auction configuration
+// errors wrap ErrInvalidAuction with the reason
+func (a *Auction) Validate() error {
+
+	if a.CollectionId == "" || a.TokenId < 0 {
+		return fmt.Errorf("%w: collection and token are required", ErrInvalidAuction)
+	}
+	if a.StartsAt.IsZero() || !a.StartsAt.Before(a.EndsAt) {
+		return fmt.Errorf("%w: auction must start before it ends", ErrInvalidAuction)
+	}
+
+	switch a.Mode {
+
+	case ModeEnglish:
+		if _, err := parseAmount(a.ReservePrice, true); err != nil {
+			return fmt.Errorf("%w: invalid reserve price", ErrInvalidAuction)
+		}
+		if _, err := parseAmount(a.MinIncrement, false); err != nil {
+			return fmt.Errorf("%w: invalid minimum increment", ErrInvalidAuction)
+		}
+		if a.ExtensionWindow < 0 || a.Extension < 0 {
+			return fmt.Errorf("%w: invalid soft close", ErrInvalidAuction)
+		}
+
+	case ModeDutch:
+		if a.Dutch == nil {
+			return fmt.Errorf("%w: dutch configuration is required", ErrInvalidAuction)
+		}
+		if err := a.Dutch.validate(a.EndsAt.Sub(a.StartsAt)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAuction, err)
+		}
+
+	case ModeSealed:
+		if _, err := parseAmount(a.ReservePrice, true); err != nil {
+			return fmt.Errorf("%w: invalid reserve price", ErrInvalidAuction)
+		}
+		if a.Sealed == nil || !a.EndsAt.Before(a.Sealed.RevealEndsAt) {
+			return fmt.Errorf("%w: reveal window must follow the bidding window", ErrInvalidAuction)
+		}
+
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidAuction, a.Mode)
+	}
+
+	return nil
+}
+
+// status of the auction at a point in time
+// dutch auctions also end when sold out, sealed-bid auctions reveal bids before they end
+func (a *Auction) status(now time.Time) Status {
+	switch {
+	case now.Before(a.StartsAt):
+		return StatusScheduled
+	case a.Mode == ModeDutch && len(a.Bids) >= a.Dutch.Quantity:
+		return StatusEnded
+	case now.Before(a.EndsAt):
+		return StatusLive
+	case a.Mode == ModeSealed && now.Before(a.Sealed.RevealEndsAt):
+		return StatusRevealing
+	default:
+		return StatusEnded
+	}
+}
+
+// current highest bid, nil without bids
+func (a *Auction) leader() *Bid {
+	if len(a.Bids) == 0 {
+		return nil
+	}
+	return &a.Bids[len(a.Bids)-1]
+}
+
+// copy of the auction for clients, with derived fields and without user identifiers
+func (a Auction) Public(now time.Time) Auction {
+
+	a.Status = a.status(now)
+	if a.Status != StatusEnded {
+		if min, err := a.minimumBid(now); err == nil {
+			a.MinimumBid = min.String()
+		}
+	}
+
+	a.Bids = publicBids(a.Bids)
+	if a.Mode == ModeSealed {
+		hidden := a.Status != StatusEnded
+		a.Commits = publicCommits(a.Commits, hidden)
+		a.Audit = publicAudit(a.Audit, hidden)
+	}
+	if a.Settlement != nil {
+		settlement := *a.Settlement
+		settlement.Winners = publicWinners(settlement.Winners)
+		a.Settlement = &settlement
+	}
+
+	return a
+}
+
+// lowest acceptable bid, the current price in dutch mode and the reserve price for sealed bids
+func (a *Auction) minimumBid(now time.Time) (*big.Int, error) {
+	switch a.Mode {
+	case ModeDutch:
+		return a.Dutch.Price(a.StartsAt, a.EndsAt, now)
+	case ModeSealed:
+		return parseAmount(a.ReservePrice, true)
+	}
+	return minimumBid(a)
+}
+
+// bids without user identifiers
+func publicBids(bids []Bid) []Bid {
+	public := make([]Bid, len(bids))
+	for i, b := range bids {
+		b.UserId = ""
+		public[i] = b
+	}
+	return public
+}
+
+// ******* Auction store interface *********
+
+var ErrAuctionNotFound = errors.New("Auction not found")
+
+// identifiers are hex encoded random bytes, validated to prevent path traversal
+var idPattern = regexp.MustCompile("^[0-9a-f]{32}$")
+
+type AuctionStoreConfig struct {
+	AuctionsPath string
+}
+
+type AuctionStore interface {
+	WriteAuction(a Auction) error
+	ReadAuction(id string) (Auction, error)
+	ReadAuctions() ([]Auction, error)
+}
+
+type auctionStore struct {
+	mu     sync.RWMutex
+	config AuctionStoreConfig
+	logger log.Logger
+}
+
+func (as *auctionStore) WriteAuction(a Auction) error {
+
+	// log level
+	logger := log.With(as.logger, "method", "WriteAuction")
+
+	if !idPattern.MatchString(a.Id) {
+		return ErrAuctionNotFound
+	}
+
+	// derived fields are not persisted
+	a.Status = ""
+	a.MinimumBid = ""
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := os.MkdirAll(as.config.AuctionsPath, 0755); err != nil {
+		level.Error(logger).Log("os.MkdirAll:", err)
+		return err
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		level.Error(logger).Log("json.Marshal:", err)
+		return err
+	}
+
+	// write temporary file first, readers never see partial ledgers
+	path := getAuctionPath(a.Id, as.config.AuctionsPath, ".json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		level.Error(logger).Log("os.WriteFile:", err)
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		level.Error(logger).Log("os.Rename:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (as *auctionStore) ReadAuction(id string) (a Auction, err error) {
+
+	// log level
+	logger := log.With(as.logger, "method", "ReadAuction")
+
+	if !idPattern.MatchString(id) {
+		return a, ErrAuctionNotFound
+	}
+
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	data, err := os.ReadFile(getAuctionPath(id, as.config.AuctionsPath, ".json"))
+	if os.IsNotExist(err) {
+		return a, ErrAuctionNotFound
+	}
+	if err != nil {
+		level.Error(logger).Log("os.ReadFile:", err)
+		return a, err
+	}
+
+	if err := json.Unmarshal(data, &a); err != nil {
+		level.Error(logger).Log("json.Unmarshal:", err)
+		return a, err
+	}
+
+	return a, nil
+}
+
+// reads all auctions ordered by start time
+func (as *auctionStore) ReadAuctions() ([]Auction, error) {
+
+	// log level
+	logger := log.With(as.logger, "method", "ReadAuctions")
+
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(as.config.AuctionsPath, "*.json"))
+	if err != nil {
+		level.Error(logger).Log("filepath.Glob:", err)
+		return nil, err
+	}
+
+	auctions := make([]Auction, 0, len(files))
+	for _, file := range files {
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			level.Error(logger).Log("os.ReadFile:", err)
+			return nil, err
+		}
+
+		var a Auction
+		if err := json.Unmarshal(data, &a); err != nil {
+			level.Error(logger).Log("json.Unmarshal:", err, "file", file)
+			return nil, err
+		}
+		auctions = append(auctions, a)
+	}
+
+	sort.Slice(auctions, func(i, j int) bool {
+		return auctions[i].StartsAt.Before(auctions[j].StartsAt)
+	})
+
+	return auctions, nil
+}
+
+func NewAuctionStore(config AuctionStoreConfig, logger log.Logger) AuctionStore {
+	return &auctionStore{
+		config: config,
+		logger: logger,
+	}
+}
+
+// ******* utils functions ********
+
+func getAuctionPath(id, auctionsPath, ending string) string {
+	return filepath.Join(auctionsPath, id+ending)
+}
+
+// parses a decimal wei amount, zero only if allowed
+func parseAmount(s string, zero bool) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok || v.Sign() < 0 || (!zero && v.Sign() == 0) {
+		return nil, ErrInvalidAmount
+	}
+	return v, nil
+}
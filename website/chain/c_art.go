@@ -0,0 +1,327 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ******** Art contract bindings **********
+
+// ArtABI is the interface of contracts/contracts/ArtToken.sol
+// keep in sync with the contract, the simulated backend dispatches calls by this ABI as well
+const ArtABI = `[
+	{"type":"constructor","inputs":[{"name":"account","type":"address"},{"name":"minter_","type":"address"},{"name":"mintingAllowedAfter_","type":"uint256"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"name","inputs":[],"outputs":[{"name":"","type":"string"}],"stateMutability":"view"},
+	{"type":"function","name":"symbol","inputs":[],"outputs":[{"name":"","type":"string"}],"stateMutability":"view"},
+	{"type":"function","name":"decimals","inputs":[],"outputs":[{"name":"","type":"uint8"}],"stateMutability":"view"},
+	{"type":"function","name":"totalSupply","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"minter","inputs":[],"outputs":[{"name":"","type":"address"}],"stateMutability":"view"},
+	{"type":"function","name":"mintingAllowedAfter","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"minimumTimeBetweenMints","inputs":[],"outputs":[{"name":"","type":"uint32"}],"stateMutability":"view"},
+	{"type":"function","name":"mintCap","inputs":[],"outputs":[{"name":"","type":"uint8"}],"stateMutability":"view"},
+	{"type":"function","name":"DOMAIN_TYPEHASH","inputs":[],"outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view"},
+	{"type":"function","name":"DELEGATION_TYPEHASH","inputs":[],"outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view"},
+	{"type":"function","name":"PERMIT_TYPEHASH","inputs":[],"outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view"},
+	{"type":"function","name":"nonces","inputs":[{"name":"","type":"address"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"delegates","inputs":[{"name":"","type":"address"}],"outputs":[{"name":"","type":"address"}],"stateMutability":"view"},
+	{"type":"function","name":"checkpoints","inputs":[{"name":"","type":"address"},{"name":"","type":"uint32"}],"outputs":[{"name":"fromBlock","type":"uint32"},{"name":"votes","type":"uint96"}],"stateMutability":"view"},
+	{"type":"function","name":"numCheckpoints","inputs":[{"name":"","type":"address"}],"outputs":[{"name":"","type":"uint32"}],"stateMutability":"view"},
+	{"type":"function","name":"allowance","inputs":[{"name":"account","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"balanceOf","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"getCurrentVotes","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint96"}],"stateMutability":"view"},
+	{"type":"function","name":"getPriorVotes","inputs":[{"name":"account","type":"address"},{"name":"blockNumber","type":"uint256"}],"outputs":[{"name":"","type":"uint96"}],"stateMutability":"view"},
+	{"type":"function","name":"setMinter","inputs":[{"name":"minter_","type":"address"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"mint","inputs":[{"name":"dst","type":"address"},{"name":"rawAmount","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"rawAmount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"rawAmount","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"transfer","inputs":[{"name":"dst","type":"address"},{"name":"rawAmount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"transferFrom","inputs":[{"name":"src","type":"address"},{"name":"dst","type":"address"},{"name":"rawAmount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"delegate","inputs":[{"name":"delegatee","type":"address"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"delegateBySig","inputs":[{"name":"delegatee","type":"address"},{"name":"nonce","type":"uint256"},{"name":"expiry","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"event","name":"MinterChanged","inputs":[{"name":"minter","type":"address","indexed":false},{"name":"newMinter","type":"address","indexed":false}],"anonymous":false},
+	{"type":"event","name":"DelegateChanged","inputs":[{"name":"delegator","type":"address","indexed":true},{"name":"fromDelegate","type":"address","indexed":true},{"name":"toDelegate","type":"address","indexed":true}],"anonymous":false},
+	{"type":"event","name":"DelegateVotesChanged","inputs":[{"name":"delegate","type":"address","indexed":true},{"name":"previousBalance","type":"uint256","indexed":false},{"name":"newBalance","type":"uint256","indexed":false}],"anonymous":false},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}],"anonymous":false},
+	{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}],"anonymous":false}
+]`
+
+// parsed once, the ABI is a constant
+var artABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(ArtABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+var ErrUnknownEvent = errors.New("Log is not an Art event")
+
+// vote checkpoint as stored by the contract
+type Checkpoint struct {
+	FromBlock uint32   `json:"from_block"`
+	Votes     *big.Int `json:"votes"`
+}
+
+// VotesAt looks up the votes at a block in the checkpoints of a delegate, oldest first
+// it is the binary search of getPriorVotes without the check that the block is below the head
+func VotesAt(cps []Checkpoint, blockNumber uint64) *big.Int {
+
+	n := uint32(len(cps))
+	if n == 0 {
+		return new(big.Int)
+	}
+
+	// first check most recent balance
+	if blockNumber >= uint64(cps[n-1].FromBlock) {
+		return cps[n-1].Votes
+	}
+
+	// next check implicit zero balance
+	if blockNumber < uint64(cps[0].FromBlock) {
+		return new(big.Int)
+	}
+
+	// blockNumber is below the last checkpoint and so fits 32 bits
+	block := uint32(blockNumber)
+	lower, upper := uint32(0), n-1
+	for upper > lower {
+		center := upper - (upper-lower)/2
+		cp := cps[center]
+		if cp.FromBlock == block {
+			return cp.Votes
+		} else if cp.FromBlock < block {
+			lower = center
+		} else {
+			upper = center - 1
+		}
+	}
+	return cps[lower].Votes
+}
+
+// Art binds the contract at one address to a backend
+// calls are read only, transactions are signed by the caller supplied TransactOpts
+type Art struct {
+	address  common.Address
+	contract *bind.BoundContract
+	filterer bind.ContractFilterer
+}
+
+// NewArt binds the Art contract deployed at address
+func NewArt(address common.Address, backend bind.ContractBackend) *Art {
+	return &Art{
+		address:  address,
+		contract: bind.NewBoundContract(address, artABI, backend, backend, backend),
+		filterer: backend,
+	}
+}
+
+// Address of the bound contract
+func (a *Art) Address() common.Address { return a.address }
+
+// Pack abi encodes a method call, for transactions signed outside of TransactOpts
+func (a *Art) Pack(method string, args ...interface{}) ([]byte, error) {
+	return artABI.Pack(method, args...)
+}
+
+// calls a view function returning a single value
+func (a *Art) call(opts *bind.CallOpts, method string, args ...interface{}) (interface{}, error) {
+	var out []interface{}
+	if err := a.contract.Call(opts, &out, method, args...); err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// calls a view function returning a single integer
+func (a *Art) callBig(opts *bind.CallOpts, method string, args ...interface{}) (*big.Int, error) {
+	out, err := a.call(opts, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out.(*big.Int), nil
+}
+
+func (a *Art) TotalSupply(opts *bind.CallOpts) (*big.Int, error) {
+	return a.callBig(opts, "totalSupply")
+}
+
+func (a *Art) MintingAllowedAfter(opts *bind.CallOpts) (*big.Int, error) {
+	return a.callBig(opts, "mintingAllowedAfter")
+}
+
+func (a *Art) Minter(opts *bind.CallOpts) (common.Address, error) {
+	out, err := a.call(opts, "minter")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return out.(common.Address), nil
+}
+
+func (a *Art) BalanceOf(opts *bind.CallOpts, account common.Address) (*big.Int, error) {
+	return a.callBig(opts, "balanceOf", account)
+}
+
+func (a *Art) Allowance(opts *bind.CallOpts, account common.Address, spender common.Address) (*big.Int, error) {
+	return a.callBig(opts, "allowance", account, spender)
+}
+
+func (a *Art) Nonces(opts *bind.CallOpts, account common.Address) (*big.Int, error) {
+	return a.callBig(opts, "nonces", account)
+}
+
+func (a *Art) Delegates(opts *bind.CallOpts, account common.Address) (common.Address, error) {
+	out, err := a.call(opts, "delegates", account)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return out.(common.Address), nil
+}
+
+func (a *Art) GetCurrentVotes(opts *bind.CallOpts, account common.Address) (*big.Int, error) {
+	return a.callBig(opts, "getCurrentVotes", account)
+}
+
+// GetPriorVotes reverts unless blockNumber is below the block the call executes in
+func (a *Art) GetPriorVotes(opts *bind.CallOpts, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return a.callBig(opts, "getPriorVotes", account, blockNumber)
+}
+
+func (a *Art) NumCheckpoints(opts *bind.CallOpts, account common.Address) (uint32, error) {
+	out, err := a.call(opts, "numCheckpoints", account)
+	if err != nil {
+		return 0, err
+	}
+	return out.(uint32), nil
+}
+
+func (a *Art) Checkpoints(opts *bind.CallOpts, account common.Address, index uint32) (Checkpoint, error) {
+	var out []interface{}
+	if err := a.contract.Call(opts, &out, "checkpoints", account, index); err != nil {
+		return Checkpoint{}, err
+	}
+	return Checkpoint{FromBlock: out[0].(uint32), Votes: out[1].(*big.Int)}, nil
+}
+
+func (a *Art) SetMinter(opts *bind.TransactOpts, minter common.Address) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "setMinter", minter)
+}
+
+func (a *Art) Mint(opts *bind.TransactOpts, dst common.Address, amount *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "mint", dst, amount)
+}
+
+func (a *Art) Approve(opts *bind.TransactOpts, spender common.Address, amount *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "approve", spender, amount)
+}
+
+func (a *Art) Permit(opts *bind.TransactOpts, owner common.Address, spender common.Address, amount *big.Int, deadline *big.Int, v uint8, r [32]byte, s [32]byte) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "permit", owner, spender, amount, deadline, v, r, s)
+}
+
+func (a *Art) Transfer(opts *bind.TransactOpts, dst common.Address, amount *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "transfer", dst, amount)
+}
+
+func (a *Art) TransferFrom(opts *bind.TransactOpts, src common.Address, dst common.Address, amount *big.Int) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "transferFrom", src, dst, amount)
+}
+
+func (a *Art) Delegate(opts *bind.TransactOpts, delegatee common.Address) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "delegate", delegatee)
+}
+
+func (a *Art) DelegateBySig(opts *bind.TransactOpts, delegatee common.Address, nonce *big.Int, expiry *big.Int, v uint8, r [32]byte, s [32]byte) (*types.Transaction, error) {
+	return a.contract.Transact(opts, "delegateBySig", delegatee, nonce, expiry, v, r, s)
+}
+
+// ******** Art events **********
+
+type ArtMinterChanged struct {
+	Minter    common.Address
+	NewMinter common.Address
+	Raw       types.Log
+}
+
+type ArtDelegateChanged struct {
+	Delegator    common.Address
+	FromDelegate common.Address
+	ToDelegate   common.Address
+	Raw          types.Log
+}
+
+type ArtDelegateVotesChanged struct {
+	Delegate        common.Address
+	PreviousBalance *big.Int
+	NewBalance      *big.Int
+	Raw             types.Log
+}
+
+type ArtTransfer struct {
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+	Raw    types.Log
+}
+
+type ArtApproval struct {
+	Owner   common.Address
+	Spender common.Address
+	Amount  *big.Int
+	Raw     types.Log
+}
+
+// EventTopic returns the topic identifying an Art event by name
+func EventTopic(name string) common.Hash {
+	return artABI.Events[name].ID
+}
+
+// FilterLogs reads all Art logs in the inclusive block range, oldest first
+// topics narrow the result like eth_getLogs, the first position selects events
+func (a *Art) FilterLogs(ctx context.Context, from uint64, to uint64, topics ...[]common.Hash) ([]types.Log, error) {
+	return a.filterer.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{a.address},
+		Topics:    topics,
+	})
+}
+
+// ParseLog decodes a log of the contract into one of the Art event types
+func (a *Art) ParseLog(log types.Log) (interface{}, error) {
+
+	if len(log.Topics) == 0 || log.Address != a.address {
+		return nil, ErrUnknownEvent
+	}
+	event, err := artABI.EventByID(log.Topics[0])
+	if err != nil {
+		return nil, ErrUnknownEvent
+	}
+
+	var out interface{}
+	switch event.Name {
+	case "MinterChanged":
+		out = &ArtMinterChanged{Raw: log}
+	case "DelegateChanged":
+		out = &ArtDelegateChanged{Raw: log}
+	case "DelegateVotesChanged":
+		out = &ArtDelegateVotesChanged{Raw: log}
+	case "Transfer":
+		out = &ArtTransfer{Raw: log}
+	case "Approval":
+		out = &ArtApproval{Raw: log}
+	default:
+		return nil, ErrUnknownEvent
+	}
+	if err := a.contract.UnpackLog(out, event.Name, log); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,293 @@
+package chain_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/selection"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	_ chain.Backend         = (*chain.SimulatedBackend)(nil)
+	_ chain.Backend         = (*ethclient.Client)(nil)
+	_ selection.VotesSource = chain.Client(nil)
+)
+
+var (
+	artInitialSupply = new(big.Int).Mul(big.NewInt(1_000_000_000), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+	maxUint96        = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+)
+
+// returns a helper committing the pending block and reading the receipt of a sent transaction
+func miner(t *testing.T, sim *chain.SimulatedBackend) func(*types.Transaction, error) *types.Receipt {
+	return func(tx *types.Transaction, err error) *types.Receipt {
+		if err != nil {
+			t.Fatalf("transaction failed, error: %v.", err)
+		}
+		sim.Commit()
+		receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+		if err != nil {
+			t.Fatalf("TransactionReceipt failed, error: %v.", err)
+		}
+		return receipt
+	}
+}
+
+func TestReadToken(t *testing.T) {
+
+	holder, minter := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	_, c := chaintest.DeployArt(t, holder, minter.Addr)
+	ctx := context.Background()
+
+	supply, err := c.TotalSupply(ctx)
+	if err != nil || supply.Cmp(artInitialSupply) != 0 {
+		t.Errorf("TotalSupply returned %v, error: %v.", supply, err)
+	}
+	if balance, _ := c.BalanceOf(ctx, holder.Addr.Hex()); balance.Cmp(artInitialSupply) != 0 {
+		t.Errorf("BalanceOf returned %v, expected %v.", balance, artInitialSupply)
+	}
+	if m, _ := c.Minter(ctx); m != strings.ToLower(minter.Addr.Hex()) {
+		t.Errorf("Minter returned %s, expected %s.", m, minter.Addr.Hex())
+	}
+	if _, err := c.BalanceOf(ctx, "0x1234"); err != chain.ErrInvalidAddress {
+		t.Errorf("BalanceOf returned %v, expected %v.", err, chain.ErrInvalidAddress)
+	}
+
+	var name []interface{}
+	parsed, _ := abi.JSON(strings.NewReader(chain.ArtABI))
+	caller := bind.NewBoundContract(c.Art().Address(), parsed, c.Backend(), c.Backend(), c.Backend())
+	if err := caller.Call(nil, &name, "name"); err != nil || name[0].(string) != "ArtToken" {
+		t.Errorf("name returned %v, error: %v.", name, err)
+	}
+}
+
+func TestVotesAndCheckpoints(t *testing.T) {
+
+	holder, minter, alice := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, c := chaintest.DeployArt(t, holder, minter.Addr)
+	ctx := context.Background()
+	art := c.Art()
+	mine := miner(t, sim)
+
+	mine(art.Transfer(holder.Opts, alice.Addr, big.NewInt(1000)))
+	receipt := mine(art.Delegate(alice.Opts, alice.Addr))
+	delegated := receipt.BlockNumber.Uint64()
+
+	mine(art.Transfer(holder.Opts, alice.Addr, big.NewInt(5)))
+	mine(art.Transfer(alice.Opts, holder.Addr, big.NewInt(300)))
+
+	// two transfers in one block write a single checkpoint
+	if _, err := art.Transfer(holder.Opts, alice.Addr, big.NewInt(100)); err != nil {
+		t.Fatalf("Transfer failed, error: %v.", err)
+	}
+	mine(art.Transfer(holder.Opts, alice.Addr, big.NewInt(100)))
+
+	votes, err := c.CurrentVotes(ctx, alice.Addr.Hex())
+	if err != nil || votes.Int64() != 905 {
+		t.Errorf("CurrentVotes returned %v, error: %v.", votes, err)
+	}
+
+	cps, err := c.Checkpoints(ctx, alice.Addr.Hex())
+	if err != nil || len(cps) != 4 {
+		t.Fatalf("Checkpoints returned %+v, error: %v.", cps, err)
+	}
+	want := []int64{1000, 1005, 705, 905}
+	for i, cp := range cps {
+		if cp.Votes.Int64() != want[i] {
+			t.Errorf("checkpoint %d has %v votes, expected %d.", i, cp.Votes, want[i])
+		}
+	}
+	if cps[0].FromBlock != uint32(delegated) {
+		t.Errorf("first checkpoint from block %d, expected %d.", cps[0].FromBlock, delegated)
+	}
+
+	for block, expected := range map[uint64]int64{
+		delegated - 1: 0,
+		delegated:     1000,
+		delegated + 1: 1005,
+		delegated + 2: 705,
+	} {
+		if votes, err := c.PriorVotes(ctx, alice.Addr.Hex(), block); err != nil || votes.Int64() != expected {
+			t.Errorf("PriorVotes at %d returned %v, expected %d, error: %v.", block, votes, expected, err)
+		}
+	}
+	head, _ := c.BlockNumber(ctx)
+	if _, err := c.PriorVotes(ctx, alice.Addr.Hex(), head); err != chain.ErrNotDetermined {
+		t.Errorf("PriorVotes returned %v, expected %v.", err, chain.ErrNotDetermined)
+	}
+
+	// the contract itself refuses the head block as well
+	_, err = art.GetPriorVotes(nil, alice.Addr, new(big.Int).SetUint64(head))
+	if err == nil || err.Error() != "execution reverted: Art::getPriorVotes: not yet determined" {
+		t.Errorf("GetPriorVotes returned %v, expected revert.", err)
+	}
+}
+
+func TestRevertsAndEvents(t *testing.T) {
+
+	holder, minter, alice := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, c := chaintest.DeployArt(t, holder, minter.Addr)
+	ctx := context.Background()
+	art := c.Art()
+	mine := miner(t, sim)
+
+	// gas estimation surfaces the revert reason before anything is sent
+	if _, err := art.Transfer(alice.Opts, holder.Addr, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "transfer amount exceeds balance") {
+		t.Errorf("Transfer returned %v, expected balance revert.", err)
+	}
+	if _, err := art.Mint(minter.Opts, alice.Addr, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "minting not allowed yet") {
+		t.Errorf("Mint returned %v, expected timing revert.", err)
+	}
+
+	// with a fixed gas limit the reverted transaction is mined and fails
+	alice.Opts.GasLimit = 100000
+	receipt := mine(art.Transfer(alice.Opts, holder.Addr, big.NewInt(1)))
+	if receipt.Status != types.ReceiptStatusFailed || len(receipt.Logs) != 0 {
+		t.Errorf("receipt %+v, expected failure without logs.", receipt)
+	}
+	alice.Opts.GasLimit = 0
+
+	mine(art.Approve(holder.Opts, alice.Addr, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))))
+	mine(art.TransferFrom(alice.Opts, holder.Addr, alice.Addr, big.NewInt(7)))
+	if allowance, _ := c.Allowance(ctx, holder.Addr.Hex(), alice.Addr.Hex()); allowance.Cmp(maxUint96) != 0 {
+		t.Errorf("infinite allowance changed to %v.", allowance)
+	}
+
+	// a year later the minter may mint up to 2% of the supply
+	if err := sim.AdjustTime(366 * 24 * time.Hour); err != nil {
+		t.Fatalf("AdjustTime failed, error: %v.", err)
+	}
+	sim.Commit()
+	limit := new(big.Int).Div(new(big.Int).Mul(artInitialSupply, big.NewInt(2)), big.NewInt(100))
+	if _, err := art.Mint(minter.Opts, alice.Addr, new(big.Int).Add(limit, big.NewInt(1))); err == nil || !strings.Contains(err.Error(), "exceeded mint cap") {
+		t.Errorf("Mint returned %v, expected cap revert.", err)
+	}
+	mine(art.Mint(minter.Opts, alice.Addr, limit))
+	if supply, _ := c.TotalSupply(ctx); supply.Cmp(new(big.Int).Add(artInitialSupply, limit)) != 0 {
+		t.Errorf("TotalSupply returned %v after mint.", supply)
+	}
+
+	head, _ := c.BlockNumber(ctx)
+	logs, err := art.FilterLogs(ctx, 0, head)
+	if err != nil {
+		t.Fatalf("FilterLogs failed, error: %v.", err)
+	}
+	var names []string
+	for _, l := range logs {
+		event, err := art.ParseLog(l)
+		if err != nil {
+			t.Fatalf("ParseLog failed, error: %v.", err)
+		}
+		switch e := event.(type) {
+		case *chain.ArtTransfer:
+			names = append(names, "Transfer")
+			if e.From == (common.Address{}) && e.To == alice.Addr && e.Amount.Cmp(limit) != 0 {
+				t.Errorf("mint transfer of %v, expected %v.", e.Amount, limit)
+			}
+		case *chain.ArtApproval:
+			names = append(names, "Approval")
+		case *chain.ArtMinterChanged:
+			names = append(names, "MinterChanged")
+			if e.NewMinter != minter.Addr {
+				t.Errorf("minter changed to %s, expected %s.", e.NewMinter.Hex(), minter.Addr.Hex())
+			}
+		default:
+			names = append(names, "other")
+		}
+	}
+	expected := "Transfer MinterChanged Approval Transfer Transfer"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("events %q, expected %q.", got, expected)
+	}
+
+	// topic filters select events and indexed arguments
+	transfers, _ := art.FilterLogs(ctx, 0, head, []common.Hash{chain.EventTopic("Transfer")}, nil, []common.Hash{common.BytesToHash(alice.Addr.Bytes())})
+	if len(transfers) != 2 {
+		t.Errorf("FilterLogs returned %d transfers to alice, expected 2.", len(transfers))
+	}
+}
+
+func TestForkReplacesBlocks(t *testing.T) {
+
+	holder, minter, alice := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, c := chaintest.DeployArt(t, holder, minter.Addr)
+	ctx := context.Background()
+	art := c.Art()
+	mine := miner(t, sim)
+
+	base, _ := c.BlockNumber(ctx)
+	receipt := mine(art.Transfer(holder.Opts, alice.Addr, big.NewInt(10)))
+	old := receipt.BlockHash
+
+	if err := sim.Fork(base); err != nil {
+		t.Fatalf("Fork failed, error: %v.", err)
+	}
+	tx, err := art.Transfer(holder.Opts, alice.Addr, big.NewInt(20))
+	if err != nil {
+		t.Fatalf("Transfer failed, error: %v.", err)
+	}
+	sim.Commit()
+
+	// the branch replaces the old block once it is longer
+	sim.Commit()
+	if balance, _ := c.BalanceOf(ctx, alice.Addr.Hex()); balance.Int64() != 20 {
+		t.Errorf("balance %v after the fork, expected 20.", balance)
+	}
+	if _, err := sim.TransactionReceipt(ctx, receipt.TxHash); err == nil {
+		t.Errorf("receipt of the dropped block is still found.")
+	}
+	receipt, err = sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("TransactionReceipt failed, error: %v.", err)
+	}
+	if receipt.BlockNumber.Uint64() != base+1 || receipt.BlockHash == old {
+		t.Errorf("receipt in block %v %s, expected a new block at %d.", receipt.BlockNumber, receipt.BlockHash.Hex(), base+1)
+	}
+}
+
+// accounts without code and contracts without isValidSignature never validate a signature
+func TestIsValidSignature(t *testing.T) {
+
+	ctx := context.Background()
+	holder, minter := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	_, client := chaintest.DeployArt(t, holder, minter.Addr)
+	hash := common.HexToHash("0x01")
+
+	for _, account := range []string{holder.Addr.Hex(), client.Art().Address().Hex()} {
+		valid, err := client.IsValidSignature(ctx, account, hash, make([]byte, 65))
+		if err != nil {
+			t.Fatalf("IsValidSignature failed, error: %v.", err)
+		}
+		if valid {
+			t.Errorf("%s validated a signature.", account)
+		}
+	}
+	if _, err := client.IsValidSignature(ctx, "wallet", hash, nil); err != chain.ErrInvalidAddress {
+		t.Errorf("IsValidSignature returned %v, expected %v.", err, chain.ErrInvalidAddress)
+	}
+}
+
+// lookups before, on, between and after checkpoints
+func TestVotesAt(t *testing.T) {
+
+	cps := []chain.Checkpoint{{FromBlock: 3, Votes: big.NewInt(10)}, {FromBlock: 5, Votes: big.NewInt(7)}, {FromBlock: 9, Votes: big.NewInt(12)}}
+	for block, expected := range map[uint64]int64{0: 0, 2: 0, 3: 10, 4: 10, 5: 7, 8: 7, 9: 12, 1 << 40: 12} {
+		if votes := chain.VotesAt(cps, block); votes.Int64() != expected {
+			t.Errorf("VotesAt %d returned %v, expected %d.", block, votes, expected)
+		}
+	}
+	if votes := chain.VotesAt(nil, 100); votes.Sign() != 0 {
+		t.Errorf("VotesAt without checkpoints returned %v.", votes)
+	}
+}
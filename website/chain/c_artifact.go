@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate sh -c "cd ../../contracts && npx hardhat compile && cp artifacts/contracts/ArtToken.sol/Art.json ../website/chain/artifacts/ArtToken.json"
+
+// ******** Compiled contracts **********
+
+//go:embed artifacts
+var artifacts embed.FS
+
+var ErrNoArtifact = errors.New("Art is not compiled, run go generate in website/chain")
+
+var ErrArtifactMismatch = errors.New("Compiled Art does not match ArtABI")
+
+// hardhat artifact of a compiled contract
+type artifact struct {
+	ContractName string          `json:"contractName"`
+	ABI          json.RawMessage `json:"abi"`
+	Bytecode     string          `json:"bytecode"`
+}
+
+// creation code of Art from its artifact
+// the artifact must declare every method and event of ArtABI, the bindings are written against it
+func artBytecode() ([]byte, error) {
+
+	data, err := artifacts.ReadFile("artifacts/ArtToken.json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoArtifact
+	}
+	if err != nil {
+		return nil, err
+	}
+	var a artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+
+	compiled, err := abi.JSON(strings.NewReader(string(a.ABI)))
+	if err != nil {
+		return nil, err
+	}
+	for name, m := range artABI.Methods {
+		if c, ok := compiled.Methods[name]; !ok || c.Sig != m.Sig || len(c.Outputs) != len(m.Outputs) {
+			return nil, ErrArtifactMismatch
+		}
+	}
+	for name, e := range artABI.Events {
+		if c, ok := compiled.Events[name]; !ok || c.ID != e.ID {
+			return nil, ErrArtifactMismatch
+		}
+	}
+	if len(compiled.Constructor.Inputs) != len(artABI.Constructor.Inputs) {
+		return nil, ErrArtifactMismatch
+	}
+
+	return hexutil.Decode(a.Bytecode)
+}
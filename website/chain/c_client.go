@@ -0,0 +1,278 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrNotDetermined = errors.New("Votes at this block are not yet determined")
+
+var ErrWrongChain = errors.New("Node serves a different chain")
+
+// Backend is what the client needs from a node
+// both *ethclient.Client and *SimulatedBackend implement it
+type Backend interface {
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// account state at one block, amounts in the token's smallest unit
+type Account struct {
+	Address      string `json:"address"`
+	Block        uint64 `json:"block"`
+	Balance      string `json:"balance"`
+	Delegate     string `json:"delegate"`
+	CurrentVotes string `json:"current_votes"`
+}
+
+// Client reads the Art token, accounts are hex addresses
+// PriorVotes makes a Client usable as selection.VotesSource
+// IsValidSignature checks EIP-1271 signatures of contract wallets
+type Client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	TotalSupply(ctx context.Context) (*big.Int, error)
+	Minter(ctx context.Context) (string, error)
+	MintingAllowedAfter(ctx context.Context) (*big.Int, error)
+	BalanceOf(ctx context.Context, account string) (*big.Int, error)
+	Allowance(ctx context.Context, account string, spender string) (*big.Int, error)
+	Nonce(ctx context.Context, account string) (*big.Int, error)
+	Delegates(ctx context.Context, account string) (string, error)
+	CurrentVotes(ctx context.Context, account string) (*big.Int, error)
+	PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error)
+	Checkpoints(ctx context.Context, account string) ([]Checkpoint, error)
+	ReadAccount(ctx context.Context, account string) (Account, error)
+	IsValidSignature(ctx context.Context, account string, hash common.Hash, signature []byte) (bool, error)
+	Art() *Art
+	Backend() Backend
+}
+
+type client struct {
+	backend Backend
+	art     *Art
+	logger  log.Logger
+}
+
+// parses a hex address, lowercase and checksummed forms are accepted
+func parseAddress(account string) (common.Address, error) {
+	if !common.IsHexAddress(account) {
+		return common.Address{}, ErrInvalidAddress
+	}
+	return common.HexToAddress(account), nil
+}
+
+// lowercase hex form used for addresses across services
+func formatAddress(a common.Address) string {
+	return strings.ToLower(a.Hex())
+}
+
+func (c *client) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.backend.BlockNumber(ctx)
+}
+
+func (c *client) TotalSupply(ctx context.Context) (*big.Int, error) {
+	return c.art.TotalSupply(&bind.CallOpts{Context: ctx})
+}
+
+func (c *client) Minter(ctx context.Context) (string, error) {
+	minter, err := c.art.Minter(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return "", err
+	}
+	return formatAddress(minter), nil
+}
+
+func (c *client) MintingAllowedAfter(ctx context.Context) (*big.Int, error) {
+	return c.art.MintingAllowedAfter(&bind.CallOpts{Context: ctx})
+}
+
+func (c *client) BalanceOf(ctx context.Context, account string) (*big.Int, error) {
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	return c.art.BalanceOf(&bind.CallOpts{Context: ctx}, a)
+}
+
+func (c *client) Allowance(ctx context.Context, account string, spender string) (*big.Int, error) {
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseAddress(spender)
+	if err != nil {
+		return nil, err
+	}
+	return c.art.Allowance(&bind.CallOpts{Context: ctx}, a, s)
+}
+
+func (c *client) Nonce(ctx context.Context, account string) (*big.Int, error) {
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	return c.art.Nonces(&bind.CallOpts{Context: ctx}, a)
+}
+
+func (c *client) Delegates(ctx context.Context, account string) (string, error) {
+	a, err := parseAddress(account)
+	if err != nil {
+		return "", err
+	}
+	delegate, err := c.art.Delegates(&bind.CallOpts{Context: ctx}, a)
+	if err != nil {
+		return "", err
+	}
+	return formatAddress(delegate), nil
+}
+
+func (c *client) CurrentVotes(ctx context.Context, account string) (*big.Int, error) {
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	return c.art.GetCurrentVotes(&bind.CallOpts{Context: ctx}, a)
+}
+
+// PriorVotes follows getPriorVotes, blocks at or above the head are not yet determined
+// checked before the call so callers get ErrNotDetermined instead of a node specific revert message
+func (c *client) PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error) {
+
+	// log level
+	logger := log.With(c.logger, "method", "PriorVotes")
+
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	head, err := c.backend.BlockNumber(ctx)
+	if err != nil {
+		level.Error(logger).Log("c.backend.BlockNumber:", err)
+		return nil, err
+	}
+	if blockNumber >= head {
+		return nil, ErrNotDetermined
+	}
+
+	votes, err := c.art.GetPriorVotes(&bind.CallOpts{Context: ctx}, a, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		level.Error(logger).Log("c.art.GetPriorVotes:", err)
+		return nil, err
+	}
+	return votes, nil
+}
+
+// Checkpoints of a delegate, oldest first
+// all reads are pinned to one block so the list is consistent
+func (c *client) Checkpoints(ctx context.Context, account string) ([]Checkpoint, error) {
+
+	a, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	head, err := c.backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(head)}
+
+	n, err := c.art.NumCheckpoints(opts, a)
+	if err != nil {
+		return nil, err
+	}
+	cps := make([]Checkpoint, 0, n)
+	for i := uint32(0); i < n; i++ {
+		cp, err := c.art.Checkpoints(opts, a, i)
+		if err != nil {
+			return nil, err
+		}
+		cps = append(cps, cp)
+	}
+	return cps, nil
+}
+
+// ReadAccount reads balance, delegate and votes of an account at the head block
+func (c *client) ReadAccount(ctx context.Context, account string) (Account, error) {
+
+	a, err := parseAddress(account)
+	if err != nil {
+		return Account{}, err
+	}
+	head, err := c.backend.BlockNumber(ctx)
+	if err != nil {
+		return Account{}, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(head)}
+
+	balance, err := c.art.BalanceOf(opts, a)
+	if err != nil {
+		return Account{}, err
+	}
+	delegate, err := c.art.Delegates(opts, a)
+	if err != nil {
+		return Account{}, err
+	}
+	votes, err := c.art.GetCurrentVotes(opts, a)
+	if err != nil {
+		return Account{}, err
+	}
+
+	return Account{
+		Address:      formatAddress(a),
+		Block:        head,
+		Balance:      balance.String(),
+		Delegate:     formatAddress(delegate),
+		CurrentVotes: votes.String(),
+	}, nil
+}
+
+// Art returns the contract bindings, e.g. to send transactions
+func (c *client) Art() *Art {
+	return c.art
+}
+
+func (c *client) Backend() Backend {
+	return c.backend
+}
+
+// NewClient binds the Art contract at address on the backend
+func NewClient(backend Backend, address common.Address, logger log.Logger) Client {
+	return &client{
+		backend: backend,
+		art:     NewArt(address, backend),
+		logger:  logger,
+	}
+}
+
+// Dial connects to a JSON-RPC endpoint (http, ws or ipc) and checks it serves the expected chain
+// this function should is called in main.go
+func Dial(ctx context.Context, rawurl string, chainId *big.Int, address common.Address, logger log.Logger) (Client, error) {
+
+	backend, err := ethclient.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	id, err := backend.ChainID(ctx)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	if id.Cmp(chainId) != 0 {
+		backend.Close()
+		return nil, ErrWrongChain
+	}
+	return NewClient(backend, address, logger), nil
+}
@@ -0,0 +1,60 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** EIP-1271 contract signatures **********
+
+const ERC1271ABI = `[{"type":"function","name":"isValidSignature","inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"outputs":[{"name":"magicValue","type":"bytes4"}],"stateMutability":"view"}]`
+
+var erc1271ABI, _ = abi.JSON(strings.NewReader(ERC1271ABI))
+
+// bytes4(keccak256("isValidSignature(bytes32,bytes)"))
+var erc1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// IsValidSignature asks a contract wallet whether it signed hash
+// accounts without code are never valid, a reverting wallet rejects the signature
+func (c *client) IsValidSignature(ctx context.Context, account string, hash common.Hash, signature []byte) (bool, error) {
+
+	// log level
+	logger := log.With(c.logger, "method", "IsValidSignature")
+
+	a, err := parseAddress(account)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := c.backend.CodeAt(ctx, a, nil)
+	if err != nil {
+		level.Error(logger).Log("c.backend.CodeAt:", err)
+		return false, err
+	}
+	if len(code) == 0 {
+		return false, nil
+	}
+
+	data, err := erc1271ABI.Pack("isValidSignature", hash, signature)
+	if err != nil {
+		return false, err
+	}
+	out, err := c.backend.CallContract(ctx, ethereum.CallMsg{To: &a, Data: data}, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "execution reverted") {
+			return false, nil
+		}
+		level.Error(logger).Log("c.backend.CallContract:", err)
+		return false, err
+	}
+
+	// bytes4 is returned left aligned in one word
+	return len(out) >= 32 && bytes.Equal(out[:4], erc1271MagicValue), nil
+}
@@ -0,0 +1,416 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// ******** Simulated backend **********
+
+// gas of the simulated chain, blocks are never congested unless a minimum tip holds transactions back
+const (
+	simGasLimit     = 30000000
+	simGasTipCapWei = 1000000000
+)
+
+// ether of every funded account, 1 million
+var simFunds = new(big.Int).Mul(big.NewInt(1000000), big.NewInt(1000000000000000000))
+
+var ErrBlockNotFound = errors.New("Block not found")
+
+var ErrNonEmptyBlock = errors.New("Pending block has transactions")
+
+// replacements of a waiting transaction must raise both fees by this percentage, like geth's default price bump
+const simPriceBump = 10
+
+// SimulatedBackend is an in-process chain for tests and local development
+// transactions run on go-ethereum's simulated backend, a real EVM sealing a block on Commit, its chain id is always 1337
+// on top it answers calls at past blocks, forks by block number and holds back transactions below a minimum tip
+// transactions the EVM would refuse to include are rejected on send with the error a node returns
+type SimulatedBackend struct {
+	*backends.SimulatedBackend
+
+	mtx    sync.Mutex
+	parent *types.Header
+	gas    uint64
+	spent  map[common.Address]*big.Int
+
+	// transactions tipping less than min_tip wait here by sender and nonce, as when blocks are full
+	minTip *big.Int
+	pool   map[common.Address]map[uint64]*types.Transaction
+}
+
+// NewSimulatedBackend starts a chain whose genesis block funds the given accounts
+func NewSimulatedBackend(funded ...common.Address) *SimulatedBackend {
+
+	alloc := make(core.GenesisAlloc, len(funded))
+	for _, a := range funded {
+		alloc[a] = core.GenesisAccount{Balance: new(big.Int).Set(simFunds)}
+	}
+	b := &SimulatedBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, simGasLimit),
+		minTip:           new(big.Int),
+		pool:             make(map[common.Address]map[uint64]*types.Transaction),
+	}
+	b.reset(b.Blockchain().CurrentHeader())
+	return b
+}
+
+// starts an empty pending block on parent
+func (b *SimulatedBackend) reset(parent *types.Header) {
+	b.parent = parent
+	b.gas = 0
+	b.spent = make(map[common.Address]*big.Int)
+}
+
+// Commit seals the pending block and returns its hash
+// the simulated backend is not locked meanwhile, log subscribers may call back into it
+func (b *SimulatedBackend) Commit() common.Hash {
+
+	b.mtx.Lock()
+	b.promote()
+	b.mtx.Unlock()
+
+	hash := b.SimulatedBackend.Commit()
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.reset(b.Blockchain().GetHeaderByHash(hash))
+	return hash
+}
+
+// Rollback discards the pending block
+func (b *SimulatedBackend) Rollback() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.SimulatedBackend.Rollback()
+	b.reset(b.Blockchain().CurrentHeader())
+}
+
+// Fork discards the pending block and builds the following commits on parent
+// like on a real network the new branch replaces the blocks above parent once it is longer
+func (b *SimulatedBackend) Fork(parent uint64) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	header := b.Blockchain().GetHeaderByNumber(parent)
+	if header == nil {
+		return ErrBlockNotFound
+	}
+	b.SimulatedBackend.Rollback()
+	if err := b.SimulatedBackend.Fork(context.Background(), header.Hash()); err != nil {
+		return err
+	}
+	b.reset(header)
+	return nil
+}
+
+// AdjustTime seals an empty block d later than the next block would be, the following blocks continue from its time
+// go-ethereum's simulated backend forgets the shift of a pending block once a transaction is sent to it
+func (b *SimulatedBackend) AdjustTime(d time.Duration) error {
+
+	b.mtx.Lock()
+	if b.gas > 0 {
+		b.mtx.Unlock()
+		return ErrNonEmptyBlock
+	}
+	err := b.SimulatedBackend.AdjustTime(d)
+	b.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	hash := b.SimulatedBackend.Commit()
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.reset(b.Blockchain().GetHeaderByHash(hash))
+	return nil
+}
+
+// DeployArt deploys the compiled Art contract from auth.From into the pending block
+func (b *SimulatedBackend) DeployArt(auth *bind.TransactOpts, account common.Address, minter common.Address, mintingAllowedAfter *big.Int) (common.Address, *types.Transaction, error) {
+
+	code, err := artBytecode()
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	address, tx, _, err := bind.DeployContract(auth, artABI, code, b, account, minter, mintingAllowedAfter)
+	return address, tx, err
+}
+
+// ChainID of the simulated chain
+func (b *SimulatedBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).Set(b.Blockchain().Config().ChainID), nil
+}
+
+// BlockNumber of the head block
+func (b *SimulatedBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	return b.Blockchain().CurrentHeader().Number.Uint64(), nil
+}
+
+// HeaderByNumber returns a canonical header, nil selects the head
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return b.Blockchain().CurrentHeader(), nil
+	}
+	if !number.IsUint64() {
+		return nil, ethereum.NotFound
+	}
+	header := b.Blockchain().GetHeaderByNumber(number.Uint64())
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+// CallContract executes a call at a canonical block, nil selects the head
+// like eth_call the block number seen by the contract is the block's own
+func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+
+	head := b.Blockchain().CurrentHeader()
+	if blockNumber == nil || blockNumber.Cmp(head.Number) == 0 {
+		return b.SimulatedBackend.CallContract(ctx, call, nil)
+	}
+	header, err := b.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, ErrBlockNotFound
+	}
+	state, err := b.Blockchain().StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	value := call.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	gas := call.Gas
+	if gas == 0 {
+		gas = header.GasLimit
+	}
+	// free of charge like eth_call, the caller can afford any value
+	state.SetBalance(call.From, cmath.MaxBig256)
+	msg := types.NewMessage(call.From, call.To, 0, value, gas, new(big.Int), new(big.Int), new(big.Int), call.Data, nil, true)
+	evm := vm.NewEVM(core.NewEVMBlockContext(header, b.Blockchain(), nil), core.NewEVMTxContext(msg), state, b.Blockchain().Config(), vm.Config{NoBaseFee: true})
+
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Revert()) > 0 {
+		reason, err := abi.UnpackRevert(result.Revert())
+		if err != nil {
+			return nil, errors.New("execution reverted")
+		}
+		return nil, fmt.Errorf("execution reverted: %v", reason)
+	}
+	return result.Return(), result.Err
+}
+
+// base fee of the pending block
+func (b *SimulatedBackend) baseFee() *big.Int {
+	return misc.CalcBaseFee(b.Blockchain().Config(), b.parent)
+}
+
+// SuggestGasPrice returns the base fee plus the tip
+func (b *SimulatedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return new(big.Int).Add(b.baseFee(), big.NewInt(simGasTipCapWei)), nil
+}
+
+// SuggestGasTipCap returns 1 gwei, the tip wallets default to
+func (b *SimulatedBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(simGasTipCapWei), nil
+}
+
+// PendingNonceAt counts the transactions waiting in the pool like the txpool's nonce
+func (b *SimulatedBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	nonce, err := b.SimulatedBackend.PendingNonceAt(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	for b.pool[account][nonce] != nil {
+		nonce++
+	}
+	return nonce, nil
+}
+
+// SetMinTip makes transactions with a lower effective tip wait in the pool until the tip is lowered again
+// waiting transactions can be replaced with higher fees, the way stuck transactions are on a congested chain
+func (b *SimulatedBackend) SetMinTip(tip *big.Int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.minTip = new(big.Int).Set(tip)
+	b.promote()
+}
+
+// tip the block producer receives at the pending base fee
+func effectiveTip(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	tip := new(big.Int).Sub(tx.GasFeeCap(), baseFee)
+	if tip.Cmp(tx.GasTipCap()) > 0 {
+		tip.Set(tx.GasTipCap())
+	}
+	return tip
+}
+
+// checks what the EVM requires to include tx in the pending block, the simulated backend panics otherwise
+// the balance check leaves out what the pending block pays to the sender, so it may refuse early but never late
+func (b *SimulatedBackend) validate(tx *types.Transaction, sender common.Address) error {
+
+	if baseFee := b.baseFee(); tx.GasFeeCap().Cmp(baseFee) < 0 {
+		return fmt.Errorf("%w: have %v, want %v", core.ErrFeeCapTooLow, tx.GasFeeCap(), baseFee)
+	}
+	if tx.GasTipCap().Cmp(tx.GasFeeCap()) > 0 {
+		return core.ErrTipAboveFeeCap
+	}
+	intrinsic, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, true)
+	if err != nil {
+		return err
+	}
+	if tx.Gas() < intrinsic {
+		return fmt.Errorf("%w: have %d, want %d", core.ErrIntrinsicGas, tx.Gas(), intrinsic)
+	}
+	if b.gas+tx.Gas() > b.parent.GasLimit {
+		return core.ErrGasLimitReached
+	}
+
+	state, err := b.Blockchain().StateAt(b.parent.Root)
+	if err != nil {
+		return err
+	}
+	cost := tx.Cost()
+	if spent, ok := b.spent[sender]; ok {
+		cost.Add(cost, spent)
+	}
+	if balance := state.GetBalance(sender); balance.Cmp(cost) < 0 {
+		return fmt.Errorf("%w: address %v have %v want %v", core.ErrInsufficientFunds, sender, balance, cost)
+	}
+	return nil
+}
+
+// includes a validated transaction in the pending block
+func (b *SimulatedBackend) include(tx *types.Transaction, sender common.Address) error {
+	if err := b.SimulatedBackend.SendTransaction(context.Background(), tx); err != nil {
+		return err
+	}
+	b.gas += tx.Gas()
+	if spent, ok := b.spent[sender]; ok {
+		spent.Add(spent, tx.Cost())
+	} else {
+		b.spent[sender] = tx.Cost()
+	}
+	return nil
+}
+
+// executes waiting transactions in nonce order as long as they tip enough
+// transactions left behind by a rollback or fork and those no longer valid are dropped
+func (b *SimulatedBackend) promote() {
+
+	// senders in address order keep blocks deterministic
+	senders := make([]common.Address, 0, len(b.pool))
+	for sender := range b.pool {
+		senders = append(senders, sender)
+	}
+	sort.Slice(senders, func(i, j int) bool { return bytes.Compare(senders[i][:], senders[j][:]) < 0 })
+
+	baseFee := b.baseFee()
+	for _, sender := range senders {
+		txs := b.pool[sender]
+		next, _ := b.SimulatedBackend.PendingNonceAt(context.Background(), sender)
+		for nonce := range txs {
+			if nonce < next {
+				delete(txs, nonce)
+			}
+		}
+		for {
+			tx, ok := txs[next]
+			if !ok || effectiveTip(tx, baseFee).Cmp(b.minTip) < 0 {
+				break
+			}
+			delete(txs, next)
+			if b.validate(tx, sender) != nil || b.include(tx, sender) != nil {
+				break
+			}
+			next++
+		}
+		if len(txs) == 0 {
+			delete(b.pool, sender)
+		}
+	}
+}
+
+// SendTransaction adds a signed transaction to the pool and executes what became executable on the pending block
+// reverted transactions are included with a failed receipt like on a real chain
+func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	sender, err := types.Sender(types.LatestSignerForChainID(b.Blockchain().Config().ChainID), tx)
+	if err != nil {
+		return fmt.Errorf("invalid transaction: %v", err)
+	}
+	if _, _, err := b.TransactionByHash(ctx, tx.Hash()); err == nil {
+		return fmt.Errorf("already known")
+	}
+	waiting := b.pool[sender]
+	want, err := b.SimulatedBackend.PendingNonceAt(ctx, sender)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce() < want || tx.Nonce() > want+uint64(len(waiting)) {
+		return fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), want+uint64(len(waiting)))
+	}
+	if err := b.validate(tx, sender); err != nil {
+		return err
+	}
+
+	if old, ok := waiting[tx.Nonce()]; ok {
+		if old.Hash() == tx.Hash() {
+			return fmt.Errorf("already known")
+		}
+		if !bumped(old.GasTipCap(), tx.GasTipCap()) || !bumped(old.GasFeeCap(), tx.GasFeeCap()) {
+			return fmt.Errorf("replacement transaction underpriced")
+		}
+	}
+	if waiting == nil {
+		waiting = make(map[uint64]*types.Transaction)
+		b.pool[sender] = waiting
+	}
+	waiting[tx.Nonce()] = tx
+	b.promote()
+	return nil
+}
+
+// whether next raises old by at least the price bump
+func bumped(old, next *big.Int) bool {
+	min := new(big.Int).Mul(old, big.NewInt(100+simPriceBump))
+	return new(big.Int).Mul(next, big.NewInt(100)).Cmp(min) >= 0
+}
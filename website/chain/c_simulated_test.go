@@ -0,0 +1,236 @@
+package chain_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var gwei = big.NewInt(1000000000)
+
+// signs an ether transfer of one wei, fees in gwei
+func transfer(t *testing.T, from chaintest.Account, to common.Address, nonce uint64, tip int64, feeCap int64, gas uint64) *types.Transaction {
+	tx, err := types.SignNewTx(from.Key, types.LatestSignerForChainID(chaintest.ChainId), &types.DynamicFeeTx{
+		ChainID:   chaintest.ChainId,
+		Nonce:     nonce,
+		GasTipCap: new(big.Int).Mul(big.NewInt(tip), gwei),
+		GasFeeCap: new(big.Int).Mul(big.NewInt(feeCap), gwei),
+		Gas:       gas,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("SignNewTx failed, error: %v.", err)
+	}
+	return tx
+}
+
+// transactions the EVM cannot include are refused on send, the chain keeps working
+func TestSendRejects(t *testing.T) {
+
+	ctx := context.Background()
+	sim := chaintest.NewBackend(t)
+	alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t)
+
+	if _, err := sim.HeaderByNumber(ctx, big.NewInt(5)); err != ethereum.NotFound {
+		t.Errorf("HeaderByNumber of a future block returned %v, expected NotFound.", err)
+	}
+	if _, err := sim.CallContract(ctx, ethereum.CallMsg{To: &bob.Addr}, big.NewInt(5)); err != chain.ErrBlockNotFound {
+		t.Errorf("CallContract at a future block returned %v, expected %v.", err, chain.ErrBlockNotFound)
+	}
+
+	for _, c := range []struct {
+		tx     *types.Transaction
+		reason string
+	}{
+		{transfer(t, alice, bob.Addr, 1, 1, 3, 21000), "invalid transaction nonce"},
+		{transfer(t, alice, bob.Addr, 0, 0, 0, 21000), "max fee per gas less than block base fee"},
+		{transfer(t, alice, bob.Addr, 0, 4, 3, 21000), "max priority fee per gas higher than max fee per gas"},
+		{transfer(t, alice, bob.Addr, 0, 1, 3, 20000), "intrinsic gas too low"},
+		{transfer(t, alice, bob.Addr, 0, 1, 3, 40000000), "gas limit reached"},
+	} {
+		if err := sim.SendTransaction(ctx, c.tx); err == nil || !strings.Contains(err.Error(), c.reason) {
+			t.Errorf("SendTransaction returned %v, expected %q.", err, c.reason)
+		}
+	}
+
+	unfunded := chain.NewSimulatedBackend()
+	defer unfunded.Close()
+	if err := unfunded.SendTransaction(ctx, transfer(t, alice, bob.Addr, 0, 1, 3, 21000)); err == nil || !strings.Contains(err.Error(), "insufficient funds") {
+		t.Errorf("SendTransaction from an unfunded account returned %v, expected insufficient funds.", err)
+	}
+
+	tx := transfer(t, alice, bob.Addr, 0, 1, 3, 21000)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	if err := sim.SendTransaction(ctx, tx); err == nil || err.Error() != "already known" {
+		t.Errorf("SendTransaction of a pending transaction returned %v, expected already known.", err)
+	}
+	if err := sim.AdjustTime(time.Hour); err != chain.ErrNonEmptyBlock {
+		t.Errorf("AdjustTime returned %v, expected %v.", err, chain.ErrNonEmptyBlock)
+	}
+	sim.Commit()
+
+	if receipt, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("TransactionReceipt returned %+v, error: %v.", receipt, err)
+	}
+	before, _ := sim.BalanceAt(ctx, bob.Addr, big.NewInt(0))
+	if after, _ := sim.BalanceAt(ctx, bob.Addr, nil); new(big.Int).Sub(after, before).Int64() != 1 {
+		t.Errorf("balance of bob went from %v to %v, expected one more wei.", before, after)
+	}
+}
+
+// transactions below the minimum tip wait, a replacement must raise both fees by 10%
+func TestMinTip(t *testing.T) {
+
+	ctx := context.Background()
+	sim := chaintest.NewBackend(t)
+	alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim.SetMinTip(new(big.Int).Mul(big.NewInt(2), gwei))
+
+	held := transfer(t, alice, bob.Addr, 0, 1, 3, 21000)
+	if err := sim.SendTransaction(ctx, held); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, alice.Addr); nonce != 1 {
+		t.Errorf("PendingNonceAt returned %d, expected 1.", nonce)
+	}
+	sim.Commit()
+	if _, err := sim.TransactionReceipt(ctx, held.Hash()); err != ethereum.NotFound {
+		t.Fatalf("TransactionReceipt of a waiting transaction returned %v, expected NotFound.", err)
+	}
+
+	if err := sim.SendTransaction(ctx, transfer(t, alice, bob.Addr, 0, 1, 4, 21000)); err == nil || err.Error() != "replacement transaction underpriced" {
+		t.Errorf("SendTransaction returned %v, expected replacement transaction underpriced.", err)
+	}
+	replacement := transfer(t, alice, bob.Addr, 0, 2, 4, 21000)
+	if err := sim.SendTransaction(ctx, replacement); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	sim.Commit()
+	if _, err := sim.TransactionReceipt(ctx, replacement.Hash()); err != nil {
+		t.Errorf("TransactionReceipt of the replacement failed, error: %v.", err)
+	}
+	if _, err := sim.TransactionReceipt(ctx, held.Hash()); err != ethereum.NotFound {
+		t.Errorf("TransactionReceipt of the replaced transaction returned %v, expected NotFound.", err)
+	}
+}
+
+// a fork replaces the blocks above its parent once the new branch is longer
+func TestFork(t *testing.T) {
+
+	ctx := context.Background()
+	sim := chaintest.NewBackend(t)
+	alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t)
+
+	sim.Commit()
+	base, _ := sim.BlockNumber(ctx)
+	dropped := transfer(t, alice, bob.Addr, 0, 1, 3, 21000)
+	if err := sim.SendTransaction(ctx, dropped); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	old := sim.Commit()
+
+	if err := sim.Fork(base + 5); err != chain.ErrBlockNotFound {
+		t.Errorf("Fork above the head returned %v, expected %v.", err, chain.ErrBlockNotFound)
+	}
+	if err := sim.Fork(base); err != nil {
+		t.Fatalf("Fork failed, error: %v.", err)
+	}
+	kept := transfer(t, alice, bob.Addr, 0, 2, 4, 21000)
+	if err := sim.SendTransaction(ctx, kept); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	sim.Commit()
+	sim.Commit()
+
+	if head, _ := sim.BlockNumber(ctx); head != base+2 {
+		t.Errorf("head is %d, expected %d.", head, base+2)
+	}
+	header, _ := sim.HeaderByNumber(ctx, new(big.Int).SetUint64(base+1))
+	if header.Hash() == old {
+		t.Errorf("block %d was not replaced.", base+1)
+	}
+	if _, err := sim.TransactionReceipt(ctx, dropped.Hash()); err != ethereum.NotFound {
+		t.Errorf("TransactionReceipt of the dropped transaction returned %v, expected NotFound.", err)
+	}
+	if receipt, err := sim.TransactionReceipt(ctx, kept.Hash()); err != nil || receipt.BlockHash != header.Hash() {
+		t.Errorf("TransactionReceipt returned %+v, error: %v.", receipt, err)
+	}
+}
+
+// a time shift holds for the blocks after it, also once transactions are sent
+func TestAdjustTime(t *testing.T) {
+
+	ctx := context.Background()
+	sim := chaintest.NewBackend(t)
+	alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t)
+
+	before, _ := sim.HeaderByNumber(ctx, nil)
+	if err := sim.AdjustTime(time.Hour); err != nil {
+		t.Fatalf("AdjustTime failed, error: %v.", err)
+	}
+	tx := transfer(t, alice, bob.Addr, 0, 1, 3, 21000)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	sim.Commit()
+
+	receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("TransactionReceipt failed, error: %v.", err)
+	}
+	header, _ := sim.HeaderByNumber(ctx, receipt.BlockNumber)
+	if header.Time < before.Time+3600 {
+		t.Errorf("block time %d, expected at least an hour after %d.", header.Time, before.Time)
+	}
+}
+
+// calls at past blocks run on the state and block number of that block
+func TestCallAtBlock(t *testing.T) {
+
+	ctx := context.Background()
+	sim := chaintest.NewBackend(t)
+	alice := chaintest.NewAccount(t)
+
+	// creation code of a contract returning the block number: NUMBER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	code := common.FromHex("0x6009600c60003960096000f3" + "4360005260206000f3")
+	tx, err := types.SignNewTx(alice.Key, types.LatestSignerForChainID(chaintest.ChainId), &types.DynamicFeeTx{
+		ChainID: chaintest.ChainId, GasTipCap: gwei, GasFeeCap: new(big.Int).Mul(big.NewInt(3), gwei), Gas: 100000, Data: code,
+	})
+	if err != nil {
+		t.Fatalf("SignNewTx failed, error: %v.", err)
+	}
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("TransactionReceipt returned %+v, error: %v.", receipt, err)
+	}
+	deployed := receipt.BlockNumber.Uint64()
+	sim.Commit()
+	sim.Commit()
+
+	call := ethereum.CallMsg{From: alice.Addr, To: &receipt.ContractAddress}
+	for _, block := range []uint64{deployed, deployed + 1, deployed + 2} {
+		out, err := sim.CallContract(ctx, call, new(big.Int).SetUint64(block))
+		if err != nil || new(big.Int).SetBytes(out).Uint64() != block {
+			t.Errorf("CallContract at %d returned %x, error: %v.", block, out, err)
+		}
+	}
+	if out, err := sim.CallContract(ctx, call, new(big.Int).SetUint64(deployed-1)); err != nil || len(out) != 0 {
+		t.Errorf("CallContract before the deployment returned %x, error: %v.", out, err)
+	}
+}
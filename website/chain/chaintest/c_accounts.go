@@ -0,0 +1,55 @@
+// Package chaintest holds the fixtures of tests against the simulated chain:
+// funded accounts, a deployed Art contract and an index of it
+package chaintest
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ChainId of the simulated chain, go-ethereum fixes it
+var ChainId = big.NewInt(1337)
+
+// accounts funded in the genesis block of every backend, handed out in turn
+const funded = 256
+
+var (
+	keys = func() []*ecdsa.PrivateKey {
+		keys := make([]*ecdsa.PrivateKey, funded)
+		for i := range keys {
+			keys[i], _ = crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("chaintest account %d", i))))
+		}
+		return keys
+	}()
+	next uint32
+)
+
+// Account is a funded key with a transactor for the simulated chain
+type Account struct {
+	Key  *ecdsa.PrivateKey
+	Addr common.Address
+	Opts *bind.TransactOpts
+}
+
+// NewAccount hands out the next funded key, a test gets distinct keys as long as it takes fewer than 256
+func NewAccount(t *testing.T) Account {
+	key := keys[atomic.AddUint32(&next, 1)%funded]
+	opts, err := bind.NewKeyedTransactorWithChainID(key, ChainId)
+	if err != nil {
+		t.Fatalf("NewKeyedTransactorWithChainID failed, error: %v.", err)
+	}
+	return Account{Key: key, Addr: crypto.PubkeyToAddress(key.PublicKey), Opts: opts}
+}
+
+// Hex is the lowercase address, the way services store addresses
+func (a Account) Hex() string {
+	return strings.ToLower(a.Addr.Hex())
+}
@@ -0,0 +1,51 @@
+package chaintest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"website/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/kit/log"
+)
+
+// minting opens a year after deployment unless a test needs it earlier
+const mintingDelay = 365 * 24 * 3600
+
+// NewBackend starts an empty simulated chain with every account of NewAccount funded
+func NewBackend(t *testing.T) *chain.SimulatedBackend {
+
+	addresses := make([]common.Address, len(keys))
+	for i, key := range keys {
+		addresses[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	sim := chain.NewSimulatedBackend(addresses...)
+	t.Cleanup(func() { sim.Close() })
+	return sim
+}
+
+// DeployArt starts a simulated chain with Art deployed and committed
+// the whole supply is on holder, minting is allowed a year after the genesis block
+func DeployArt(t *testing.T, holder Account, minter common.Address) (*chain.SimulatedBackend, chain.Client) {
+
+	sim := NewBackend(t)
+	head, err := sim.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("HeaderByNumber failed, error: %v.", err)
+	}
+	return sim, DeployArtOn(t, sim, holder, minter, new(big.Int).SetUint64(head.Time+mintingDelay))
+}
+
+// DeployArtOn deploys Art on an existing chain from holder, commits it and returns a client of it
+func DeployArtOn(t *testing.T, sim *chain.SimulatedBackend, holder Account, minter common.Address, mintingAllowedAfter *big.Int) chain.Client {
+
+	address, _, err := sim.DeployArt(holder.Opts, holder.Addr, minter, mintingAllowedAfter)
+	if err != nil {
+		t.Fatalf("DeployArt failed, error: %v.", err)
+	}
+	sim.Commit()
+	return chain.NewClient(sim, address, log.NewNopLogger())
+}
@@ -0,0 +1,40 @@
+package chaintest
+
+import (
+	"context"
+	"testing"
+
+	"website/chain"
+	"website/indexer"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NewIndex indexes the Art contract of client into a store in dir
+func NewIndex(t *testing.T, dir string, client chain.Client, config indexer.Config) (indexer.Service, indexer.IndexStore) {
+
+	store, err := indexer.NewIndexStore(indexer.IndexStoreConfig{IndexPath: dir}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewIndexStore failed, error: %v.", err)
+	}
+	s, err := indexer.NewService(store, client, config, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewService failed, error: %v.", err)
+	}
+	return s, store
+}
+
+// Sync syncs the index until it reaches the confirmed head
+func Sync(t *testing.T, s indexer.Service) indexer.Status {
+	for i := 0; i < 100; i++ {
+		st, err := s.Sync(context.Background())
+		if err != nil {
+			t.Fatalf("Sync failed, error: %v.", err)
+		}
+		if st.Synced {
+			return st
+		}
+	}
+	t.Fatalf("Sync did not reach the head.")
+	return indexer.Status{}
+}
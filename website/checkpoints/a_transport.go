@@ -0,0 +1,162 @@
+package checkpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"website/chain"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// delegates listed when no limit is given
+const defaultDelegates = 100
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidLimit),
+		errors.Is(err, ErrTooManyAccounts):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, chain.ErrNotDetermined):
+		return http.StatusConflict
+
+	case errors.Is(err, ErrIndexChanged):
+		return http.StatusServiceUnavailable
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// required block from query parameters
+func decodeBlock(r *http.Request) (uint64, error) {
+	block, err := strconv.ParseUint(r.URL.Query().Get("block"), 10, 64)
+	if err != nil {
+		return 0, ErrBadRequest
+	}
+	return block, nil
+}
+
+// decode block and repeated address query parameters
+func decodeVotesAt(_ context.Context, r *http.Request) (interface{}, error) {
+
+	block, err := decodeBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	accounts := r.URL.Query()["address"]
+	if len(accounts) == 0 {
+		return nil, ErrBadRequest
+	}
+	return VotesAtRequest{Accounts: accounts, Block: block}, nil
+}
+
+// decode block and optional limit from query parameters
+func decodeTopDelegates(_ context.Context, r *http.Request) (interface{}, error) {
+
+	block, err := decodeBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	req := TopDelegatesRequest{Block: block, Limit: defaultDelegates}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+// decode address from route
+func decodeReadCheckpoints(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadCheckpointsRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching checkpoint handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	votesAtHandler := httptransport.NewServer(
+		e.VotesAt,
+		decodeVotesAt,
+		encodeResponse,
+		options...,
+	)
+
+	topDelegatesHandler := httptransport.NewServer(
+		e.TopDelegates,
+		decodeTopDelegates,
+		encodeResponse,
+		options...,
+	)
+
+	readCheckpointsHandler := httptransport.NewServer(
+		e.ReadCheckpoints,
+		decodeReadCheckpoints,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/token/votes", votesAtHandler).Methods("GET")
+	router.Handle("/token/delegates", topDelegatesHandler).Methods("GET")
+	router.Handle("/token/delegates/{address}/checkpoints", readCheckpointsHandler).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,110 @@
+package checkpoints
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type VotesAtRequest struct {
+	Accounts []string
+	Block    uint64
+}
+
+type TopDelegatesRequest struct {
+	Block uint64
+	Limit int
+}
+
+type ReadCheckpointsRequest struct {
+	Address string
+}
+
+type VotesResponse struct {
+	Data []Votes `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have VotesResponse follow the customError interface defined in a_transport.go
+func (r VotesResponse) error() error { return r.Err }
+
+type CheckpointsResponse struct {
+	Data []Checkpoint `json:"data"`
+	Err  error        `json:"errors"`
+}
+
+// have CheckpointsResponse follow the customError interface defined in a_transport.go
+func (r CheckpointsResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	VotesAt         endpoint.Endpoint
+	TopDelegates    endpoint.Endpoint
+	ReadCheckpoints endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		VotesAt:         epVotesAt(s),
+		TopDelegates:    epTopDelegates(s),
+		ReadCheckpoints: epReadCheckpoints(s),
+	}
+}
+
+// votes of several accounts at a block endpoint
+func epVotesAt(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(VotesAtRequest)
+
+		// call service method
+		votes, err := s.VotesAt(ctx, req.Accounts, req.Block)
+		if err != nil {
+			return VotesResponse{Err: err}, err
+		}
+
+		return VotesResponse{Data: votes, Err: nil}, nil
+	}
+}
+
+// top delegates at a block endpoint
+func epTopDelegates(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(TopDelegatesRequest)
+
+		// call service method
+		votes, err := s.TopDelegates(ctx, req.Block, req.Limit)
+		if err != nil {
+			return VotesResponse{Err: err}, err
+		}
+
+		return VotesResponse{Data: votes, Err: nil}, nil
+	}
+}
+
+// checkpoints of a delegate endpoint
+func epReadCheckpoints(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadCheckpointsRequest)
+
+		// call service method
+		cps, err := s.ReadCheckpoints(ctx, req.Address)
+		if err != nil {
+			return CheckpointsResponse{Err: err}, err
+		}
+
+		return CheckpointsResponse{Data: cps, Err: nil}, nil
+	}
+}
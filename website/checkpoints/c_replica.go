@@ -0,0 +1,95 @@
+package checkpoints
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+
+	"website/chain"
+	"website/indexer"
+)
+
+// ******** Checkpoints replayed from events **********
+
+var ErrBlockOverflow = errors.New("Block number exceeds 32 bits")
+
+var ErrInconsistent = errors.New("Indexed votes do not continue the last checkpoint")
+
+// checkpoints of every delegate, oldest first, as written by the contract
+// every DelegateVotesChanged event is emitted by exactly one _writeCheckpoint call
+type replica struct {
+	checkpoints map[string][]chain.Checkpoint
+}
+
+func newReplica() *replica {
+	return &replica{checkpoints: make(map[string][]chain.Checkpoint)}
+}
+
+func amount(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
+// follows _writeCheckpoint, a second write in the same block replaces the votes of the last checkpoint
+// the previous balance of the event must match the last checkpoint, otherwise events are missing
+func (r *replica) write(e indexer.Event) error {
+
+	if e.Type != indexer.EventDelegateVotesChanged {
+		return nil
+	}
+	if e.Block > math.MaxUint32 {
+		return ErrBlockOverflow
+	}
+	block := uint32(e.Block)
+
+	cps := r.checkpoints[e.Delegate]
+	if r.current(e.Delegate).Cmp(amount(e.PreviousBalance)) != 0 {
+		return ErrInconsistent
+	}
+
+	votes := amount(e.NewBalance)
+	if n := len(cps); n > 0 && cps[n-1].FromBlock == block {
+		cps[n-1].Votes = votes
+	} else {
+		r.checkpoints[e.Delegate] = append(cps, chain.Checkpoint{FromBlock: block, Votes: votes})
+	}
+	return nil
+}
+
+// follows getCurrentVotes
+func (r *replica) current(delegate string) *big.Int {
+	cps := r.checkpoints[delegate]
+	if n := len(cps); n > 0 {
+		return cps[n-1].Votes
+	}
+	return new(big.Int)
+}
+
+// follows getPriorVotes without the determined check, callers bound the block
+func (r *replica) prior(delegate string, blockNumber uint64) *big.Int {
+	return chain.VotesAt(r.checkpoints[delegate], blockNumber)
+}
+
+// delegates with votes at the block, most votes first, ties by address
+func (r *replica) top(blockNumber uint64) []Votes {
+
+	top := []Votes{}
+	values := make(map[string]*big.Int)
+	for delegate := range r.checkpoints {
+		if v := r.prior(delegate, blockNumber); v.Sign() > 0 {
+			values[delegate] = v
+			top = append(top, Votes{Address: delegate, Votes: v.String()})
+		}
+	}
+	sort.Slice(top, func(i, k int) bool {
+		if c := values[top[i].Address].Cmp(values[top[k].Address]); c != 0 {
+			return c > 0
+		}
+		return top[i].Address < top[k].Address
+	})
+	return top
+}
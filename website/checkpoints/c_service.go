@@ -0,0 +1,242 @@
+package checkpoints
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"website/chain"
+	"website/indexer"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidLimit = errors.New("Invalid limit")
+
+var ErrTooManyAccounts = errors.New("Too many accounts in one query")
+
+var ErrIndexChanged = errors.New("Index changed while reading events")
+
+// accounts answered by one VotesAt call
+const maxAccounts = 1000
+
+// Index reads indexed events, implemented by the indexer service
+type Index interface {
+	ReadStatus(ctx context.Context) (indexer.Status, error)
+	ListEvents(ctx context.Context, from uint64, to uint64) ([]indexer.Event, error)
+}
+
+// votes of an address at a block, decimal string in the token's smallest unit
+type Votes struct {
+	Address string `json:"address"`
+	Votes   string `json:"votes"`
+}
+
+// checkpoint as stored by the contract
+type Checkpoint struct {
+	FromBlock uint32 `json:"from_block"`
+	Votes     string `json:"votes"`
+}
+
+// service interface defining all required methods
+// queries follow getPriorVotes and only answer confirmed blocks below the head, others return chain.ErrNotDetermined
+type Service interface {
+	PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error)
+	VotesAt(ctx context.Context, accounts []string, blockNumber uint64) ([]Votes, error)
+	TopDelegates(ctx context.Context, blockNumber uint64, limit int) ([]Votes, error)
+	ReadCheckpoints(ctx context.Context, account string) ([]Checkpoint, error)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+	index Index
+
+	// guards the replica, next is the first block not yet replayed
+	mtx     sync.Mutex
+	replica *replica
+	next    uint64
+	reorgs  int
+	status  indexer.Status
+
+	logger log.Logger
+}
+
+// replays events indexed since the last call
+// a reorg in the index drops the replica and replays all events
+func (s *service) refresh(ctx context.Context) error {
+
+	// log level
+	logger := log.With(s.logger, "method", "refresh")
+
+	st, err := s.index.ReadStatus(ctx)
+	if err != nil {
+		level.Error(logger).Log("s.index.ReadStatus:", err)
+		return err
+	}
+	if st.Reorgs != s.reorgs || st.Indexed+1 < s.next {
+		s.replica, s.next = newReplica(), 0
+	}
+	s.reorgs = st.Reorgs
+
+	if st.Indexed >= s.next {
+		events, err := s.index.ListEvents(ctx, s.next, st.Indexed)
+		if err != nil {
+			level.Error(logger).Log("s.index.ListEvents:", err)
+			return err
+		}
+
+		// a rollback between the two reads leaves the events incomplete
+		after, err := s.index.ReadStatus(ctx)
+		if err != nil {
+			level.Error(logger).Log("s.index.ReadStatus:", err)
+			return err
+		}
+		if after.Reorgs != st.Reorgs || after.Indexed < st.Indexed {
+			return ErrIndexChanged
+		}
+
+		for _, e := range events {
+			if err := s.replica.write(e); err != nil {
+				level.Error(logger).Log("s.replica.write:", err, "block", e.Block, "delegate", e.Delegate)
+				s.replica, s.next = newReplica(), 0
+				return err
+			}
+		}
+		s.next = st.Indexed + 1
+	}
+	s.status = st
+
+	return nil
+}
+
+// blocks must be indexed, confirmed and below the head
+// unconfirmed blocks could still change with a reorg
+func (s *service) determined(blockNumber uint64) error {
+	st := s.status
+	if st.Head == 0 || blockNumber >= st.Head || blockNumber > st.Indexed || blockNumber > st.Confirmed {
+		return chain.ErrNotDetermined
+	}
+	return nil
+}
+
+// parses an address into the lowercase form events are indexed with
+func parseAddress(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(common.HexToAddress(address).Hex()), nil
+}
+
+// PriorVotes makes the service usable as selection.VotesSource
+func (s *service) PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error) {
+
+	account, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.determined(blockNumber); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(s.replica.prior(account, blockNumber)), nil
+}
+
+// VotesAt answers the votes of many accounts at one block, in the order given
+func (s *service) VotesAt(ctx context.Context, accounts []string, blockNumber uint64) ([]Votes, error) {
+
+	if len(accounts) > maxAccounts {
+		return nil, ErrTooManyAccounts
+	}
+	addresses := make([]string, len(accounts))
+	for i, account := range accounts {
+		a, err := parseAddress(account)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = a
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.determined(blockNumber); err != nil {
+		return nil, err
+	}
+
+	votes := make([]Votes, len(addresses))
+	for i, a := range addresses {
+		votes[i] = Votes{Address: a, Votes: s.replica.prior(a, blockNumber).String()}
+	}
+	return votes, nil
+}
+
+// TopDelegates lists delegates by votes at the block, limit 0 lists all
+func (s *service) TopDelegates(ctx context.Context, blockNumber uint64, limit int) ([]Votes, error) {
+
+	if limit < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.determined(blockNumber); err != nil {
+		return nil, err
+	}
+
+	top := s.replica.top(blockNumber)
+	if limit > 0 && limit < len(top) {
+		top = top[:limit]
+	}
+	return top, nil
+}
+
+// ReadCheckpoints returns the checkpoints of a delegate up to the last indexed block, oldest first
+func (s *service) ReadCheckpoints(ctx context.Context, account string) ([]Checkpoint, error) {
+
+	account, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	cps := make([]Checkpoint, 0, len(s.replica.checkpoints[account]))
+	for _, cp := range s.replica.checkpoints[account] {
+		cps = append(cps, Checkpoint{FromBlock: cp.FromBlock, Votes: cp.Votes.String()})
+	}
+	return cps, nil
+}
+
+// init checkpoint service, the replica is built on the first query
+// this function should is called in main.go
+func NewService(index Index, logger log.Logger) Service {
+	return &service{
+		index:   index,
+		replica: newReplica(),
+		logger:  logger,
+	}
+}
@@ -0,0 +1,251 @@
+package checkpoints
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/indexer"
+	"website/selection"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+)
+
+var _ selection.VotesSource = Service(nil)
+
+// indexes a simulated chain and serves checkpoints from the index
+func testService(t *testing.T, client chain.Client) (indexer.Service, Service) {
+	index, _ := chaintest.NewIndex(t, t.TempDir(), client, indexer.Config{BatchSize: 16})
+	return index, NewService(index, log.NewNopLogger())
+}
+
+// random transfers and delegations, several per block so checkpoints are overwritten within a block
+// the replica must answer every account at every block exactly like the compiled contract
+func TestReplicaMatchesContract(t *testing.T) {
+
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(43))
+
+	accounts := make([]chaintest.Account, 5)
+	for i := range accounts {
+		accounts[i] = chaintest.NewAccount(t)
+	}
+	holder := accounts[0]
+
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	art := client.Art()
+	var err error
+
+	for block := 0; block < 60; block++ {
+		for n := r.Intn(4); n > 0; n-- {
+			from := accounts[r.Intn(len(accounts))]
+			to := accounts[r.Intn(len(accounts))]
+			switch r.Intn(3) {
+			case 0:
+				// the zero delegate removes the votes from the previous delegate
+				delegatee := to.Addr
+				if r.Intn(4) == 0 {
+					delegatee = common.Address{}
+				}
+				_, err = art.Delegate(from.Opts, delegatee)
+			default:
+				balance, _ := client.BalanceOf(ctx, from.Hex())
+				if balance.Sign() == 0 {
+					from = holder
+					balance, _ = client.BalanceOf(ctx, from.Hex())
+				}
+				value := new(big.Int).Rand(r, new(big.Int).Div(balance, big.NewInt(4)))
+				_, err = art.Transfer(from.Opts, to.Addr, value)
+			}
+			if err != nil {
+				t.Fatalf("transaction failed, error: %v.", err)
+			}
+		}
+		sim.Commit()
+	}
+
+	index, s := testService(t, client)
+	chaintest.Sync(t, index)
+	last, _ := client.BlockNumber(ctx)
+
+	for _, a := range accounts {
+		onchain, err := client.Checkpoints(ctx, a.Hex())
+		if err != nil {
+			t.Fatalf("Checkpoints failed, error: %v.", err)
+		}
+		replicated, err := s.ReadCheckpoints(ctx, a.Hex())
+		if err != nil {
+			t.Fatalf("ReadCheckpoints failed, error: %v.", err)
+		}
+		if len(onchain) != len(replicated) {
+			t.Fatalf("%s has %d checkpoints, contract has %d.", a.Hex(), len(replicated), len(onchain))
+		}
+		for i := range onchain {
+			if onchain[i].FromBlock != replicated[i].FromBlock || onchain[i].Votes.String() != replicated[i].Votes {
+				t.Errorf("checkpoint %d of %s is %+v, contract has %+v.", i, a.Hex(), replicated[i], onchain[i])
+			}
+		}
+	}
+
+	holders := make([]string, len(accounts))
+	for i, a := range accounts {
+		holders[i] = a.Hex()
+	}
+	for block := uint64(0); block < last; block++ {
+
+		bulk, err := s.VotesAt(ctx, holders, block)
+		if err != nil {
+			t.Fatalf("VotesAt failed, error: %v.", err)
+		}
+		expected := []Votes{}
+		for i, a := range accounts {
+			onchain, err := client.PriorVotes(ctx, a.Hex(), block)
+			if err != nil {
+				t.Fatalf("PriorVotes failed, error: %v.", err)
+			}
+			if bulk[i].Votes != onchain.String() {
+				t.Errorf("%s has %s votes at block %d, contract reports %v.", a.Hex(), bulk[i].Votes, block, onchain)
+			}
+			if onchain.Sign() > 0 {
+				expected = append(expected, Votes{Address: a.Hex(), Votes: onchain.String()})
+			}
+		}
+
+		sort.Slice(expected, func(i, k int) bool {
+			vi, _ := new(big.Int).SetString(expected[i].Votes, 10)
+			vk, _ := new(big.Int).SetString(expected[k].Votes, 10)
+			if c := vi.Cmp(vk); c != 0 {
+				return c > 0
+			}
+			return expected[i].Address < expected[k].Address
+		})
+		top, err := s.TopDelegates(ctx, block, 0)
+		if err != nil {
+			t.Fatalf("TopDelegates failed, error: %v.", err)
+		}
+		if len(top) != len(expected) {
+			t.Fatalf("%d delegates at block %d, expected %d.", len(top), block, len(expected))
+		}
+		for i := range top {
+			if top[i] != expected[i] {
+				t.Errorf("delegate %d at block %d is %+v, expected %+v.", i, block, top[i], expected[i])
+			}
+		}
+	}
+
+	// like the contract, the head block is not yet determined
+	if _, err := s.PriorVotes(ctx, holder.Hex(), last); err != chain.ErrNotDetermined {
+		t.Errorf("PriorVotes at the head returned %v, expected %v.", err, chain.ErrNotDetermined)
+	}
+}
+
+// blocks become determined once the index follows the chain
+func TestReplicaFollowsIndex(t *testing.T) {
+
+	ctx := context.Background()
+	holder, alice := chaintest.NewAccount(t), chaintest.NewAccount(t)
+
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+
+	index, s := testService(t, client)
+	chaintest.Sync(t, index)
+
+	if _, err := client.Art().Delegate(holder.Opts, alice.Addr); err != nil {
+		t.Fatalf("Delegate failed, error: %v.", err)
+	}
+	delegated := sim.Commit()
+	sim.Commit()
+	header, _ := sim.HeaderByHash(ctx, delegated)
+	block := header.Number.Uint64()
+
+	// the delegation block is mined but not indexed yet
+	if _, err := s.PriorVotes(ctx, alice.Hex(), block); err != chain.ErrNotDetermined {
+		t.Errorf("PriorVotes returned %v before indexing, expected %v.", err, chain.ErrNotDetermined)
+	}
+
+	chaintest.Sync(t, index)
+	votes, err := s.PriorVotes(ctx, alice.Hex(), block)
+	if err != nil {
+		t.Fatalf("PriorVotes failed, error: %v.", err)
+	}
+	supply, _ := client.TotalSupply(ctx)
+	if votes.Cmp(supply) != 0 {
+		t.Errorf("alice has %v votes, expected the supply %v.", votes, supply)
+	}
+	before, _ := s.PriorVotes(ctx, alice.Hex(), block-1)
+	if before.Sign() != 0 {
+		t.Errorf("alice has %v votes before the delegation, expected 0.", before)
+	}
+	if _, err := s.VotesAt(ctx, []string{"alice"}, block); err != ErrInvalidAddress {
+		t.Errorf("VotesAt returned %v, expected %v.", err, ErrInvalidAddress)
+	}
+}
+
+// replays votes changes without a chain: same-block overwrites, gaps in the events and ties in the ranking
+func TestReplicaWrite(t *testing.T) {
+
+	alice, bob, carol := "0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000b2", "0x00000000000000000000000000000000000000c3"
+	changed := func(block uint64, delegate string, previous, next string) indexer.Event {
+		return indexer.Event{Type: indexer.EventDelegateVotesChanged, Block: block, Delegate: delegate, PreviousBalance: previous, NewBalance: next}
+	}
+
+	r := newReplica()
+	for _, e := range []indexer.Event{
+		changed(3, alice, "0", "10"),
+		{Type: indexer.EventTransfer, Block: 3, From: alice, To: bob, Amount: "4"},
+		// several transfers in one block write one checkpoint holding the last votes
+		changed(5, alice, "10", "6"),
+		changed(5, bob, "0", "4"),
+		changed(5, alice, "6", "9"),
+		changed(5, bob, "4", "1"),
+		changed(7, carol, "0", "9"),
+		changed(8, bob, "1", "9"),
+	} {
+		if err := r.write(e); err != nil {
+			t.Fatalf("write of %+v failed, error: %v.", e, err)
+		}
+	}
+
+	cps := r.checkpoints[alice]
+	if len(cps) != 2 || cps[1].FromBlock != 5 || cps[1].Votes.Int64() != 9 {
+		t.Errorf("alice has checkpoints %+v, expected the block 5 checkpoint overwritten to 9.", cps)
+	}
+	for _, c := range []struct {
+		delegate string
+		block    uint64
+		votes    int64
+	}{
+		{alice, 2, 0}, {alice, 3, 10}, {alice, 4, 10}, {alice, 5, 9}, {bob, 5, 1}, {bob, 7, 1}, {bob, 8, 9}, {carol, 6, 0},
+	} {
+		if votes := r.prior(c.delegate, c.block); votes.Int64() != c.votes {
+			t.Errorf("%s has %v votes at block %d, expected %d.", c.delegate, votes, c.block, c.votes)
+		}
+	}
+
+	// equal votes rank by address
+	expected := []Votes{{alice, "9"}, {bob, "9"}, {carol, "9"}}
+	if top := r.top(8); fmt.Sprint(top) != fmt.Sprint(expected) {
+		t.Errorf("top at block 8 is %v, expected %v.", top, expected)
+	}
+	expected = []Votes{{alice, "9"}, {carol, "9"}, {bob, "1"}}
+	if top := r.top(7); fmt.Sprint(top) != fmt.Sprint(expected) {
+		t.Errorf("top at block 7 is %v, expected %v.", top, expected)
+	}
+
+	// a change not continuing the last checkpoint means events were missed, the replica stays as it was
+	if err := r.write(changed(9, alice, "10", "0")); err != ErrInconsistent {
+		t.Errorf("write of a gap returned %v, expected %v.", err, ErrInconsistent)
+	}
+	if err := r.write(changed(1<<32, alice, "9", "0")); err != ErrBlockOverflow {
+		t.Errorf("write above 32 bits returned %v, expected %v.", err, ErrBlockOverflow)
+	}
+	if n := len(r.checkpoints[alice]); n != 2 || r.current(alice).Int64() != 9 {
+		t.Errorf("alice has %d checkpoints and %v votes after failed writes, expected 2 and 9.", n, r.current(alice))
+	}
+}
@@ -0,0 +1,42 @@
+// keystore creates the encrypted key file of the hot wallet
+//
+// usage:
+//
+//	KEYSTORE_PASSWORD=... go run ./cmd/keystore -dir ./storage/keystore
+//
+// the key is stored as Web3 Secret Storage v3 file with standard scrypt parameters
+// point KEYSTORE_PATH at the printed file and fund the printed address before starting the server
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"website/txmanager"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+func main() {
+
+	dir := flag.String("dir", "./storage/keystore", "directory the key file is written to")
+	flag.Parse()
+
+	passphrase := os.Getenv("KEYSTORE_PASSWORD")
+	if passphrase == "" {
+		exit("KEYSTORE_PASSWORD must be set")
+	}
+
+	path, address, err := txmanager.CreateKey(*dir, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		exit("creating key failed: %v", err)
+	}
+
+	fmt.Printf("key file:        %s\n", path)
+	fmt.Printf("address:         %s\n", address.Hex())
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,89 @@
+// minting prints the mint schedule of the Art token and prepares mint transactions for the minter
+//
+// usage:
+//
+//	go run ./cmd/minting -rpc http://localhost:8545 -chain-id 1 -art 0x... -years 10
+//	go run ./cmd/minting -rpc http://localhost:8545 -chain-id 1 -art 0x... -dst 0x... -amount 1000
+//
+// amounts are in the token's smallest unit
+// the mint transaction is printed as JSON and not signed, it is meant to be proposed to the minter multisig
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+	"website/chain"
+	"website/minting"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+)
+
+func main() {
+
+	rpc := flag.String("rpc", "", "JSON-RPC endpoint of a node")
+	id := flag.String("chain-id", "1", "expected chain id")
+	art := flag.String("art", "", "address of the Art token")
+	years := flag.Int("years", 10, "years of supply projection")
+	dst := flag.String("dst", "", "recipient of the prepared mint")
+	amount := flag.String("amount", "", "amount of the prepared mint, max for the largest allowed")
+	flag.Parse()
+
+	chainId, ok := new(big.Int).SetString(*id, 10)
+	if *rpc == "" || !common.IsHexAddress(*art) || !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := chain.Dial(ctx, *rpc, chainId, common.HexToAddress(*art), log.NewNopLogger())
+	if err != nil {
+		exit("connecting to %s failed: %v", *rpc, err)
+	}
+	s := minting.NewService(client, chainId, log.NewNopLogger())
+
+	schedule, err := s.ReadSchedule(ctx, *years)
+	if err != nil {
+		exit("reading schedule failed: %v", err)
+	}
+
+	fmt.Printf("block:                 %d (%s)\n", schedule.Block, format(schedule.Time))
+	fmt.Printf("minter:                %s\n", schedule.Minter)
+	fmt.Printf("total supply:          %s\n", schedule.TotalSupply)
+	fmt.Printf("minting allowed after: %s\n", format(schedule.MintingAllowedAfter))
+	fmt.Printf("mintable now:          %t\n", schedule.MintableNow)
+	fmt.Printf("next mint at:          %s\n", format(schedule.NextMintAt))
+	fmt.Printf("max mint:              %s\n\n", schedule.MaxMint)
+	for _, m := range schedule.Projection {
+		fmt.Printf("year %d\t%s\t%s\t%s\n", m.Year, format(m.At), m.Amount, m.Supply)
+	}
+
+	if *dst == "" {
+		return
+	}
+	if *amount == "max" {
+		*amount = schedule.MaxMint
+	}
+	tx, err := s.PrepareMint(ctx, *dst, *amount)
+	if err != nil {
+		exit("preparing mint failed: %v", err)
+	}
+	out, _ := json.MarshalIndent(tx, "", "  ")
+	fmt.Printf("\n%s\n", out)
+}
+
+func format(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,121 @@
+// odds computes raffle winning chances from a weight table
+//
+// usage:
+//
+//	go run ./cmd/odds -slots 10 -url https://host:6443/collections/{id}/weights
+//	go run ./cmd/odds -slots 10 -file weights.json -address 0x... -extra 1000 -mode sqrt -cap 10000
+//
+// the input is the JSON response of the weights endpoint, for uniform raffles every weight is 1
+// odds are exact for uniform weights, a single slot or few entrants and simulated otherwise
+// with -address the tool also prints the odds that address would have with -extra additional votes,
+// the weighting flags must match the collection's weighting configuration
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"website/collection"
+	"website/selection"
+)
+
+func main() {
+
+	url := flag.String("url", "", "weights endpoint of a collection")
+	file := flag.String("file", "", "file containing the weights endpoint response")
+	insecure := flag.Bool("insecure", false, "skip TLS verification, e.g. for the local test certificate")
+	slots := flag.Int("slots", 0, "number of artworks drawn")
+	iterations := flag.Int("iterations", selection.DefaultIterations, "simulation rounds if odds cannot be computed exactly")
+	address := flag.String("address", "", "address for a what-if scenario")
+	extra := flag.String("extra", "0", "additional votes of the what-if address")
+	mode := flag.String("mode", "", "weighting mode, linear or sqrt, empty for uniform raffles")
+	votesCap := flag.String("cap", "", "votes cap of the weighting")
+	base := flag.String("base", "", "base weight of the weighting")
+	flag.Parse()
+
+	var r io.Reader
+	switch {
+
+	case *url != "":
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure}}}
+		resp, err := client.Get(*url)
+		if err != nil {
+			exit("fetching weights failed: %v", err)
+		}
+		defer resp.Body.Close()
+		r = resp.Body
+
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			exit("opening file failed: %v", err)
+		}
+		defer f.Close()
+		r = f
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *slots <= 0 {
+		exit("number of slots must be positive")
+	}
+
+	var response collection.WeightsResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		exit("decoding weights failed: %v", err)
+	}
+	table := response.Data
+
+	report, err := selection.TableOdds(table, *slots, *iterations)
+	if err != nil {
+		exit("computing odds failed: %v", err)
+	}
+
+	if *address != "" {
+
+		var cfg *selection.WeightConfig
+		if *mode != "" {
+			cfg = &selection.WeightConfig{Mode: selection.WeightMode(*mode), Cap: *votesCap, Base: *base}
+		}
+
+		votes, ok := new(big.Int).SetString(*extra, 10)
+		if !ok {
+			exit("invalid extra votes %q", *extra)
+		}
+
+		if err := report.AddWhatIf(table, cfg, strings.ToLower(*address), votes, *iterations); err != nil {
+			exit("computing what-if odds failed: %v", err)
+		}
+	}
+
+	method := "exact"
+	if !report.Exact {
+		method = fmt.Sprintf("simulated, %d rounds", report.Iterations)
+	}
+	fmt.Printf("entrants:      %d\n", len(report.Entrants))
+	fmt.Printf("slots:         %d\n", report.Slots)
+	fmt.Printf("odds:          %s\n\n", method)
+
+	for _, e := range report.Entrants {
+		fmt.Printf("%s\t%s\t%8.4f%%\n", e.Address, e.Weight, 100*e.Probability)
+	}
+
+	if w := report.WhatIf; w != nil {
+		fmt.Printf("\nwhat-if:       %s with %s extra votes\n", w.Address, w.Extra)
+		fmt.Printf("weight:        %s\n", w.Weight)
+		fmt.Printf("odds:          %.4f%% (currently %.4f%%)\n", 100*w.Probability, 100*w.Current)
+	}
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,80 @@
+// provenance verifies the published art order of a revealed collection
+//
+// usage:
+//
+//	go run ./cmd/provenance -url https://host:6443/collections/{id}/provenance
+//	go run ./cmd/provenance -file provenance.json
+//
+// the input is the JSON response of the provenance endpoint
+// the tool recomputes the provenance hash from the image hashes, offset and salt
+// and prints the resulting token id to image assignment
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"website/collection"
+)
+
+func main() {
+
+	url := flag.String("url", "", "provenance endpoint of a collection")
+	file := flag.String("file", "", "file containing the provenance endpoint response")
+	insecure := flag.Bool("insecure", false, "skip TLS verification, e.g. for the local test certificate")
+	flag.Parse()
+
+	var r io.Reader
+	switch {
+
+	case *url != "":
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure}}}
+		resp, err := client.Get(*url)
+		if err != nil {
+			exit("fetching provenance failed: %v", err)
+		}
+		defer resp.Body.Close()
+		r = resp.Body
+
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			exit("opening file failed: %v", err)
+		}
+		defer f.Close()
+		r = f
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var response collection.ProvenanceResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		exit("decoding provenance failed: %v", err)
+	}
+	p := response.Data.Provenance
+
+	fmt.Printf("provenance hash: %s\n", p.Hash)
+	fmt.Printf("images hash:     %s\n", p.ImagesHash)
+
+	assignments, err := collection.VerifyProvenance(p)
+	if err != nil {
+		exit("verification failed: %v", err)
+	}
+
+	fmt.Printf("offset:          %d\n", *p.Offset)
+	fmt.Printf("verified:        true\n\n")
+	for _, a := range assignments {
+		fmt.Printf("token %d\t%s\n", a.TokenId, a.ImageHash)
+	}
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
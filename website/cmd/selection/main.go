@@ -0,0 +1,83 @@
+// selection replays a published raffle draw
+//
+// usage:
+//
+//	go run ./cmd/selection -url https://host:6443/collections/{id}/transcript
+//	go run ./cmd/selection -file transcript.json
+//
+// the input is the JSON response of the transcript endpoint
+// the tool checks the revealed server secret against its commitment,
+// recomputes the seed from the entrants and the block hash and replays the draw
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"website/collection"
+	"website/selection"
+)
+
+func main() {
+
+	url := flag.String("url", "", "transcript endpoint of a collection")
+	file := flag.String("file", "", "file containing the transcript endpoint response")
+	insecure := flag.Bool("insecure", false, "skip TLS verification, e.g. for the local test certificate")
+	flag.Parse()
+
+	var r io.Reader
+	switch {
+
+	case *url != "":
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure}}}
+		resp, err := client.Get(*url)
+		if err != nil {
+			exit("fetching transcript failed: %v", err)
+		}
+		defer resp.Body.Close()
+		r = resp.Body
+
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			exit("opening file failed: %v", err)
+		}
+		defer f.Close()
+		r = f
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var response collection.TranscriptResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		exit("decoding transcript failed: %v", err)
+	}
+	t := response.Data
+
+	fmt.Printf("commitment:    %s\n", t.Commitment)
+	fmt.Printf("entrants:      %d (%s)\n", len(t.Entrants), t.EntrantsHash)
+	if t.BlockHash != "" {
+		fmt.Printf("block:         %d (%s)\n", t.BlockNumber, t.BlockHash)
+	}
+	fmt.Printf("seed:          %s\n", t.Seed)
+
+	if err := selection.Verify(t); err != nil {
+		exit("verification failed: %v", err)
+	}
+
+	fmt.Printf("verified:      true\n\n")
+	for _, w := range t.Winners {
+		fmt.Printf("token %d\t%s\n", w.TokenId, w.Address)
+	}
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,128 @@
+// snapshot lists the Art holders at a block for promotional drops
+//
+// usage:
+//
+//	go run ./cmd/snapshot -block 15000000 -min 1000000000000000000000 -csv drop.csv
+//	go run ./cmd/snapshot -block 15000000 -min 1000 -cap 50000 -exclude 0x...,0x... -rpc http://localhost:8545 -exclude-contracts -claims claims.json
+//
+// balances are replayed from the events of the indexer, the block must be indexed and confirmed
+// amounts are in the token's smallest unit
+// the snapshot is recorded in the store, taking it again with the same block and rules
+// fails unless it reproduces the recorded Merkle root
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"website/indexer"
+	"website/snapshot"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-kit/kit/log"
+)
+
+func main() {
+
+	index := flag.String("index", "./storage/index/", "directory of the indexer")
+	store := flag.String("store", "./storage/snapshots/", "directory of the recorded snapshots")
+	block := flag.Uint64("block", 0, "snapshot block")
+	min := flag.String("min", "0", "minimum balance")
+	limit := flag.String("cap", "", "largest amount per address, empty for no cap")
+	exclude := flag.String("exclude", "", "comma separated addresses to leave out, e.g. the treasury")
+	contracts := flag.Bool("exclude-contracts", false, "leave out addresses with code, requires -rpc")
+	rpc := flag.String("rpc", "", "JSON-RPC endpoint of a node, supplies the block hash and contract code")
+	out := flag.String("csv", "", "file for the CSV, standard output when empty")
+	claims := flag.String("claims", "", "file for the claims with their Merkle proofs")
+	flag.Parse()
+
+	if *block == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rules := snapshot.Rules{MinBalance: *min, Cap: *limit, ExcludeContracts: *contracts}
+	if *exclude != "" {
+		rules.Exclude = strings.Split(*exclude, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var backend snapshot.Backend
+	if *rpc != "" {
+		client, err := ethclient.DialContext(ctx, *rpc)
+		if err != nil {
+			exit("connecting to %s failed: %v", *rpc, err)
+		}
+		backend = client
+	}
+
+	indexStore, err := indexer.NewIndexStore(indexer.IndexStoreConfig{IndexPath: *index}, log.NewNopLogger())
+	if err != nil {
+		exit("opening index failed: %v", err)
+	}
+	cursor, err := indexStore.ReadCursor()
+	if err != nil {
+		exit("reading cursor failed: %v", err)
+	}
+	events, err := indexStore.ReadEvents()
+	if err != nil {
+		exit("reading events failed: %v", err)
+	}
+
+	s, err := snapshot.Take(ctx, events, cursor, *block, rules, backend)
+	if err != nil {
+		exit("taking snapshot failed: %v", err)
+	}
+	s, err = snapshot.Record(snapshot.NewSnapshotStore(snapshot.SnapshotStoreConfig{SnapshotsPath: *store}, log.NewNopLogger()), s)
+	if err != nil {
+		exit("recording snapshot %s failed: %v", s.Id, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			exit("creating %s failed: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := snapshot.WriteCSV(w, s); err != nil {
+		exit("writing CSV failed: %v", err)
+	}
+
+	if *claims != "" {
+		c, err := snapshot.Claims(s)
+		if err != nil {
+			exit("computing claims failed: %v", err)
+		}
+		data, _ := json.MarshalIndent(struct {
+			Root   string           `json:"root"`
+			Claims []snapshot.Claim `json:"claims"`
+		}{s.Root, c}, "", "  ")
+		if err := os.WriteFile(*claims, data, 0644); err != nil {
+			exit("writing claims failed: %v", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "snapshot:    %s (%s)\n", s.Id, s.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(os.Stderr, "block:       %d %s\n", s.Block, s.BlockHash)
+	fmt.Fprintf(os.Stderr, "events hash: %s\n", s.EventsHash)
+	fmt.Fprintf(os.Stderr, "holders:     %d\n", s.Holders)
+	fmt.Fprintf(os.Stderr, "recipients:  %d\n", len(s.Rows))
+	fmt.Fprintf(os.Stderr, "excluded:    %d\n", len(s.Excluded))
+	fmt.Fprintf(os.Stderr, "total:       %s\n", s.Total)
+	fmt.Fprintf(os.Stderr, "root:        %s\n", s.Root)
+}
+
+func exit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
@@ -0,0 +1,378 @@
+package collection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"website/selection"
+	"website/signing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidCollection),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrUnknownTrait),
+		errors.Is(err, ErrInvalidQuery),
+		errors.Is(err, selection.ErrInvalidContribution),
+		errors.Is(err, selection.ErrInvalidIterations),
+		errors.Is(err, selection.ErrInvalidStake):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrNotOwner):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrCollectionNotFound),
+		errors.Is(err, ErrNotRevealed),
+		errors.Is(err, ErrTokenNotFound),
+		errors.Is(err, ErrNotDrawn),
+		errors.Is(err, ErrNotOnAllowlist),
+		errors.Is(err, ErrNotWeighted):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrInvalidTransition),
+		errors.Is(err, ErrTransitionGuard),
+		errors.Is(err, ErrNotEditable),
+		errors.Is(err, ErrEntryClosed),
+		errors.Is(err, ErrDuplicateEntry),
+		errors.Is(err, ErrProvenanceMismatch),
+		errors.Is(err, ErrAlreadyDrawn),
+		errors.Is(err, ErrDrawBlockNotFinal),
+		errors.Is(err, selection.ErrNoWeight):
+		return http.StatusConflict
+
+	case errors.Is(err, ErrNoChain):
+		return http.StatusServiceUnavailable
+
+	default:
+		return signing.ErrorStatusCode(err)
+	}
+}
+
+// decode create collection
+func decodeCreateCollection(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Collection); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode collection identifier from route
+func decodeReadCollection(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadCollectionRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode listing filters, sort order and page from query parameters
+func decodeListCollections(_ context.Context, r *http.Request) (interface{}, error) {
+
+	v := r.URL.Query()
+	q := Query{
+		State:  State(v.Get("state")),
+		Artist: v.Get("artist"),
+		Tag:    v.Get("tag"),
+		Text:   v.Get("q"),
+		Sort:   Sort(v.Get("sort")),
+		Cursor: v.Get("cursor"),
+	}
+
+	if s := v.Get("ends_before"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		q.EndsBefore = t
+	}
+
+	if s := v.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		q.Limit = limit
+	}
+
+	return ListCollectionsRequest{Query: q}, nil
+}
+
+// decode collection identifier for provenance report
+func decodeReadProvenance(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadProvenanceRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier for rarity report
+func decodeReadRarity(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadRarityRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier and token id for token metadata
+func decodeReadTokenMetadata(_ context.Context, r *http.Request) (interface{}, error) {
+
+	vars := mux.Vars(r)
+	tokenId, err := strconv.Atoi(vars["tokenId"])
+	if err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return ReadTokenMetadataRequest{Id: vars["id"], TokenId: tokenId}, nil
+}
+
+// decode update collection
+func decodeUpdateCollection(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := UpdateCollectionRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req.Collection); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode lifecycle transition
+func decodeTransition(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := TransitionRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode raffle entry
+func decodeEnter(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := EnterRequest{Id: mux.Vars(r)["id"]}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return req, nil
+}
+
+// decode raffle draw, the block hash is read from the chain
+func decodeDraw(_ context.Context, r *http.Request) (interface{}, error) {
+	return DrawRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier for raffle transcript
+func decodeReadTranscript(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadTranscriptRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier for raffle weights
+func decodeReadWeights(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadWeightsRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier and address for allowlist proofs
+func decodeReadProof(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return ReadProofRequest{Id: vars["id"], Address: vars["address"]}, nil
+}
+
+// decode odds query, address and extra votes are optional
+func decodeReadOdds(_ context.Context, r *http.Request) (interface{}, error) {
+
+	v := r.URL.Query()
+	req := ReadOddsRequest{Id: mux.Vars(r)["id"], Address: v.Get("address"), Extra: new(big.Int)}
+
+	if s := v.Get("extra"); s != "" {
+		if _, ok := req.Extra.SetString(s, 10); !ok || req.Extra.Sign() < 0 {
+			return nil, ErrBadRequest
+		}
+	}
+
+	if s := v.Get("iterations"); s != "" {
+		iterations, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		req.Iterations = iterations
+	}
+
+	return req, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching collection handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	createCollectionHandler := httptransport.NewServer(
+		e.CreateCollection,
+		decodeCreateCollection,
+		encodeResponse,
+		options...,
+	)
+
+	listCollectionsHandler := httptransport.NewServer(
+		e.ListCollections,
+		decodeListCollections,
+		encodeResponse,
+		options...,
+	)
+
+	readCollectionHandler := httptransport.NewServer(
+		e.ReadCollection,
+		decodeReadCollection,
+		encodeResponse,
+		options...,
+	)
+
+	updateCollectionHandler := httptransport.NewServer(
+		e.UpdateCollection,
+		decodeUpdateCollection,
+		encodeResponse,
+		options...,
+	)
+
+	transitionHandler := httptransport.NewServer(
+		e.Transition,
+		decodeTransition,
+		encodeResponse,
+		options...,
+	)
+
+	enterHandler := httptransport.NewServer(
+		e.Enter,
+		decodeEnter,
+		encodeResponse,
+		options...,
+	)
+
+	drawHandler := httptransport.NewServer(
+		e.Draw,
+		decodeDraw,
+		encodeResponse,
+		options...,
+	)
+
+	readTranscriptHandler := httptransport.NewServer(
+		e.ReadTranscript,
+		decodeReadTranscript,
+		encodeResponse,
+		options...,
+	)
+
+	readWeightsHandler := httptransport.NewServer(
+		e.ReadWeights,
+		decodeReadWeights,
+		encodeResponse,
+		options...,
+	)
+
+	readOddsHandler := httptransport.NewServer(
+		e.ReadOdds,
+		decodeReadOdds,
+		encodeResponse,
+		options...,
+	)
+
+	readProofHandler := httptransport.NewServer(
+		e.ReadProof,
+		decodeReadProof,
+		encodeResponse,
+		options...,
+	)
+
+	readProvenanceHandler := httptransport.NewServer(
+		e.ReadProvenance,
+		decodeReadProvenance,
+		encodeResponse,
+		options...,
+	)
+
+	readRarityHandler := httptransport.NewServer(
+		e.ReadRarity,
+		decodeReadRarity,
+		encodeResponse,
+		options...,
+	)
+
+	readTokenMetadataHandler := httptransport.NewServer(
+		e.ReadTokenMetadata,
+		decodeReadTokenMetadata,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/collections", createCollectionHandler).Methods("POST")
+	router.Handle("/collections", listCollectionsHandler).Methods("GET")
+	router.Handle("/collections/{id}", readCollectionHandler).Methods("GET")
+	router.Handle("/collections/{id}", updateCollectionHandler).Methods("PUT")
+	router.Handle("/collections/{id}/transitions", transitionHandler).Methods("POST")
+	router.Handle("/collections/{id}/entries", enterHandler).Methods("POST")
+	router.Handle("/collections/{id}/draw", drawHandler).Methods("POST")
+	router.Handle("/collections/{id}/transcript", readTranscriptHandler).Methods("GET")
+	router.Handle("/collections/{id}/weights", readWeightsHandler).Methods("GET")
+	router.Handle("/collections/{id}/odds", readOddsHandler).Methods("GET")
+	router.Handle("/collections/{id}/proof/{address}", readProofHandler).Methods("GET")
+	router.Handle("/collections/{id}/provenance", readProvenanceHandler).Methods("GET")
+	router.Handle("/collections/{id}/rarity", readRarityHandler).Methods("GET")
+	router.Handle("/collections/{id}/tokens/{tokenId}", readTokenMetadataHandler).Methods("GET")
+
+	return router
+}
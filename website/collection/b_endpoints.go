@@ -0,0 +1,455 @@
+package collection
+
+import (
+	"context"
+	"math/big"
+
+	"website/selection"
+	"website/signing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type CreateCollectionRequest struct {
+	Collection Collection
+}
+
+type ReadCollectionRequest struct {
+	Id string
+}
+
+type ListCollectionsRequest struct {
+	Query Query
+}
+
+type ListCollectionsResponse struct {
+	Data       []Collection `json:"data"`
+	NextCursor string       `json:"next_cursor"`
+	Err        error        `json:"errors"`
+}
+
+// have ListCollectionsResponse follow the customError interface defined in a_transport.go
+func (r ListCollectionsResponse) error() error { return r.Err }
+
+type UpdateCollectionRequest struct {
+	Id         string
+	Collection Collection
+}
+
+type TransitionRequest struct {
+	Id    string
+	State State `json:"state"`
+}
+
+type EnterRequest struct {
+	Id           string
+	Address      string `json:"address"`
+	Contribution string `json:"contribution"`
+	signing.Proof
+}
+
+type DrawRequest struct {
+	Id string
+}
+
+type ReadTranscriptRequest struct {
+	Id string
+}
+
+type ReadWeightsRequest struct {
+	Id string
+}
+
+type WeightsResponse struct {
+	Data []selection.WeightEntry `json:"data"`
+	Err  error                   `json:"errors"`
+}
+
+// have WeightsResponse follow the customError interface defined in a_transport.go
+func (r WeightsResponse) error() error { return r.Err }
+
+type ReadOddsRequest struct {
+	Id         string
+	Address    string
+	Extra      *big.Int
+	Iterations int
+}
+
+type OddsResponse struct {
+	Data selection.OddsReport `json:"data"`
+	Err  error                `json:"errors"`
+}
+
+// have OddsResponse follow the customError interface defined in a_transport.go
+func (r OddsResponse) error() error { return r.Err }
+
+type ReadProofRequest struct {
+	Id      string
+	Address string
+}
+
+type ProofResponse struct {
+	Data AllowlistProof `json:"data"`
+	Err  error          `json:"errors"`
+}
+
+// have ProofResponse follow the customError interface defined in a_transport.go
+func (r ProofResponse) error() error { return r.Err }
+
+type TranscriptResponse struct {
+	Data selection.Transcript `json:"data"`
+	Err  error                `json:"errors"`
+}
+
+// have TranscriptResponse follow the customError interface defined in a_transport.go
+func (r TranscriptResponse) error() error { return r.Err }
+
+type CollectionResponse struct {
+	Data Collection `json:"data"`
+	Err  error      `json:"errors"`
+}
+
+// have CollectionResponse follow the customError interface defined in a_transport.go
+func (r CollectionResponse) error() error { return r.Err }
+
+type ReadProvenanceRequest struct {
+	Id string
+}
+
+// assignments are only set once the collection is revealed
+type ProvenanceReport struct {
+	Provenance  Provenance   `json:"provenance"`
+	Assignments []Assignment `json:"assignments,omitempty"`
+}
+
+type ProvenanceResponse struct {
+	Data ProvenanceReport `json:"data"`
+	Err  error            `json:"errors"`
+}
+
+// have ProvenanceResponse follow the customError interface defined in a_transport.go
+func (r ProvenanceResponse) error() error { return r.Err }
+
+type ReadRarityRequest struct {
+	Id string
+}
+
+type RarityResponse struct {
+	Data RarityReport `json:"data"`
+	Err  error        `json:"errors"`
+}
+
+// have RarityResponse follow the customError interface defined in a_transport.go
+func (r RarityResponse) error() error { return r.Err }
+
+type ReadTokenMetadataRequest struct {
+	Id      string
+	TokenId int
+}
+
+// metadata is returned without envelope, marketplaces expect the plain ERC-721 document
+type TokenMetadataResponse struct {
+	TokenMetadata
+	Err error `json:"-"`
+}
+
+// have TokenMetadataResponse follow the customError interface defined in a_transport.go
+func (r TokenMetadataResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	CreateCollection  endpoint.Endpoint
+	ReadCollection    endpoint.Endpoint
+	ListCollections   endpoint.Endpoint
+	UpdateCollection  endpoint.Endpoint
+	Transition        endpoint.Endpoint
+	Enter             endpoint.Endpoint
+	Draw              endpoint.Endpoint
+	ReadTranscript    endpoint.Endpoint
+	ReadWeights       endpoint.Endpoint
+	ReadOdds          endpoint.Endpoint
+	ReadProof         endpoint.Endpoint
+	ReadProvenance    endpoint.Endpoint
+	ReadRarity        endpoint.Endpoint
+	ReadTokenMetadata endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		CreateCollection:  epCreateCollection(s),
+		ReadCollection:    epReadCollection(s),
+		ListCollections:   epListCollections(s),
+		UpdateCollection:  epUpdateCollection(s),
+		Transition:        epTransition(s),
+		Enter:             epEnter(s),
+		Draw:              epDraw(s),
+		ReadTranscript:    epReadTranscript(s),
+		ReadWeights:       epReadWeights(s),
+		ReadOdds:          epReadOdds(s),
+		ReadProof:         epReadProof(s),
+		ReadProvenance:    epReadProvenance(s),
+		ReadRarity:        epReadRarity(s),
+		ReadTokenMetadata: epReadTokenMetadata(s),
+	}
+}
+
+// create collection endpoint
+func epCreateCollection(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(CreateCollectionRequest)
+
+		// call service method
+		c, err := s.CreateCollection(ctx, req.Collection)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// read collection endpoint
+func epReadCollection(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadCollectionRequest)
+
+		// call service method
+		c, err := s.ReadCollection(ctx, req.Id)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// list collections endpoint
+func epListCollections(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListCollectionsRequest)
+
+		// call service method
+		collections, next, err := s.ListCollections(ctx, req.Query)
+		if err != nil {
+			return ListCollectionsResponse{Err: err}, err
+		}
+
+		public := make([]Collection, len(collections))
+		for i, c := range collections {
+			public[i] = c.Public()
+		}
+
+		return ListCollectionsResponse{Data: public, NextCursor: next, Err: nil}, nil
+	}
+}
+
+// update collection endpoint
+func epUpdateCollection(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(UpdateCollectionRequest)
+
+		// call service method
+		c, err := s.UpdateCollection(ctx, req.Id, req.Collection)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// lifecycle transition endpoint
+func epTransition(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(TransitionRequest)
+
+		// call service method
+		c, err := s.Transition(ctx, req.Id, req.State)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// raffle entry endpoint
+func epEnter(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(EnterRequest)
+
+		// call service method
+		c, err := s.Enter(ctx, req.Id, req.Address, req.Contribution, req.Proof)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// raffle draw endpoint
+func epDraw(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(DrawRequest)
+
+		// call service method
+		c, err := s.Draw(ctx, req.Id)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c.Public(), Err: nil}, nil
+	}
+}
+
+// raffle transcript endpoint
+func epReadTranscript(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadTranscriptRequest)
+
+		// call service method
+		t, err := s.ReadTranscript(ctx, req.Id)
+		if err != nil {
+			return TranscriptResponse{Err: err}, err
+		}
+
+		return TranscriptResponse{Data: t, Err: nil}, nil
+	}
+}
+
+// raffle weight table endpoint
+func epReadWeights(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadWeightsRequest)
+
+		// call service method
+		table, err := s.ReadWeights(ctx, req.Id)
+		if err != nil {
+			return WeightsResponse{Err: err}, err
+		}
+
+		return WeightsResponse{Data: table, Err: nil}, nil
+	}
+}
+
+// raffle odds endpoint
+func epReadOdds(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadOddsRequest)
+
+		// call service method
+		report, err := s.ReadOdds(ctx, req.Id, req.Address, req.Extra, req.Iterations)
+		if err != nil {
+			return OddsResponse{Err: err}, err
+		}
+
+		return OddsResponse{Data: report, Err: nil}, nil
+	}
+}
+
+// provenance verification endpoint
+func epReadProvenance(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadProvenanceRequest)
+
+		// call service method
+		p, assignments, err := s.ReadProvenance(ctx, req.Id)
+		if err != nil {
+			return ProvenanceResponse{Err: err}, err
+		}
+
+		return ProvenanceResponse{Data: ProvenanceReport{Provenance: p, Assignments: assignments}, Err: nil}, nil
+	}
+}
+
+// rarity report endpoint
+func epReadRarity(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadRarityRequest)
+
+		// call service method
+		report, err := s.ReadRarity(ctx, req.Id)
+		if err != nil {
+			return RarityResponse{Err: err}, err
+		}
+
+		return RarityResponse{Data: report, Err: nil}, nil
+	}
+}
+
+// token metadata endpoint
+func epReadTokenMetadata(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadTokenMetadataRequest)
+
+		// call service method
+		metadata, err := s.ReadTokenMetadata(ctx, req.Id, req.TokenId)
+		if err != nil {
+			return TokenMetadataResponse{Err: err}, err
+		}
+
+		return TokenMetadataResponse{TokenMetadata: metadata, Err: nil}, nil
+	}
+}
+
+// allowlist proof endpoint
+func epReadProof(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadProofRequest)
+
+		// call service method
+		proof, err := s.ReadProof(ctx, req.Id, req.Address)
+		if err != nil {
+			return ProofResponse{Err: err}, err
+		}
+
+		return ProofResponse{Data: proof, Err: nil}, nil
+	}
+}
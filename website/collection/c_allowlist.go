@@ -0,0 +1,114 @@
+package collection
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"website/merkle"
+	"website/selection"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ******** Minting allowlist **********
+
+var ErrNotOnAllowlist = errors.New("Address is not on the allowlist")
+
+var ErrAllowlistMismatch = errors.New("Allowlist does not match the stored root")
+
+// Merkle root over the raffle winners, set on the mint contract to authorise minting
+// each leaf is abi.encode(winner address, token id), see merkle.Leaf
+type Allowlist struct {
+	Root   string `json:"root"`
+	Leaves int    `json:"leaves"`
+}
+
+// a token an address may mint with its Merkle proof
+type Claim struct {
+	TokenId int      `json:"token_id"`
+	Leaf    string   `json:"leaf"`
+	Proof   []string `json:"proof"`
+}
+
+// all claims of an address against the allowlist root
+type AllowlistProof struct {
+	Root    string  `json:"root"`
+	Address string  `json:"address"`
+	Claims  []Claim `json:"claims"`
+}
+
+// allowlist leaves of the raffle winners, nil if nobody won
+func allowlistLeaves(t *selection.Transcript) []merkle.Leaf {
+	var leaves []merkle.Leaf
+	for _, w := range t.Winners {
+		leaves = append(leaves, merkle.Leaf{Address: common.HexToAddress(w.Address), Value: big.NewInt(int64(w.TokenId))})
+	}
+	return leaves
+}
+
+// builds the allowlist of a drawn raffle
+func newAllowlist(t *selection.Transcript) (*Allowlist, error) {
+
+	leaves := allowlistLeaves(t)
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+
+	tree, err := merkle.NewTree(leaves)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Allowlist{Root: tree.Root().Hex(), Leaves: len(leaves)}, nil
+}
+
+// proofs for every token a normalized address won
+// the tree is rebuilt from the transcript and must match the stored root
+func allowlistProof(c *Collection, address string) (AllowlistProof, error) {
+
+	if c.Raffle == nil || c.Raffle.Transcript == nil {
+		return AllowlistProof{}, ErrNotDrawn
+	}
+
+	leaves := allowlistLeaves(c.Raffle.Transcript)
+	if len(leaves) == 0 {
+		return AllowlistProof{}, ErrNotOnAllowlist
+	}
+
+	tree, err := merkle.NewTree(leaves)
+	if err != nil {
+		return AllowlistProof{}, err
+	}
+
+	// raffles drawn before allowlists were stored have no root yet
+	root := tree.Root().Hex()
+	if c.Raffle.Allowlist != nil && c.Raffle.Allowlist.Root != root {
+		return AllowlistProof{}, ErrAllowlistMismatch
+	}
+
+	result := AllowlistProof{Root: root, Address: address, Claims: []Claim{}}
+	for _, w := range c.Raffle.Transcript.Winners {
+		if strings.ToLower(w.Address) != address {
+			continue
+		}
+
+		leaf := merkle.Leaf{Address: common.HexToAddress(w.Address), Value: big.NewInt(int64(w.TokenId))}
+		proof, err := tree.Proof(leaf)
+		if err != nil {
+			return AllowlistProof{}, err
+		}
+
+		claim := Claim{TokenId: w.TokenId, Leaf: leaf.Hash().Hex(), Proof: make([]string, len(proof))}
+		for i, p := range proof {
+			claim.Proof[i] = p.Hex()
+		}
+		result.Claims = append(result.Claims, claim)
+	}
+
+	if len(result.Claims) == 0 {
+		return AllowlistProof{}, ErrNotOnAllowlist
+	}
+
+	return result, nil
+}
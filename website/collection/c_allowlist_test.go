@@ -0,0 +1,88 @@
+package collection
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"website/merkle"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAllowlistProof(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	c, _ := s.CreateCollection(ctx, Collection{
+		Name:     "test",
+		Items:    []Item{{ImageHash: "a"}, {ImageHash: "b"}, {ImageHash: "c"}},
+		OpensAt:  now.Add(time.Hour),
+		ClosesAt: now.Add(2 * time.Hour),
+	})
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+	now = now.Add(90 * time.Minute)
+	s.Advance(ctx)
+	entrants := []string{
+		"0x00000000000000000000000000000000000000aa",
+		"0x00000000000000000000000000000000000000bb",
+	}
+	for _, address := range entrants {
+		if _, err := s.Enter(ctx, c.Id, address, "", signing.Proof{}); err != nil {
+			t.Fatalf("Enter failed, error: %v.", err)
+		}
+	}
+
+	if _, err := s.ReadProof(ctx, c.Id, entrants[0]); err != ErrNotDrawn {
+		t.Errorf("ReadProof returned %v, expected %v.", err, ErrNotDrawn)
+	}
+
+	now = now.Add(time.Hour)
+	s.Advance(ctx)
+	c, err := s.Draw(ctx, c.Id)
+	if err != nil {
+		t.Fatalf("Draw failed, error: %v.", err)
+	}
+	if c.Raffle.Allowlist == nil || c.Raffle.Allowlist.Leaves != len(c.Raffle.Transcript.Winners) {
+		t.Fatalf("unexpected allowlist %+v.", c.Raffle.Allowlist)
+	}
+
+	// every won token has a proof verifying against the stored root
+	root := common.HexToHash(c.Raffle.Allowlist.Root)
+	claims := 0
+	for _, address := range entrants {
+		proof, err := s.ReadProof(ctx, c.Id, address)
+		if err == ErrNotOnAllowlist {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadProof failed, error: %v.", err)
+		}
+		for _, claim := range proof.Claims {
+			leaf := merkle.Leaf{Address: common.HexToAddress(address), Value: big.NewInt(int64(claim.TokenId))}
+			var hashes []common.Hash
+			for _, p := range claim.Proof {
+				hashes = append(hashes, common.HexToHash(p))
+			}
+			if !merkle.Verify(hashes, root, leaf.Hash()) {
+				t.Errorf("proof of token %d for %s does not verify.", claim.TokenId, address)
+			}
+			claims++
+		}
+	}
+	if claims != len(c.Raffle.Transcript.Winners) {
+		t.Errorf("found %d claims, expected %d.", claims, len(c.Raffle.Transcript.Winners))
+	}
+
+	if _, err := s.ReadProof(ctx, c.Id, "0x00000000000000000000000000000000000000cc"); err != ErrNotOnAllowlist {
+		t.Errorf("ReadProof returned %v, expected %v.", err, ErrNotOnAllowlist)
+	}
+	if _, err := s.ReadProof(ctx, c.Id, "cc"); err != ErrInvalidAddress {
+		t.Errorf("ReadProof returned %v, expected %v.", err, ErrInvalidAddress)
+	}
+}
@@ -0,0 +1,173 @@
+package collection
+
+import (
+	"errors"
+	"time"
+)
+
+// ******** Lifecycle states **********
+
+type State string
+
+const (
+	StateDraft     State = "draft"
+	StateScheduled State = "scheduled"
+	StateOpen      State = "open"
+	StateClosed    State = "closed"
+	StateDrawn     State = "drawn"
+	StateRevealed  State = "revealed"
+	StateMinted    State = "minted"
+)
+
+var ErrInvalidTransition = errors.New("Invalid state transition")
+
+var ErrTransitionGuard = errors.New("State transition precondition not met")
+
+// allowed transitions, a scheduled collection may be pulled back into draft before it opens
+var transitions = map[State][]State{
+	StateDraft:     {StateScheduled},
+	StateScheduled: {StateDraft, StateOpen},
+	StateOpen:      {StateClosed},
+	StateClosed:    {StateDrawn},
+	StateDrawn:     {StateRevealed},
+	StateRevealed:  {StateMinted},
+}
+
+// records a state change of a collection
+type Transition struct {
+	From State     `json:"from"`
+	To   State     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// emitted to subscribers after a transition has been stored
+type Event struct {
+	CollectionId string    `json:"collection_id"`
+	From         State     `json:"from"`
+	To           State     `json:"to"`
+	At           time.Time `json:"at"`
+}
+
+// called synchronously for every emitted event
+type EventHandler func(Event)
+
+// checks if a transition is allowed from the current state
+func canTransition(from, to State) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// guard checks the preconditions of entering a state
+func guard(c *Collection, to State, now time.Time) error {
+
+	switch to {
+
+	// schedule must be complete and art must be locked in
+	case StateScheduled:
+		if c.Name == "" || len(c.Items) == 0 {
+			return ErrTransitionGuard
+		}
+		if c.OpensAt.IsZero() || c.ClosesAt.IsZero() || !c.OpensAt.Before(c.ClosesAt) {
+			return ErrTransitionGuard
+		}
+		if !c.RevealAt.IsZero() && c.RevealAt.Before(c.ClosesAt) {
+			return ErrTransitionGuard
+		}
+
+	// entry window
+	case StateOpen:
+		if now.Before(c.OpensAt) {
+			return ErrTransitionGuard
+		}
+
+	case StateClosed:
+		if now.Before(c.ClosesAt) {
+			return ErrTransitionGuard
+		}
+
+	// winners are only set by a draw
+	case StateDrawn:
+		if c.Raffle == nil || c.Raffle.Transcript == nil {
+			return ErrTransitionGuard
+		}
+
+	case StateRevealed:
+		if c.Provenance == nil {
+			return ErrTransitionGuard
+		}
+		if !c.RevealAt.IsZero() && now.Before(c.RevealAt) {
+			return ErrTransitionGuard
+		}
+	}
+
+	return nil
+}
+
+// applies a transition to the collection if allowed and all guards pass
+func transition(c *Collection, to State, now time.Time) (Event, error) {
+
+	if !canTransition(c.State, to) {
+		return Event{}, ErrInvalidTransition
+	}
+
+	if err := guard(c, to, now); err != nil {
+		return Event{}, err
+	}
+
+	// art order is committed on lock and published on reveal
+	// the raffle secret is committed before the first entry
+	switch to {
+	case StateScheduled:
+		p, err := newProvenance(c.Items, now)
+		if err != nil {
+			return Event{}, err
+		}
+		c.Provenance = p
+	case StateDraft:
+		c.Provenance = nil
+	case StateOpen:
+		r, err := newRaffle()
+		if err != nil {
+			return Event{}, err
+		}
+		c.Raffle = r
+	case StateRevealed:
+		c.Provenance.RevealedAt = now
+	}
+
+	from := c.State
+	c.State = to
+	c.UpdatedAt = now
+	c.Transitions = append(c.Transitions, Transition{From: from, To: to, At: now})
+
+	return Event{CollectionId: c.Id, From: from, To: to, At: now}, nil
+}
+
+// next state the scheduler moves a collection into at time now
+// returns false if the collection is not due for a timed transition
+func scheduledTransition(c *Collection, now time.Time) (State, bool) {
+
+	switch c.State {
+
+	case StateScheduled:
+		if !now.Before(c.OpensAt) {
+			return StateOpen, true
+		}
+
+	case StateOpen:
+		if !now.Before(c.ClosesAt) {
+			return StateClosed, true
+		}
+
+	case StateDrawn:
+		if !c.RevealAt.IsZero() && !now.Before(c.RevealAt) {
+			return StateRevealed, true
+		}
+	}
+
+	return "", false
+}
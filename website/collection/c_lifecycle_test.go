@@ -0,0 +1,137 @@
+package collection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"website/session"
+	"website/signing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// sessions of fixed users, the session identifier is the file hash
+type staticSessions map[string]time.Time
+
+func (ss staticSessions) ReadSession(sessionId string) (session.Session, error) {
+	return session.Session{FileHash: sessionId, ExpiresAt: ss[sessionId]}, nil
+}
+
+// context of a request with a session cookie
+func withSession(sessionId string) context.Context {
+	return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), sessionId)
+}
+
+// service with a temporary store and a controllable clock
+// the users owner and other have sessions that do not expire
+func testService(t *testing.T, now *time.Time) *service {
+	store := NewCollectionStore(CollectionStoreConfig{CollectionsPath: t.TempDir()}, log.NewNopLogger())
+	forever := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	sessions := staticSessions{"owner": forever, "other": forever}
+	s := NewService(store, nil, nil, nil, sessions, nil, Config{}, log.NewNopLogger()).(*service)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestTransitionGuards(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	c, err := s.CreateCollection(ctx, Collection{Name: "test"})
+	if err != nil {
+		t.Fatalf("CreateCollection failed, error: %v.", err)
+	}
+
+	// no art and no schedule yet
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != ErrTransitionGuard {
+		t.Errorf("Transition returned %v, expected %v.", err, ErrTransitionGuard)
+	}
+
+	// skipping states is not allowed
+	if _, err := s.Transition(ctx, c.Id, StateOpen); err != ErrInvalidTransition {
+		t.Errorf("Transition returned %v, expected %v.", err, ErrInvalidTransition)
+	}
+
+	c.Items = []Item{{ImageHash: "abc", Name: "one"}}
+	c.OpensAt = now.Add(time.Hour)
+	c.ClosesAt = now.Add(2 * time.Hour)
+	if _, err := s.UpdateCollection(ctx, c.Id, c); err != nil {
+		t.Fatalf("UpdateCollection failed, error: %v.", err)
+	}
+
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+
+	// locked after scheduling
+	if _, err := s.UpdateCollection(ctx, c.Id, c); err != ErrNotEditable {
+		t.Errorf("UpdateCollection returned %v, expected %v.", err, ErrNotEditable)
+	}
+
+	// opening time not reached
+	if _, err := s.Transition(ctx, c.Id, StateOpen); err != ErrTransitionGuard {
+		t.Errorf("Transition returned %v, expected %v.", err, ErrTransitionGuard)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	var events []Event
+	s.Subscribe(func(e Event) { events = append(events, e) })
+
+	c, _ := s.CreateCollection(ctx, Collection{
+		Name:     "test",
+		Items:    []Item{{ImageHash: "abc"}},
+		OpensAt:  now.Add(time.Hour),
+		ClosesAt: now.Add(2 * time.Hour),
+	})
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+
+	// opens after opening time
+	now = now.Add(90 * time.Minute)
+	s.Advance(ctx)
+	c, _ = s.ReadCollection(ctx, c.Id)
+	if c.State != StateOpen {
+		t.Fatalf("collection in state %s, expected %s.", c.State, StateOpen)
+	}
+
+	if _, err := s.Enter(ctx, c.Id, "0x00000000000000000000000000000000000000AA", "", signing.Proof{}); err != nil {
+		t.Fatalf("Enter failed, error: %v.", err)
+	}
+	if _, err := s.Enter(ctx, c.Id, "0x00000000000000000000000000000000000000aa", "", signing.Proof{}); err != ErrDuplicateEntry {
+		t.Errorf("Enter returned %v, expected %v.", err, ErrDuplicateEntry)
+	}
+
+	// closes after closing time
+	now = now.Add(time.Hour)
+	s.Advance(ctx)
+	c, _ = s.ReadCollection(ctx, c.Id)
+	if c.State != StateClosed {
+		t.Fatalf("collection in state %s, expected %s.", c.State, StateClosed)
+	}
+
+	if len(events) != 3 || len(c.Transitions) != 3 {
+		t.Errorf("recorded %d events and %d transitions, expected 3.", len(events), len(c.Transitions))
+	}
+
+	// drawn state is only reachable through a draw
+	if _, err := s.Transition(ctx, c.Id, StateDrawn); err != ErrTransitionGuard {
+		t.Errorf("Transition returned %v, expected %v.", err, ErrTransitionGuard)
+	}
+	c, err := s.Draw(ctx, c.Id)
+	if err != nil {
+		t.Fatalf("Draw failed, error: %v.", err)
+	}
+	if c.State != StateDrawn || len(c.Raffle.Transcript.Winners) != 1 {
+		t.Errorf("unexpected draw result %+v.", c.Raffle)
+	}
+}
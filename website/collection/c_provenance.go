@@ -0,0 +1,116 @@
+package collection
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ******** Provenance struct **********
+
+var ErrProvenanceMismatch = errors.New("Provenance hash does not match")
+
+var ErrNotRevealed = errors.New("Provenance not revealed yet")
+
+// commitment to the order of the art of a collection
+// hash and image order are published when the collection is locked (scheduled)
+// offset and salt stay secret until the collection is revealed
+// the salt prevents guessing the offset by trying all item counts
+type Provenance struct {
+	Hash        string    `json:"hash"`
+	ImagesHash  string    `json:"images_hash"`
+	ImageHashes []string  `json:"image_hashes"`
+	Offset      *int      `json:"offset,omitempty"`
+	Salt        string    `json:"salt,omitempty"`
+	CommittedAt time.Time `json:"committed_at"`
+	RevealedAt  time.Time `json:"revealed_at"`
+}
+
+// token id to artwork mapping derived from the revealed offset
+type Assignment struct {
+	TokenId   int    `json:"token_id"`
+	ImageHash string `json:"image_hash"`
+}
+
+// Revealed returns true if offset and salt have been published
+func (p *Provenance) Revealed() bool {
+	return !p.RevealedAt.IsZero()
+}
+
+// ImagesHash is the sha256 sum over the concatenated hex image hashes in locked order
+func ImagesHash(imageHashes []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(imageHashes, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ProvenanceHash commits to the image order, the secret offset and the salt
+// sha256(images_hash | offset | salt)
+func ProvenanceHash(imageHashes []string, offset int, salt string) string {
+	preimage := ImagesHash(imageHashes) + "|" + strconv.Itoa(offset) + "|" + salt
+	sum := sha256.Sum256([]byte(preimage))
+	return hex.EncodeToString(sum[:])
+}
+
+// Assign maps token id t to the image at position (t + offset) mod n of the locked order
+func Assign(imageHashes []string, offset int) []Assignment {
+	n := len(imageHashes)
+	assignments := make([]Assignment, n)
+	for t := 0; t < n; t++ {
+		assignments[t] = Assignment{TokenId: t, ImageHash: imageHashes[(t+offset)%n]}
+	}
+	return assignments
+}
+
+// VerifyProvenance recomputes the commitment of a revealed provenance and returns the token assignment
+// anyone can run this with the published values, see cmd/provenance
+func VerifyProvenance(p Provenance) ([]Assignment, error) {
+
+	if p.Offset == nil || p.Salt == "" {
+		return nil, ErrNotRevealed
+	}
+
+	if ImagesHash(p.ImageHashes) != p.ImagesHash {
+		return nil, ErrProvenanceMismatch
+	}
+
+	if ProvenanceHash(p.ImageHashes, *p.Offset, p.Salt) != p.Hash {
+		return nil, ErrProvenanceMismatch
+	}
+
+	return Assign(p.ImageHashes, *p.Offset), nil
+}
+
+// commits to the current item order with a random offset and salt
+func newProvenance(items []Item, now time.Time) (*Provenance, error) {
+
+	imageHashes := make([]string, len(items))
+	for i, item := range items {
+		imageHashes[i] = item.ImageHash
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(items))))
+	if err != nil {
+		return nil, err
+	}
+	offset := int(n.Int64())
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	salt := hex.EncodeToString(b)
+
+	return &Provenance{
+		Hash:        ProvenanceHash(imageHashes, offset, salt),
+		ImagesHash:  ImagesHash(imageHashes),
+		ImageHashes: imageHashes,
+		Offset:      &offset,
+		Salt:        salt,
+		CommittedAt: now,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package collection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	c, _ := s.CreateCollection(ctx, Collection{
+		Name:     "test",
+		Items:    []Item{{ImageHash: "a"}, {ImageHash: "b"}, {ImageHash: "c"}},
+		OpensAt:  now.Add(time.Hour),
+		ClosesAt: now.Add(2 * time.Hour),
+	})
+
+	// committed on lock, secret hidden from clients
+	c, err := s.Transition(ctx, c.Id, StateScheduled)
+	if err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+	if c.Provenance == nil || c.Provenance.Offset == nil {
+		t.Fatalf("provenance not committed on lock.")
+	}
+	if public := c.Public(); public.Provenance.Offset != nil || public.Provenance.Salt != "" {
+		t.Errorf("Public leaked provenance secret.")
+	}
+	if _, err := VerifyProvenance(*c.Public().Provenance); err != ErrNotRevealed {
+		t.Errorf("VerifyProvenance returned %v, expected %v.", err, ErrNotRevealed)
+	}
+
+	// published values verify and assign every image exactly once
+	assignments, err := VerifyProvenance(*c.Provenance)
+	if err != nil {
+		t.Fatalf("VerifyProvenance failed, error: %v.", err)
+	}
+	seen := make(map[string]bool)
+	for i, a := range assignments {
+		if a.TokenId != i || a.ImageHash != c.Provenance.ImageHashes[(i+*c.Provenance.Offset)%3] {
+			t.Errorf("unexpected assignment %v.", a)
+		}
+		seen[a.ImageHash] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("assignment is not a permutation.")
+	}
+
+	// a reshuffled order no longer matches the commitment
+	p := *c.Provenance
+	p.ImageHashes = []string{"b", "a", "c"}
+	p.ImagesHash = ImagesHash(p.ImageHashes)
+	if _, err := VerifyProvenance(p); err != ErrProvenanceMismatch {
+		t.Errorf("VerifyProvenance returned %v, expected %v.", err, ErrProvenanceMismatch)
+	}
+}
@@ -0,0 +1,195 @@
+package collection
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ******** Collection query **********
+
+type Sort string
+
+const (
+	SortEndingSoonest Sort = "ending_soonest"
+	SortNewest        Sort = "newest"
+	SortMostEntrants  Sort = "most_entrants"
+)
+
+const defaultLimit = 20
+
+const maxLimit = 100
+
+var ErrInvalidQuery = errors.New("Invalid query")
+
+// filters, sort order and page of a collection listing
+// without state filter, drafts are excluded from listings
+type Query struct {
+	State      State
+	Artist     string
+	EndsBefore time.Time
+	Tag        string
+	Text       string
+	Sort       Sort
+	Cursor     string
+	Limit      int
+}
+
+// keyset cursor, points at the last collection of the previous page
+type cursor struct {
+	Sort Sort   `json:"s"`
+	Key  int64  `json:"k"`
+	Id   string `json:"i"`
+}
+
+// validates the query and fills in defaults
+func (q *Query) normalize() error {
+
+	if q.Sort == "" {
+		q.Sort = SortEndingSoonest
+	}
+	if q.Sort != SortEndingSoonest && q.Sort != SortNewest && q.Sort != SortMostEntrants {
+		return ErrInvalidQuery
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit < 0 || q.Limit > maxLimit {
+		return ErrInvalidQuery
+	}
+
+	return nil
+}
+
+// sort key of a collection, ascending keys come first for ending soonest, descending for the others
+func sortKey(c *Collection, s Sort) int64 {
+
+	switch s {
+
+	case SortNewest:
+		return c.CreatedAt.UnixNano()
+
+	case SortMostEntrants:
+		return int64(len(c.Entries))
+
+	default:
+		// unscheduled collections end last
+		if c.ClosesAt.IsZero() {
+			return math.MaxInt64
+		}
+		return c.ClosesAt.UnixNano()
+	}
+}
+
+// total order over (key, id) for a sort
+func before(s Sort, keyA int64, idA string, keyB int64, idB string) bool {
+
+	if keyA != keyB {
+		if s == SortEndingSoonest {
+			return keyA < keyB
+		}
+		return keyA > keyB
+	}
+
+	return idA < idB
+}
+
+// matches checks all filters except full-text search
+func (q *Query) matches(c *Collection) bool {
+
+	if q.State == "" && c.State == StateDraft {
+		return false
+	}
+	if q.State != "" && c.State != q.State {
+		return false
+	}
+	if q.Artist != "" && !strings.EqualFold(c.Artist, q.Artist) {
+		return false
+	}
+	if !q.EndsBefore.IsZero() && (c.ClosesAt.IsZero() || !c.ClosesAt.Before(q.EndsBefore)) {
+		return false
+	}
+	if q.Tag != "" {
+		found := false
+		for _, t := range c.Tags {
+			if strings.EqualFold(t, q.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// query filters, sorts and pages collections
+// textMatches restricts results to full-text hits, nil if no text query was given
+// returns the page and the cursor of the next page, empty if there is none
+func query(collections []Collection, q Query, textMatches map[string]struct{}) ([]Collection, string, error) {
+
+	if err := q.normalize(); err != nil {
+		return nil, "", err
+	}
+
+	var after *cursor
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != q.Sort {
+			return nil, "", ErrInvalidQuery
+		}
+		after = &c
+	}
+
+	filtered := make([]Collection, 0, len(collections))
+	for i := range collections {
+		c := &collections[i]
+		if q.Text != "" {
+			if _, ok := textMatches[c.Id]; !ok {
+				continue
+			}
+		}
+		if !q.matches(c) {
+			continue
+		}
+		if after != nil && !before(q.Sort, after.Key, after.Id, sortKey(c, q.Sort), c.Id) {
+			continue
+		}
+		filtered = append(filtered, *c)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return before(q.Sort, sortKey(&filtered[i], q.Sort), filtered[i].Id, sortKey(&filtered[j], q.Sort), filtered[j].Id)
+	})
+
+	if len(filtered) <= q.Limit {
+		return filtered, "", nil
+	}
+
+	page := filtered[:q.Limit]
+	last := &page[len(page)-1]
+	next := encodeCursor(cursor{Sort: q.Sort, Key: sortKey(last, q.Sort), Id: last.Id})
+
+	return page, next, nil
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (c cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
@@ -0,0 +1,64 @@
+package collection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListCollections(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	names := []string{"Pixel Dreams", "Blocks of Time", "Virtual Worlds"}
+	ids := make([]string, len(names))
+	for i, name := range names {
+		c, _ := s.CreateCollection(ctx, Collection{
+			Name:     name,
+			Artist:   "Ada",
+			Tags:     []string{"digital"},
+			Items:    []Item{{ImageHash: "a"}},
+			OpensAt:  now.Add(time.Hour),
+			ClosesAt: now.Add(time.Duration(10-i) * time.Hour),
+		})
+		ids[i] = c.Id
+		if i < 2 {
+			s.Transition(ctx, c.Id, StateScheduled)
+		}
+	}
+
+	// drafts are hidden, ending soonest first
+	page, next, err := s.ListCollections(ctx, Query{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListCollections failed, error: %v.", err)
+	}
+	if len(page) != 1 || page[0].Id != ids[1] || next == "" {
+		t.Fatalf("unexpected first page %v, cursor %q.", page, next)
+	}
+	page, next, _ = s.ListCollections(ctx, Query{Limit: 1, Cursor: next})
+	if len(page) != 1 || page[0].Id != ids[0] || next != "" {
+		t.Fatalf("unexpected second page %v, cursor %q.", page, next)
+	}
+
+	// full-text index follows updates
+	page, _, _ = s.ListCollections(ctx, Query{Text: "virt", State: StateDraft})
+	if len(page) != 1 || page[0].Id != ids[2] {
+		t.Errorf("unexpected search result %v.", page)
+	}
+	s.UpdateCollection(ctx, ids[2], Collection{Name: "Oceans", Artist: "Ada"})
+	page, _, _ = s.ListCollections(ctx, Query{Text: "virt", State: StateDraft})
+	if len(page) != 0 {
+		t.Errorf("search returned stale result %v.", page)
+	}
+
+	// filters
+	page, _, _ = s.ListCollections(ctx, Query{Tag: "DIGITAL", EndsBefore: now.Add(10 * time.Hour)})
+	if len(page) != 1 || page[0].Id != ids[1] {
+		t.Errorf("unexpected filter result %v.", page)
+	}
+
+	if _, _, err := s.ListCollections(ctx, Query{Sort: "random"}); err != ErrInvalidQuery {
+		t.Errorf("ListCollections returned %v, expected %v.", err, ErrInvalidQuery)
+	}
+}
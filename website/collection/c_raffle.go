@@ -0,0 +1,180 @@
+package collection
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"website/selection"
+)
+
+// ******** Raffle struct **********
+
+var ErrNotDrawn = errors.New("Raffle not drawn yet")
+
+var ErrNotWeighted = errors.New("Raffle is not stake weighted")
+
+var ErrAlreadyDrawn = errors.New("Raffle already drawn")
+
+// simulation rounds allowed on the public odds route, the odds command keeps selection.MaxIterations
+const MaxOddsIterations = 20000
+
+// server side of the raffle commit-reveal
+// the commitment is published when entries open, the secret is revealed with the transcript
+type Raffle struct {
+	Commitment string                `json:"commitment"`
+	Secret     string                `json:"secret,omitempty"`
+	Transcript *selection.Transcript `json:"transcript,omitempty"`
+	Allowlist  *Allowlist            `json:"allowlist,omitempty"`
+}
+
+// commits to a fresh server secret
+func newRaffle() (*Raffle, error) {
+
+	secret, err := selection.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Raffle{Commitment: selection.Commit(secret), Secret: secret}, nil
+}
+
+// stake of one collection read like votes
+type collectionStake struct {
+	stakes StakeSource
+	id     string
+}
+
+func (cs collectionStake) PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error) {
+	return cs.stakes.PriorStake(ctx, cs.id, account, blockNumber)
+}
+
+// source of the weights of a weighted raffle, nil if it is not configured
+func (s *service) weights(c *Collection) selection.VotesSource {
+
+	if c.Weighting == nil || c.Weighting.Source != selection.SourceStake {
+		return s.votes
+	}
+	if s.stakes == nil {
+		return nil
+	}
+	return collectionStake{stakes: s.stakes, id: c.Id}
+}
+
+// weight table of a collection at its snapshot block
+// votes at a finalized block never change, the table is reused while the weighting and the entrants stay the same
+type cachedTable struct {
+	weighting selection.WeightConfig
+	entrants  []string
+	table     []selection.WeightEntry
+}
+
+// weight table of the current entrants, read from the chain only when the cached one is stale
+func (s *service) weightTable(ctx context.Context, c *Collection) ([]selection.WeightEntry, error) {
+
+	entrants := c.entrants()
+	addresses := make([]string, len(entrants))
+	for i, e := range entrants {
+		addresses[i] = e.Address
+	}
+
+	s.tablesMu.Lock()
+	cached, ok := s.tables[c.Id]
+	s.tablesMu.Unlock()
+	if ok && cached.weighting == *c.Weighting && equalStrings(cached.entrants, addresses) {
+		return cached.table, nil
+	}
+
+	table, err := selection.WeightTable(ctx, s.weights(c), entrants, *c.Weighting)
+	if err != nil {
+		return nil, err
+	}
+
+	s.tablesMu.Lock()
+	s.tables[c.Id] = cachedTable{weighting: *c.Weighting, entrants: addresses, table: table}
+	s.tablesMu.Unlock()
+
+	return table, nil
+}
+
+func equalStrings(a, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// draws one winner per artwork from the closed entrant list
+// if a draw block is configured its hash, read from the chain by the caller, is mixed into the seed
+// stake weighted raffles use the weight table the caller read at the snapshot block
+func draw(c *Collection, table []selection.WeightEntry, blockHash string, now time.Time) error {
+
+	if c.State != StateClosed || c.Raffle == nil {
+		return ErrTransitionGuard
+	}
+
+	entrants := c.entrants()
+
+	var t selection.Transcript
+	var err error
+	if c.Weighting == nil {
+		t, err = selection.NewTranscript(c.Raffle.Secret, c.Raffle.Commitment, entrants, c.DrawBlock, blockHash, len(c.Items), now)
+	} else {
+		t, err = selection.NewWeightedTranscript(c.Raffle.Secret, c.Raffle.Commitment, entrants, c.DrawBlock, blockHash, len(c.Items), *c.Weighting, table, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	allowlist, err := newAllowlist(&t)
+	if err != nil {
+		return err
+	}
+	c.Raffle.Transcript = &t
+	c.Raffle.Allowlist = allowlist
+
+	return nil
+}
+
+// true if both versions of a collection draw from the same block, weighting and entries
+func sameDraw(a, b *Collection) bool {
+
+	if a.DrawBlock != b.DrawBlock || len(a.Entries) != len(b.Entries) {
+		return false
+	}
+	if (a.Weighting == nil) != (b.Weighting == nil) || (a.Weighting != nil && *a.Weighting != *b.Weighting) {
+		return false
+	}
+	for i := range a.Entries {
+		if a.Entries[i].Address != b.Entries[i].Address || a.Entries[i].Contribution != b.Entries[i].Contribution {
+			return false
+		}
+	}
+	return true
+}
+
+// entrants of the raffle as used by the selection engine
+func (c *Collection) entrants() []selection.Entrant {
+	entrants := make([]selection.Entrant, len(c.Entries))
+	for i, e := range c.Entries {
+		entrants[i] = selection.Entrant{Address: e.Address, Contribution: e.Contribution}
+	}
+	return entrants
+}
+
+// true if the normalized address has an entry
+func (c *Collection) hasEntered(address string) bool {
+	for _, e := range c.Entries {
+		if e.Address == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package collection
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"website/selection"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-kit/kit/log"
+)
+
+type fixedVotes int64
+
+func (v fixedVotes) PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error) {
+	return big.NewInt(int64(v)), nil
+}
+
+// stake per collection
+type fixedStakes map[string]int64
+
+func (fs fixedStakes) PriorStake(ctx context.Context, collectionId string, account string, blockNumber uint64) (*big.Int, error) {
+	return big.NewInt(fs[collectionId]), nil
+}
+
+// stake weighted raffles read the stake toward their own collection
+func TestWeightSource(t *testing.T) {
+
+	store := NewCollectionStore(CollectionStoreConfig{CollectionsPath: t.TempDir()}, log.NewNopLogger())
+	s := NewService(store, fixedVotes(7), fixedStakes{"a": 3, "b": 5}, nil, nil, nil, Config{}, log.NewNopLogger()).(*service)
+
+	cases := []struct {
+		source   selection.WeightSource
+		expected int64
+	}{{"", 7}, {selection.SourceVotes, 7}, {selection.SourceStake, 5}}
+	for _, c := range cases {
+		collection := Collection{Id: "b", Weighting: &selection.WeightConfig{Mode: selection.WeightLinear, Source: c.source, SnapshotBlock: 1}}
+		votes, err := s.weights(&collection).PriorVotes(context.Background(), "0x000000000000000000000000000000000000000a", 1)
+		if err != nil || votes.Int64() != c.expected {
+			t.Errorf("source %q returned %v, %v, expected %d.", c.source, votes, err, c.expected)
+		}
+	}
+
+	s.stakes = nil
+	if src := s.weights(&Collection{Id: "b", Weighting: &selection.WeightConfig{Mode: selection.WeightLinear, Source: selection.SourceStake}}); src != nil {
+		t.Errorf("weights returned %v without a stake source, expected nil.", src)
+	}
+	if err := (&selection.WeightConfig{Mode: selection.WeightLinear, Source: "balance"}).Validate(); err != selection.ErrInvalidWeighting {
+		t.Errorf("Validate returned %v, expected %v.", err, selection.ErrInvalidWeighting)
+	}
+}
+
+// chain head at a fixed block, header hashes derive from the block number
+type fixedBlocks uint64
+
+func (b fixedBlocks) BlockNumber(ctx context.Context) (uint64, error) {
+	return uint64(b), nil
+}
+
+func (b fixedBlocks) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number}, nil
+}
+
+// only the owner draws, and only once the draw block is final, with the hash of the chain
+func TestDrawBlock(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	s.config.Confirmations = 2
+	ctx := withSession("owner")
+
+	c, _ := s.CreateCollection(ctx, Collection{
+		Name:      "test",
+		Items:     []Item{{ImageHash: "a"}},
+		OpensAt:   now.Add(time.Hour),
+		ClosesAt:  now.Add(2 * time.Hour),
+		DrawBlock: 10,
+	})
+	if _, err := s.Transition(withSession("other"), c.Id, StateScheduled); err != ErrNotOwner {
+		t.Errorf("Transition by another user returned %v, expected %v.", err, ErrNotOwner)
+	}
+	if _, err := s.Transition(context.Background(), c.Id, StateScheduled); err != ErrUnauthorized {
+		t.Errorf("Transition without session returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+	now = now.Add(90 * time.Minute)
+	s.Advance(ctx)
+	if _, err := s.Enter(ctx, c.Id, "0x00000000000000000000000000000000000000aa", "", signing.Proof{}); err != nil {
+		t.Fatalf("Enter failed, error: %v.", err)
+	}
+	now = now.Add(time.Hour)
+	s.Advance(ctx)
+
+	if _, err := s.Draw(withSession("other"), c.Id); err != ErrNotOwner {
+		t.Errorf("Draw by another user returned %v, expected %v.", err, ErrNotOwner)
+	}
+	if _, err := s.Draw(ctx, c.Id); err != ErrNoChain {
+		t.Errorf("Draw without chain returned %v, expected %v.", err, ErrNoChain)
+	}
+	s.blocks = fixedBlocks(11)
+	if _, err := s.Draw(ctx, c.Id); err != ErrDrawBlockNotFinal {
+		t.Errorf("Draw before the block is final returned %v, expected %v.", err, ErrDrawBlockNotFinal)
+	}
+
+	s.blocks = fixedBlocks(12)
+	c, err := s.Draw(ctx, c.Id)
+	if err != nil {
+		t.Fatalf("Draw failed, error: %v.", err)
+	}
+	header, _ := fixedBlocks(12).HeaderByNumber(ctx, big.NewInt(10))
+	if c.Raffle.Transcript.BlockHash != header.Hash().Hex() {
+		t.Errorf("transcript has block hash %s, expected %s.", c.Raffle.Transcript.BlockHash, header.Hash().Hex())
+	}
+}
+
+// votes of one per account, counting the reads
+type countingVotes struct {
+	reads int
+}
+
+func (v *countingVotes) PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error) {
+	v.reads++
+	return big.NewInt(1), nil
+}
+
+// the public odds route caps the simulation and reads the votes at a snapshot block once
+func TestReadOdds(t *testing.T) {
+
+	ctx := context.Background()
+	votes := &countingVotes{}
+	store := NewCollectionStore(CollectionStoreConfig{CollectionsPath: t.TempDir()}, log.NewNopLogger())
+	s := NewService(store, votes, nil, nil, nil, nil, Config{}, log.NewNopLogger()).(*service)
+
+	c := Collection{
+		Id:        "0123456789abcdef0123456789abcdef",
+		Items:     []Item{{ImageHash: "a"}},
+		Weighting: &selection.WeightConfig{Mode: selection.WeightLinear, SnapshotBlock: 1},
+		Entries: []Entry{
+			{Address: "0x00000000000000000000000000000000000000aa"},
+			{Address: "0x00000000000000000000000000000000000000bb"},
+		},
+	}
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		t.Fatalf("WriteCollection failed, error: %v.", err)
+	}
+
+	if _, err := s.ReadOdds(ctx, c.Id, "", big.NewInt(0), MaxOddsIterations+1); err != selection.ErrInvalidIterations {
+		t.Errorf("ReadOdds above the public cap returned %v, expected %v.", err, selection.ErrInvalidIterations)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.ReadOdds(ctx, c.Id, "", big.NewInt(0), 0); err != nil {
+			t.Fatalf("ReadOdds failed, error: %v.", err)
+		}
+	}
+	if votes.reads != 2 {
+		t.Errorf("votes were read %d times, expected 2.", votes.reads)
+	}
+
+	c.Entries = append(c.Entries, Entry{Address: "0x00000000000000000000000000000000000000cc"})
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		t.Fatalf("WriteCollection failed, error: %v.", err)
+	}
+	table, err := s.ReadWeights(ctx, c.Id)
+	if err != nil || len(table) != 3 {
+		t.Fatalf("ReadWeights returned %v, error: %v, expected 3 entries.", table, err)
+	}
+	if votes.reads != 5 {
+		t.Errorf("votes were read %d times, expected 5.", votes.reads)
+	}
+}
+
+// chain head at a fixed block, the first read waits until it is released
+type gatedBlocks struct {
+	fixedBlocks
+	entered chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	reads   int
+}
+
+func (b *gatedBlocks) BlockNumber(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	b.reads++
+	first := b.reads == 1
+	b.mu.Unlock()
+	if first {
+		close(b.entered)
+		<-b.release
+	}
+	return b.fixedBlocks.BlockNumber(ctx)
+}
+
+// chain reads of a draw do not hold the service lock, the state is checked again once it is taken
+func TestDrawConcurrent(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testService(t, &now)
+	ctx := withSession("owner")
+
+	c, _ := s.CreateCollection(ctx, Collection{
+		Name:      "test",
+		Items:     []Item{{ImageHash: "a"}},
+		OpensAt:   now.Add(time.Hour),
+		ClosesAt:  now.Add(2 * time.Hour),
+		DrawBlock: 10,
+	})
+	if _, err := s.Transition(ctx, c.Id, StateScheduled); err != nil {
+		t.Fatalf("Transition failed, error: %v.", err)
+	}
+	now = now.Add(90 * time.Minute)
+	s.Advance(ctx)
+	if _, err := s.Enter(ctx, c.Id, "0x00000000000000000000000000000000000000aa", "", signing.Proof{}); err != nil {
+		t.Fatalf("Enter failed, error: %v.", err)
+	}
+	now = now.Add(time.Hour)
+	s.Advance(ctx)
+
+	blocks := &gatedBlocks{fixedBlocks: 12, entered: make(chan struct{}), release: make(chan struct{})}
+	s.blocks = blocks
+
+	first := make(chan error)
+	go func() {
+		_, err := s.Draw(ctx, c.Id)
+		first <- err
+	}()
+	<-blocks.entered
+
+	// the waiting draw must not block the second one
+	if _, err := s.Draw(ctx, c.Id); err != nil {
+		t.Fatalf("Draw failed, error: %v.", err)
+	}
+	close(blocks.release)
+	if err := <-first; err != ErrTransitionGuard {
+		t.Errorf("Draw of an already drawn collection returned %v, expected %v.", err, ErrTransitionGuard)
+	}
+}
@@ -0,0 +1,170 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ******** Trait structs **********
+
+// value used for items which do not carry a trait type, counted like any other value
+const traitNone = "None"
+
+var ErrUnknownTrait = errors.New("Unknown trait")
+
+var ErrTokenNotFound = errors.New("Token not found")
+
+// a trait type of a collection with its allowed values
+type TraitType struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// rarity of a single artwork
+// statistical rarity is the probability of its trait combination, lower is rarer
+// information content is the sum of -log2(p) over its traits, higher is rarer
+// score is the sum of 1/p over its traits, higher is rarer
+type ItemRarity struct {
+	Index              int     `json:"index"`
+	ImageHash          string  `json:"image_hash"`
+	StatisticalRarity  float64 `json:"statistical_rarity"`
+	InformationContent float64 `json:"information_content"`
+	Score              float64 `json:"score"`
+	Rank               int     `json:"rank"`
+}
+
+// trait counts and per item rarity of a collection
+type RarityReport struct {
+	Frequencies map[string]map[string]int `json:"frequencies"`
+	Items       []ItemRarity              `json:"items"`
+}
+
+// ERC-721 metadata attribute
+type Attribute struct {
+	TraitType   string      `json:"trait_type"`
+	Value       interface{} `json:"value"`
+	DisplayType string      `json:"display_type,omitempty"`
+}
+
+// ERC-721 token metadata
+type TokenMetadata struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Image       string      `json:"image,omitempty"`
+	Attributes  []Attribute `json:"attributes"`
+}
+
+// checks that every item trait is defined in the trait schema
+func validateTraits(schema []TraitType, items []Item) error {
+
+	allowed := make(map[string]map[string]bool)
+	for _, t := range schema {
+		allowed[t.Name] = make(map[string]bool)
+		for _, v := range t.Values {
+			allowed[t.Name][v] = true
+		}
+	}
+
+	for i, item := range items {
+		for name, value := range item.Traits {
+			values, ok := allowed[name]
+			if !ok || !values[value] {
+				return fmt.Errorf("%w: item %d %s=%s", ErrUnknownTrait, i, name, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Rarity computes trait frequencies and rarity ranks of all items
+// items sharing the same information content share a rank
+func Rarity(schema []TraitType, items []Item) RarityReport {
+
+	n := float64(len(items))
+
+	// count values including missing traits
+	frequencies := make(map[string]map[string]int)
+	for _, t := range schema {
+		frequencies[t.Name] = make(map[string]int)
+		for _, item := range items {
+			frequencies[t.Name][traitValue(item, t.Name)]++
+		}
+	}
+
+	rarities := make([]ItemRarity, len(items))
+	for i, item := range items {
+
+		r := ItemRarity{Index: i, ImageHash: item.ImageHash, StatisticalRarity: 1}
+		for _, t := range schema {
+			p := float64(frequencies[t.Name][traitValue(item, t.Name)]) / n
+			r.StatisticalRarity *= p
+			r.InformationContent -= math.Log2(p)
+			r.Score += 1 / p
+		}
+		rarities[i] = r
+	}
+
+	// rank by information content, rarest first
+	order := make([]int, len(rarities))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rarities[order[a]].InformationContent > rarities[order[b]].InformationContent
+	})
+	for pos, i := range order {
+		if pos > 0 && rarities[order[pos-1]].InformationContent == rarities[i].InformationContent {
+			rarities[i].Rank = rarities[order[pos-1]].Rank
+		} else {
+			rarities[i].Rank = pos + 1
+		}
+	}
+
+	return RarityReport{Frequencies: frequencies, Items: rarities}
+}
+
+// builds the metadata of a revealed token including its traits and rarity
+func tokenMetadata(c *Collection, tokenId int) (TokenMetadata, error) {
+
+	if tokenId < 0 || tokenId >= len(c.Items) {
+		return TokenMetadata{}, ErrTokenNotFound
+	}
+
+	metadata := TokenMetadata{
+		Name:        c.Name + " #" + strconv.Itoa(tokenId),
+		Description: c.Description,
+		Attributes:  []Attribute{},
+	}
+
+	// art is unknown until the provenance offset is revealed
+	if c.Provenance == nil || !c.Provenance.Revealed() {
+		return metadata, nil
+	}
+
+	index := (tokenId + *c.Provenance.Offset) % len(c.Items)
+	item := c.Items[index]
+	rarity := Rarity(c.TraitSchema, c.Items).Items[index]
+
+	metadata.Image = "/media/" + item.ImageHash + "/preview"
+	for _, t := range c.TraitSchema {
+		metadata.Attributes = append(metadata.Attributes, Attribute{TraitType: t.Name, Value: traitValue(item, t.Name)})
+	}
+	metadata.Attributes = append(metadata.Attributes,
+		Attribute{TraitType: "Rarity Rank", Value: rarity.Rank, DisplayType: "number"},
+		Attribute{TraitType: "Rarity Score", Value: math.Round(rarity.Score*100) / 100, DisplayType: "number"},
+	)
+
+	return metadata, nil
+}
+
+// trait value of an item, traitNone if missing
+func traitValue(item Item, name string) string {
+	if v, ok := item.Traits[name]; ok {
+		return v
+	}
+	return traitNone
+}
@@ -0,0 +1,44 @@
+package collection
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRarity(t *testing.T) {
+
+	schema := []TraitType{
+		{Name: "background", Values: []string{"blue", "gold"}},
+		{Name: "hat", Values: []string{"cap"}},
+	}
+	items := []Item{
+		{ImageHash: "a", Traits: map[string]string{"background": "blue"}},
+		{ImageHash: "b", Traits: map[string]string{"background": "blue", "hat": "cap"}},
+		{ImageHash: "c", Traits: map[string]string{"background": "blue"}},
+		{ImageHash: "d", Traits: map[string]string{"background": "gold"}},
+	}
+
+	if err := validateTraits(schema, items); err != nil {
+		t.Fatalf("validateTraits failed, error: %v.", err)
+	}
+	if err := validateTraits(schema, []Item{{Traits: map[string]string{"hat": "crown"}}}); err == nil {
+		t.Errorf("validateTraits accepted a value outside the schema.")
+	}
+
+	report := Rarity(schema, items)
+
+	// missing traits are counted as None
+	if report.Frequencies["hat"][traitNone] != 3 || report.Frequencies["background"]["blue"] != 3 {
+		t.Errorf("unexpected frequencies %v.", report.Frequencies)
+	}
+
+	// b and d both carry one trait with p=1/4 and one with p=3/4
+	b, d := report.Items[1], report.Items[3]
+	expected := -math.Log2(0.25) - math.Log2(0.75)
+	if math.Abs(b.InformationContent-expected) > 1e-9 || math.Abs(b.StatisticalRarity-0.1875) > 1e-9 {
+		t.Errorf("unexpected rarity %+v.", b)
+	}
+	if b.Rank != 1 || d.Rank != 1 || report.Items[0].Rank != 3 || report.Items[2].Rank != 3 {
+		t.Errorf("unexpected ranks %+v.", report.Items)
+	}
+}
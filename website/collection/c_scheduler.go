@@ -0,0 +1,47 @@
+package collection
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Scheduler periodically advances collections whose configured opening, closing or reveal time has passed
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and advances collections every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+
+		if err := sc.service.Advance(ctx); err != nil {
+			level.Error(logger).Log("sc.service.Advance:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
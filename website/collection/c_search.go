@@ -0,0 +1,164 @@
+package collection
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ******** Full-text index **********
+
+// inverted index over collection names, descriptions and artist names
+type searchIndex struct {
+	mu sync.RWMutex
+
+	// token -> collection ids
+	postings map[string]map[string]struct{}
+
+	// collection id -> indexed tokens, required to remove stale postings on update
+	documents map[string][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:  make(map[string]map[string]struct{}),
+		documents: make(map[string][]string),
+	}
+}
+
+// replaces all postings of a collection
+func (si *searchIndex) put(c Collection) {
+
+	tokens := tokenize(c.Name + " " + c.Description + " " + c.Artist)
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	si.remove(c.Id)
+	for _, t := range tokens {
+		if si.postings[t] == nil {
+			si.postings[t] = make(map[string]struct{})
+		}
+		si.postings[t][c.Id] = struct{}{}
+	}
+	si.documents[c.Id] = tokens
+}
+
+func (si *searchIndex) delete(id string) {
+	si.mu.Lock()
+	si.remove(id)
+	si.mu.Unlock()
+}
+
+// removes postings of a collection, caller holds si.mu
+func (si *searchIndex) remove(id string) {
+	for _, t := range si.documents[id] {
+		delete(si.postings[t], id)
+		if len(si.postings[t]) == 0 {
+			delete(si.postings, t)
+		}
+	}
+	delete(si.documents, id)
+}
+
+// search returns the ids of collections matching every query token
+// the last token is matched as prefix to support search as you type
+func (si *searchIndex) search(query string) map[string]struct{} {
+
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	var result map[string]struct{}
+	for i, t := range tokens {
+
+		matches := make(map[string]struct{})
+		if i == len(tokens)-1 {
+			for token, ids := range si.postings {
+				if strings.HasPrefix(token, t) {
+					for id := range ids {
+						matches[id] = struct{}{}
+					}
+				}
+			}
+		} else {
+			for id := range si.postings[t] {
+				matches[id] = struct{}{}
+			}
+		}
+
+		// intersect with previous tokens
+		if result == nil {
+			result = matches
+			continue
+		}
+		for id := range result {
+			if _, ok := matches[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	return result
+}
+
+// lowercases text and splits it into unique alphanumeric tokens
+func tokenize(text string) []string {
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[string]bool)
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			tokens = append(tokens, f)
+		}
+	}
+
+	return tokens
+}
+
+// ******** Indexed store **********
+
+// collection store decorator keeping the search index in sync on every write
+type indexedStore struct {
+	CollectionStore
+	index *searchIndex
+}
+
+func (is *indexedStore) WriteCollection(c Collection) error {
+	if err := is.CollectionStore.WriteCollection(c); err != nil {
+		return err
+	}
+	is.index.put(c)
+	return nil
+}
+
+func (is *indexedStore) DeleteCollection(id string) error {
+	if err := is.CollectionStore.DeleteCollection(id); err != nil {
+		return err
+	}
+	is.index.delete(id)
+	return nil
+}
+
+// wraps a store and indexes all stored collections
+func newIndexedStore(store CollectionStore, index *searchIndex) (*indexedStore, error) {
+
+	collections, err := store.ReadCollections()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range collections {
+		index.put(c)
+	}
+
+	return &indexedStore{CollectionStore: store, index: index}, nil
+}
@@ -0,0 +1,812 @@
+package collection
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"website/selection"
+	"website/session"
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrNotEditable = errors.New("Collection can only be edited in draft state")
+
+var ErrEntryClosed = errors.New("Collection is not open for entry")
+
+var ErrDuplicateEntry = errors.New("Address already entered")
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrNotOwner = errors.New("Only the owner of the collection can do this")
+
+var ErrNoChain = errors.New("Drawing at a block requires a chain client")
+
+var ErrDrawBlockNotFinal = errors.New("Draw block is not final yet")
+
+// hex encoded 20 byte ethereum address
+var addressPattern = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// service interface defining all required methods
+type Service interface {
+	CreateCollection(ctx context.Context, c Collection) (Collection, error)
+	ReadCollection(ctx context.Context, id string) (Collection, error)
+	ListCollections(ctx context.Context, q Query) ([]Collection, string, error)
+	UpdateCollection(ctx context.Context, id string, c Collection) (Collection, error)
+	Transition(ctx context.Context, id string, to State) (Collection, error)
+	Enter(ctx context.Context, id string, address string, contribution string, proof signing.Proof) (Collection, error)
+	Draw(ctx context.Context, id string) (Collection, error)
+	ReadTranscript(ctx context.Context, id string) (selection.Transcript, error)
+	ReadWeights(ctx context.Context, id string) ([]selection.WeightEntry, error)
+	ReadOdds(ctx context.Context, id string, address string, extra *big.Int, iterations int) (selection.OddsReport, error)
+	ReadProof(ctx context.Context, id string, address string) (AllowlistProof, error)
+	ReadProvenance(ctx context.Context, id string) (Provenance, []Assignment, error)
+	ReadRarity(ctx context.Context, id string) (RarityReport, error)
+	ReadTokenMetadata(ctx context.Context, id string, tokenId int) (TokenMetadata, error)
+	Advance(ctx context.Context) error
+	Subscribe(h EventHandler)
+}
+
+// EntryVerifier checks wallet signatures of raffle entries, implemented by the signing service
+type EntryVerifier interface {
+	VerifyEntry(ctx context.Context, e signing.Entry, signature string) error
+}
+
+// StakeSource reads ART staked toward a collection at a past block, implemented by the staking service
+// it follows the semantics of selection.VotesSource
+type StakeSource interface {
+	PriorStake(ctx context.Context, collectionId string, account string, blockNumber uint64) (*big.Int, error)
+}
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// Blocks reads the chain head and headers, implemented by chain.Backend
+type Blocks interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Config of the collection service
+// a draw block is final once confirmations blocks are built on top of it
+type Config struct {
+	Confirmations uint64
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// serialises read-modify-write cycles on collections
+	mu sync.Mutex
+
+	collectionStore CollectionStore
+	index           *searchIndex
+	votes           selection.VotesSource
+	stakes          StakeSource
+	blocks          Blocks
+	sessions        Sessions
+	verifier        EntryVerifier
+	config          Config
+	handlers        []EventHandler
+	handlersMu      sync.RWMutex
+	tables          map[string]cachedTable
+	tablesMu        sync.Mutex
+	now             func() time.Time
+	logger          log.Logger
+}
+
+// user identifier of the active session attached to the request context
+func (s *service) user(ctx context.Context) (string, error) {
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok || s.sessions == nil {
+		return "", ErrUnauthorized
+	}
+
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	return sess.UserId(), nil
+}
+
+// checks that the session user owns the collection
+func (s *service) authorize(ctx context.Context, c *Collection) error {
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return err
+	}
+	if c.Owner == "" || c.Owner != userId {
+		return ErrNotOwner
+	}
+
+	return nil
+}
+
+// service struct create collection method
+// new collections always start in draft state and belong to the session user
+func (s *service) CreateCollection(ctx context.Context, c Collection) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "CreateCollection")
+
+	owner, err := s.user(ctx)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	if err := c.Validate(); err != nil {
+		level.Error(logger).Log("c.Validate:", err)
+		return Collection{}, err
+	}
+
+	id, err := newId()
+	if err != nil {
+		level.Error(logger).Log("newId:", err)
+		return Collection{}, err
+	}
+
+	now := s.now()
+	c.Id = id
+	c.Owner = owner
+	c.State = StateDraft
+	c.Transitions = nil
+	c.Entries = nil
+	c.Provenance = nil
+	c.Raffle = nil
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		level.Error(logger).Log("s.collectionStore.WriteCollection:", err)
+		return Collection{}, err
+	}
+
+	return c, nil
+}
+
+// service struct read collection method
+func (s *service) ReadCollection(ctx context.Context, id string) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadCollection")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+
+	return c, nil
+}
+
+// service struct list collections method
+// returns a page of collections matching the query and the cursor of the next page
+func (s *service) ListCollections(ctx context.Context, q Query) ([]Collection, string, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ListCollections")
+
+	collections, err := s.collectionStore.ReadCollections()
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollections:", err)
+		return nil, "", err
+	}
+
+	var textMatches map[string]struct{}
+	if q.Text != "" {
+		textMatches = s.index.search(q.Text)
+	}
+
+	return query(collections, q, textMatches)
+}
+
+// service struct update collection method
+// art, schedule and descriptions are locked once a collection leaves draft state
+func (s *service) UpdateCollection(ctx context.Context, id string, update Collection) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "UpdateCollection")
+
+	if err := update.Validate(); err != nil {
+		level.Error(logger).Log("update.Validate:", err)
+		return Collection{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+
+	if err := s.authorize(ctx, &c); err != nil {
+		return Collection{}, err
+	}
+
+	if c.State != StateDraft {
+		return Collection{}, ErrNotEditable
+	}
+
+	c.Name = update.Name
+	c.Description = update.Description
+	c.Artist = update.Artist
+	c.Tags = update.Tags
+	c.TraitSchema = update.TraitSchema
+	c.Items = update.Items
+	c.OpensAt = update.OpensAt
+	c.ClosesAt = update.ClosesAt
+	c.RevealAt = update.RevealAt
+	c.DrawBlock = update.DrawBlock
+	c.MintPrice = update.MintPrice
+	c.Weighting = update.Weighting
+	c.UpdatedAt = s.now()
+
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		level.Error(logger).Log("s.collectionStore.WriteCollection:", err)
+		return Collection{}, err
+	}
+
+	return c, nil
+}
+
+// service struct transition method
+// moves a collection of the session user into the next lifecycle state if guards pass
+func (s *service) Transition(ctx context.Context, id string, to State) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Transition")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+	if err := s.authorize(ctx, &c); err != nil {
+		return Collection{}, err
+	}
+
+	s.mu.Lock()
+	c, event, err := s.transition(id, to)
+	s.mu.Unlock()
+
+	if err != nil {
+		level.Error(logger).Log("s.transition:", err, "id", id, "to", to)
+		return Collection{}, err
+	}
+
+	s.emit(event)
+	return c, nil
+}
+
+// applies and stores a transition, caller holds s.mu
+func (s *service) transition(id string, to State) (Collection, Event, error) {
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		return Collection{}, Event{}, err
+	}
+
+	event, err := transition(&c, to, s.now())
+	if err != nil {
+		return Collection{}, Event{}, err
+	}
+
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		return Collection{}, Event{}, err
+	}
+
+	return c, event, nil
+}
+
+// service struct enter method
+// registers a wallet address for the raffle of an open collection
+// with a verifier, the entry must be signed by that wallet, see signing.Entry
+func (s *service) Enter(ctx context.Context, id string, address string, contribution string, proof signing.Proof) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Enter")
+
+	address, err := normalizeAddress(address)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	contribution = strings.ToLower(strings.TrimSpace(contribution))
+	if !selection.ValidContribution(contribution) {
+		return Collection{}, selection.ErrInvalidContribution
+	}
+
+	if s.verifier != nil {
+		entry := signing.Entry{
+			Collection:   id,
+			Entrant:      common.HexToAddress(address),
+			Contribution: contribution,
+			Nonce:        proof.Nonce,
+			Deadline:     proof.Deadline,
+		}
+		if err := s.verifier.VerifyEntry(ctx, entry, proof.Signature); err != nil {
+			return Collection{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+
+	// entry window defined by state and schedule
+	now := s.now()
+	if c.State != StateOpen || !now.Before(c.ClosesAt) {
+		return Collection{}, ErrEntryClosed
+	}
+
+	if c.hasEntered(address) {
+		return Collection{}, ErrDuplicateEntry
+	}
+
+	c.Entries = append(c.Entries, Entry{Address: address, Contribution: contribution, CreatedAt: now})
+	c.UpdatedAt = now
+
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		level.Error(logger).Log("s.collectionStore.WriteCollection:", err)
+		return Collection{}, err
+	}
+
+	return c, nil
+}
+
+// hash of the draw block read from the chain, empty without a draw block
+// the block must be final so that nobody can pick or replace the hash mixed into the seed
+func (s *service) drawHash(ctx context.Context, block uint64) (string, error) {
+
+	if block == 0 {
+		return "", nil
+	}
+	if s.blocks == nil {
+		return "", ErrNoChain
+	}
+
+	head, err := s.blocks.BlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	if head < block+s.config.Confirmations {
+		return "", ErrDrawBlockNotFinal
+	}
+
+	header, err := s.blocks.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if err != nil {
+		return "", err
+	}
+
+	return header.Hash().Hex(), nil
+}
+
+// service struct draw method
+// reveals the raffle secret, draws winners and moves the collection into drawn state
+// only the owner draws, the hash of the draw block is read from the chain once the block is final
+func (s *service) Draw(ctx context.Context, id string) (Collection, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Draw")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+
+	if err := s.authorize(ctx, &c); err != nil {
+		return Collection{}, err
+	}
+	if c.State != StateClosed || c.Raffle == nil {
+		return Collection{}, ErrTransitionGuard
+	}
+
+	// chain reads happen before the lock, the collection is checked again once it is held
+	blockHash, err := s.drawHash(ctx, c.DrawBlock)
+	if err != nil {
+		level.Error(logger).Log("s.drawHash:", err, "id", id)
+		return Collection{}, err
+	}
+
+	var table []selection.WeightEntry
+	if c.Weighting != nil {
+		table, err = s.weightTable(ctx, &c)
+		if err != nil {
+			level.Error(logger).Log("s.weightTable:", err, "id", id)
+			return Collection{}, err
+		}
+	}
+
+	s.mu.Lock()
+
+	fresh, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		s.mu.Unlock()
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Collection{}, err
+	}
+
+	if err := s.authorize(ctx, &fresh); err != nil {
+		s.mu.Unlock()
+		return Collection{}, err
+	}
+	if !sameDraw(&c, &fresh) {
+		s.mu.Unlock()
+		return Collection{}, ErrTransitionGuard
+	}
+	c = fresh
+
+	now := s.now()
+	if err := draw(&c, table, blockHash, now); err != nil {
+		s.mu.Unlock()
+		level.Error(logger).Log("draw:", err, "id", id)
+		return Collection{}, err
+	}
+
+	event, err := transition(&c, StateDrawn, now)
+	if err != nil {
+		s.mu.Unlock()
+		level.Error(logger).Log("transition:", err, "id", id)
+		return Collection{}, err
+	}
+
+	if err := s.collectionStore.WriteCollection(c); err != nil {
+		s.mu.Unlock()
+		level.Error(logger).Log("s.collectionStore.WriteCollection:", err)
+		return Collection{}, err
+	}
+
+	s.mu.Unlock()
+
+	s.tablesMu.Lock()
+	delete(s.tables, id)
+	s.tablesMu.Unlock()
+
+	s.emit(event)
+	return c, nil
+}
+
+// service struct read transcript method
+// returns the published draw transcript, anyone can replay it with selection.Verify
+func (s *service) ReadTranscript(ctx context.Context, id string) (selection.Transcript, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadTranscript")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return selection.Transcript{}, err
+	}
+
+	if c.Raffle == nil || c.Raffle.Transcript == nil {
+		return selection.Transcript{}, ErrNotDrawn
+	}
+
+	return *c.Raffle.Transcript, nil
+}
+
+// service struct read weights method
+// returns the published weight table of a drawn raffle, or a live preview at the snapshot block
+func (s *service) ReadWeights(ctx context.Context, id string) ([]selection.WeightEntry, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadWeights")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return nil, err
+	}
+
+	if c.Weighting == nil {
+		return nil, ErrNotWeighted
+	}
+
+	if c.Raffle != nil && c.Raffle.Transcript != nil {
+		return c.Raffle.Transcript.Weights, nil
+	}
+
+	table, err := s.weightTable(ctx, &c)
+	if err != nil {
+		level.Error(logger).Log("s.weightTable:", err)
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// service struct read odds method
+// computes every entrant's chance to win at least one artwork of an undrawn raffle
+// with an address, also the odds that address would have with extra votes at the snapshot block
+func (s *service) ReadOdds(ctx context.Context, id string, address string, extra *big.Int, iterations int) (selection.OddsReport, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadOdds")
+
+	if iterations < 0 || iterations > MaxOddsIterations {
+		return selection.OddsReport{}, selection.ErrInvalidIterations
+	}
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return selection.OddsReport{}, err
+	}
+
+	if c.Raffle != nil && c.Raffle.Transcript != nil {
+		return selection.OddsReport{}, ErrAlreadyDrawn
+	}
+
+	table := selection.UniformTable(c.entrants())
+	if c.Weighting != nil {
+		table, err = s.weightTable(ctx, &c)
+		if err != nil {
+			level.Error(logger).Log("s.weightTable:", err)
+			return selection.OddsReport{}, err
+		}
+	}
+
+	report, err := selection.TableOdds(table, len(c.Items), iterations)
+	if err != nil {
+		return selection.OddsReport{}, err
+	}
+
+	if address == "" {
+		return report, nil
+	}
+
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return selection.OddsReport{}, err
+	}
+
+	// addresses that have not entered yet bring their own votes at the snapshot block
+	stake := new(big.Int).Set(extra)
+	if c.Weighting != nil && !c.hasEntered(address) {
+		src := s.weights(&c)
+		if src == nil {
+			return selection.OddsReport{}, selection.ErrNoVotesSource
+		}
+		votes, err := src.PriorVotes(ctx, address, c.Weighting.SnapshotBlock)
+		if err != nil {
+			level.Error(logger).Log("src.PriorVotes:", err)
+			return selection.OddsReport{}, err
+		}
+		stake.Add(stake, votes)
+	}
+
+	if err := report.AddWhatIf(table, c.Weighting, address, stake, iterations); err != nil {
+		return selection.OddsReport{}, err
+	}
+	report.WhatIf.Extra = extra.String()
+
+	return report, nil
+}
+
+// service struct read proof method
+// returns the Merkle proofs an address needs to mint the tokens it won
+func (s *service) ReadProof(ctx context.Context, id string, address string) (AllowlistProof, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadProof")
+
+	address, err := normalizeAddress(address)
+	if err != nil {
+		return AllowlistProof{}, err
+	}
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return AllowlistProof{}, err
+	}
+
+	proof, err := allowlistProof(&c, address)
+	if errors.Is(err, ErrAllowlistMismatch) {
+		level.Error(logger).Log("allowlistProof:", err, "id", id)
+	}
+	if err != nil {
+		return AllowlistProof{}, err
+	}
+
+	return proof, nil
+}
+
+// service struct read provenance method
+// returns the public commitment and, once revealed, the verified token assignment
+func (s *service) ReadProvenance(ctx context.Context, id string) (Provenance, []Assignment, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadProvenance")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return Provenance{}, nil, err
+	}
+
+	// not locked yet
+	if c.Provenance == nil {
+		return Provenance{}, nil, ErrNotFound
+	}
+
+	c = c.Public()
+	if !c.Provenance.Revealed() {
+		return *c.Provenance, nil, nil
+	}
+
+	assignments, err := VerifyProvenance(*c.Provenance)
+	if err != nil {
+		level.Error(logger).Log("VerifyProvenance:", err, "id", id)
+		return Provenance{}, nil, err
+	}
+
+	return *c.Provenance, assignments, nil
+}
+
+// service struct read rarity method
+// trait frequencies and rarity ranks of all artworks of a collection
+func (s *service) ReadRarity(ctx context.Context, id string) (RarityReport, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadRarity")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return RarityReport{}, err
+	}
+
+	return Rarity(c.TraitSchema, c.Items), nil
+}
+
+// service struct read token metadata method
+// ERC-721 metadata of a token, traits and rarity are included once the collection is revealed
+func (s *service) ReadTokenMetadata(ctx context.Context, id string, tokenId int) (TokenMetadata, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadTokenMetadata")
+
+	c, err := s.collectionStore.ReadCollection(id)
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollection:", err)
+		return TokenMetadata{}, err
+	}
+
+	return tokenMetadata(&c, tokenId)
+}
+
+// service struct advance method
+// applies all timed transitions which are due, called periodically by the scheduler
+func (s *service) Advance(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Advance")
+
+	collections, err := s.collectionStore.ReadCollections()
+	if err != nil {
+		level.Error(logger).Log("s.collectionStore.ReadCollections:", err)
+		return err
+	}
+
+	for _, c := range collections {
+
+		// a collection may be due for several transitions, e.g. after downtime
+		for {
+			to, due := scheduledTransition(&c, s.now())
+			if !due {
+				break
+			}
+
+			s.mu.Lock()
+			next, event, err := s.transition(c.Id, to)
+			s.mu.Unlock()
+
+			if err != nil {
+				level.Error(logger).Log("s.transition:", err, "id", c.Id, "to", to)
+				break
+			}
+
+			level.Info(logger).Log("msg", "scheduled transition", "id", c.Id, "from", event.From, "to", event.To)
+			s.emit(event)
+			c = next
+		}
+	}
+
+	return nil
+}
+
+// registers a handler which is called for every lifecycle event
+func (s *service) Subscribe(h EventHandler) {
+	s.handlersMu.Lock()
+	s.handlers = append(s.handlers, h)
+	s.handlersMu.Unlock()
+}
+
+// emits an event to all subscribers
+func (s *service) emit(event Event) {
+
+	s.handlersMu.RLock()
+	handlers := s.handlers
+	s.handlersMu.RUnlock()
+
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// initialization function to return service struct
+// wraps the store to keep the full-text index in sync with every write
+// votes and stakes are required for raffles weighted by the respective source only and may be nil
+// without a verifier, entries are accepted without wallet signatures
+// this function is called in main.go
+func NewService(collectionStore CollectionStore, votes selection.VotesSource, stakes StakeSource, blocks Blocks, sessions Sessions, verifier EntryVerifier, config Config, logger log.Logger) Service {
+
+	index := newSearchIndex()
+	store, err := newIndexedStore(collectionStore, index)
+	if err != nil {
+		level.Error(logger).Log("newIndexedStore:", err)
+		store = &indexedStore{CollectionStore: collectionStore, index: index}
+	}
+
+	return &service{
+		collectionStore: store,
+		index:           index,
+		votes:           votes,
+		stakes:          stakes,
+		blocks:          blocks,
+		sessions:        sessions,
+		verifier:        verifier,
+		config:          config,
+		tables:          map[string]cachedTable{},
+		now:             time.Now,
+		logger:          logger,
+	}
+}
+
+// ******* utils functions ********
+
+// random collection identifier
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validates and lowercases an ethereum address
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !addressPattern.MatchString(address) {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(address), nil
+}
@@ -0,0 +1,290 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"website/selection"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Collection struct **********
+
+// an artwork of a collection, image_hash references the media pipeline
+type Item struct {
+	ImageHash string            `json:"image_hash"`
+	Name      string            `json:"name"`
+	Traits    map[string]string `json:"traits,omitempty"`
+}
+
+// raffle entry of a wallet address
+// contribution is an optional random hex string mixed into the draw seed
+type Entry struct {
+	Address      string    `json:"address"`
+	Contribution string    `json:"contribution"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Collection struct {
+	Id          string                  `json:"id"`
+	Owner       string                  `json:"owner,omitempty"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Artist      string                  `json:"artist"`
+	Tags        []string                `json:"tags"`
+	TraitSchema []TraitType             `json:"trait_schema"`
+	Items       []Item                  `json:"items"`
+	State       State                   `json:"state"`
+	OpensAt     time.Time               `json:"opens_at"`
+	ClosesAt    time.Time               `json:"closes_at"`
+	RevealAt    time.Time               `json:"reveal_at"`
+	DrawBlock   uint64                  `json:"draw_block"`
+	Weighting   *selection.WeightConfig `json:"weighting,omitempty"`
+	MintPrice   string                  `json:"mint_price,omitempty"`
+	Transitions []Transition            `json:"transitions"`
+	Entries     []Entry                 `json:"entries"`
+	Provenance  *Provenance             `json:"provenance,omitempty"`
+	Raffle      *Raffle                 `json:"raffle,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+var ErrInvalidCollection = errors.New("Invalid collection")
+
+// Validate collection input
+func (c *Collection) Validate() error {
+
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("%w: Name is required", ErrInvalidCollection)
+	}
+	for _, item := range c.Items {
+		if item.ImageHash == "" {
+			return fmt.Errorf("%w: ImageHash is required", ErrInvalidCollection)
+		}
+	}
+	if !c.OpensAt.IsZero() && !c.ClosesAt.IsZero() && !c.OpensAt.Before(c.ClosesAt) {
+		return fmt.Errorf("%w: OpensAt must be before ClosesAt", ErrInvalidCollection)
+	}
+	if err := validateTraits(c.TraitSchema, c.Items); err != nil {
+		return err
+	}
+	if c.MintPrice != "" {
+		if price, ok := new(big.Int).SetString(c.MintPrice, 10); !ok || price.Sign() < 0 {
+			return fmt.Errorf("%w: MintPrice must be a non-negative amount in wei", ErrInvalidCollection)
+		}
+	}
+	if c.Weighting != nil {
+		if err := c.Weighting.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCollection, err)
+		}
+		if c.Weighting.SnapshotBlock == 0 {
+			return fmt.Errorf("%w: SnapshotBlock is required for weighted draws", ErrInvalidCollection)
+		}
+	}
+
+	return nil
+}
+
+// Public returns a copy of the collection safe to send to clients
+// provenance offset and salt are hidden until the collection is revealed
+// the raffle secret is hidden until winners are drawn
+func (c Collection) Public() Collection {
+
+	c.Owner = ""
+
+	if c.Provenance != nil && !c.Provenance.Revealed() {
+		p := *c.Provenance
+		p.Offset = nil
+		p.Salt = ""
+		c.Provenance = &p
+	}
+
+	if c.Raffle != nil && c.Raffle.Transcript == nil {
+		r := *c.Raffle
+		r.Secret = ""
+		c.Raffle = &r
+	}
+
+	return c
+}
+
+// ******* Collection store interface *********
+
+var ErrCollectionNotFound = errors.New("Collection not found")
+
+// identifiers are hex encoded random bytes, validated to prevent path traversal
+var idPattern = regexp.MustCompile("^[0-9a-f]{32}$")
+
+type CollectionStoreConfig struct {
+	CollectionsPath string
+}
+
+type CollectionStore interface {
+	WriteCollection(c Collection) error
+	ReadCollection(id string) (Collection, error)
+	ReadCollections() ([]Collection, error)
+	DeleteCollection(id string) error
+}
+
+type collectionStore struct {
+	mu     sync.RWMutex
+	config CollectionStoreConfig
+	logger log.Logger
+}
+
+func (cs *collectionStore) WriteCollection(c Collection) error {
+
+	// log level
+	logger := log.With(cs.logger, "method", "WriteCollection")
+
+	if !idPattern.MatchString(c.Id) {
+		return ErrCollectionNotFound
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if err := os.MkdirAll(cs.config.CollectionsPath, 0755); err != nil {
+		level.Error(logger).Log("os.MkdirAll:", err)
+		return err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		level.Error(logger).Log("json.Marshal:", err)
+		return err
+	}
+
+	// write temporary file first, readers never see partial collections
+	path := getCollectionPath(c.Id, cs.config.CollectionsPath, ".json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		level.Error(logger).Log("os.WriteFile:", err)
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		level.Error(logger).Log("os.Rename:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (cs *collectionStore) ReadCollection(id string) (c Collection, err error) {
+
+	// log level
+	logger := log.With(cs.logger, "method", "ReadCollection")
+
+	if !idPattern.MatchString(id) {
+		return c, ErrCollectionNotFound
+	}
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	data, err := os.ReadFile(getCollectionPath(id, cs.config.CollectionsPath, ".json"))
+	if os.IsNotExist(err) {
+		return c, ErrCollectionNotFound
+	}
+	if err != nil {
+		level.Error(logger).Log("os.ReadFile:", err)
+		return c, err
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		level.Error(logger).Log("json.Unmarshal:", err)
+		return c, err
+	}
+
+	return c, nil
+}
+
+// reads all collections ordered by creation time
+func (cs *collectionStore) ReadCollections() ([]Collection, error) {
+
+	// log level
+	logger := log.With(cs.logger, "method", "ReadCollections")
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(cs.config.CollectionsPath, "*.json"))
+	if err != nil {
+		level.Error(logger).Log("filepath.Glob:", err)
+		return nil, err
+	}
+
+	collections := make([]Collection, 0, len(files))
+	for _, file := range files {
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			level.Error(logger).Log("os.ReadFile:", err)
+			return nil, err
+		}
+
+		var c Collection
+		if err := json.Unmarshal(data, &c); err != nil {
+			level.Error(logger).Log("json.Unmarshal:", err, "file", file)
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].CreatedAt.Before(collections[j].CreatedAt)
+	})
+
+	return collections, nil
+}
+
+func (cs *collectionStore) DeleteCollection(id string) error {
+
+	// log level
+	logger := log.With(cs.logger, "method", "DeleteCollection")
+
+	if !idPattern.MatchString(id) {
+		return ErrCollectionNotFound
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	err := os.Remove(getCollectionPath(id, cs.config.CollectionsPath, ".json"))
+	if os.IsNotExist(err) {
+		return ErrCollectionNotFound
+	}
+	if err != nil {
+		level.Error(logger).Log("os.Remove:", err)
+		return err
+	}
+
+	return nil
+}
+
+func NewCollectionStore(config CollectionStoreConfig, logger log.Logger) CollectionStore {
+	return &collectionStore{
+		config: config,
+		logger: logger,
+	}
+}
+
+// ******* utils functions ********
+
+func getCollectionPath(id, collectionsPath, ending string) string {
+	var b bytes.Buffer
+	b.WriteString(filepath.Join(collectionsPath, id))
+	b.WriteString(ending)
+	return b.String()
+}
@@ -3,16 +3,36 @@ package configs
 import "os"
 
 type Config struct {
-	StaticAssetsDir   string
-	TlsCertPath       string
-	TlsKeyPath        string
-	HttpAddr          string
-	HttpPort          string
-	HttpsAddr         string
-	HttpsPort         string
-	HttpSessionSecret string
-	UsersPath         string
-	CollectionsPath   string
+	StaticAssetsDir    string
+	TlsCertPath        string
+	TlsKeyPath         string
+	HttpAddr           string
+	HttpPort           string
+	HttpsAddr          string
+	HttpsPort          string
+	HttpSessionSecret  string
+	UsersPath          string
+	CollectionsPath    string
+	MediaPath          string
+	AuctionsPath       string
+	NoncesPath         string
+	ChainId            string
+	ArtAddress         string
+	LedgerPath         string
+	PlatformFeeBps     string
+	Operators          string
+	RpcUrl             string
+	IndexPath          string
+	IndexStartBlock    string
+	IndexConfirmations string
+	KeystorePath       string
+	KeystorePassword   string
+	TxsPath            string
+	RelaysPath         string
+	StakesPath         string
+	StakingAddress     string
+	StakingEpochStart  string
+	StakingEpochReward string
 }
 
 var (
@@ -66,22 +86,134 @@ func init() {
 		UsersPath = "./storage/users/"
 	}
 
-	CollectionsPath := os.Getenv("HTTP_SESSION_SECRET")
+	CollectionsPath := os.Getenv("COLLECTIONS_PATH")
 	if CollectionsPath == "" {
 		CollectionsPath = "./storage/collections/"
 	}
 
+	MediaPath := os.Getenv("MEDIA_PATH")
+	if MediaPath == "" {
+		MediaPath = "./storage/media/"
+	}
+
+	AuctionsPath := os.Getenv("AUCTIONS_PATH")
+	if AuctionsPath == "" {
+		AuctionsPath = "./storage/auctions/"
+	}
+
+	NoncesPath := os.Getenv("NONCES_PATH")
+	if NoncesPath == "" {
+		NoncesPath = "./storage/nonces/"
+	}
+
+	ChainId := os.Getenv("CHAIN_ID")
+	if ChainId == "" {
+		ChainId = "1"
+	}
+
+	ArtAddress := os.Getenv("ART_CONTRACT_ADDRESS")
+	if ArtAddress == "" {
+		ArtAddress = "0x0000000000000000000000000000000000000000"
+	}
+
+	LedgerPath := os.Getenv("LEDGER_PATH")
+	if LedgerPath == "" {
+		LedgerPath = "./storage/ledger/"
+	}
+
+	PlatformFeeBps := os.Getenv("PLATFORM_FEE_BPS")
+	if PlatformFeeBps == "" {
+		PlatformFeeBps = "250"
+	}
+
+	// comma separated emails of the users that may settle jobs and record payments
+	Operators := os.Getenv("OPERATORS")
+
+	// empty disables the chain client
+	RpcUrl := os.Getenv("RPC_URL")
+
+	IndexPath := os.Getenv("INDEX_PATH")
+	if IndexPath == "" {
+		IndexPath = "./storage/index/"
+	}
+
+	// block the Art token was deployed in, nothing is indexed below it
+	IndexStartBlock := os.Getenv("INDEX_START_BLOCK")
+	if IndexStartBlock == "" {
+		IndexStartBlock = "0"
+	}
+
+	IndexConfirmations := os.Getenv("INDEX_CONFIRMATIONS")
+	if IndexConfirmations == "" {
+		IndexConfirmations = "12"
+	}
+
+	// encrypted key file of the hot wallet sending all transactions, empty disables the transaction manager and relayer
+	KeystorePath := os.Getenv("KEYSTORE_PATH")
+
+	KeystorePassword := os.Getenv("KEYSTORE_PASSWORD")
+
+	TxsPath := os.Getenv("TXS_PATH")
+	if TxsPath == "" {
+		TxsPath = "./storage/txs/"
+	}
+
+	RelaysPath := os.Getenv("RELAYS_PATH")
+	if RelaysPath == "" {
+		RelaysPath = "./storage/relays/"
+	}
+
+	StakesPath := os.Getenv("STAKES_PATH")
+	if StakesPath == "" {
+		StakesPath = "./storage/stakes/"
+	}
+
+	// receives custodial stakes and delegations, empty uses the hot wallet
+	StakingAddress := os.Getenv("STAKING_ADDRESS")
+
+	// start of the first weekly reward epoch, RFC 3339
+	StakingEpochStart := os.Getenv("STAKING_EPOCH_START")
+	if StakingEpochStart == "" {
+		StakingEpochStart = "2022-01-03T00:00:00Z"
+	}
+
+	// reward pool of every staked collection per epoch, in the token's smallest unit
+	StakingEpochReward := os.Getenv("STAKING_EPOCH_REWARD")
+	if StakingEpochReward == "" {
+		StakingEpochReward = "0"
+	}
+
 	config = &Config{
-		StaticAssetsDir:   StaticAssetsDir,
-		TlsCertPath:       TlsCertPath,
-		TlsKeyPath:        TlsKeyPath,
-		HttpAddr:          HttpAddr,
-		HttpPort:          HttpPort,
-		HttpsAddr:         HttpsAddr,
-		HttpsPort:         HttpsPort,
-		HttpSessionSecret: HttpSessionSecret,
-		UsersPath:         UsersPath,
-		CollectionsPath:   CollectionsPath,
+		StaticAssetsDir:    StaticAssetsDir,
+		TlsCertPath:        TlsCertPath,
+		TlsKeyPath:         TlsKeyPath,
+		HttpAddr:           HttpAddr,
+		HttpPort:           HttpPort,
+		HttpsAddr:          HttpsAddr,
+		HttpsPort:          HttpsPort,
+		HttpSessionSecret:  HttpSessionSecret,
+		UsersPath:          UsersPath,
+		CollectionsPath:    CollectionsPath,
+		MediaPath:          MediaPath,
+		AuctionsPath:       AuctionsPath,
+		NoncesPath:         NoncesPath,
+		ChainId:            ChainId,
+		ArtAddress:         ArtAddress,
+		LedgerPath:         LedgerPath,
+		PlatformFeeBps:     PlatformFeeBps,
+		Operators:          Operators,
+		RpcUrl:             RpcUrl,
+		IndexPath:          IndexPath,
+		IndexStartBlock:    IndexStartBlock,
+		IndexConfirmations: IndexConfirmations,
+		KeystorePath:       KeystorePath,
+		KeystorePassword:   KeystorePassword,
+		TxsPath:            TxsPath,
+		RelaysPath:         RelaysPath,
+		StakesPath:         StakesPath,
+		StakingAddress:     StakingAddress,
+		StakingEpochStart:  StakingEpochStart,
+		StakingEpochReward: StakingEpochReward,
 	}
 }
 
@@ -0,0 +1,148 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// holders listed when no limit is given
+const defaultHolders = 100
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidLimit),
+		errors.Is(err, ErrInvalidRange):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// does not decode request
+func decodeReadStatus(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadStatusRequest{}, nil
+}
+
+// decode optional limit from query parameters
+func decodeListHolders(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := ListHoldersRequest{Limit: defaultHolders}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+// decode address from route
+func decodeReadAccount(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadAccountRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// decode address from route
+func decodeReadHistory(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadHistoryRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching token index handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	readStatusHandler := httptransport.NewServer(
+		e.ReadStatus,
+		decodeReadStatus,
+		encodeResponse,
+		options...,
+	)
+
+	listHoldersHandler := httptransport.NewServer(
+		e.ListHolders,
+		decodeListHolders,
+		encodeResponse,
+		options...,
+	)
+
+	readAccountHandler := httptransport.NewServer(
+		e.ReadAccount,
+		decodeReadAccount,
+		encodeResponse,
+		options...,
+	)
+
+	readHistoryHandler := httptransport.NewServer(
+		e.ReadHistory,
+		decodeReadHistory,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/token/status", readStatusHandler).Methods("GET")
+	router.Handle("/token/holders", listHoldersHandler).Methods("GET")
+	router.Handle("/token/accounts/{address}", readAccountHandler).Methods("GET")
+	router.Handle("/token/accounts/{address}/history", readHistoryHandler).Methods("GET")
+
+	return router
+}
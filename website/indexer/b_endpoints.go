@@ -0,0 +1,143 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ReadStatusRequest struct{}
+
+type ListHoldersRequest struct {
+	Limit int
+}
+
+type ReadAccountRequest struct {
+	Address string
+}
+
+type ReadHistoryRequest struct {
+	Address string
+}
+
+type StatusResponse struct {
+	Data Status `json:"data"`
+	Err  error  `json:"errors"`
+}
+
+// have StatusResponse follow the customError interface defined in a_transport.go
+func (r StatusResponse) error() error { return r.Err }
+
+type HoldersResponse struct {
+	Data []Holder `json:"data"`
+	Err  error    `json:"errors"`
+}
+
+// have HoldersResponse follow the customError interface defined in a_transport.go
+func (r HoldersResponse) error() error { return r.Err }
+
+type AccountResponse struct {
+	Data Account `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have AccountResponse follow the customError interface defined in a_transport.go
+func (r AccountResponse) error() error { return r.Err }
+
+type EventsResponse struct {
+	Data []Event `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have EventsResponse follow the customError interface defined in a_transport.go
+func (r EventsResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	ReadStatus  endpoint.Endpoint
+	ListHolders endpoint.Endpoint
+	ReadAccount endpoint.Endpoint
+	ReadHistory endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		ReadStatus:  epReadStatus(s),
+		ListHolders: epListHolders(s),
+		ReadAccount: epReadAccount(s),
+		ReadHistory: epReadHistory(s),
+	}
+}
+
+// indexing status endpoint
+func epReadStatus(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		st, err := s.ReadStatus(ctx)
+		if err != nil {
+			return StatusResponse{Err: err}, err
+		}
+
+		return StatusResponse{Data: st, Err: nil}, nil
+	}
+}
+
+// holders endpoint
+func epListHolders(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListHoldersRequest)
+
+		// call service method
+		holders, err := s.ListHolders(ctx, req.Limit)
+		if err != nil {
+			return HoldersResponse{Err: err}, err
+		}
+
+		return HoldersResponse{Data: holders, Err: nil}, nil
+	}
+}
+
+// account endpoint
+func epReadAccount(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadAccountRequest)
+
+		// call service method
+		a, err := s.ReadAccount(ctx, req.Address)
+		if err != nil {
+			return AccountResponse{Err: err}, err
+		}
+
+		return AccountResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// account history endpoint
+func epReadHistory(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadHistoryRequest)
+
+		// call service method
+		events, err := s.ReadHistory(ctx, req.Address)
+		if err != nil {
+			return EventsResponse{Err: err}, err
+		}
+
+		return EventsResponse{Data: events, Err: nil}, nil
+	}
+}
@@ -0,0 +1,217 @@
+package indexer
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"website/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ******** Indexed events **********
+
+type EventType string
+
+const (
+	EventTransfer             EventType = "transfer"
+	EventApproval             EventType = "approval"
+	EventDelegateChanged      EventType = "delegate_changed"
+	EventDelegateVotesChanged EventType = "delegate_votes_changed"
+)
+
+// topics of the indexed events, MinterChanged is not indexed
+var eventTopics = []common.Hash{
+	chain.EventTopic("Transfer"),
+	chain.EventTopic("Approval"),
+	chain.EventTopic("DelegateChanged"),
+	chain.EventTopic("DelegateVotesChanged"),
+}
+
+// Art log flattened for storage, only the members of its type are set
+// addresses are lowercase hex, amounts decimal strings in the token's smallest unit
+type Event struct {
+	Type      EventType `json:"type"`
+	Block     uint64    `json:"block"`
+	BlockHash string    `json:"block_hash"`
+	TxHash    string    `json:"tx_hash"`
+	LogIndex  uint      `json:"log_index"`
+
+	// transfer
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Amount string `json:"amount,omitempty"`
+
+	// approval, amount is the new allowance
+	Owner   string `json:"owner,omitempty"`
+	Spender string `json:"spender,omitempty"`
+
+	// delegate changed
+	Delegator    string `json:"delegator,omitempty"`
+	FromDelegate string `json:"from_delegate,omitempty"`
+	ToDelegate   string `json:"to_delegate,omitempty"`
+
+	// delegate votes changed
+	Delegate        string `json:"delegate,omitempty"`
+	PreviousBalance string `json:"previous_balance,omitempty"`
+	NewBalance      string `json:"new_balance,omitempty"`
+}
+
+// involves reports whether address takes part in the event
+func (e Event) involves(address string) bool {
+	for _, a := range []string{e.From, e.To, e.Owner, e.Spender, e.Delegator, e.FromDelegate, e.ToDelegate, e.Delegate} {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func hexAddress(a common.Address) string {
+	return strings.ToLower(a.Hex())
+}
+
+// converts a log of the Art contract, ok is false for events that are not indexed
+func newEvent(art *chain.Art, l types.Log) (e Event, ok bool, err error) {
+
+	parsed, err := art.ParseLog(l)
+	if err != nil {
+		return Event{}, false, err
+	}
+
+	e = Event{
+		Block:     l.BlockNumber,
+		BlockHash: l.BlockHash.Hex(),
+		TxHash:    l.TxHash.Hex(),
+		LogIndex:  l.Index,
+	}
+	switch p := parsed.(type) {
+	case *chain.ArtTransfer:
+		e.Type, e.From, e.To, e.Amount = EventTransfer, hexAddress(p.From), hexAddress(p.To), p.Amount.String()
+	case *chain.ArtApproval:
+		e.Type, e.Owner, e.Spender, e.Amount = EventApproval, hexAddress(p.Owner), hexAddress(p.Spender), p.Amount.String()
+	case *chain.ArtDelegateChanged:
+		e.Type, e.Delegator, e.FromDelegate, e.ToDelegate = EventDelegateChanged, hexAddress(p.Delegator), hexAddress(p.FromDelegate), hexAddress(p.ToDelegate)
+	case *chain.ArtDelegateVotesChanged:
+		e.Type, e.Delegate, e.PreviousBalance, e.NewBalance = EventDelegateVotesChanged, hexAddress(p.Delegate), p.PreviousBalance.String(), p.NewBalance.String()
+	default:
+		return Event{}, false, nil
+	}
+	return e, true, nil
+}
+
+// ******** Token state projected from events **********
+
+var zeroAddress = hexAddress(common.Address{})
+
+// balances, delegates, votes and allowances after the applied events
+// balances are only complete when indexing starts at or before the contract's deployment
+type projection struct {
+	balances   map[string]*big.Int
+	delegates  map[string]string
+	votes      map[string]*big.Int
+	allowances map[string]map[string]*big.Int
+}
+
+func newProjection() *projection {
+	return &projection{
+		balances:   make(map[string]*big.Int),
+		delegates:  make(map[string]string),
+		votes:      make(map[string]*big.Int),
+		allowances: make(map[string]map[string]*big.Int),
+	}
+}
+
+func amount(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
+func (p *projection) balance(address string) *big.Int {
+	if b, ok := p.balances[address]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+func (p *projection) apply(e Event) {
+
+	switch e.Type {
+
+	case EventTransfer:
+		value := amount(e.Amount)
+		if e.From != zeroAddress {
+			p.setBalance(e.From, new(big.Int).Sub(p.balance(e.From), value))
+		}
+		if e.To != zeroAddress {
+			p.setBalance(e.To, new(big.Int).Add(p.balance(e.To), value))
+		}
+
+	case EventApproval:
+		if p.allowances[e.Owner] == nil {
+			p.allowances[e.Owner] = make(map[string]*big.Int)
+		}
+		if value := amount(e.Amount); value.Sign() > 0 {
+			p.allowances[e.Owner][e.Spender] = value
+		} else {
+			delete(p.allowances[e.Owner], e.Spender)
+		}
+
+	case EventDelegateChanged:
+		if e.ToDelegate == zeroAddress {
+			delete(p.delegates, e.Delegator)
+		} else {
+			p.delegates[e.Delegator] = e.ToDelegate
+		}
+
+	case EventDelegateVotesChanged:
+		if value := amount(e.NewBalance); value.Sign() > 0 {
+			p.votes[e.Delegate] = value
+		} else {
+			delete(p.votes, e.Delegate)
+		}
+	}
+}
+
+// empty balances are removed so holders only lists accounts holding tokens
+func (p *projection) setBalance(address string, value *big.Int) {
+	if value.Sign() == 0 {
+		delete(p.balances, address)
+		return
+	}
+	p.balances[address] = value
+}
+
+// holders ordered by balance, largest first, ties by address
+func (p *projection) holders() []Holder {
+
+	holders := make([]Holder, 0, len(p.balances))
+	for address, balance := range p.balances {
+		holders = append(holders, p.holder(address, balance))
+	}
+	sort.Slice(holders, func(i, k int) bool {
+		bi, bk := p.balances[holders[i].Address], p.balances[holders[k].Address]
+		if c := bi.Cmp(bk); c != 0 {
+			return c > 0
+		}
+		return holders[i].Address < holders[k].Address
+	})
+	return holders
+}
+
+func (p *projection) holder(address string, balance *big.Int) Holder {
+	delegate := p.delegates[address]
+	if delegate == "" {
+		delegate = zeroAddress
+	}
+	votes := p.votes[address]
+	if votes == nil {
+		votes = new(big.Int)
+	}
+	return Holder{Address: address, Balance: balance.String(), Delegate: delegate, Votes: votes.String()}
+}
@@ -0,0 +1,456 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"website/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidLimit = errors.New("Invalid limit")
+
+var ErrInvalidRange = errors.New("Invalid block range")
+
+var ErrReorgTooDeep = errors.New("Reorg deeper than the confirmation depth")
+
+var ErrReorgDuringSync = errors.New("Chain reorganised while reading a batch")
+
+// Config of the indexer
+// blocks deeper than confirmations below the head are final, reorgs above are rolled back
+type Config struct {
+	StartBlock    uint64
+	Confirmations uint64
+	BatchSize     uint64
+}
+
+// indexing progress, indexed is the last indexed block, confirmed the last final block of the chain
+// head is unknown and synced false until the first sync after a start
+type Status struct {
+	StartBlock uint64 `json:"start_block"`
+	Indexed    uint64 `json:"indexed"`
+	Head       uint64 `json:"head"`
+	Confirmed  uint64 `json:"confirmed"`
+	Events     int    `json:"events"`
+	Holders    int    `json:"holders"`
+	Reorgs     int    `json:"reorgs"`
+	Synced     bool   `json:"synced"`
+}
+
+// token holder with delegation and the votes delegated to it
+type Holder struct {
+	Address  string `json:"address"`
+	Balance  string `json:"balance"`
+	Delegate string `json:"delegate"`
+	Votes    string `json:"votes"`
+}
+
+// indexed state of one address
+type Account struct {
+	Holder
+	Allowances map[string]string `json:"allowances"`
+	Events     int               `json:"events"`
+}
+
+// indexer service interface
+type Service interface {
+	Sync(ctx context.Context) (Status, error)
+	ReadStatus(ctx context.Context) (Status, error)
+	ListHolders(ctx context.Context, limit int) ([]Holder, error)
+	ReadAccount(ctx context.Context, address string) (Account, error)
+	ReadHistory(ctx context.Context, address string) ([]Event, error)
+	ListEvents(ctx context.Context, from uint64, to uint64) ([]Event, error)
+}
+
+type service struct {
+	indexStore IndexStore
+	chain      chain.Client
+	config     Config
+
+	// sync serializes Sync, mtx guards the projection and counters
+	sync       sync.Mutex
+	mtx        sync.RWMutex
+	projection *projection
+	events     int
+	next       uint64
+	head       uint64
+	reorgs     int
+
+	logger log.Logger
+}
+
+// cursor of the store, a new index starts at the configured block
+func (s *service) cursor() (Cursor, error) {
+	c, err := s.indexStore.ReadCursor()
+	if err == ErrNoCursor {
+		return Cursor{Next: s.config.StartBlock}, nil
+	}
+	return c, err
+}
+
+// rebuilds the projection from all stored events
+func (s *service) rebuild() error {
+
+	events, err := s.indexStore.ReadEvents()
+	if err != nil {
+		return err
+	}
+	c, err := s.cursor()
+	if err != nil {
+		return err
+	}
+
+	p := newProjection()
+	for _, e := range events {
+		p.apply(e)
+	}
+
+	s.mtx.Lock()
+	s.projection, s.events = p, len(events)
+	s.next = c.Next
+	s.mtx.Unlock()
+
+	return nil
+}
+
+// finds the newest recent block still on the chain and rolls back everything above it
+// when no recent block matches, the whole unconfirmed window is dropped and ErrReorgTooDeep returned
+func (s *service) checkReorg(ctx context.Context, c Cursor) (Cursor, error) {
+
+	// log level
+	logger := log.With(s.logger, "method", "checkReorg")
+
+	keep := len(c.Recent)
+	for keep > 0 {
+		ref := c.Recent[keep-1]
+		header, err := s.chain.Backend().HeaderByNumber(ctx, new(big.Int).SetUint64(ref.Number))
+		if err == nil && header.Hash().Hex() == ref.Hash {
+			break
+		}
+		if err != nil && !isNotFound(err) {
+			return c, err
+		}
+		keep--
+	}
+	if keep == len(c.Recent) {
+		return c, nil
+	}
+
+	var tooDeep error
+	rolled := Cursor{Recent: c.Recent[:keep:keep]}
+	if keep > 0 {
+		rolled.Next = c.Recent[keep-1].Number + 1
+	} else {
+		// the oldest unconfirmed block changed as well, blocks below it are taken as final
+		rolled.Next = c.Recent[0].Number
+		tooDeep = ErrReorgTooDeep
+	}
+	if err := s.indexStore.Rollback(rolled); err != nil {
+		level.Error(logger).Log("s.indexStore.Rollback:", err)
+		return c, err
+	}
+	level.Info(logger).Log("msg", "reorg rolled back", "from", c.Next-1, "to", rolled.Next-1)
+
+	s.mtx.Lock()
+	s.reorgs++
+	s.mtx.Unlock()
+	if err := s.rebuild(); err != nil {
+		return rolled, err
+	}
+	return rolled, tooDeep
+}
+
+// the go-ethereum clients report unknown blocks as "not found"
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "not found")
+}
+
+// Sync indexes the next batch of blocks, reorgs are rolled back first
+// returns the status after the batch, Synced is set once the head is reached
+func (s *service) Sync(ctx context.Context) (Status, error) {
+
+	// log level
+	logger := log.With(s.logger, "method", "Sync")
+
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	c, err := s.cursor()
+	if err != nil {
+		level.Error(logger).Log("s.cursor:", err)
+		return Status{}, err
+	}
+	head, err := s.chain.BlockNumber(ctx)
+	if err != nil {
+		level.Error(logger).Log("s.chain.BlockNumber:", err)
+		return Status{}, err
+	}
+	s.mtx.Lock()
+	s.head = head
+	s.mtx.Unlock()
+
+	c, err = s.checkReorg(ctx, c)
+	if err == ErrReorgTooDeep {
+		level.Error(logger).Log("s.checkReorg:", err)
+	} else if err != nil {
+		return Status{}, err
+	}
+
+	if c.Next > head {
+		return s.ReadStatus(ctx)
+	}
+	to := head
+	if s.config.BatchSize > 0 && c.Next+s.config.BatchSize-1 < head {
+		to = c.Next + s.config.BatchSize - 1
+	}
+
+	logs, err := s.chain.Art().FilterLogs(ctx, c.Next, to, eventTopics)
+	if err != nil {
+		level.Error(logger).Log("FilterLogs:", err)
+		return Status{}, err
+	}
+
+	// hashes of blocks that are not yet confirmed
+	hashes := make(map[uint64]string)
+	recent := c.Recent
+	from := c.Next
+	if head >= s.config.Confirmations && head-s.config.Confirmations+1 > from {
+		from = head - s.config.Confirmations + 1
+	}
+	for n := from; n <= to && s.config.Confirmations > 0; n++ {
+		header, err := s.chain.Backend().HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			if isNotFound(err) {
+				return Status{}, ErrReorgDuringSync
+			}
+			level.Error(logger).Log("HeaderByNumber:", err)
+			return Status{}, err
+		}
+		hashes[n] = header.Hash().Hex()
+		recent = append(recent, BlockRef{Number: n, Hash: hashes[n]})
+	}
+	for len(recent) > 0 && recent[0].Number+s.config.Confirmations <= head {
+		recent = recent[1:]
+	}
+
+	events := make([]Event, 0, len(logs))
+	for _, l := range logs {
+		if hash, ok := hashes[l.BlockNumber]; ok && hash != l.BlockHash.Hex() {
+			return Status{}, ErrReorgDuringSync
+		}
+		e, ok, err := newEvent(s.chain.Art(), l)
+		if err != nil {
+			level.Error(logger).Log("newEvent:", err)
+			return Status{}, err
+		}
+		if ok {
+			events = append(events, e)
+		}
+	}
+
+	if err := s.indexStore.Append(Cursor{Next: to + 1, Recent: append([]BlockRef{}, recent...)}, events); err != nil {
+		level.Error(logger).Log("s.indexStore.Append:", err)
+		return Status{}, err
+	}
+
+	s.mtx.Lock()
+	for _, e := range events {
+		s.projection.apply(e)
+	}
+	s.events += len(events)
+	s.next = to + 1
+	s.mtx.Unlock()
+
+	return s.ReadStatus(ctx)
+}
+
+// ReadStatus reports progress as of the last sync
+func (s *service) ReadStatus(ctx context.Context) (Status, error) {
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	st := Status{
+		StartBlock: s.config.StartBlock,
+		Head:       s.head,
+		Events:     s.events,
+		Holders:    len(s.projection.balances),
+		Reorgs:     s.reorgs,
+		Synced:     s.head > 0 && s.next > s.head,
+	}
+	if s.next > 0 {
+		st.Indexed = s.next - 1
+	}
+	if s.head >= s.config.Confirmations {
+		st.Confirmed = s.head - s.config.Confirmations
+	}
+	return st, nil
+}
+
+// ListHolders returns the largest holders, limit 0 lists all
+func (s *service) ListHolders(ctx context.Context, limit int) ([]Holder, error) {
+
+	if limit < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	holders := s.projection.holders()
+	if limit > 0 && limit < len(holders) {
+		holders = holders[:limit]
+	}
+	return holders, nil
+}
+
+// parses an address into the lowercase form events are stored with
+func parseAddress(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", ErrInvalidAddress
+	}
+	return hexAddress(common.HexToAddress(address)), nil
+}
+
+// ReadAccount returns the indexed state of an address, unknown addresses have zero balances
+func (s *service) ReadAccount(ctx context.Context, address string) (Account, error) {
+
+	address, err := parseAddress(address)
+	if err != nil {
+		return Account{}, err
+	}
+
+	history, err := s.ReadHistory(ctx, address)
+	if err != nil {
+		return Account{}, err
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	a := Account{
+		Holder:     s.projection.holder(address, s.projection.balance(address)),
+		Allowances: make(map[string]string),
+		Events:     len(history),
+	}
+	for spender, value := range s.projection.allowances[address] {
+		a.Allowances[spender] = value.String()
+	}
+	return a, nil
+}
+
+// ReadHistory returns all events an address takes part in, oldest first
+func (s *service) ReadHistory(ctx context.Context, address string) ([]Event, error) {
+
+	address, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.indexStore.ReadEvents()
+	if err != nil {
+		return nil, err
+	}
+	history := []Event{}
+	for _, e := range events {
+		if e.involves(address) {
+			history = append(history, e)
+		}
+	}
+	return history, nil
+}
+
+// ListEvents returns the indexed events of an inclusive block range
+func (s *service) ListEvents(ctx context.Context, from uint64, to uint64) ([]Event, error) {
+
+	if to < from {
+		return nil, ErrInvalidRange
+	}
+
+	events, err := s.indexStore.ReadEvents()
+	if err != nil {
+		return nil, err
+	}
+	selected := []Event{}
+	for _, e := range events {
+		if e.Block >= from && e.Block <= to {
+			selected = append(selected, e)
+		}
+	}
+	return selected, nil
+}
+
+// Scheduler follows the chain, it syncs batches back to back until the head is reached
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and syncs every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+
+		st, err := sc.service.Sync(ctx)
+		if err != nil {
+			level.Error(logger).Log("sc.service.Sync:", err)
+		}
+
+		// a batch left blocks behind, continue right away
+		if err == nil && !st.Synced {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// init indexer service and rebuild the token state from stored events
+// this function should is called in main.go
+func NewService(indexStore IndexStore, client chain.Client, config Config, logger log.Logger) (Service, error) {
+
+	s := &service{
+		indexStore: indexStore,
+		chain:      client,
+		config:     config,
+		projection: newProjection(),
+		logger:     logger,
+	}
+	if err := s.rebuild(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
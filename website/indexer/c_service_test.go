@@ -0,0 +1,180 @@
+package indexer_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/indexer"
+)
+
+func send(t *testing.T, sim *chain.SimulatedBackend, err error) {
+	if err != nil {
+		t.Fatalf("transaction failed, error: %v.", err)
+	}
+	sim.Commit()
+}
+
+func balanceOf(t *testing.T, s indexer.Service, a chaintest.Account) string {
+	account, err := s.ReadAccount(context.Background(), a.Hex())
+	if err != nil {
+		t.Fatalf("ReadAccount failed, error: %v.", err)
+	}
+	return account.Balance
+}
+
+func TestIndexHolders(t *testing.T) {
+
+	holder, alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	art := client.Art()
+	ctx := context.Background()
+
+	_, err := art.Transfer(holder.Opts, alice.Addr, big.NewInt(500))
+	send(t, sim, err)
+	_, err = art.Delegate(alice.Opts, bob.Addr)
+	send(t, sim, err)
+	_, err = art.Approve(alice.Opts, bob.Addr, big.NewInt(200))
+	send(t, sim, err)
+	_, err = art.TransferFrom(bob.Opts, alice.Addr, bob.Addr, big.NewInt(150))
+	send(t, sim, err)
+
+	dir := t.TempDir()
+	s, _ := chaintest.NewIndex(t, dir, client, indexer.Config{Confirmations: 3, BatchSize: 2})
+	st := chaintest.Sync(t, s)
+	if st.Events != 8 || st.Holders != 3 {
+		t.Errorf("status %+v, expected 8 events of 3 holders.", st)
+	}
+
+	holders, _ := s.ListHolders(ctx, 0)
+	if len(holders) != 3 || holders[0].Address != holder.Hex() || holders[1].Balance != "350" || holders[2].Balance != "150" {
+		t.Errorf("holders %+v, not ordered by balance.", holders)
+	}
+
+	a, _ := s.ReadAccount(ctx, alice.Addr.Hex())
+	if a.Delegate != bob.Hex() || a.Allowances[bob.Hex()] != "50" || a.Events != 5 {
+		t.Errorf("account %+v, expected delegation to bob with 50 allowed.", a)
+	}
+	b, _ := s.ReadAccount(ctx, bob.Hex())
+	if b.Votes != "350" {
+		t.Errorf("bob has %s votes, expected 350.", b.Votes)
+	}
+	if _, err := s.ReadAccount(ctx, "bob"); err != indexer.ErrInvalidAddress {
+		t.Errorf("ReadAccount returned %v, expected %v.", err, indexer.ErrInvalidAddress)
+	}
+
+	history, _ := s.ReadHistory(ctx, bob.Hex())
+	if len(history) != 6 || history[0].Type != indexer.EventDelegateChanged {
+		t.Errorf("history %+v, expected 6 events starting with the delegation.", history)
+	}
+
+	// a restarted service rebuilds the same state from the store
+	restarted, _ := chaintest.NewIndex(t, dir, client, indexer.Config{Confirmations: 3, BatchSize: 2})
+	if got := balanceOf(t, restarted, alice); got != "350" {
+		t.Errorf("restarted balance %s, expected 350.", got)
+	}
+	if st, _ := restarted.ReadStatus(ctx); st.Events != 8 || st.Indexed != 5 || st.Synced {
+		t.Errorf("restarted status %+v.", st)
+	}
+}
+
+func TestIndexReorg(t *testing.T) {
+
+	holder, alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	art := client.Art()
+	ctx := context.Background()
+
+	_, err := art.Transfer(holder.Opts, alice.Addr, big.NewInt(100))
+	send(t, sim, err)
+	base, _ := client.BlockNumber(ctx)
+
+	// indexed on the branch that will be dropped
+	_, err = art.Transfer(holder.Opts, alice.Addr, big.NewInt(900))
+	send(t, sim, err)
+	sim.Commit()
+
+	s, _ := chaintest.NewIndex(t, t.TempDir(), client, indexer.Config{Confirmations: 5})
+	chaintest.Sync(t, s)
+	if got := balanceOf(t, s, alice); got != "1000" {
+		t.Fatalf("balance %s before reorg, expected 1000.", got)
+	}
+
+	// the competing branch sends to bob instead and is longer
+	if err := sim.Fork(base); err != nil {
+		t.Fatalf("Fork failed, error: %v.", err)
+	}
+	_, err = art.Transfer(holder.Opts, bob.Addr, big.NewInt(7))
+	send(t, sim, err)
+	sim.Commit()
+	sim.Commit()
+
+	st := chaintest.Sync(t, s)
+	if st.Reorgs != 1 {
+		t.Errorf("status %+v, expected one reorg.", st)
+	}
+	if got := balanceOf(t, s, alice); got != "100" {
+		t.Errorf("alice balance %s after reorg, expected 100.", got)
+	}
+	if got := balanceOf(t, s, bob); got != "7" {
+		t.Errorf("bob balance %s after reorg, expected 7.", got)
+	}
+	history, _ := s.ReadHistory(ctx, alice.Hex())
+	if len(history) != 1 {
+		t.Errorf("alice history %+v, the dropped transfer is still indexed.", history)
+	}
+
+	// agrees with the contract
+	for _, a := range []chaintest.Account{holder, alice, bob} {
+		onchain, _ := client.BalanceOf(ctx, a.Hex())
+		if got := balanceOf(t, s, a); got != onchain.String() {
+			t.Errorf("indexed balance %s, contract reports %v.", got, onchain)
+		}
+	}
+}
+
+// a reorg below the unconfirmed window drops the whole window, older blocks are taken as final
+func TestIndexReorgTooDeep(t *testing.T) {
+
+	holder, alice, bob := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	art := client.Art()
+	ctx := context.Background()
+
+	base, _ := client.BlockNumber(ctx)
+	_, err := art.Transfer(holder.Opts, alice.Addr, big.NewInt(100))
+	send(t, sim, err)
+	_, err = art.Transfer(holder.Opts, alice.Addr, big.NewInt(900))
+	send(t, sim, err)
+	_, err = art.Transfer(holder.Opts, alice.Addr, big.NewInt(50))
+	send(t, sim, err)
+
+	s, _ := chaintest.NewIndex(t, t.TempDir(), client, indexer.Config{Confirmations: 2})
+	chaintest.Sync(t, s)
+	if got := balanceOf(t, s, alice); got != "1050" {
+		t.Fatalf("balance %s before reorg, expected 1050.", got)
+	}
+
+	// the fork replaces the confirmed transfer of 100 as well
+	if err := sim.Fork(base); err != nil {
+		t.Fatalf("Fork failed, error: %v.", err)
+	}
+	_, err = art.Transfer(holder.Opts, bob.Addr, big.NewInt(7))
+	send(t, sim, err)
+	for i := 0; i < 3; i++ {
+		sim.Commit()
+	}
+
+	st := chaintest.Sync(t, s)
+	if st.Reorgs != 1 || st.Indexed != base+4 {
+		t.Errorf("status %+v, expected one reorg and block %d indexed.", st, base+4)
+	}
+	if got := balanceOf(t, s, alice); got != "100" {
+		t.Errorf("alice balance %s after reorg, expected the confirmed 100 to stay.", got)
+	}
+	if got := balanceOf(t, s, bob); got != "0" {
+		t.Errorf("bob balance %s after reorg, expected the transfer below the window to be missed.", got)
+	}
+}
@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******* Index store interface *********
+
+var ErrNoCursor = errors.New("Nothing indexed yet")
+
+type IndexStoreConfig struct {
+	IndexPath string
+}
+
+// indexed block, hashes of recent blocks detect reorgs
+type BlockRef struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// progress of the indexer, next is the first block not yet indexed
+// recent holds the hashes of indexed blocks that are not yet confirmed, oldest first
+type Cursor struct {
+	Next   uint64     `json:"next"`
+	Recent []BlockRef `json:"recent"`
+}
+
+// events are appended together with the cursor and removed from the tip on rollback
+type IndexStore interface {
+	ReadCursor() (Cursor, error)
+	ReadEvents() ([]Event, error)
+	Append(cursor Cursor, events []Event) error
+	Rollback(cursor Cursor) error
+}
+
+// persisted index file
+type indexFile struct {
+	Cursor *Cursor `json:"cursor"`
+	Events []Event `json:"events"`
+}
+
+type indexStore struct {
+	mu     sync.Mutex
+	index  indexFile
+	config IndexStoreConfig
+	logger log.Logger
+}
+
+func (is *indexStore) ReadCursor() (Cursor, error) {
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if is.index.Cursor == nil {
+		return Cursor{}, ErrNoCursor
+	}
+	c := *is.index.Cursor
+	c.Recent = append([]BlockRef(nil), c.Recent...)
+
+	return c, nil
+}
+
+// all events in chain order
+func (is *indexStore) ReadEvents() ([]Event, error) {
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	events := make([]Event, len(is.index.Events))
+	copy(events, is.index.Events)
+
+	return events, nil
+}
+
+// appends the events of the blocks up to cursor.Next in one write
+func (is *indexStore) Append(cursor Cursor, events []Event) error {
+
+	// log level
+	logger := log.With(is.logger, "method", "Append")
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	previous := is.index.Cursor
+	n := len(is.index.Events)
+	is.index.Cursor = &cursor
+	is.index.Events = append(is.index.Events, events...)
+
+	if err := is.write(); err != nil {
+		is.index.Cursor = previous
+		is.index.Events = is.index.Events[:n]
+		level.Error(logger).Log("is.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// drops all events at or above cursor.Next
+func (is *indexStore) Rollback(cursor Cursor) error {
+
+	// log level
+	logger := log.With(is.logger, "method", "Rollback")
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	previous, events := is.index.Cursor, is.index.Events
+	n := len(events)
+	for n > 0 && events[n-1].Block >= cursor.Next {
+		n--
+	}
+	is.index.Cursor = &cursor
+	is.index.Events = events[:n:n]
+
+	if err := is.write(); err != nil {
+		is.index.Cursor, is.index.Events = previous, events
+		level.Error(logger).Log("is.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// writes the index, temporary file first so that a crash never leaves a partial index
+func (is *indexStore) write() error {
+
+	if err := os.MkdirAll(is.config.IndexPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(is.index)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(is.config.IndexPath, "index.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads the persisted index, a missing file starts an empty index
+func NewIndexStore(config IndexStoreConfig, logger log.Logger) (IndexStore, error) {
+
+	is := &indexStore{
+		index:  indexFile{Events: []Event{}},
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.IndexPath, "index.json"))
+	if os.IsNotExist(err) {
+		return is, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &is.index); err != nil {
+		return nil, err
+	}
+
+	return is, nil
+}
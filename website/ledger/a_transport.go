@@ -0,0 +1,189 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"website/auction"
+	"website/collection"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidJob),
+		errors.Is(err, ErrInvalidAccount),
+		errors.Is(err, ErrInvalidPayment),
+		errors.Is(err, ErrInvalidAmount):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrNotOperator),
+		errors.Is(err, ErrWalletNotLinked):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrJobNotFound),
+		errors.Is(err, auction.ErrAuctionNotFound),
+		errors.Is(err, collection.ErrCollectionNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrReferenceConflict),
+		errors.Is(err, ErrJobDone):
+		return http.StatusConflict
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decode settlement job
+func decodeSettle(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req SettleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// does not decode request
+func decodeListJobs(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListJobsRequest{}, nil
+}
+
+// decode payment
+func decodeRecordPayment(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req RecordPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Payment); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode account name from route
+func decodeReadAccount(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadAccountRequest{Account: mux.Vars(r)["account"]}, nil
+}
+
+// decode wallet address from route
+func decodeReadUser(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadUserRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// does not decode request
+func decodeReconcile(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReconcileRequest{}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching ledger handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	settleHandler := httptransport.NewServer(
+		e.Settle,
+		decodeSettle,
+		encodeResponse,
+		options...,
+	)
+
+	listJobsHandler := httptransport.NewServer(
+		e.ListJobs,
+		decodeListJobs,
+		encodeResponse,
+		options...,
+	)
+
+	recordPaymentHandler := httptransport.NewServer(
+		e.RecordPayment,
+		decodeRecordPayment,
+		encodeResponse,
+		options...,
+	)
+
+	readAccountHandler := httptransport.NewServer(
+		e.ReadAccount,
+		decodeReadAccount,
+		encodeResponse,
+		options...,
+	)
+
+	readUserHandler := httptransport.NewServer(
+		e.ReadUser,
+		decodeReadUser,
+		encodeResponse,
+		options...,
+	)
+
+	reconcileHandler := httptransport.NewServer(
+		e.Reconcile,
+		decodeReconcile,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/ledger/jobs", settleHandler).Methods("POST")
+	router.Handle("/ledger/jobs", listJobsHandler).Methods("GET")
+	router.Handle("/ledger/payments", recordPaymentHandler).Methods("POST")
+	router.Handle("/ledger/accounts/{account}", readAccountHandler).Methods("GET")
+	router.Handle("/ledger/users/{address}", readUserHandler).Methods("GET")
+	router.Handle("/ledger/reconciliation", reconcileHandler).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,195 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type SettleRequest struct {
+	Kind JobKind `json:"kind"`
+	Ref  string  `json:"ref"`
+}
+
+type ListJobsRequest struct{}
+
+type RecordPaymentRequest struct {
+	Payment Payment
+}
+
+type ReadAccountRequest struct {
+	Account string
+}
+
+type ReadUserRequest struct {
+	Address string
+}
+
+type ReconcileRequest struct{}
+
+type JobResponse struct {
+	Data Job   `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have JobResponse follow the customError interface defined in a_transport.go
+func (r JobResponse) error() error { return r.Err }
+
+type JobsResponse struct {
+	Data []Job `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have JobsResponse follow the customError interface defined in a_transport.go
+func (r JobsResponse) error() error { return r.Err }
+
+type TransactionResponse struct {
+	Data Transaction `json:"data"`
+	Err  error       `json:"errors"`
+}
+
+// have TransactionResponse follow the customError interface defined in a_transport.go
+func (r TransactionResponse) error() error { return r.Err }
+
+type StatementResponse struct {
+	Data Statement `json:"data"`
+	Err  error     `json:"errors"`
+}
+
+// have StatementResponse follow the customError interface defined in a_transport.go
+func (r StatementResponse) error() error { return r.Err }
+
+type ReportResponse struct {
+	Data Report `json:"data"`
+	Err  error  `json:"errors"`
+}
+
+// have ReportResponse follow the customError interface defined in a_transport.go
+func (r ReportResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	Settle        endpoint.Endpoint
+	ListJobs      endpoint.Endpoint
+	RecordPayment endpoint.Endpoint
+	ReadAccount   endpoint.Endpoint
+	ReadUser      endpoint.Endpoint
+	Reconcile     endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		Settle:        epSettle(s),
+		ListJobs:      epListJobs(s),
+		RecordPayment: epRecordPayment(s),
+		ReadAccount:   epReadAccount(s),
+		ReadUser:      epReadUser(s),
+		Reconcile:     epReconcile(s),
+	}
+}
+
+// settlement endpoint, enqueues the job and runs it right away
+func epSettle(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(SettleRequest)
+
+		// call service method
+		j, err := s.Settle(ctx, req.Kind, req.Ref)
+		if err != nil {
+			return JobResponse{Err: err}, err
+		}
+
+		return JobResponse{Data: j, Err: nil}, nil
+	}
+}
+
+// list jobs endpoint
+func epListJobs(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		jobs, err := s.ListJobs(ctx)
+		if err != nil {
+			return JobsResponse{Err: err}, err
+		}
+
+		return JobsResponse{Data: jobs, Err: nil}, nil
+	}
+}
+
+// record payment endpoint
+func epRecordPayment(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(RecordPaymentRequest)
+
+		// call service method
+		tx, err := s.RecordPayment(ctx, req.Payment)
+		if err != nil {
+			return TransactionResponse{Err: err}, err
+		}
+
+		return TransactionResponse{Data: tx, Err: nil}, nil
+	}
+}
+
+// account statement endpoint
+func epReadAccount(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadAccountRequest)
+
+		// call service method
+		st, err := s.ReadAccount(ctx, req.Account)
+		if err != nil {
+			return StatementResponse{Err: err}, err
+		}
+
+		return StatementResponse{Data: st, Err: nil}, nil
+	}
+}
+
+// wallet statement endpoint
+func epReadUser(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadUserRequest)
+
+		// call service method
+		st, err := s.ReadUser(ctx, req.Address)
+		if err != nil {
+			return StatementResponse{Err: err}, err
+		}
+
+		return StatementResponse{Data: st, Err: nil}, nil
+	}
+}
+
+// reconciliation report endpoint
+func epReconcile(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		report, err := s.Reconcile(ctx)
+		if err != nil {
+			return ReportResponse{Err: err}, err
+		}
+
+		return ReportResponse{Data: report, Err: nil}, nil
+	}
+}
@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Settlement jobs **********
+
+type JobKind string
+
+const (
+	JobAuction JobKind = "auction"
+	JobRaffle  JobKind = "raffle"
+	JobPayment JobKind = "payment"
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// settles one auction, raffle or payment
+// the job identifier is derived from what is settled, so enqueueing twice yields the same job
+// and its transactions are committed at most once
+type Job struct {
+	Id          string     `json:"id"`
+	Kind        JobKind    `json:"kind"`
+	Ref         string     `json:"ref"`
+	Status      JobStatus  `json:"status"`
+	Attempts    int        `json:"attempts"`
+	FeeBps      int64      `json:"fee_bps"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// job identifier of a settlement
+func jobId(kind JobKind, ref string) string {
+	return string(kind) + ":" + ref
+}
+
+// Scheduler periodically runs pending settlement jobs
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and runs pending jobs every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+
+		if err := sc.service.RunJobs(ctx); err != nil {
+			level.Error(logger).Log("sc.service.RunJobs:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
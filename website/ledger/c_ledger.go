@@ -0,0 +1,260 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"website/auction"
+	"website/collection"
+)
+
+// ******** Double-entry ledger **********
+
+var ErrInvalidAmount = errors.New("Invalid amount")
+
+// account names, amounts are in wei
+// a positive balance is a credit, the platform owes the holder
+// a negative balance is a debit, the holder owes the platform
+// the cash account's debit balance is the money the platform holds
+const (
+	AccountFees = "platform:fees"
+	AccountCash = "platform:cash"
+)
+
+// account of a bidder or entrant wallet
+func UserAccount(address string) string {
+	return "user:" + strings.ToLower(address)
+}
+
+// proceeds of the artist of a collection
+func ArtistAccount(collectionId string) string {
+	return "artist:" + collectionId
+}
+
+// amounts held for an auction until it is settled
+func AuctionEscrow(auctionId string) string {
+	return "escrow:auction:" + auctionId
+}
+
+// mint price deposits held for a raffle until it is drawn
+func RaffleEscrow(collectionId string) string {
+	return "escrow:raffle:" + collectionId
+}
+
+type Kind string
+
+const (
+	KindEscrow  Kind = "escrow"
+	KindPayment Kind = "payment"
+	KindRefund  Kind = "refund"
+	KindReceipt Kind = "receipt"
+	KindPayout  Kind = "payout"
+)
+
+// signed amount booked on an account
+type Posting struct {
+	Account string `json:"account"`
+	Amount  string `json:"amount"`
+}
+
+// balanced set of postings, the amounts of a transaction sum to zero
+type Transaction struct {
+	Id        string    `json:"id"`
+	JobId     string    `json:"job_id"`
+	Kind      Kind      `json:"kind"`
+	Memo      string    `json:"memo"`
+	Postings  []Posting `json:"postings"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// true if the postings sum to zero
+func (tx Transaction) Balanced() bool {
+	sum := new(big.Int)
+	for _, p := range tx.Postings {
+		amount, ok := new(big.Int).SetString(p.Amount, 10)
+		if !ok {
+			return false
+		}
+		sum.Add(sum, amount)
+	}
+	return sum.Sign() == 0
+}
+
+// builds the transactions of one job, transaction ids are derived from the job id
+// so that planning the same job twice yields the same transactions
+type planner struct {
+	jobId  string
+	feeBps int64
+	now    time.Time
+	txs    []Transaction
+}
+
+// moves an amount from one account to another, zero amounts are skipped
+func (p *planner) transfer(kind Kind, memo, from, to string, amount *big.Int) {
+	if amount.Sign() == 0 {
+		return
+	}
+	p.post(kind, memo, []Posting{
+		{Account: from, Amount: new(big.Int).Neg(amount).String()},
+		{Account: to, Amount: amount.String()},
+	})
+}
+
+// pays a price from an account, the platform fee goes to the fee account and the rest to the artist
+func (p *planner) pay(memo, from, artist string, price *big.Int) {
+	if price.Sign() == 0 {
+		return
+	}
+	fee := new(big.Int).Mul(price, big.NewInt(p.feeBps))
+	fee.Quo(fee, big.NewInt(10000))
+	proceeds := new(big.Int).Sub(price, fee)
+
+	postings := []Posting{{Account: from, Amount: new(big.Int).Neg(price).String()}}
+	if fee.Sign() > 0 {
+		postings = append(postings, Posting{Account: AccountFees, Amount: fee.String()})
+	}
+	if proceeds.Sign() > 0 {
+		postings = append(postings, Posting{Account: artist, Amount: proceeds.String()})
+	}
+	p.post(KindPayment, memo, postings)
+}
+
+func (p *planner) post(kind Kind, memo string, postings []Posting) {
+	p.txs = append(p.txs, Transaction{
+		Id:        fmt.Sprintf("%s/%d", p.jobId, len(p.txs)+1),
+		JobId:     p.jobId,
+		Kind:      kind,
+		Memo:      memo,
+		Postings:  postings,
+		CreatedAt: p.now,
+	})
+}
+
+// transactions settling an auction
+// every bid is escrowed, winners pay their price from escrow and receive the difference back,
+// all other bids are refunded in full, so the escrow account ends at zero
+// forfeited sealed bids were never revealed and hold no amount
+func planAuction(jobId string, a auction.Auction, feeBps int64, now time.Time) ([]Transaction, error) {
+
+	if a.Settlement == nil {
+		return nil, auction.ErrNotEnded
+	}
+
+	p := &planner{jobId: jobId, feeBps: feeBps, now: now}
+	escrow := AuctionEscrow(a.Id)
+	artist := ArtistAccount(a.CollectionId)
+
+	type held struct {
+		seq     int
+		address string
+		amount  *big.Int
+	}
+	var bids []held
+
+	if a.Mode == auction.ModeSealed {
+		for _, c := range a.Commits {
+			if c.RevealedAt == nil {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(c.Amount, 10)
+			if !ok {
+				return nil, ErrInvalidAmount
+			}
+			bids = append(bids, held{seq: c.Seq, address: c.Address, amount: amount})
+		}
+	} else {
+		for _, b := range a.Bids {
+			amount, ok := new(big.Int).SetString(b.Amount, 10)
+			if !ok {
+				return nil, ErrInvalidAmount
+			}
+			bids = append(bids, held{seq: b.Seq, address: b.Address, amount: amount})
+		}
+	}
+
+	for _, b := range bids {
+		p.transfer(KindEscrow, fmt.Sprintf("bid #%d", b.seq), UserAccount(b.address), escrow, b.amount)
+	}
+
+	winners := make(map[int]auction.Winner)
+	for _, w := range a.Settlement.Winners {
+		winners[w.Seq] = w
+	}
+
+	for _, b := range bids {
+		w, won := winners[b.seq]
+		if !won {
+			p.transfer(KindRefund, fmt.Sprintf("losing bid #%d", b.seq), escrow, UserAccount(b.address), b.amount)
+			continue
+		}
+
+		price, ok := new(big.Int).SetString(w.Price, 10)
+		if !ok {
+			return nil, ErrInvalidAmount
+		}
+		refund, ok := new(big.Int).SetString(w.Refund, 10)
+		if !ok {
+			return nil, ErrInvalidAmount
+		}
+		if new(big.Int).Add(price, refund).Cmp(b.amount) != 0 {
+			return nil, fmt.Errorf("%w: winning bid #%d does not cover price and refund", ErrInvalidAmount, b.seq)
+		}
+
+		p.pay(fmt.Sprintf("winning bid #%d, token %d", b.seq, a.TokenId), escrow, artist, price)
+		p.transfer(KindRefund, fmt.Sprintf("overpayment of bid #%d", b.seq), escrow, UserAccount(b.address), refund)
+	}
+
+	return p.txs, nil
+}
+
+// transactions settling a drawn raffle with a mint price
+// every entrant escrows the mint price once, a winner's deposit pays for its first token
+// and further tokens are owed directly, entrants without a token are refunded
+func planRaffle(jobId string, c collection.Collection, feeBps int64, now time.Time) ([]Transaction, error) {
+
+	if c.Raffle == nil || c.Raffle.Transcript == nil {
+		return nil, collection.ErrNotDrawn
+	}
+
+	price := new(big.Int)
+	if c.MintPrice != "" {
+		if _, ok := price.SetString(c.MintPrice, 10); !ok || price.Sign() < 0 {
+			return nil, ErrInvalidAmount
+		}
+	}
+
+	p := &planner{jobId: jobId, feeBps: feeBps, now: now}
+	escrow := RaffleEscrow(c.Id)
+	artist := ArtistAccount(c.Id)
+
+	for _, e := range c.Entries {
+		p.transfer(KindEscrow, "raffle entry", UserAccount(e.Address), escrow, price)
+	}
+
+	deposit := make(map[string]bool)
+	for _, e := range c.Entries {
+		deposit[strings.ToLower(e.Address)] = true
+	}
+
+	for _, w := range c.Raffle.Transcript.Winners {
+		address := strings.ToLower(w.Address)
+		memo := fmt.Sprintf("raffle token %d", w.TokenId)
+		if deposit[address] {
+			deposit[address] = false
+			p.pay(memo, escrow, artist, price)
+		} else {
+			p.pay(memo, UserAccount(address), artist, price)
+		}
+	}
+
+	for _, e := range c.Entries {
+		if deposit[strings.ToLower(e.Address)] {
+			p.transfer(KindRefund, "raffle entry without token", escrow, UserAccount(e.Address), price)
+		}
+	}
+
+	return p.txs, nil
+}
@@ -0,0 +1,424 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"website/auction"
+	"website/collection"
+	"website/selection"
+	"website/session"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	auctionId    = "0123456789abcdef0123456789abcdef"
+	collectionId = "fedcba9876543210fedcba9876543210"
+	walletA      = "0x000000000000000000000000000000000000000a"
+	walletB      = "0x000000000000000000000000000000000000000b"
+	walletC      = "0x000000000000000000000000000000000000000c"
+)
+
+// auctions, collections and wallet links held in memory, auctions without settlement have not ended
+type stubs struct {
+	auctions    map[string]auction.Auction
+	collections map[string]collection.Collection
+	owners      map[string]string
+}
+
+func (st *stubs) ReadAuction(ctx context.Context, id string) (auction.Auction, error) {
+	a, ok := st.auctions[id]
+	if !ok {
+		return auction.Auction{}, auction.ErrAuctionNotFound
+	}
+	return a, nil
+}
+
+func (st *stubs) ListAuctions(ctx context.Context, collectionId string) ([]auction.Auction, error) {
+	list := []auction.Auction{}
+	for _, a := range st.auctions {
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+func (st *stubs) Finalize(ctx context.Context, id string) (auction.Auction, error) {
+	a, err := st.ReadAuction(ctx, id)
+	if err == nil && a.Settlement == nil {
+		return auction.Auction{}, auction.ErrNotEnded
+	}
+	return a, err
+}
+
+func (st *stubs) ReadCollection(ctx context.Context, id string) (collection.Collection, error) {
+	c, ok := st.collections[id]
+	if !ok {
+		return collection.Collection{}, collection.ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+// all collections on one page
+func (st *stubs) ListCollections(ctx context.Context, q collection.Query) ([]collection.Collection, string, error) {
+	list := []collection.Collection{}
+	for _, c := range st.collections {
+		list = append(list, c)
+	}
+	return list, "", nil
+}
+
+func (st *stubs) IsLinked(ctx context.Context, userId string, address string) (bool, error) {
+	return st.owners[address] == userId, nil
+}
+
+// sessions of fixed users that do not expire, the session identifier is the file hash
+type staticSessions struct{}
+
+func (staticSessions) ReadSession(sessionId string) (session.Session, error) {
+	return session.Session{FileHash: sessionId, ExpiresAt: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+// context of a request with a session cookie
+func withSession(sessionId string) context.Context {
+	return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), sessionId)
+}
+
+// service with a temporary store and a 2.5% platform fee, the user operator is an operator
+func testService(t *testing.T) (*service, *stubs) {
+	store, err := NewLedgerStore(LedgerStoreConfig{LedgerPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewLedgerStore failed, error: %v.", err)
+	}
+	st := &stubs{auctions: make(map[string]auction.Auction), collections: make(map[string]collection.Collection), owners: make(map[string]string)}
+	s := NewService(store, st, st, staticSessions{}, st, []string{session.Session{FileHash: "operator"}.UserId()}, 250, log.NewNopLogger()).(*service)
+	return s, st
+}
+
+// balance of an account
+func balance(t *testing.T, s Service, account string) string {
+	st, err := s.ReadAccount(withSession("operator"), account)
+	if err != nil {
+		t.Fatalf("ReadAccount failed, error: %v.", err)
+	}
+	return st.Balance
+}
+
+func TestSettleEnglishAuction(t *testing.T) {
+
+	s, st := testService(t)
+	ctx := context.Background()
+
+	a := auction.Auction{
+		Id:           auctionId,
+		CollectionId: collectionId,
+		Mode:         auction.ModeEnglish,
+		Bids: []auction.Bid{
+			{Seq: 1, Address: walletA, Amount: "1000"},
+			{Seq: 2, Address: walletB, Amount: "2000"},
+			{Seq: 3, Address: walletA, Amount: "3000"},
+		},
+	}
+	st.auctions[auctionId] = a
+
+	// not ended, the job waits
+	j, err := s.Enqueue(ctx, JobAuction, auctionId)
+	if err != nil {
+		t.Fatalf("Enqueue failed, error: %v.", err)
+	}
+	if j, _ = s.RunJob(ctx, j.Id); j.Status != JobPending || j.Attempts != 1 {
+		t.Fatalf("job %+v, expected pending after one attempt.", j)
+	}
+
+	a.Settlement = &auction.Settlement{Winners: []auction.Winner{{Seq: 3, Address: walletA, Paid: "3000", Price: "3000", Refund: "0"}}}
+	st.auctions[auctionId] = a
+
+	if err := s.RunJobs(ctx); err != nil {
+		t.Fatalf("RunJobs failed, error: %v.", err)
+	}
+	if j, _ = s.RunJob(ctx, j.Id); j.Status != JobDone {
+		t.Fatalf("job %+v, expected done.", j)
+	}
+
+	// running and enqueueing again posts nothing
+	s.RunJobs(ctx)
+	s.Enqueue(ctx, JobAuction, auctionId)
+	s.RunJobs(ctx)
+
+	want := map[string]string{
+		UserAccount(walletA):        "-3000",
+		UserAccount(walletB):        "0",
+		AuctionEscrow(auctionId):    "0",
+		AccountFees:                 "75",
+		ArtistAccount(collectionId): "2925",
+	}
+	for account, amount := range want {
+		if got := balance(t, s, account); got != amount {
+			t.Errorf("%s balance %s, expected %s.", account, got, amount)
+		}
+	}
+
+	statement, _ := s.ReadAccount(withSession("operator"), UserAccount(walletA))
+	if statement.Owes != "3000" || statement.Owed != "0" || len(statement.Entries) != 3 {
+		t.Errorf("statement %+v, expected 3000 owed in 3 entries.", statement)
+	}
+
+	report, err := s.Reconcile(withSession("operator"))
+	if err != nil || !report.Ok || report.Transactions != 6 {
+		t.Errorf("report %+v, error: %v.", report, err)
+	}
+
+	// a settlement changed after posting is reported
+	a.Settlement.Winners[0] = auction.Winner{Seq: 2, Address: walletB, Paid: "2000", Price: "2000", Refund: "0"}
+	st.auctions[auctionId] = a
+	if report, _ := s.Reconcile(withSession("operator")); report.Ok || len(report.Mismatches) != 1 {
+		t.Errorf("report %+v, expected one mismatch.", report)
+	}
+}
+
+func TestSettleDutchAuction(t *testing.T) {
+
+	s, st := testService(t)
+	ctx := context.Background()
+
+	st.auctions[auctionId] = auction.Auction{
+		Id:           auctionId,
+		CollectionId: collectionId,
+		Mode:         auction.ModeDutch,
+		Bids: []auction.Bid{
+			{Seq: 1, Address: walletA, Amount: "500"},
+			{Seq: 2, Address: walletB, Amount: "400"},
+		},
+		Settlement: &auction.Settlement{
+			ClearingPrice: "400",
+			Winners: []auction.Winner{
+				{Seq: 1, Address: walletA, Paid: "500", Price: "400", Refund: "100"},
+				{Seq: 2, Address: walletB, Paid: "400", Price: "400", Refund: "0"},
+			},
+		},
+	}
+
+	j, _ := s.Enqueue(ctx, JobAuction, auctionId)
+	if j, _ = s.RunJob(ctx, j.Id); j.Status != JobDone {
+		t.Fatalf("job %+v, expected done.", j)
+	}
+
+	for account, amount := range map[string]string{
+		UserAccount(walletA):        "-400",
+		UserAccount(walletB):        "-400",
+		AccountFees:                 "20",
+		ArtistAccount(collectionId): "780",
+		AuctionEscrow(auctionId):    "0",
+	} {
+		if got := balance(t, s, account); got != amount {
+			t.Errorf("%s balance %s, expected %s.", account, got, amount)
+		}
+	}
+}
+
+func TestSettleRaffle(t *testing.T) {
+
+	s, st := testService(t)
+	ctx := context.Background()
+
+	c := collection.Collection{
+		Id:        collectionId,
+		MintPrice: "1000",
+		Entries:   []collection.Entry{{Address: walletA}, {Address: walletB}, {Address: walletC}},
+		Raffle:    &collection.Raffle{},
+	}
+	st.collections[collectionId] = c
+
+	j, _ := s.Enqueue(ctx, JobRaffle, collectionId)
+	if j, _ = s.RunJob(ctx, j.Id); j.Status != JobPending {
+		t.Fatalf("job %+v, expected pending until drawn.", j)
+	}
+
+	// A wins two tokens, B one, C none
+	c.Raffle.Transcript = &selection.Transcript{Winners: []selection.Winner{
+		{TokenId: 0, Address: walletA},
+		{TokenId: 1, Address: walletB},
+		{TokenId: 2, Address: walletA},
+	}}
+	st.collections[collectionId] = c
+	s.RunJobs(ctx)
+
+	for account, amount := range map[string]string{
+		UserAccount(walletA):        "-2000",
+		UserAccount(walletB):        "-1000",
+		UserAccount(walletC):        "0",
+		RaffleEscrow(collectionId):  "0",
+		AccountFees:                 "75",
+		ArtistAccount(collectionId): "2925",
+	} {
+		if got := balance(t, s, account); got != amount {
+			t.Errorf("%s balance %s, expected %s.", account, got, amount)
+		}
+	}
+
+	if _, err := s.Enqueue(ctx, JobPayment, collectionId); err != ErrInvalidJob {
+		t.Errorf("Enqueue returned %v, expected %v.", err, ErrInvalidJob)
+	}
+}
+
+// auctions that ended and raffles drawn while no event reached the ledger are queued by the scheduler
+func TestSweepMissedSettlements(t *testing.T) {
+
+	s, st := testService(t)
+	ctx := context.Background()
+
+	const liveId, failedId = "0000000000000000000000000000000c", "0000000000000000000000000000000d"
+	st.auctions[auctionId] = auction.Auction{
+		Id:           auctionId,
+		CollectionId: collectionId,
+		Mode:         auction.ModeEnglish,
+		Status:       auction.StatusEnded,
+		Bids:         []auction.Bid{{Seq: 1, Address: walletA, Amount: "1000"}},
+		Settlement:   &auction.Settlement{Winners: []auction.Winner{{Seq: 1, Address: walletA, Paid: "1000", Price: "1000", Refund: "0"}}},
+	}
+	st.auctions[liveId] = auction.Auction{Id: liveId, CollectionId: collectionId, Status: auction.StatusLive}
+	st.collections[collectionId] = collection.Collection{
+		Id:     collectionId,
+		Raffle: &collection.Raffle{Transcript: &selection.Transcript{Winners: []selection.Winner{{TokenId: 0, Address: walletB}}}},
+	}
+
+	// a failed job is not queued again behind the operator's back
+	failed := Job{Id: jobId(JobAuction, failedId), Kind: JobAuction, Ref: failedId, Status: JobFailed}
+	if err := s.ledgerStore.WriteJob(failed); err != nil {
+		t.Fatalf("WriteJob failed, error: %v.", err)
+	}
+	st.auctions[failedId] = auction.Auction{Id: failedId, CollectionId: collectionId, Status: auction.StatusEnded}
+
+	if err := s.RunJobs(ctx); err != nil {
+		t.Fatalf("RunJobs failed, error: %v.", err)
+	}
+	for _, c := range []struct {
+		id     string
+		status JobStatus
+	}{
+		{jobId(JobAuction, auctionId), JobDone},
+		{jobId(JobRaffle, collectionId), JobDone},
+		{jobId(JobAuction, failedId), JobFailed},
+	} {
+		if j, err := s.ledgerStore.ReadJob(c.id); err != nil || j.Status != c.status {
+			t.Errorf("job %s is %+v, error: %v, expected %s.", c.id, j, err, c.status)
+		}
+	}
+	if _, err := s.ledgerStore.ReadJob(jobId(JobAuction, liveId)); err != ErrJobNotFound {
+		t.Errorf("ReadJob of a live auction returned %v, expected %v.", err, ErrJobNotFound)
+	}
+	if got := balance(t, s, UserAccount(walletA)); got != "-1000" {
+		t.Errorf("%s balance %s, expected -1000.", UserAccount(walletA), got)
+	}
+}
+
+func TestRecordPayment(t *testing.T) {
+
+	s, _ := testService(t)
+	ctx := withSession("operator")
+
+	// only operators change the ledger
+	p := Payment{Account: UserAccount(walletA), Amount: "3000", Direction: "in", Reference: "0xabc"}
+	if _, err := s.RecordPayment(context.Background(), p); err != ErrUnauthorized {
+		t.Errorf("RecordPayment without session returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := s.RecordPayment(withSession("bidder"), p); err != ErrNotOperator {
+		t.Errorf("RecordPayment by a user returned %v, expected %v.", err, ErrNotOperator)
+	}
+	if _, err := s.Settle(withSession("bidder"), JobAuction, auctionId); err != ErrNotOperator {
+		t.Errorf("Settle by a user returned %v, expected %v.", err, ErrNotOperator)
+	}
+
+	tx, err := s.RecordPayment(ctx, p)
+	if err != nil {
+		t.Fatalf("RecordPayment failed, error: %v.", err)
+	}
+
+	// same reference and payment, recorded once
+	again, err := s.RecordPayment(ctx, p)
+	if err != nil || again.Id != tx.Id {
+		t.Errorf("repeated payment returned %+v, error: %v.", again, err)
+	}
+	if got := balance(t, s, UserAccount(walletA)); got != "3000" {
+		t.Errorf("balance %s, expected 3000.", got)
+	}
+	if got := balance(t, s, AccountCash); got != "-3000" {
+		t.Errorf("cash balance %s, expected -3000.", got)
+	}
+
+	p.Amount = "1"
+	if _, err := s.RecordPayment(ctx, p); err != ErrReferenceConflict {
+		t.Errorf("RecordPayment returned %v, expected %v.", err, ErrReferenceConflict)
+	}
+	if _, err := s.RecordPayment(ctx, Payment{Account: AccountFees, Amount: "1", Direction: "out", Reference: "x"}); err != ErrInvalidPayment {
+		t.Errorf("RecordPayment returned %v, expected %v.", err, ErrInvalidPayment)
+	}
+	if _, err := s.RecordPayment(ctx, Payment{Account: UserAccount(walletB), Amount: "-1", Direction: "out", Reference: "y"}); err != ErrInvalidAmount {
+		t.Errorf("RecordPayment returned %v, expected %v.", err, ErrInvalidAmount)
+	}
+
+	if report, _ := s.Reconcile(ctx); !report.Ok || report.Totals["cash"] != "-3000" {
+		t.Errorf("report %+v, expected balanced cash of -3000.", report)
+	}
+}
+
+// users read the statements of their own wallets, everything else is for operators
+func TestReadAuthorization(t *testing.T) {
+
+	s, st := testService(t)
+	st.owners[walletA] = session.Session{FileHash: "bidder"}.UserId()
+	p := Payment{Account: UserAccount(walletA), Amount: "3000", Direction: "in", Reference: "0xabc"}
+	if _, err := s.RecordPayment(withSession("operator"), p); err != nil {
+		t.Fatalf("RecordPayment failed, error: %v.", err)
+	}
+
+	bidder := withSession("bidder")
+	if st, err := s.ReadUser(bidder, "0x000000000000000000000000000000000000000A"); err != nil || st.Owed != "3000" {
+		t.Errorf("ReadUser of a linked wallet returned %+v, error: %v.", st, err)
+	}
+	if _, err := s.ReadUser(bidder, walletB); err != ErrWalletNotLinked {
+		t.Errorf("ReadUser of another wallet returned %v, expected %v.", err, ErrWalletNotLinked)
+	}
+	if _, err := s.ReadUser(context.Background(), walletA); err != ErrUnauthorized {
+		t.Errorf("ReadUser without session returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if st, err := s.ReadUser(withSession("operator"), walletB); err != nil || st.Balance != "0" {
+		t.Errorf("ReadUser by an operator returned %+v, error: %v.", st, err)
+	}
+
+	if _, err := s.ReadAccount(bidder, UserAccount(walletA)); err != ErrNotOperator {
+		t.Errorf("ReadAccount by a user returned %v, expected %v.", err, ErrNotOperator)
+	}
+	if _, err := s.ListJobs(bidder); err != ErrNotOperator {
+		t.Errorf("ListJobs by a user returned %v, expected %v.", err, ErrNotOperator)
+	}
+	if _, err := s.Reconcile(context.Background()); err != ErrUnauthorized {
+		t.Errorf("Reconcile without session returned %v, expected %v.", err, ErrUnauthorized)
+	}
+}
+
+// ledger survives a restart and completed jobs are not posted again
+func TestLedgerStorePersists(t *testing.T) {
+
+	dir := t.TempDir()
+	store, _ := NewLedgerStore(LedgerStoreConfig{LedgerPath: dir}, log.NewNopLogger())
+
+	now := time.Now()
+	j := Job{Id: "payment:x", Kind: JobPayment, Status: JobDone, CompletedAt: &now}
+	tx := Transaction{Id: "payment:x/1", JobId: j.Id, Postings: []Posting{{Account: AccountCash, Amount: "-1"}, {Account: UserAccount(walletA), Amount: "1"}}}
+	if err := store.Commit(j, []Transaction{tx}); err != nil {
+		t.Fatalf("Commit failed, error: %v.", err)
+	}
+
+	store, err := NewLedgerStore(LedgerStoreConfig{LedgerPath: dir}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewLedgerStore failed, error: %v.", err)
+	}
+	if err := store.Commit(j, []Transaction{tx}); err != ErrJobDone {
+		t.Errorf("Commit returned %v, expected %v.", err, ErrJobDone)
+	}
+	if txs, _ := store.ReadTransactions(); len(txs) != 1 || !txs[0].Balanced() {
+		t.Errorf("read transactions %+v, expected one balanced transaction.", txs)
+	}
+}
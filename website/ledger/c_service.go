@@ -0,0 +1,741 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"website/auction"
+	"website/collection"
+	"website/session"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidJob = errors.New("Invalid settlement job")
+
+var ErrInvalidAccount = errors.New("Invalid account")
+
+var ErrInvalidPayment = errors.New("Invalid payment")
+
+var ErrReferenceConflict = errors.New("Payment reference already used for another payment")
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrNotOperator = errors.New("Only operators can change or inspect the ledger")
+
+var ErrWalletNotLinked = errors.New("Wallet is not linked to the user")
+
+// auction and collection identifiers
+var refPattern = regexp.MustCompile("^[0-9a-f]{32}$")
+
+// payment references, e.g. transaction hashes or bank references
+var referencePattern = regexp.MustCompile("^[0-9A-Za-z_.:-]{1,100}$")
+
+var accountPattern = regexp.MustCompile("^(user:0x[0-9a-f]{40}|artist:[0-9a-f]{32}|escrow:(auction|raffle):[0-9a-f]{32}|platform:(fees|cash))$")
+
+// accounts that receive and send payments
+var payeePattern = regexp.MustCompile("^(user:0x[0-9a-f]{40}|artist:[0-9a-f]{32})$")
+
+// Auctions settles and reads auctions, implemented by the auction service
+type Auctions interface {
+	ReadAuction(ctx context.Context, id string) (auction.Auction, error)
+	ListAuctions(ctx context.Context, collectionId string) ([]auction.Auction, error)
+	Finalize(ctx context.Context, id string) (auction.Auction, error)
+}
+
+// Collections reads collections, implemented by the collection service
+type Collections interface {
+	ReadCollection(ctx context.Context, id string) (collection.Collection, error)
+	ListCollections(ctx context.Context, q collection.Query) ([]collection.Collection, string, error)
+}
+
+// Wallets checks whether a wallet address belongs to a user, implemented by the wallet service
+type Wallets interface {
+	IsLinked(ctx context.Context, userId string, address string) (bool, error)
+}
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// money received from or paid out to an account holder
+// direction "in" reduces what the holder owes, "out" reduces what the platform owes
+type Payment struct {
+	Account   string `json:"account"`
+	Amount    string `json:"amount"`
+	Direction string `json:"direction"`
+	Reference string `json:"reference"`
+}
+
+// line of an account statement
+type Entry struct {
+	TransactionId string    `json:"transaction_id"`
+	Kind          Kind      `json:"kind"`
+	Memo          string    `json:"memo"`
+	Amount        string    `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// balance and history of an account
+// owed is what the platform owes the holder, owes is what the holder owes the platform
+type Statement struct {
+	Account string  `json:"account"`
+	Balance string  `json:"balance"`
+	Owed    string  `json:"owed"`
+	Owes    string  `json:"owes"`
+	Entries []Entry `json:"entries"`
+}
+
+type AccountBalance struct {
+	Account string `json:"account"`
+	Balance string `json:"balance"`
+}
+
+type Mismatch struct {
+	JobId  string `json:"job_id"`
+	Reason string `json:"reason"`
+}
+
+// result of checking the ledger against itself and against the settled auctions and raffles
+type Report struct {
+	GeneratedAt  time.Time         `json:"generated_at"`
+	Transactions int               `json:"transactions"`
+	Unbalanced   []string          `json:"unbalanced"`
+	OpenEscrows  []AccountBalance  `json:"open_escrows"`
+	Mismatches   []Mismatch        `json:"mismatches"`
+	Pending      []Job             `json:"pending"`
+	Failed       []Job             `json:"failed"`
+	Totals       map[string]string `json:"totals"`
+	Ok           bool              `json:"ok"`
+}
+
+// service interface defining all required methods
+type Service interface {
+	Enqueue(ctx context.Context, kind JobKind, ref string) (Job, error)
+	Settle(ctx context.Context, kind JobKind, ref string) (Job, error)
+	RunJob(ctx context.Context, id string) (Job, error)
+	RunJobs(ctx context.Context) error
+	ListJobs(ctx context.Context) ([]Job, error)
+	RecordPayment(ctx context.Context, p Payment) (Transaction, error)
+	ReadAccount(ctx context.Context, account string) (Statement, error)
+	ReadUser(ctx context.Context, address string) (Statement, error)
+	Reconcile(ctx context.Context) (Report, error)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// jobs run one at a time
+	runMu sync.Mutex
+
+	ledgerStore LedgerStore
+	auctions    Auctions
+	collections Collections
+	sessions    Sessions
+	wallets     Wallets
+	operators   map[string]bool
+	feeBps      int64
+	now         func() time.Time
+	logger      log.Logger
+}
+
+// service struct enqueue method
+// creates the settlement job of an auction or raffle, enqueueing an existing job returns it unchanged
+// a failed job is queued again
+func (s *service) Enqueue(ctx context.Context, kind JobKind, ref string) (Job, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Enqueue")
+
+	if (kind != JobAuction && kind != JobRaffle) || !refPattern.MatchString(ref) {
+		return Job{}, ErrInvalidJob
+	}
+
+	id := jobId(kind, ref)
+	j, err := s.ledgerStore.ReadJob(id)
+	if err == nil && j.Status != JobFailed {
+		return j, nil
+	}
+	if err != nil && !errors.Is(err, ErrJobNotFound) {
+		level.Error(logger).Log("s.ledgerStore.ReadJob:", err)
+		return Job{}, err
+	}
+	if errors.Is(err, ErrJobNotFound) {
+		j = Job{Id: id, Kind: kind, Ref: ref, CreatedAt: s.now()}
+	}
+	j.Status = JobPending
+
+	if err := s.ledgerStore.WriteJob(j); err != nil {
+		level.Error(logger).Log("s.ledgerStore.WriteJob:", err)
+		return Job{}, err
+	}
+
+	return j, nil
+}
+
+// user identifier of the active session attached to the request context
+func (s *service) user(ctx context.Context) (string, error) {
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok || s.sessions == nil {
+		return "", ErrUnauthorized
+	}
+
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	return sess.UserId(), nil
+}
+
+// checks that the session user of the request is an operator
+func (s *service) operator(ctx context.Context) error {
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return err
+	}
+	if !s.operators[userId] {
+		return ErrNotOperator
+	}
+
+	return nil
+}
+
+// service struct settle method
+// enqueues and runs the settlement job of an auction or raffle right away, only for operators
+func (s *service) Settle(ctx context.Context, kind JobKind, ref string) (Job, error) {
+
+	if err := s.operator(ctx); err != nil {
+		return Job{}, err
+	}
+
+	j, err := s.Enqueue(ctx, kind, ref)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return s.RunJob(ctx, j.Id)
+}
+
+// service struct run job method
+// settles the auction or raffle of a pending job and posts its transactions
+// jobs whose auction has not ended or raffle is not drawn stay pending
+func (s *service) RunJob(ctx context.Context, id string) (Job, error) {
+
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	return s.run(ctx, id)
+}
+
+// service struct run jobs method
+// queues the jobs of ended auctions and drawn raffles that have none, then runs all pending jobs
+// called periodically by the scheduler
+func (s *service) RunJobs(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "RunJobs")
+
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if err := s.sweep(ctx); err != nil {
+		level.Error(logger).Log("s.sweep:", err)
+	}
+
+	jobs, err := s.ledgerStore.ReadJobs()
+	if err != nil {
+		level.Error(logger).Log("s.ledgerStore.ReadJobs:", err)
+		return err
+	}
+
+	for _, j := range jobs {
+		if j.Status != JobPending {
+			continue
+		}
+		if _, err := s.run(ctx, j.Id); err != nil {
+			level.Error(logger).Log("s.run:", err, "job", j.Id)
+		}
+	}
+
+	return nil
+}
+
+// queues settlement jobs of ended auctions and drawn raffles without a job
+// events only announce transitions while the process runs, what ended while it was down is found here
+// failed jobs are left for an operator to settle again
+func (s *service) sweep(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "sweep")
+
+	missing := func(kind JobKind, ref string) {
+		_, err := s.ledgerStore.ReadJob(jobId(kind, ref))
+		if !errors.Is(err, ErrJobNotFound) {
+			return
+		}
+		if _, err := s.Enqueue(ctx, kind, ref); err != nil {
+			level.Error(logger).Log("s.Enqueue:", err, "ref", ref)
+		}
+	}
+
+	auctions, err := s.auctions.ListAuctions(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, a := range auctions {
+		if a.Status == auction.StatusEnded {
+			missing(JobAuction, a.Id)
+		}
+	}
+
+	q := collection.Query{Sort: collection.SortNewest, Limit: 100}
+	for {
+		collections, next, err := s.collections.ListCollections(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, c := range collections {
+			if c.Raffle != nil && c.Raffle.Transcript != nil {
+				missing(JobRaffle, c.Id)
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		q.Cursor = next
+	}
+}
+
+// runs a job, caller holds s.runMu
+func (s *service) run(ctx context.Context, id string) (Job, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "run")
+
+	j, err := s.ledgerStore.ReadJob(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if j.Status != JobPending {
+		return j, nil
+	}
+
+	now := s.now()
+	txs, err := s.plan(ctx, j, now)
+	j.Attempts++
+
+	if err != nil {
+		j.Error = err.Error()
+		if !errors.Is(err, auction.ErrNotEnded) && !errors.Is(err, collection.ErrNotDrawn) {
+			j.Status = JobFailed
+		}
+		if err := s.ledgerStore.WriteJob(j); err != nil {
+			level.Error(logger).Log("s.ledgerStore.WriteJob:", err)
+			return Job{}, err
+		}
+		return j, nil
+	}
+
+	j.Status = JobDone
+	j.Error = ""
+	j.FeeBps = s.feeBps
+	j.CompletedAt = &now
+
+	if err := s.ledgerStore.Commit(j, txs); err != nil {
+		level.Error(logger).Log("s.ledgerStore.Commit:", err)
+		return Job{}, err
+	}
+
+	level.Info(logger).Log("job", j.Id, "transactions", len(txs))
+
+	return j, nil
+}
+
+// transactions of a job, settling the auction first
+func (s *service) plan(ctx context.Context, j Job, now time.Time) ([]Transaction, error) {
+
+	switch j.Kind {
+
+	case JobAuction:
+		// settling is idempotent, a settled auction returns its stored settlement
+		a, err := s.auctions.Finalize(ctx, j.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return planAuction(j.Id, a, s.feeBps, now)
+
+	case JobRaffle:
+		c, err := s.collections.ReadCollection(ctx, j.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return planRaffle(j.Id, c, s.feeBps, now)
+
+	default:
+		return nil, ErrInvalidJob
+	}
+}
+
+// service struct list jobs method
+func (s *service) ListJobs(ctx context.Context) ([]Job, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ListJobs")
+
+	if err := s.operator(ctx); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.ledgerStore.ReadJobs()
+	if err != nil {
+		level.Error(logger).Log("s.ledgerStore.ReadJobs:", err)
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// service struct record payment method
+// books money received from or paid out to a user or artist against the cash account
+// the reference makes recording idempotent, repeating a payment returns the recorded transaction
+// only operators record payments
+func (s *service) RecordPayment(ctx context.Context, p Payment) (Transaction, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "RecordPayment")
+
+	if err := s.operator(ctx); err != nil {
+		return Transaction{}, err
+	}
+
+	p.Account = strings.ToLower(p.Account)
+	if !payeePattern.MatchString(p.Account) || !referencePattern.MatchString(p.Reference) {
+		return Transaction{}, ErrInvalidPayment
+	}
+	amount, ok := new(big.Int).SetString(p.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return Transaction{}, ErrInvalidAmount
+	}
+
+	pl := &planner{jobId: jobId(JobPayment, p.Reference), now: s.now()}
+	switch p.Direction {
+	case "in":
+		pl.transfer(KindReceipt, "payment received", AccountCash, p.Account, amount)
+	case "out":
+		pl.transfer(KindPayout, "payment sent", p.Account, AccountCash, amount)
+	default:
+		return Transaction{}, ErrInvalidPayment
+	}
+	tx := pl.txs[0]
+
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if _, err := s.ledgerStore.ReadJob(pl.jobId); err == nil {
+		recorded, err := s.transaction(tx.Id)
+		if err != nil {
+			return Transaction{}, err
+		}
+		if recorded.Kind != tx.Kind || !reflect.DeepEqual(recorded.Postings, tx.Postings) {
+			return Transaction{}, ErrReferenceConflict
+		}
+		return recorded, nil
+	}
+
+	j := Job{Id: pl.jobId, Kind: JobPayment, Ref: p.Reference, Status: JobDone, Attempts: 1, CreatedAt: pl.now, CompletedAt: &pl.now}
+	if err := s.ledgerStore.Commit(j, []Transaction{tx}); err != nil {
+		level.Error(logger).Log("s.ledgerStore.Commit:", err)
+		return Transaction{}, err
+	}
+
+	return tx, nil
+}
+
+// recorded transaction by identifier
+func (s *service) transaction(id string) (Transaction, error) {
+
+	txs, err := s.ledgerStore.ReadTransactions()
+	if err != nil {
+		return Transaction{}, err
+	}
+	for _, tx := range txs {
+		if tx.Id == id {
+			return tx, nil
+		}
+	}
+
+	return Transaction{}, ErrJobNotFound
+}
+
+// service struct read account method
+// returns what the platform owes the account holder or the holder owes, with all postings
+// any account for operators, see ReadUser for users
+func (s *service) ReadAccount(ctx context.Context, account string) (Statement, error) {
+
+	if err := s.operator(ctx); err != nil {
+		return Statement{}, err
+	}
+
+	return s.statement(account)
+}
+
+// service struct read user method
+// returns the statement of a wallet linked to the session user, operators read every wallet
+func (s *service) ReadUser(ctx context.Context, address string) (Statement, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadUser")
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	address = strings.ToLower(address)
+	account := UserAccount(address)
+	if !accountPattern.MatchString(account) {
+		return Statement{}, ErrInvalidAccount
+	}
+
+	if !s.operators[userId] {
+		if s.wallets == nil {
+			return Statement{}, ErrWalletNotLinked
+		}
+		linked, err := s.wallets.IsLinked(ctx, userId, address)
+		if err != nil {
+			level.Error(logger).Log("s.wallets.IsLinked:", err)
+			return Statement{}, err
+		}
+		if !linked {
+			return Statement{}, ErrWalletNotLinked
+		}
+	}
+
+	return s.statement(account)
+}
+
+// balance and postings of an account
+func (s *service) statement(account string) (Statement, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "statement")
+
+	account = strings.ToLower(account)
+	if !accountPattern.MatchString(account) {
+		return Statement{}, ErrInvalidAccount
+	}
+
+	txs, err := s.ledgerStore.ReadTransactions()
+	if err != nil {
+		level.Error(logger).Log("s.ledgerStore.ReadTransactions:", err)
+		return Statement{}, err
+	}
+
+	balance := new(big.Int)
+	st := Statement{Account: account, Entries: []Entry{}}
+	for _, tx := range txs {
+		for _, p := range tx.Postings {
+			if p.Account != account {
+				continue
+			}
+			amount, _ := new(big.Int).SetString(p.Amount, 10)
+			balance.Add(balance, amount)
+			st.Entries = append(st.Entries, Entry{TransactionId: tx.Id, Kind: tx.Kind, Memo: tx.Memo, Amount: p.Amount, CreatedAt: tx.CreatedAt})
+		}
+	}
+
+	st.Balance = balance.String()
+	st.Owed, st.Owes = "0", "0"
+	if balance.Sign() > 0 {
+		st.Owed = balance.String()
+	} else if balance.Sign() < 0 {
+		st.Owes = new(big.Int).Neg(balance).String()
+	}
+
+	return st, nil
+}
+
+// service struct reconcile method
+// checks that every transaction balances, that no escrow holds funds after settlement
+// and that the transactions of completed jobs still match the settled auctions and raffles
+func (s *service) Reconcile(ctx context.Context) (Report, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Reconcile")
+
+	if err := s.operator(ctx); err != nil {
+		return Report{}, err
+	}
+
+	txs, err := s.ledgerStore.ReadTransactions()
+	if err != nil {
+		level.Error(logger).Log("s.ledgerStore.ReadTransactions:", err)
+		return Report{}, err
+	}
+	jobs, err := s.ledgerStore.ReadJobs()
+	if err != nil {
+		level.Error(logger).Log("s.ledgerStore.ReadJobs:", err)
+		return Report{}, err
+	}
+
+	report := Report{
+		GeneratedAt:  s.now(),
+		Transactions: len(txs),
+		Unbalanced:   []string{},
+		OpenEscrows:  []AccountBalance{},
+		Mismatches:   []Mismatch{},
+		Pending:      []Job{},
+		Failed:       []Job{},
+		Totals:       make(map[string]string),
+	}
+
+	balances := make(map[string]*big.Int)
+	byJob := make(map[string][]Transaction)
+	for _, tx := range txs {
+		if !tx.Balanced() {
+			report.Unbalanced = append(report.Unbalanced, tx.Id)
+		}
+		byJob[tx.JobId] = append(byJob[tx.JobId], tx)
+		for _, p := range tx.Postings {
+			amount, ok := new(big.Int).SetString(p.Amount, 10)
+			if !ok {
+				continue
+			}
+			if balances[p.Account] == nil {
+				balances[p.Account] = new(big.Int)
+			}
+			balances[p.Account].Add(balances[p.Account], amount)
+		}
+	}
+
+	// totals per account class, users are split into what they are owed and what they owe
+	totals := make(map[string]*big.Int)
+	add := func(class string, amount *big.Int) {
+		if totals[class] == nil {
+			totals[class] = new(big.Int)
+		}
+		totals[class].Add(totals[class], amount)
+	}
+	accounts := make([]string, 0, len(balances))
+	for account := range balances {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		balance := balances[account]
+		switch {
+		case strings.HasPrefix(account, "escrow:"):
+			add("escrow", balance)
+			if balance.Sign() != 0 {
+				report.OpenEscrows = append(report.OpenEscrows, AccountBalance{Account: account, Balance: balance.String()})
+			}
+		case strings.HasPrefix(account, "user:") && balance.Sign() > 0:
+			add("users_owed", balance)
+		case strings.HasPrefix(account, "user:"):
+			add("users_owing", balance)
+		case strings.HasPrefix(account, "artist:"):
+			add("artists", balance)
+		case account == AccountFees:
+			add("fees", balance)
+		case account == AccountCash:
+			add("cash", balance)
+		}
+	}
+	for class, total := range totals {
+		report.Totals[class] = total.String()
+	}
+
+	for _, j := range jobs {
+		switch j.Status {
+		case JobPending:
+			report.Pending = append(report.Pending, j)
+			continue
+		case JobFailed:
+			report.Failed = append(report.Failed, j)
+			continue
+		}
+
+		if j.Kind == JobPayment {
+			continue
+		}
+		if reason := s.verifyJob(ctx, j, byJob[j.Id]); reason != "" {
+			report.Mismatches = append(report.Mismatches, Mismatch{JobId: j.Id, Reason: reason})
+		}
+	}
+
+	report.Ok = len(report.Unbalanced) == 0 && len(report.OpenEscrows) == 0 && len(report.Mismatches) == 0 && len(report.Failed) == 0
+
+	return report, nil
+}
+
+// compares recorded transactions of a completed job with a fresh plan at the job's fee, returns the reason of a mismatch
+func (s *service) verifyJob(ctx context.Context, j Job, recorded []Transaction) string {
+
+	var planned []Transaction
+	var err error
+	switch j.Kind {
+	case JobAuction:
+		var a auction.Auction
+		a, err = s.auctions.ReadAuction(ctx, j.Ref)
+		if err == nil {
+			planned, err = planAuction(j.Id, a, j.FeeBps, *j.CompletedAt)
+		}
+	case JobRaffle:
+		var c collection.Collection
+		c, err = s.collections.ReadCollection(ctx, j.Ref)
+		if err == nil {
+			planned, err = planRaffle(j.Id, c, j.FeeBps, *j.CompletedAt)
+		}
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	if len(planned) != len(recorded) {
+		return "transaction count differs from settlement"
+	}
+	for i := range planned {
+		if planned[i].Id != recorded[i].Id || planned[i].Kind != recorded[i].Kind || !reflect.DeepEqual(planned[i].Postings, recorded[i].Postings) {
+			return "transaction " + recorded[i].Id + " differs from settlement"
+		}
+	}
+
+	return ""
+}
+
+// initialization function to return service struct
+// feeBps is the platform fee in basis points of every payment
+// this function should is called in main.go
+// operators are the user identifiers allowed to settle jobs, record payments and read the whole ledger, see session.Session.UserId
+// users read the accounts of their linked wallets
+func NewService(ledgerStore LedgerStore, auctions Auctions, collections Collections, sessions Sessions, wallets Wallets, operators []string, feeBps int64, logger log.Logger) Service {
+
+	ops := make(map[string]bool, len(operators))
+	for _, o := range operators {
+		ops[o] = true
+	}
+
+	return &service{
+		ledgerStore: ledgerStore,
+		auctions:    auctions,
+		collections: collections,
+		sessions:    sessions,
+		wallets:     wallets,
+		operators:   ops,
+		feeBps:      feeBps,
+		now:         time.Now,
+		logger:      logger,
+	}
+}
@@ -0,0 +1,199 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******* Ledger store interface *********
+
+var ErrJobNotFound = errors.New("Settlement job not found")
+
+var ErrJobDone = errors.New("Settlement job already completed")
+
+type LedgerStoreConfig struct {
+	LedgerPath string
+}
+
+// transactions are only ever appended, together with the job that produced them
+type LedgerStore interface {
+	ReadTransactions() ([]Transaction, error)
+	ReadJob(id string) (Job, error)
+	ReadJobs() ([]Job, error)
+	WriteJob(j Job) error
+	Commit(j Job, txs []Transaction) error
+}
+
+// persisted ledger file
+type ledgerFile struct {
+	Transactions []Transaction  `json:"transactions"`
+	Jobs         map[string]Job `json:"jobs"`
+}
+
+type ledgerStore struct {
+	mu     sync.Mutex
+	ledger ledgerFile
+	config LedgerStoreConfig
+	logger log.Logger
+}
+
+// all transactions in the order they were recorded
+func (ls *ledgerStore) ReadTransactions() ([]Transaction, error) {
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	txs := make([]Transaction, len(ls.ledger.Transactions))
+	copy(txs, ls.ledger.Transactions)
+
+	return txs, nil
+}
+
+// job by identifier
+func (ls *ledgerStore) ReadJob(id string) (Job, error) {
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	j, ok := ls.ledger.Jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	return j, nil
+}
+
+// all jobs ordered by creation
+func (ls *ledgerStore) ReadJobs() ([]Job, error) {
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	jobs := make([]Job, 0, len(ls.ledger.Jobs))
+	for _, j := range ls.ledger.Jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		if jobs[i].CreatedAt.Equal(jobs[k].CreatedAt) {
+			return jobs[i].Id < jobs[k].Id
+		}
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+// stores a job that has not completed, completed jobs only change through Commit
+func (ls *ledgerStore) WriteJob(j Job) error {
+
+	// log level
+	logger := log.With(ls.logger, "method", "WriteJob")
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	previous, existed := ls.ledger.Jobs[j.Id]
+	if existed && previous.Status == JobDone {
+		return ErrJobDone
+	}
+
+	ls.ledger.Jobs[j.Id] = j
+	if err := ls.write(); err != nil {
+		if existed {
+			ls.ledger.Jobs[j.Id] = previous
+		} else {
+			delete(ls.ledger.Jobs, j.Id)
+		}
+		level.Error(logger).Log("ls.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// appends the transactions of a job and marks it done in one write
+// committing a completed job again is rejected, so a job never posts twice
+func (ls *ledgerStore) Commit(j Job, txs []Transaction) error {
+
+	// log level
+	logger := log.With(ls.logger, "method", "Commit")
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	previous, existed := ls.ledger.Jobs[j.Id]
+	if existed && previous.Status == JobDone {
+		return ErrJobDone
+	}
+
+	n := len(ls.ledger.Transactions)
+	ls.ledger.Transactions = append(ls.ledger.Transactions, txs...)
+	ls.ledger.Jobs[j.Id] = j
+
+	if err := ls.write(); err != nil {
+		ls.ledger.Transactions = ls.ledger.Transactions[:n]
+		if existed {
+			ls.ledger.Jobs[j.Id] = previous
+		} else {
+			delete(ls.ledger.Jobs, j.Id)
+		}
+		level.Error(logger).Log("ls.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// writes the ledger, temporary file first so that a crash never leaves a partial ledger
+func (ls *ledgerStore) write() error {
+
+	if err := os.MkdirAll(ls.config.LedgerPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ls.ledger)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ls.config.LedgerPath, "ledger.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads the persisted ledger, a missing file starts an empty ledger
+func NewLedgerStore(config LedgerStoreConfig, logger log.Logger) (LedgerStore, error) {
+
+	ls := &ledgerStore{
+		ledger: ledgerFile{Transactions: []Transaction{}, Jobs: make(map[string]Job)},
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.LedgerPath, "ledger.json"))
+	if os.IsNotExist(err) {
+		return ls, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ls.ledger); err != nil {
+		return nil, err
+	}
+	if ls.ledger.Jobs == nil {
+		ls.ledger.Jobs = make(map[string]Job)
+	}
+
+	return ls, nil
+}
@@ -2,14 +2,34 @@ package main
 
 import (
 	"context"
+	"math/big"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	"website/auction"
+	"website/chain"
+	"website/checkpoints"
+	"website/collection"
 	"website/configs"
+	"website/indexer"
+	"website/ledger"
+	"website/media"
+	"website/minting"
+	"website/realtime"
 	"website/redirect"
+	"website/relayer"
+	"website/selection"
 	"website/session"
+	"website/signing"
 	"website/spa"
+	"website/staking"
 	"website/storage"
+	"website/txmanager"
+	"website/wallet"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/fvbock/endless"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/log/level"
@@ -76,13 +96,340 @@ func main() {
 
 	// session service
 	var svc2 session.Service
+	storageConfig := storage.UserStoreConfig{UsersPath: config.UsersPath}
+	userStore := storage.NewUserStore(storageConfig, log.With(logger, "client", "storage"))
 	{
-		storageConfig := storage.UserStoreConfig{UsersPath: config.UsersPath}
-		userStore := storage.NewUserStore(storageConfig, log.With(logger, "client", "storage"))
 		sessionStore := session.NewSessionStore()
 		svc2 = session.NewService(userStore, sessionStore, secretSession, log.With(logger, "service", "session"))
 	}
 
+	chainId, ok := new(big.Int).SetString(config.ChainId, 10)
+	if !ok {
+		level.Error(logger).Log("msg", "invalid chain id", "chain_id", config.ChainId)
+		os.Exit(1)
+	}
+
+	// chain client, reads balances and votes of the Art token
+	// stays nil without RPC_URL, stake weighted raffles then fail to draw
+	var chainClient chain.Client
+	if config.RpcUrl != "" {
+		client, err := chain.Dial(ctx1, config.RpcUrl, chainId, common.HexToAddress(config.ArtAddress), log.With(logger, "client", "chain"))
+		if err != nil {
+			level.Error(logger).Log("msg", "connecting to chain failed", "err", err)
+			os.Exit(1)
+		}
+		chainClient = client
+	}
+
+	// wallet service, links addresses to users by signed challenges
+	// contract wallets are only supported with a chain client
+	var svc11 wallet.Service
+	{
+		var validator wallet.Validator
+		if chainClient != nil {
+			validator = chainClient
+		}
+		walletConfig := wallet.Config{Name: "ArtToken", ChainId: chainId, ChallengeTTL: 10 * time.Minute, MaxWallets: 10}
+		var err error
+		svc11, err = wallet.NewService(userStore, svc2, validator, walletConfig, log.With(logger, "service", "wallet"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading wallet links failed", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	// signing service, verifies EIP-712 signed bids and entries
+	var svc6 signing.Service
+	{
+		domain := signing.Domain{Name: "ArtToken", ChainId: chainId, VerifyingContract: common.HexToAddress(config.ArtAddress)}
+		nonceStore, err := signing.NewNonceStore(signing.NonceStoreConfig{NoncesPath: config.NoncesPath}, log.With(logger, "client", "nonces"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading nonces failed", "err", err)
+			os.Exit(1)
+		}
+		// signers need an active session and a wallet linked to its user
+		svc6 = signing.NewService(domain, nonceStore, svc2, svc11, log.With(logger, "service", "signing"))
+	}
+
+	// realtime hub, pushes auction and raffle updates to subscribed browsers
+	hub := realtime.NewHub(realtime.HubConfig{History: 256, Buffer: 64, MaxSubscribers: 10000, TTL: 5 * time.Minute})
+
+	// context for media pipeline workers
+	ctx3 := context.Background()
+	ctx3, cancel3 := context.WithCancel(ctx3)
+	defer cancel3()
+
+	// media service
+	var svc3 media.Service
+	{
+		mediaConfig := media.MediaStoreConfig{MediaPath: config.MediaPath}
+		mediaStore := media.NewMediaStore(mediaConfig, log.With(logger, "client", "media"))
+		svc3 = media.NewService(ctx3, mediaStore, svc2, log.With(logger, "service", "media"))
+	}
+
+	// context for collection scheduler
+	ctx4 := context.Background()
+	ctx4, cancel4 := context.WithCancel(ctx4)
+	defer cancel4()
+
+	// blocks deeper than confirmations below the head are final, for the indexer and raffle draws
+	confirmations, err := strconv.ParseUint(config.IndexConfirmations, 10, 64)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid index confirmations", "confirmations", config.IndexConfirmations)
+		os.Exit(1)
+	}
+
+	// Art token event indexer, only runs with a chain client
+	var svc8 indexer.Service
+	if chainClient != nil {
+		startBlock, err := strconv.ParseUint(config.IndexStartBlock, 10, 64)
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid index start block", "start_block", config.IndexStartBlock)
+			os.Exit(1)
+		}
+		indexStore, err := indexer.NewIndexStore(indexer.IndexStoreConfig{IndexPath: config.IndexPath}, log.With(logger, "client", "index"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading index failed", "err", err)
+			os.Exit(1)
+		}
+		indexConfig := indexer.Config{StartBlock: startBlock, Confirmations: confirmations, BatchSize: 2000}
+		svc8, err = indexer.NewService(indexStore, chainClient, indexConfig, log.With(logger, "service", "indexer"))
+		if err != nil {
+			level.Error(logger).Log("msg", "rebuilding index failed", "err", err)
+			os.Exit(1)
+		}
+
+		scheduler := indexer.NewScheduler(svc8, 15*time.Second, log.With(logger, "service", "indexer scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// vote checkpoints replayed from the index, answers historical votes without contract calls
+	var svc9 checkpoints.Service
+	if svc8 != nil {
+		svc9 = checkpoints.NewService(svc8, log.With(logger, "service", "checkpoints"))
+	}
+
+	// transaction manager, signs and sends everything the server puts on-chain from the hot wallet
+	var svc12 txmanager.Service
+	if chainClient != nil && config.KeystorePath != "" {
+		key, err := txmanager.LoadKey(config.KeystorePath, config.KeystorePassword)
+		if err != nil {
+			level.Error(logger).Log("msg", "loading hot wallet key failed", "err", err)
+			os.Exit(1)
+		}
+		txStore, err := txmanager.NewTxStore(txmanager.TxStoreConfig{TxsPath: config.TxsPath}, log.With(logger, "client", "txs"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading transactions failed", "err", err)
+			os.Exit(1)
+		}
+		txConfig := txmanager.Config{
+			ChainId:       chainId,
+			Confirmations: 3,
+			GasMargin:     20,
+			StuckAfter:    3 * time.Minute,
+			BumpPercent:   20,
+			MaxFeeCap:     new(big.Int).Mul(big.NewInt(500), big.NewInt(1000000000)),
+		}
+		svc12 = txmanager.NewService(txStore, chainClient.Backend(), key, txConfig, log.With(logger, "service", "txmanager"))
+
+		scheduler := txmanager.NewScheduler(svc12, 15*time.Second, log.With(logger, "service", "txmanager scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// collection store, shared by the collection service and staking
+	collectionConfig := collection.CollectionStoreConfig{CollectionsPath: config.CollectionsPath}
+	collectionStore := collection.NewCollectionStore(collectionConfig, log.With(logger, "client", "collection"))
+
+	// staking, locks ART toward collections, backed by custody, delegation or approval to the staking address
+	var svc14 staking.Service
+	if svc8 != nil && (config.StakingAddress != "" || svc12 != nil) {
+		address := config.StakingAddress
+		if address == "" {
+			address = svc12.Address()
+		}
+		epochStart, err := time.Parse(time.RFC3339, config.StakingEpochStart)
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid staking epoch start", "epoch_start", config.StakingEpochStart)
+			os.Exit(1)
+		}
+		epochReward, ok := new(big.Int).SetString(config.StakingEpochReward, 10)
+		if !ok {
+			level.Error(logger).Log("msg", "invalid staking epoch reward", "epoch_reward", config.StakingEpochReward)
+			os.Exit(1)
+		}
+		stakeStore, err := staking.NewStakeStore(staking.StakeStoreConfig{StakesPath: config.StakesPath}, log.With(logger, "client", "stakes"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading stakes failed", "err", err)
+			os.Exit(1)
+		}
+		stakingConfig := staking.Config{
+			Address:     common.HexToAddress(address),
+			EpochStart:  epochStart,
+			EpochLength: 7 * 24 * time.Hour,
+			MaxLock:     90 * 24 * time.Hour,
+			MaxBoost:    10000,
+			Cooldown:    7 * 24 * time.Hour,
+			MinStake:    new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+			EpochReward: epochReward,
+		}
+		// withdrawals are sent from the hot wallet, only if it is the staking address
+		var sender staking.Sender
+		if svc12 != nil && strings.EqualFold(svc12.Address(), address) {
+			sender = svc12
+		}
+		svc14, err = staking.NewService(stakeStore, svc8, collectionStore, svc2, svc11, chainClient, sender, stakingConfig, log.With(logger, "service", "staking"))
+		if err != nil {
+			level.Error(logger).Log("msg", "invalid staking configuration", "err", err)
+			os.Exit(1)
+		}
+
+		scheduler := staking.NewScheduler(svc14, time.Minute, log.With(logger, "service", "staking scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// collection service
+	var svc4 collection.Service
+	{
+		var votes selection.VotesSource
+		var blocks collection.Blocks
+		if chainClient != nil {
+			votes = chainClient
+			blocks = chainClient.Backend()
+		}
+		var stakes collection.StakeSource
+		if svc14 != nil {
+			stakes = svc14
+		}
+		// only owners transition and draw their collections, draws wait for the draw block to be final
+		collectionConfig := collection.Config{Confirmations: confirmations}
+		svc4 = collection.NewService(collectionStore, votes, stakes, blocks, svc2, svc6, collectionConfig, log.With(logger, "service", "collection"))
+		svc4.Subscribe(func(e collection.Event) {
+			level.Info(logger).Log("msg", "collection transition", "id", e.CollectionId, "from", e.From, "to", e.To)
+		})
+		svc4.Subscribe(func(e collection.Event) {
+			topics := []string{realtime.CollectionTopic(e.CollectionId)}
+			if _, err := hub.Publish(topics, "state", e); err != nil {
+				level.Error(logger).Log("hub.Publish:", err)
+			}
+			if e.To != collection.StateDrawn {
+				return
+			}
+			transcript, err := svc4.ReadTranscript(ctx4, e.CollectionId)
+			if err != nil {
+				level.Error(logger).Log("svc4.ReadTranscript:", err)
+				return
+			}
+			result := struct {
+				CollectionId string             `json:"collection_id"`
+				Winners      []selection.Winner `json:"winners"`
+				DrawnAt      time.Time          `json:"drawn_at"`
+			}{e.CollectionId, transcript.Winners, transcript.DrawnAt}
+			if _, err := hub.Publish(topics, "raffle", result); err != nil {
+				level.Error(logger).Log("hub.Publish:", err)
+			}
+		})
+
+		// advances collections at their configured opening, closing and reveal times
+		scheduler := collection.NewScheduler(svc4, 10*time.Second, log.With(logger, "service", "collection scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// operators settle auctions and ledger jobs and may auction any collection, configured by email
+	operators := []string{}
+	for _, e := range strings.Split(config.Operators, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			operators = append(operators, storage.User{Email: e}.UserId())
+		}
+	}
+
+	// auction service
+	var svc5 auction.Service
+	{
+		auctionConfig := auction.AuctionStoreConfig{AuctionsPath: config.AuctionsPath}
+		auctionStore := auction.NewAuctionStore(auctionConfig, log.With(logger, "client", "auction"))
+		// bids must come from a wallet linked to the bidder, signed by that wallet
+		// collection owners auction their own collections
+		svc5 = auction.NewService(auctionStore, svc4, svc2, svc11, svc6, operators, log.With(logger, "service", "auction"))
+		svc5.Subscribe(func(e auction.Event) {
+			topics := []string{
+				realtime.AuctionTopic(e.Auction.Id),
+				realtime.TokenTopic(e.Auction.CollectionId, e.Auction.TokenId),
+				realtime.CollectionTopic(e.Auction.CollectionId),
+			}
+			if _, err := hub.Publish(topics, string(e.Type), e); err != nil {
+				level.Error(logger).Log("hub.Publish:", err)
+			}
+		})
+
+		// emits status events when auctions start, end or close their reveal window
+		scheduler := auction.NewScheduler(svc5, 5*time.Second, log.With(logger, "service", "auction scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// ledger service, books escrow, payments, refunds, fees and artist proceeds of settled auctions and raffles
+	var svc7 ledger.Service
+	{
+		feeBps, err := strconv.ParseInt(config.PlatformFeeBps, 10, 64)
+		if err != nil || feeBps < 0 || feeBps > 10000 {
+			level.Error(logger).Log("msg", "invalid platform fee", "fee_bps", config.PlatformFeeBps)
+			os.Exit(1)
+		}
+		ledgerStore, err := ledger.NewLedgerStore(ledger.LedgerStoreConfig{LedgerPath: config.LedgerPath}, log.With(logger, "client", "ledger"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading ledger failed", "err", err)
+			os.Exit(1)
+		}
+		svc7 = ledger.NewService(ledgerStore, svc5, svc4, svc2, svc11, operators, feeBps, log.With(logger, "service", "ledger"))
+
+		// ended auctions and drawn raffles are queued for settlement, the scheduler runs the jobs
+		// and queues those that ended while the process was down
+		// handlers must not call back into the emitting service, they run while it holds its locks
+		svc5.Subscribe(func(e auction.Event) {
+			if e.Type == auction.EventSettled || (e.Type == auction.EventStatus && e.Auction.Status == auction.StatusEnded) {
+				if _, err := svc7.Enqueue(ctx4, ledger.JobAuction, e.Auction.Id); err != nil {
+					level.Error(logger).Log("svc7.Enqueue:", err)
+				}
+			}
+		})
+		svc4.Subscribe(func(e collection.Event) {
+			if e.To == collection.StateDrawn {
+				if _, err := svc7.Enqueue(ctx4, ledger.JobRaffle, e.CollectionId); err != nil {
+					level.Error(logger).Log("svc7.Enqueue:", err)
+				}
+			}
+		})
+
+		scheduler := ledger.NewScheduler(svc7, 10*time.Second, log.With(logger, "service", "ledger scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
+	// minting, reads the mint schedule and prepares mint transactions for the minter
+	var svc13 minting.Service
+	if chainClient != nil {
+		svc13 = minting.NewService(chainClient, chainId, log.With(logger, "service", "minting"))
+	}
+
+	// relayer, broadcasts signed permits and delegations through the transaction manager
+	var svc10 relayer.Service
+	if svc12 != nil {
+		relayStore, err := relayer.NewRelayStore(relayer.RelayStoreConfig{RelaysPath: config.RelaysPath}, log.With(logger, "client", "relays"))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading relays failed", "err", err)
+			os.Exit(1)
+		}
+		relayConfig := relayer.Config{
+			Domain:      signing.Domain{Name: "ArtToken", ChainId: chainId, VerifyingContract: common.HexToAddress(config.ArtAddress)},
+			MinValidity: 5 * time.Minute,
+			RateLimit:   10,
+			RateWindow:  24 * time.Hour,
+			Budget:      500,
+		}
+		svc10 = relayer.NewService(relayStore, chainClient, svc12, relayConfig, log.With(logger, "service", "relayer"))
+
+		scheduler := relayer.NewScheduler(svc10, 15*time.Second, log.With(logger, "service", "relayer scheduler"))
+		go scheduler.Run(ctx4)
+	}
+
 	// // storage service
 	// var svc1 storage.Service
 	// {
@@ -95,6 +442,29 @@ func main() {
 	// attach services
 	session.AttachRoutes(mux2, secretSession, ctx2, svc2, log.With(logger, "transport", "session"))
 	// storage.AttachRoutes(mux2, ctx1, svc1, log.With(logger, "transport", "storage"))
+	wallet.AttachRoutes(mux2, svc11, log.With(logger, "transport", "wallet"))
+	media.AttachRoutes(mux2, svc3, log.With(logger, "transport", "media"))
+	collection.AttachRoutes(mux2, svc4, log.With(logger, "transport", "collection"))
+	auction.AttachRoutes(mux2, svc5, log.With(logger, "transport", "auction"))
+	signing.AttachRoutes(mux2, svc6, log.With(logger, "transport", "signing"))
+	ledger.AttachRoutes(mux2, svc7, log.With(logger, "transport", "ledger"))
+	if svc8 != nil {
+		indexer.AttachRoutes(mux2, svc8, log.With(logger, "transport", "indexer"))
+		checkpoints.AttachRoutes(mux2, svc9, log.With(logger, "transport", "checkpoints"))
+	}
+	if svc13 != nil {
+		minting.AttachRoutes(mux2, svc13, log.With(logger, "transport", "minting"))
+	}
+	if svc14 != nil {
+		staking.AttachRoutes(mux2, svc14, log.With(logger, "transport", "staking"))
+	}
+	if svc12 != nil {
+		txmanager.AttachRoutes(mux2, svc12, log.With(logger, "transport", "txmanager"))
+	}
+	if svc10 != nil {
+		relayer.AttachRoutes(mux2, svc10, log.With(logger, "transport", "relayer"))
+	}
+	realtime.AttachRoutes(mux2, hub, 15*time.Second, log.With(logger, "transport", "realtime"))
 	spa.AttachRoutes(mux2, config.StaticAssetsDir, log.With(logger, "transport", "spa"))
 
 	// configure server
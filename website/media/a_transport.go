@@ -0,0 +1,158 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// maximum size of an uploaded artwork
+const maxUploadSize = 32 << 20
+
+// derived images never change for a content hash, browsers may cache them for a year
+const cacheControl = "public, max-age=31536000, immutable"
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined error types
+// returns notFound if error cannot be mapped to bad request or internal server
+func errorStatusCode(err error) int {
+
+	switch err {
+
+	case ErrBadRequest:
+		return http.StatusBadRequest
+
+	case ErrUnauthorized:
+		return http.StatusUnauthorized
+
+	case ErrImageTooLarge:
+		return http.StatusRequestEntityTooLarge
+
+	case ErrInternalServer:
+		return http.StatusInternalServerError
+
+	default:
+		return http.StatusNotFound
+	}
+}
+
+// decode multipart artwork upload, file is expected in form field "file"
+func decodeUpload(_ context.Context, r *http.Request) (interface{}, error) {
+
+	r.Body = http.MaxBytesReader(nil, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, ErrBadRequest
+	}
+
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, ErrBadRequest
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, ErrBadRequest
+	}
+
+	return UploadRequest{Data: data}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// derived images are served by a plain handler as they are not JSON encoded
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	uploadHandler := httptransport.NewServer(
+		e.Upload,
+		decodeUpload,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/media", uploadHandler).Methods("POST")
+	router.HandleFunc("/media/{hash}/{variant}", serveVariant(s, logger)).Methods("GET", "HEAD")
+
+	return router
+}
+
+// serves a derived image with long lived cache headers
+func serveVariant(s Service, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// log level
+		logger := log.With(logger, "handler", "serveVariant")
+
+		vars := mux.Vars(r)
+		hash, variant := vars["hash"], vars["variant"]
+
+		path, err := s.Derivative(r.Context(), hash, variant)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			encodeError(r.Context(), ErrNotFound, w)
+			return
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			level.Error(logger).Log("os.Open:", err)
+			w.Header().Set("Content-Type", "application/json")
+			encodeError(r.Context(), ErrInternalServer, w)
+			return
+		}
+		defer f.Close()
+
+		// content addressed, the etag never changes
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", "\""+hash+"-"+variant+"\"")
+		http.ServeContent(w, r, "", time.Time{}, f)
+	}
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type UploadRequest struct {
+	Data []byte
+}
+
+type Upload struct {
+	Hash     string            `json:"hash"`
+	Variants map[string]string `json:"variants"`
+}
+
+type UploadResponse struct {
+	Data Upload `json:"data"`
+	Err  error  `json:"errors"`
+}
+
+// have UploadResponse follow the customError interface defined in a_transport.go
+func (r UploadResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	Upload endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		Upload: epUpload(s),
+	}
+}
+
+// upload artwork endpoint
+func epUpload(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(UploadRequest)
+
+		// call service method
+		hash, err := s.Upload(ctx, req.Data)
+		if err == ErrUnsupportedImage {
+			return UploadResponse{Err: err}, ErrBadRequest
+		}
+		if err == ErrUnauthorized || err == ErrImageTooLarge {
+			return UploadResponse{Err: err}, err
+		}
+		if err != nil {
+			return UploadResponse{Err: err}, ErrInternalServer
+		}
+
+		// routes of all variants, derived in background
+		variants := make(map[string]string)
+		for variant := range Variants {
+			variants[variant] = "/media/" + hash + "/" + variant
+		}
+
+		return UploadResponse{Data: Upload{Hash: hash, Variants: variants}, Err: nil}, nil
+	}
+
+}
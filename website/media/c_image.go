@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// ******* Variant definitions *********
+
+// fixed output widths of derived images, height keeps the aspect ratio
+var Variants = map[string]int{
+	"thumb":   320,
+	"preview": 1024,
+}
+
+// jpeg quality of all derived images
+const jpegQuality = 85
+
+// derive decodes an uploaded image, normalises its orientation and scales it down to the variant width
+// re-encoding the pixels drops every metadata segment (EXIF, GPS, XMP) of the upload
+func derive(original []byte, width int) ([]byte, error) {
+
+	src, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+
+	// apply EXIF orientation before metadata is lost
+	src = orient(src, exifOrientation(original))
+
+	// never upscale small images
+	b := src.Bounds()
+	if b.Dx() < width {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	// white background for images with alpha channel as jpeg cannot store transparency
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// exifOrientation returns the EXIF orientation tag (1-8) of a jpeg file
+// returns 1 (no transformation) if the tag is missing or the data is not a jpeg
+func exifOrientation(data []byte) int {
+
+	// jpeg start of image marker
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// walk segments until APP1 with an Exif header is found
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+
+		// start of scan, no more metadata segments follow
+		if marker == 0xDA {
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+// reads the orientation tag (0x0112) of the first image file directory in a TIFF structure
+func tiffOrientation(tiff []byte) int {
+
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	// byte order
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for e := 0; e < entries; e++ {
+		start := offset + 2 + e*12
+		if start+12 > len(tiff) {
+			return 1
+		}
+		entry := tiff[start : start+12]
+		if order.Uint16(entry[0:2]) == 0x0112 {
+			o := int(order.Uint16(entry[8:10]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+
+	return 1
+}
+
+// orient rotates and flips the image so that it is displayed upright without EXIF information
+func orient(src image.Image, orientation int) image.Image {
+
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// orientations 5 to 8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 270 clockwise
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,94 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// builds a jpeg of size w x h carrying an EXIF APP1 segment with the given orientation
+func testJpeg(t *testing.T, w, h, orientation int) []byte {
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode failed, error: %v.", err)
+	}
+
+	// little endian TIFF with a single IFD0 entry
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(orientation))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(buf.Bytes()[2:])
+
+	return out.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+
+	for _, o := range []int{1, 3, 6, 8} {
+		if got := exifOrientation(testJpeg(t, 8, 4, o)); got != o {
+			t.Errorf("exifOrientation returned %d, expected %d.", got, o)
+		}
+	}
+
+	if got := exifOrientation([]byte("not an image")); got != 1 {
+		t.Errorf("exifOrientation returned %d for invalid data, expected 1.", got)
+	}
+}
+
+func TestDerive(t *testing.T) {
+
+	// rotated by 90 degrees, derived image must be portrait
+	data, err := derive(testJpeg(t, 200, 100, 6), 50)
+	if err != nil {
+		t.Fatalf("derive failed, error: %v.", err)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("jpeg.DecodeConfig failed, error: %v.", err)
+	}
+	if cfg.Width != 50 || cfg.Height != 100 {
+		t.Errorf("derive returned %dx%d, expected 50x100.", cfg.Width, cfg.Height)
+	}
+
+	// metadata stripped
+	if bytes.Contains(data, []byte("Exif")) {
+		t.Errorf("derive kept EXIF metadata.")
+	}
+
+	// small images are not upscaled
+	data, err = derive(testJpeg(t, 20, 10, 1), 320)
+	if err != nil {
+		t.Fatalf("derive failed, error: %v.", err)
+	}
+	cfg, _ = jpeg.DecodeConfig(bytes.NewReader(data))
+	if cfg.Width != 20 {
+		t.Errorf("derive upscaled image to width %d.", cfg.Width)
+	}
+}
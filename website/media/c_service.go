@@ -0,0 +1,203 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"time"
+
+	"website/session"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/sync/singleflight"
+)
+
+// number of background workers deriving variants
+const pipelineWorkers = 2
+
+// uploads waiting for derivation, further uploads are derived on first request
+const pipelineQueue = 64
+
+// largest accepted original, decoded to RGBA it takes 4 bytes per pixel
+// compressed formats declare far more pixels than their file size suggests, the budget stops decompression bombs
+const maxPixels = 50000000
+
+var ErrUnknownVariant = errors.New("Unknown variant")
+
+var ErrUnsupportedImage = errors.New("Unsupported image format")
+
+var ErrImageTooLarge = errors.New("Image has too many pixels")
+
+var ErrUnauthorized = errors.New("Active session required")
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// service interface defining all required methods
+type Service interface {
+	Upload(ctx context.Context, data []byte) (string, error)
+	Derivative(ctx context.Context, hash string, variant string) (string, error)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+	mediaStore MediaStore
+	sessions   Sessions
+	jobs       chan string
+	group      singleflight.Group
+	now        func() time.Time
+	logger     log.Logger
+}
+
+// service struct upload method
+// stores the original of a signed in user by content hash and queues the derivation of all variants
+func (s *service) Upload(ctx context.Context, data []byte) (string, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Upload")
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok || s.sessions == nil {
+		return "", ErrUnauthorized
+	}
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	// only accept formats the pipeline can decode, the header declares the size before anything is decompressed
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		level.Error(logger).Log("image.DecodeConfig:", err)
+		return "", ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return "", ErrUnsupportedImage
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return "", ErrImageTooLarge
+	}
+
+	// content hash identifies the artwork, identical uploads are stored once
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if !s.mediaStore.HasOriginal(hash) {
+		if err := s.mediaStore.WriteOriginal(hash, data); err != nil {
+			level.Error(logger).Log("s.mediaStore.WriteOriginal:", err)
+			return "", err
+		}
+	}
+
+	// hand over to background pipeline, skip if queue is full
+	select {
+	case s.jobs <- hash:
+	default:
+		level.Info(logger).Log("msg", "pipeline queue full, deriving on request", "hash", hash)
+	}
+
+	return hash, nil
+}
+
+// service struct derivative method
+// returns the file location of a variant, derives it synchronously if the pipeline has not caught up yet
+func (s *service) Derivative(ctx context.Context, hash string, variant string) (string, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Derivative")
+
+	if _, ok := Variants[variant]; !ok {
+		return "", ErrUnknownVariant
+	}
+
+	if path, err := s.mediaStore.VariantPath(hash, variant); err == nil {
+		return path, nil
+	}
+
+	if err := s.deriveAll(hash); err != nil {
+		level.Error(logger).Log("s.deriveAll:", err)
+		return "", err
+	}
+
+	return s.mediaStore.VariantPath(hash, variant)
+}
+
+// derives every missing variant of an original
+// concurrent calls for the same hash share one derivation
+func (s *service) deriveAll(hash string) error {
+
+	_, err, _ := s.group.Do(hash, func() (interface{}, error) {
+
+		original, err := s.mediaStore.ReadOriginal(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		for variant, width := range Variants {
+
+			// cached by content hash, nothing to do
+			if _, err := s.mediaStore.VariantPath(hash, variant); err == nil {
+				continue
+			}
+
+			data, err := derive(original, width)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.mediaStore.WriteVariant(hash, variant, data); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// background worker processing uploaded originals until the context is cancelled
+func (s *service) work(ctx context.Context) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "work")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case hash := <-s.jobs:
+			if err := s.deriveAll(hash); err != nil {
+				level.Error(logger).Log("s.deriveAll:", err, "hash", hash)
+				continue
+			}
+			level.Info(logger).Log("msg", "variants derived", "hash", hash)
+		}
+	}
+}
+
+// initialization function to return service struct
+// starts the background pipeline which stops when ctx is cancelled
+// uploads require an active session of sessions
+func NewService(ctx context.Context, mediaStore MediaStore, sessions Sessions, logger log.Logger) Service {
+
+	s := &service{
+		mediaStore: mediaStore,
+		sessions:   sessions,
+		jobs:       make(chan string, pipelineQueue),
+		now:        time.Now,
+		logger:     logger,
+	}
+
+	for i := 0; i < pipelineWorkers; i++ {
+		go s.work(ctx)
+	}
+
+	return s
+}
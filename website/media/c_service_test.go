@@ -0,0 +1,76 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"website/session"
+
+	"github.com/go-kit/kit/log"
+)
+
+// sessions of fixed users, the session identifier is the file hash
+type staticSessions map[string]time.Time
+
+func (ss staticSessions) ReadSession(sessionId string) (session.Session, error) {
+	return session.Session{FileHash: sessionId, ExpiresAt: ss[sessionId]}, nil
+}
+
+// signature and header chunk of a png declaring w x h pixels, enough for image.DecodeConfig
+func testPngHeader(w, h uint32) []byte {
+
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12], ihdr[13] = 8, 2 // 8 bit rgb
+
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&out, binary.BigEndian, uint32(13))
+	out.Write(ihdr)
+	binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return out.Bytes()
+}
+
+func TestUpload(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMediaStore(MediaStoreConfig{MediaPath: t.TempDir()}, log.NewNopLogger())
+	s := NewService(ctx, store, staticSessions{"artist": now.Add(time.Hour), "expired": now.Add(-time.Hour)}, log.NewNopLogger()).(*service)
+	s.now = func() time.Time { return now }
+
+	withSession := func(sessionId string) context.Context {
+		return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), sessionId)
+	}
+	artwork := testJpeg(t, 8, 4, 1)
+
+	for _, sessionCtx := range []context.Context{context.Background(), withSession("expired")} {
+		if _, err := s.Upload(sessionCtx, artwork); err != ErrUnauthorized {
+			t.Errorf("Upload returned %v, expected %v.", err, ErrUnauthorized)
+		}
+	}
+
+	// a few bytes declaring 60000 x 60000 pixels are refused before anything is decoded
+	if _, err := s.Upload(withSession("artist"), testPngHeader(60000, 60000)); err != ErrImageTooLarge {
+		t.Errorf("Upload of a decompression bomb returned %v, expected %v.", err, ErrImageTooLarge)
+	}
+	if _, err := s.Upload(withSession("artist"), []byte("not an image")); err != ErrUnsupportedImage {
+		t.Errorf("Upload of invalid data returned %v, expected %v.", err, ErrUnsupportedImage)
+	}
+
+	hash, err := s.Upload(withSession("artist"), artwork)
+	if err != nil {
+		t.Fatalf("Upload failed, error: %v.", err)
+	}
+	if !store.HasOriginal(hash) {
+		t.Errorf("original %s not stored.", hash)
+	}
+}
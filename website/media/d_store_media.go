@@ -0,0 +1,171 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******* Media store interface *********
+
+var ErrMediaNotFound = errors.New("Media not found")
+
+// content hashes are lowercase hex encoded sha256 sums
+// validated before every file access to prevent path traversal
+var hashPattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
+type MediaStoreConfig struct {
+	MediaPath string
+}
+
+type MediaStore interface {
+	WriteOriginal(hash string, data []byte) error
+	ReadOriginal(hash string) ([]byte, error)
+	HasOriginal(hash string) bool
+	WriteVariant(hash string, variant string, data []byte) error
+	VariantPath(hash string, variant string) (string, error)
+}
+
+type mediaStore struct {
+	mu     sync.RWMutex
+	config MediaStoreConfig
+	logger log.Logger
+}
+
+// stores the untouched upload, never served to clients because it may contain metadata
+func (ms *mediaStore) WriteOriginal(hash string, data []byte) error {
+
+	// log level
+	logger := log.With(ms.logger, "method", "WriteOriginal")
+
+	if !hashPattern.MatchString(hash) {
+		return ErrMediaNotFound
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// create content hash folder
+	if err := os.MkdirAll(getMediaDir(hash, ms.config.MediaPath), 0755); err != nil {
+		level.Error(logger).Log("os.MkdirAll:", err)
+		return err
+	}
+
+	if err := writeFileAtomic(getMediaPath(hash, ms.config.MediaPath, "original"), data); err != nil {
+		level.Error(logger).Log("writeFileAtomic:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (ms *mediaStore) ReadOriginal(hash string) ([]byte, error) {
+
+	// log level
+	logger := log.With(ms.logger, "method", "ReadOriginal")
+
+	if !hashPattern.MatchString(hash) {
+		return nil, ErrMediaNotFound
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	data, err := os.ReadFile(getMediaPath(hash, ms.config.MediaPath, "original"))
+	if os.IsNotExist(err) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		level.Error(logger).Log("os.ReadFile:", err)
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (ms *mediaStore) HasOriginal(hash string) bool {
+
+	if !hashPattern.MatchString(hash) {
+		return false
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, err := os.Stat(getMediaPath(hash, ms.config.MediaPath, "original"))
+	return err == nil
+}
+
+func (ms *mediaStore) WriteVariant(hash, variant string, data []byte) error {
+
+	// log level
+	logger := log.With(ms.logger, "method", "WriteVariant")
+
+	if !hashPattern.MatchString(hash) {
+		return ErrMediaNotFound
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := writeFileAtomic(getMediaPath(hash, ms.config.MediaPath, variant+".jpg"), data); err != nil {
+		level.Error(logger).Log("writeFileAtomic:", err)
+		return err
+	}
+
+	return nil
+}
+
+// returns the file location of a derived variant if it has been generated already
+func (ms *mediaStore) VariantPath(hash, variant string) (string, error) {
+
+	if !hashPattern.MatchString(hash) {
+		return "", ErrMediaNotFound
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	path := getMediaPath(hash, ms.config.MediaPath, variant+".jpg")
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrMediaNotFound
+	}
+
+	return path, nil
+}
+
+func NewMediaStore(config MediaStoreConfig, logger log.Logger) MediaStore {
+	return &mediaStore{
+		config: config,
+		logger: logger,
+	}
+}
+
+// ******* utils functions ********
+
+// writes into a temporary file first so that readers never see partially written images
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func getMediaDir(hash, mediaPath string) string {
+	return filepath.Join(mediaPath, hash)
+}
+
+func getMediaPath(hash, mediaPath, name string) string {
+	var b bytes.Buffer
+	b.WriteString(getMediaDir(hash, mediaPath))
+	b.WriteString(string(filepath.Separator))
+	b.WriteString(name)
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ******** Merkle tree compatible with OpenZeppelin **********
+
+var ErrNoLeaves = errors.New("Merkle tree requires at least one leaf")
+
+var ErrDuplicateLeaf = errors.New("Duplicate Merkle leaf")
+
+var ErrLeafNotFound = errors.New("Leaf is not part of the Merkle tree")
+
+// allowlist value, an address with a token id or quantity
+// encoded like OpenZeppelin's StandardMerkleTree with types ["address", "uint256"]
+type Leaf struct {
+	Address common.Address
+	Value   *big.Int
+}
+
+// Hash of a leaf, keccak256(bytes.concat(keccak256(abi.encode(address, value))))
+// the double hash keeps leaves from being confused with inner nodes
+// a contract verifies a claim of msg.sender with
+// MerkleProof.verify(proof, root, keccak256(bytes.concat(keccak256(abi.encode(msg.sender, value)))))
+func (l Leaf) Hash() common.Hash {
+	value := l.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	encoded := append(common.LeftPadBytes(l.Address[:], 32), common.LeftPadBytes(value.Bytes(), 32)...)
+	return crypto.Keccak256Hash(crypto.Keccak256(encoded))
+}
+
+// Tree laid out like OpenZeppelin's StandardMerkleTree
+// leaves are sorted by hash and stored at the end of a complete binary tree in an array,
+// so roots and proofs match the ones generated by OpenZeppelin's JavaScript library
+type Tree struct {
+	nodes []common.Hash
+	// tree index of every leaf hash
+	index map[common.Hash]int
+}
+
+// NewTree builds the tree of a non-empty set of leaves
+func NewTree(leaves []Leaf) (*Tree, error) {
+
+	if len(leaves) == 0 {
+		return nil, ErrNoLeaves
+	}
+
+	hashes := make([]common.Hash, len(leaves))
+	for i, l := range leaves {
+		hashes[i] = l.Hash()
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	t := &Tree{
+		nodes: make([]common.Hash, 2*len(hashes)-1),
+		index: make(map[common.Hash]int, len(hashes)),
+	}
+
+	for i, h := range hashes {
+		if _, ok := t.index[h]; ok {
+			return nil, ErrDuplicateLeaf
+		}
+		pos := len(t.nodes) - 1 - i
+		t.nodes[pos] = h
+		t.index[h] = pos
+	}
+	for i := len(t.nodes) - 1 - len(hashes); i >= 0; i-- {
+		t.nodes[i] = hashPair(t.nodes[2*i+1], t.nodes[2*i+2])
+	}
+
+	return t, nil
+}
+
+// Root of the tree
+func (t *Tree) Root() common.Hash {
+	return t.nodes[0]
+}
+
+// Proof of a leaf, sibling hashes from the leaf up to the root
+func (t *Tree) Proof(l Leaf) ([]common.Hash, error) {
+
+	i, ok := t.index[l.Hash()]
+	if !ok {
+		return nil, ErrLeafNotFound
+	}
+
+	proof := []common.Hash{}
+	for i > 0 {
+		sibling := i + 1
+		if i%2 == 0 {
+			sibling = i - 1
+		}
+		proof = append(proof, t.nodes[sibling])
+		i = (i - 1) / 2
+	}
+
+	return proof, nil
+}
+
+// Verify a proof like OpenZeppelin's MerkleProof.verify
+func Verify(proof []common.Hash, root common.Hash, leaf common.Hash) bool {
+	computed := leaf
+	for _, p := range proof {
+		computed = hashPair(computed, p)
+	}
+	return computed == root
+}
+
+// keccak256 of the sorted pair, the order of siblings does not matter
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
@@ -0,0 +1,162 @@
+package merkle
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// leaves of n distinct addresses
+func testLeaves(n int) []Leaf {
+	leaves := make([]Leaf, n)
+	for i := range leaves {
+		leaves[i] = Leaf{Address: common.BigToAddress(big.NewInt(int64(1000 + i))), Value: big.NewInt(int64(i + 1))}
+	}
+	return leaves
+}
+
+// leaf encoding must equal abi.encode(address, uint256)
+func TestLeafHash(t *testing.T) {
+
+	addressType, _ := abi.NewType("address", "", nil)
+	uintType, _ := abi.NewType("uint256", "", nil)
+	args := abi.Arguments{{Type: addressType}, {Type: uintType}}
+
+	l := Leaf{Address: common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), Value: big.NewInt(42)}
+	encoded, err := args.Pack(l.Address, l.Value)
+	if err != nil {
+		t.Fatalf("Pack failed, error: %v.", err)
+	}
+
+	if want := crypto.Keccak256Hash(crypto.Keccak256(encoded)); l.Hash() != want {
+		t.Errorf("leaf hash %s, expected %s.", l.Hash().Hex(), want.Hex())
+	}
+}
+
+func TestTreeProofs(t *testing.T) {
+
+	for n := 1; n <= 17; n++ {
+
+		leaves := testLeaves(n)
+		tree, err := NewTree(leaves)
+		if err != nil {
+			t.Fatalf("NewTree failed, error: %v.", err)
+		}
+
+		for _, l := range leaves {
+			proof, err := tree.Proof(l)
+			if err != nil {
+				t.Fatalf("Proof failed, error: %v.", err)
+			}
+			if !Verify(proof, tree.Root(), l.Hash()) {
+				t.Errorf("proof of %d leaves does not verify.", n)
+			}
+
+			// another value for the same address
+			forged := Leaf{Address: l.Address, Value: new(big.Int).Add(l.Value, big.NewInt(1))}
+			if Verify(proof, tree.Root(), forged.Hash()) {
+				t.Errorf("forged leaf verifies.")
+			}
+		}
+	}
+
+	if _, err := NewTree(nil); err != ErrNoLeaves {
+		t.Errorf("NewTree returned %v, expected %v.", err, ErrNoLeaves)
+	}
+	leaves := testLeaves(2)
+	if _, err := NewTree(append(leaves, leaves[0])); err != ErrDuplicateLeaf {
+		t.Errorf("NewTree returned %v, expected %v.", err, ErrDuplicateLeaf)
+	}
+	tree, _ := NewTree(leaves)
+	if _, err := tree.Proof(testLeaves(3)[2]); err != ErrLeafNotFound {
+		t.Errorf("Proof returned %v, expected %v.", err, ErrLeafNotFound)
+	}
+}
+
+// StandardMerkleTree layout, sorted leaves fill the array from the end
+func TestTreeLayout(t *testing.T) {
+
+	leaves := testLeaves(3)
+	tree, _ := NewTree(leaves)
+
+	var h []common.Hash
+	for _, l := range leaves {
+		h = append(h, l.Hash())
+	}
+	sort.Slice(h, func(i, j int) bool { return bytes.Compare(h[i][:], h[j][:]) < 0 })
+
+	// nodes: [root, n1, h2, h1, h0], n1 = hash(h1, h0)
+	pair := func(a, b common.Hash) common.Hash {
+		if bytes.Compare(a[:], b[:]) > 0 {
+			a, b = b, a
+		}
+		return crypto.Keccak256Hash(a[:], b[:])
+	}
+	if want := pair(pair(h[1], h[0]), h[2]); tree.Root() != want {
+		t.Errorf("root %s, expected %s.", tree.Root().Hex(), want.Hex())
+	}
+
+	// golden values of StandardMerkleTree.of(values, ["address", "uint256"])
+	// computed with a separate Python implementation of Keccak-256, checked against hashlib.sha3_256 and keccak256(""),
+	// following the library's algorithm: double hashed abi encoded leaves sorted by hash, filled into the array from the end,
+	// pairs hashed in sorted order; the two leaf root is the one printed by the example in the library's README
+	ether := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	golden := []Leaf{
+		{Address: common.HexToAddress("0x1111111111111111111111111111111111111111"), Value: new(big.Int).Mul(big.NewInt(5), ether)},
+		{Address: common.HexToAddress("0x2222222222222222222222222222222222222222"), Value: new(big.Int).Div(new(big.Int).Mul(big.NewInt(5), ether), big.NewInt(2))},
+		{Address: common.HexToAddress("0x3333333333333333333333333333333333333333"), Value: ether},
+		{Address: common.HexToAddress("0x4444444444444444444444444444444444444444"), Value: big.NewInt(42)},
+		{Address: common.HexToAddress("0x5555555555555555555555555555555555555555"), Value: big.NewInt(1)},
+	}
+	for _, c := range []struct {
+		leaves int
+		root   string
+	}{
+		{2, "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"},
+		{5, "0xbf75c4f6c7ebea01f1d82d2d5bbd8cc8c2ab2f0ce2b0f7f7d6c5ddcb673abb1f"},
+	} {
+		if tree, _ := NewTree(golden[:c.leaves]); tree.Root().Hex() != c.root {
+			t.Errorf("root of %d leaves %s, expected %s.", c.leaves, tree.Root().Hex(), c.root)
+		}
+	}
+	tree, _ = NewTree(golden)
+	for _, c := range []struct {
+		leaf  Leaf
+		hash  string
+		proof []string
+	}{
+		{golden[0], "0xeb02c421cfa48976e66dfb29120745909ea3a0f843456c263cf8f1253483e283", []string{
+			"0xecd598ccee6e7fba75e231d9b838abc3c7254281c055997a9b9b29c38ab76a73",
+			"0x36a4737d5cf925b6a812d376c062ec9d663d9f18284285d3a3ffc62ab747ebbb",
+		}},
+		{golden[3], "0x96d79d91ee415e8cfdc1631afaa11da52af8770840341365c54e0ecf33ddf931", []string{
+			"0x93295d0cc4b1f2338236c6d8909f0ee632bd0e2a8a1c4237539f42cf6d8e42c8",
+			"0xeb02c421cfa48976e66dfb29120745909ea3a0f843456c263cf8f1253483e283",
+			"0x36a4737d5cf925b6a812d376c062ec9d663d9f18284285d3a3ffc62ab747ebbb",
+		}},
+	} {
+		if c.leaf.Hash().Hex() != c.hash {
+			t.Errorf("leaf hash %s, expected %s.", c.leaf.Hash().Hex(), c.hash)
+		}
+		proof, err := tree.Proof(c.leaf)
+		if err != nil || len(proof) != len(c.proof) {
+			t.Fatalf("Proof returned %v, error: %v, expected %v.", proof, err, c.proof)
+		}
+		for i := range proof {
+			if proof[i].Hex() != c.proof[i] {
+				t.Errorf("proof hash %d is %s, expected %s.", i, proof[i].Hex(), c.proof[i])
+			}
+		}
+	}
+
+	single, _ := NewTree(leaves[:1])
+	proof, _ := single.Proof(leaves[0])
+	if single.Root() != leaves[0].Hash() || len(proof) != 0 {
+		t.Errorf("single leaf tree has root %s and %d proof hashes.", single.Root().Hex(), len(proof))
+	}
+}
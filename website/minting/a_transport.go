@@ -0,0 +1,126 @@
+package minting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// years projected when the query does not say
+const defaultYears = 10
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidAmount),
+		errors.Is(err, ErrInvalidYears),
+		errors.Is(err, ErrExceedsCap),
+		errors.Is(err, ErrWouldRevert):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decode optional years from query parameters
+func decodeReadSchedule(_ context.Context, r *http.Request) (interface{}, error) {
+
+	req := ReadScheduleRequest{Years: defaultYears}
+	if s := r.URL.Query().Get("years"); s != "" {
+		years, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		req.Years = years
+	}
+	return req, nil
+}
+
+// decode destination and amount from body
+func decodePrepareMint(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req PrepareMintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching minting handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	readScheduleHandler := httptransport.NewServer(
+		e.ReadSchedule,
+		decodeReadSchedule,
+		encodeResponse,
+		options...,
+	)
+
+	prepareMintHandler := httptransport.NewServer(
+		e.PrepareMint,
+		decodePrepareMint,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/token/minting", readScheduleHandler).Methods("GET")
+	router.Handle("/token/minting/transactions", prepareMintHandler).Methods("POST")
+
+	return router
+}
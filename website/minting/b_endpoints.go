@@ -0,0 +1,85 @@
+package minting
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ReadScheduleRequest struct {
+	Years int
+}
+
+type PrepareMintRequest struct {
+	Dst    string `json:"dst"`
+	Amount string `json:"amount"`
+}
+
+type ScheduleResponse struct {
+	Data Schedule `json:"data"`
+	Err  error    `json:"errors"`
+}
+
+// have ScheduleResponse follow the customError interface defined in a_transport.go
+func (r ScheduleResponse) error() error { return r.Err }
+
+type MintTxResponse struct {
+	Data MintTx `json:"data"`
+	Err  error  `json:"errors"`
+}
+
+// have MintTxResponse follow the customError interface defined in a_transport.go
+func (r MintTxResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	ReadSchedule endpoint.Endpoint
+	PrepareMint  endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		ReadSchedule: epReadSchedule(s),
+		PrepareMint:  epPrepareMint(s),
+	}
+}
+
+// read mint schedule endpoint
+func epReadSchedule(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadScheduleRequest)
+
+		// call service method
+		schedule, err := s.ReadSchedule(ctx, req.Years)
+		if err != nil {
+			return ScheduleResponse{Err: err}, err
+		}
+
+		return ScheduleResponse{Data: schedule, Err: nil}, nil
+	}
+}
+
+// prepare unsigned mint transaction endpoint
+func epPrepareMint(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(PrepareMintRequest)
+
+		// call service method
+		tx, err := s.PrepareMint(ctx, req.Dst, req.Amount)
+		if err != nil {
+			return MintTxResponse{Err: err}, err
+		}
+
+		return MintTxResponse{Data: tx, Err: nil}, nil
+	}
+}
@@ -0,0 +1,228 @@
+package minting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"website/artmodel"
+	"website/chain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidAmount = errors.New("Amount must be a positive decimal integer")
+
+var ErrInvalidYears = errors.New("Years must be between 0 and 100")
+
+var ErrExceedsCap = errors.New("Amount exceeds what the next mint allows")
+
+var ErrWouldRevert = errors.New("Mint would revert")
+
+// longest projection
+const maxYears = 100
+
+// one projected mint, at is the earliest timestamp and supply the total supply after it
+// amounts are decimal strings in the token's smallest unit
+type Mint struct {
+	Year   int    `json:"year"`
+	At     uint64 `json:"at"`
+	Amount string `json:"amount"`
+	Supply string `json:"supply"`
+}
+
+// what mint allows as of a block, times are unix seconds of the chain
+// the projection mints the maximum at the earliest time every year, a later mint shifts all following ones
+type Schedule struct {
+	Block               uint64 `json:"block"`
+	Time                uint64 `json:"time"`
+	Minter              string `json:"minter"`
+	TotalSupply         string `json:"total_supply"`
+	MintingAllowedAfter uint64 `json:"minting_allowed_after"`
+	MintableNow         bool   `json:"mintable_now"`
+	NextMintAt          uint64 `json:"next_mint_at"`
+	MaxMint             string `json:"max_mint"`
+	Projection          []Mint `json:"projection"`
+}
+
+// unsigned mint call for the minter, usually a multisig
+// to, value and data are what a multisig transaction proposal needs
+type MintTx struct {
+	ChainId         string `json:"chain_id"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Value           string `json:"value"`
+	Data            string `json:"data"`
+	Method          string `json:"method"`
+	Dst             string `json:"dst"`
+	Amount          string `json:"amount"`
+	ExecutableAfter uint64 `json:"executable_after"`
+}
+
+// service interface defining all required methods
+type Service interface {
+	ReadSchedule(ctx context.Context, years int) (Schedule, error)
+	PrepareMint(ctx context.Context, dst string, amount string) (MintTx, error)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+	chain   chain.Client
+	chainId *big.Int
+	logger  log.Logger
+}
+
+// largest amount the contract accepts at a supply: the cap, unless the supply would exceed 96 bits
+func MaxMint(supply *big.Int) *big.Int {
+	max := new(big.Int).Div(new(big.Int).Mul(supply, big.NewInt(artmodel.MintCap)), big.NewInt(100))
+	if room := new(big.Int).Sub(artmodel.MaxUint96, supply); room.Cmp(max) < 0 {
+		return room
+	}
+	return max
+}
+
+// Project mints the maximum every minimum_time_between_mints starting at first
+func Project(supply *big.Int, first uint64, years int) []Mint {
+
+	mints := make([]Mint, 0, years)
+	supply = new(big.Int).Set(supply)
+	at := first
+	for year := 1; year <= years; year++ {
+		amount := MaxMint(supply)
+		supply.Add(supply, amount)
+		mints = append(mints, Mint{Year: year, At: at, Amount: amount.String(), Supply: supply.String()})
+		at += artmodel.MinimumTimeBetweenMints
+	}
+	return mints
+}
+
+// service struct read schedule method
+// all contract values are read at the head block, so they are consistent with each other
+func (s *service) ReadSchedule(ctx context.Context, years int) (Schedule, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadSchedule")
+
+	if years < 0 || years > maxYears {
+		return Schedule{}, ErrInvalidYears
+	}
+
+	head, err := s.chain.Backend().HeaderByNumber(ctx, nil)
+	if err != nil {
+		level.Error(logger).Log("s.chain.Backend().HeaderByNumber:", err)
+		return Schedule{}, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: head.Number}
+	art := s.chain.Art()
+
+	supply, err := art.TotalSupply(opts)
+	if err != nil {
+		level.Error(logger).Log("art.TotalSupply:", err)
+		return Schedule{}, err
+	}
+	after, err := art.MintingAllowedAfter(opts)
+	if err != nil {
+		level.Error(logger).Log("art.MintingAllowedAfter:", err)
+		return Schedule{}, err
+	}
+	minter, err := art.Minter(opts)
+	if err != nil {
+		level.Error(logger).Log("art.Minter:", err)
+		return Schedule{}, err
+	}
+	if !after.IsUint64() {
+		return Schedule{}, fmt.Errorf("minting allowed after %v does not fit 64 bits", after)
+	}
+
+	next := after.Uint64()
+	if head.Time > next {
+		next = head.Time
+	}
+	return Schedule{
+		Block:               head.Number.Uint64(),
+		Time:                head.Time,
+		Minter:              strings.ToLower(minter.Hex()),
+		TotalSupply:         supply.String(),
+		MintingAllowedAfter: after.Uint64(),
+		MintableNow:         head.Time >= after.Uint64(),
+		NextMintAt:          next,
+		MaxMint:             MaxMint(supply).String(),
+		Projection:          Project(supply, next, years),
+	}, nil
+}
+
+// service struct prepare mint method
+// checks the amount against the cap, once minting is allowed the call is dry run from the minter
+func (s *service) PrepareMint(ctx context.Context, dst string, amount string) (MintTx, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "PrepareMint")
+
+	if !common.IsHexAddress(dst) || common.HexToAddress(dst) == (common.Address{}) {
+		return MintTx{}, ErrInvalidAddress
+	}
+	to := common.HexToAddress(dst)
+	value, ok := new(big.Int).SetString(strings.TrimSpace(amount), 10)
+	if !ok || value.Sign() <= 0 {
+		return MintTx{}, ErrInvalidAmount
+	}
+
+	schedule, err := s.ReadSchedule(ctx, 0)
+	if err != nil {
+		return MintTx{}, err
+	}
+	max, _ := new(big.Int).SetString(schedule.MaxMint, 10)
+	if value.Cmp(max) > 0 {
+		return MintTx{}, ErrExceedsCap
+	}
+
+	art := s.chain.Art()
+	data, err := art.Pack("mint", to, value)
+	if err != nil {
+		level.Error(logger).Log("art.Pack:", err)
+		return MintTx{}, err
+	}
+
+	if schedule.MintableNow {
+		address := art.Address()
+		call := ethereum.CallMsg{From: common.HexToAddress(schedule.Minter), To: &address, Data: data}
+		if _, err := s.chain.Backend().CallContract(ctx, call, new(big.Int).SetUint64(schedule.Block)); err != nil {
+			if strings.Contains(err.Error(), "execution reverted") {
+				return MintTx{}, fmt.Errorf("%w: %v", ErrWouldRevert, err)
+			}
+			level.Error(logger).Log("s.chain.Backend().CallContract:", err)
+			return MintTx{}, err
+		}
+	}
+
+	return MintTx{
+		ChainId:         s.chainId.String(),
+		From:            schedule.Minter,
+		To:              strings.ToLower(art.Address().Hex()),
+		Value:           "0",
+		Data:            hexutil.Encode(data),
+		Method:          "mint(address,uint256)",
+		Dst:             strings.ToLower(to.Hex()),
+		Amount:          value.String(),
+		ExecutableAfter: schedule.NextMintAt,
+	}, nil
+}
+
+// initialization function to return service struct
+// this function should is called in main.go
+func NewService(client chain.Client, chainId *big.Int, logger log.Logger) Service {
+	return &service{
+		chain:   client,
+		chainId: chainId,
+		logger:  logger,
+	}
+}
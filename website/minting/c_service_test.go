@@ -0,0 +1,129 @@
+package minting
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"website/artmodel"
+	"website/chain/chaintest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-kit/kit/log"
+)
+
+const day = 24 * 3600
+
+func decimal(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+func TestSchedule(t *testing.T) {
+
+	ctx := context.Background()
+	holder, minter := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	dst := common.HexToAddress("0x00000000000000000000000000000000000000d5")
+
+	sim := chaintest.NewBackend(t)
+	genesis, _ := sim.HeaderByNumber(ctx, nil)
+	after := genesis.Time + 100*day
+	client := chaintest.DeployArtOn(t, sim, holder, minter.Addr, new(big.Int).SetUint64(after))
+	address := client.Art().Address()
+	deployed, _ := sim.HeaderByNumber(ctx, nil)
+	s := NewService(client, chaintest.ChainId, log.NewNopLogger())
+
+	schedule, err := s.ReadSchedule(ctx, 3)
+	if err != nil {
+		t.Fatalf("ReadSchedule failed, error: %v.", err)
+	}
+	if schedule.MintableNow || schedule.NextMintAt != after || schedule.MaxMint != "20000000000000000000000000" || len(schedule.Projection) != 3 {
+		t.Fatalf("schedule %+v, expected 2%% of the initial supply after %d.", schedule, after)
+	}
+	if _, err := s.ReadSchedule(ctx, 101); err != ErrInvalidYears {
+		t.Errorf("ReadSchedule returned %v, expected %v.", err, ErrInvalidYears)
+	}
+
+	// the projection is exactly what the contract allows: the projected mints pass, one more token does not
+	model, _ := artmodel.New(address, chaintest.ChainId, holder.Addr, minter.Addr, new(big.Int).SetUint64(after), artmodel.Block{Number: 1, Time: deployed.Time})
+	for i, m := range schedule.Projection {
+		b := artmodel.Block{Number: uint64(i + 2), Time: m.At}
+		amount := decimal(m.Amount)
+		if err := model.Mint(minter.Addr, dst, new(big.Int).Add(amount, big.NewInt(1)), b); err == nil {
+			t.Errorf("year %d: mint above %s passed.", m.Year, m.Amount)
+		}
+		if err := model.Mint(minter.Addr, dst, amount, b); err != nil {
+			t.Errorf("year %d: projected mint failed, error: %v.", m.Year, err)
+		}
+		if model.TotalSupply().String() != m.Supply {
+			t.Errorf("year %d: supply %v, projected %s.", m.Year, model.TotalSupply(), m.Supply)
+		}
+	}
+
+	cases := []struct {
+		dst    string
+		amount string
+		err    error
+	}{
+		{"0x0000000000000000000000000000000000000000", "1", ErrInvalidAddress},
+		{dst.Hex(), "-1", ErrInvalidAmount},
+		{dst.Hex(), "1e18", ErrInvalidAmount},
+		{dst.Hex(), "20000000000000000000000001", ErrExceedsCap},
+	}
+	for _, c := range cases {
+		if _, err := s.PrepareMint(ctx, c.dst, c.amount); err != c.err {
+			t.Errorf("PrepareMint(%s, %s) returned %v, expected %v.", c.dst, c.amount, err, c.err)
+		}
+	}
+
+	// prepared ahead of time for the multisig to queue
+	tx, err := s.PrepareMint(ctx, dst.Hex(), schedule.MaxMint)
+	if err != nil {
+		t.Fatalf("PrepareMint failed, error: %v.", err)
+	}
+	if tx.ExecutableAfter != after || tx.From != schedule.Minter || tx.Value != "0" {
+		t.Errorf("mint transaction %+v, expected the minter after %d.", tx, after)
+	}
+
+	// once allowed, the prepared call executes from the minter
+	sim.AdjustTime(100 * day * time.Second)
+	sim.Commit()
+	if schedule, _ = s.ReadSchedule(ctx, 0); !schedule.MintableNow {
+		t.Fatalf("schedule %+v, expected mintable.", schedule)
+	}
+	if tx, err = s.PrepareMint(ctx, dst.Hex(), schedule.MaxMint); err != nil {
+		t.Fatalf("PrepareMint failed, error: %v.", err)
+	}
+	to := common.HexToAddress(tx.To)
+	signed, _ := types.SignNewTx(minter.Key, types.LatestSignerForChainID(chaintest.ChainId), &types.DynamicFeeTx{
+		ChainID: chaintest.ChainId, GasTipCap: big.NewInt(1000000000), GasFeeCap: big.NewInt(3000000000), Gas: 100000, To: &to, Data: hexutil.MustDecode(tx.Data),
+	})
+	if err := sim.SendTransaction(ctx, signed); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	sim.Commit()
+	if receipt, _ := sim.TransactionReceipt(ctx, signed.Hash()); receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt %+v, expected a successful mint.", receipt)
+	}
+
+	schedule, _ = s.ReadSchedule(ctx, 1)
+	if schedule.TotalSupply != "1020000000000000000000000000" || schedule.MintableNow || schedule.NextMintAt != schedule.Time+365*day {
+		t.Errorf("schedule %+v, expected the next mint a year after the last.", schedule)
+	}
+}
+
+// close to 2^96 the supply limit is tighter than the cap
+func TestMaxMint(t *testing.T) {
+
+	supply := new(big.Int).Sub(artmodel.MaxUint96, big.NewInt(5))
+	if max := MaxMint(supply); max.Int64() != 5 {
+		t.Errorf("max mint %v, expected 5.", max)
+	}
+	mints := Project(supply, 100, 2)
+	if mints[1].Amount != "0" || mints[1].At != 100+artmodel.MinimumTimeBetweenMints || mints[1].Supply != artmodel.MaxUint96.String() {
+		t.Errorf("projection %+v, expected nothing left to mint in the second year.", mints)
+	}
+}
@@ -0,0 +1,270 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// reconnect delay suggested to event source clients
+const retryMillis = 3000
+
+// time allowed to write a message to a websocket client
+const writeWait = 10 * time.Second
+
+// event types sent by the transport itself
+const (
+	// events after the client's last event id are lost, the client must reload its state
+	typeReset = "reset"
+	// the client fell behind and is disconnected, it resumes by reconnecting with its last event id
+	typeLagged = "lagged"
+)
+
+// same origin websocket upgrades only
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// encodes error to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errorStatusCode(err))
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// maps predefined errors to status codes
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidTopic),
+		errors.Is(err, ErrNoTopics):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrTooManySubscribers):
+		return http.StatusServiceUnavailable
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// subscribes to the topic query parameters
+// the last event id is read from the Last-Event-ID header sent by reconnecting event sources, or the last_event_id parameter
+func subscribe(h Hub, r *http.Request) (*Subscription, error) {
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("last_event_id")
+	}
+
+	var last uint64
+	if lastEventId != "" {
+		id, err := strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		last = id
+	}
+
+	return h.Subscribe(r.URL.Query()["topic"], last)
+}
+
+// defines multiplexer routes
+// events are streamed by plain handlers as they are long lived
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, h Hub, heartbeat time.Duration, logger log.Logger) http.Handler {
+
+	router.HandleFunc("/events", serveEvents(h, heartbeat, logger)).Methods("GET")
+	router.HandleFunc("/events/ws", serveWebSocket(h, heartbeat, logger)).Methods("GET")
+
+	return router
+}
+
+// streams events as server-sent events
+// a comment is sent every heartbeat to keep proxies from closing idle streams
+func serveEvents(h Hub, heartbeat time.Duration, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// log level
+		logger := log.With(logger, "handler", "serveEvents")
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			level.Error(logger).Log("msg", "response writer does not support flushing")
+			encodeError(r.Context(), ErrInternalServer, w)
+			return
+		}
+
+		sub, err := subscribe(h, r)
+		if err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		}
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+		if sub.Gap {
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", typeReset)
+		}
+		for _, e := range sub.Replay {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+
+			case <-r.Context().Done():
+				return
+
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case e, ok := <-sub.Events():
+				if !ok {
+					if sub.Lagged() {
+						fmt.Fprintf(w, "event: %s\ndata: {}\n\n", typeLagged)
+						flusher.Flush()
+					}
+					return
+				}
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writes an event in event stream format, the data line is the JSON encoded event
+func writeEvent(w http.ResponseWriter, e Event) error {
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+	return err
+}
+
+// streams events as JSON websocket messages, transport events carry no id
+// the connection is pinged every heartbeat and closed if the client does not answer within two heartbeats
+func serveWebSocket(h Hub, heartbeat time.Duration, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// log level
+		logger := log.With(logger, "handler", "serveWebSocket")
+
+		sub, err := subscribe(h, r)
+		if err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		}
+		defer sub.Close()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already replied with an error
+			level.Debug(logger).Log("upgrader.Upgrade:", err)
+			return
+		}
+		defer conn.Close()
+
+		// reads until the client goes away, clients do not send messages
+		done := make(chan struct{})
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+		})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		write := func(v interface{}) error {
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			return conn.WriteJSON(v)
+		}
+
+		if sub.Gap {
+			if err := write(Event{Type: typeReset}); err != nil {
+				return
+			}
+		}
+		for _, e := range sub.Replay {
+			if err := write(e); err != nil {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+
+			case <-done:
+				return
+
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+					return
+				}
+
+			case e, ok := <-sub.Events():
+				if !ok {
+					if sub.Lagged() {
+						write(Event{Type: typeLagged})
+					}
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, typeLagged), time.Now().Add(writeWait))
+					return
+				}
+				if err := write(e); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
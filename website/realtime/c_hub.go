@@ -0,0 +1,314 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ******** Publish/subscribe hub **********
+
+var ErrInvalidTopic = errors.New("Invalid topic")
+
+var ErrNoTopics = errors.New("At least one topic required")
+
+var ErrTooManySubscribers = errors.New("Too many subscribers")
+
+// collections/{id}, collections/{id}/tokens/{token id} and auctions/{id}
+var topicPattern = regexp.MustCompile("^(collections/[0-9a-f]{32}(/tokens/[0-9]+)?|auctions/[0-9a-f]{32})$")
+
+// topic of all events of a collection, including the auctions of its tokens
+func CollectionTopic(collectionId string) string {
+	return "collections/" + collectionId
+}
+
+// topic of a single NFT of a collection
+func TokenTopic(collectionId string, tokenId int) string {
+	return "collections/" + collectionId + "/tokens/" + strconv.Itoa(tokenId)
+}
+
+// topic of a single auction
+func AuctionTopic(auctionId string) string {
+	return "auctions/" + auctionId
+}
+
+type HubConfig struct {
+	// events kept per topic for clients resuming with their last event id
+	History int
+	// events queued per subscriber, a subscriber falling further behind is dropped
+	Buffer int
+	// subscribers at the same time, 0 for no limit
+	MaxSubscribers int
+	// how long a topic keeps its history after its last subscriber left, so that reconnecting clients resume
+	TTL time.Duration
+}
+
+// published event, ids increase across all topics of a hub
+type Event struct {
+	Id     uint64          `json:"id"`
+	Topics []string        `json:"topics"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+// Hub fans out events to the subscribers of their topics
+// publishing never blocks on subscribers, slow subscribers are dropped and resume with their last event id
+type Hub interface {
+	Publish(topics []string, typ string, data interface{}) (Event, error)
+	Subscribe(topics []string, lastEventId uint64) (*Subscription, error)
+}
+
+// events and subscribers of a topic
+type topic struct {
+	events []Event
+	// id of the newest event of the topic no longer in events
+	evicted     uint64
+	subscribers map[*Subscription]struct{}
+	// when the last subscriber left, or the first event was published without subscribers
+	idleSince time.Time
+}
+
+// whether the topic keeps no more history, caller holds h.mu
+func (t *topic) expired(now time.Time, ttl time.Duration) bool {
+	return len(t.subscribers) == 0 && now.Sub(t.idleSince) >= ttl
+}
+
+// topics without events only exist while they have subscribers, so that clients cannot grow the hub without bound
+// topics with events are kept, once idle for the ttl only with the id of their newest event to detect gaps
+type hub struct {
+	mu          sync.Mutex
+	seq         uint64
+	topics      map[string]*topic
+	subscribers int
+	config      HubConfig
+	now         func() time.Time
+}
+
+// Subscription receives the events of its topics published after it was created
+type Subscription struct {
+	// events after the last event id the subscriber resumed from, in publishing order
+	Replay []Event
+	// set if events after the last event id are no longer available, clients must reload their state
+	Gap bool
+
+	hub    *hub
+	topics []string
+	ch     chan Event
+	closed bool
+	lagged bool
+}
+
+// Events is closed when the subscription is closed or dropped
+func (sub *Subscription) Events() <-chan Event {
+	return sub.ch
+}
+
+// Lagged reports whether the subscription was dropped for falling behind
+func (sub *Subscription) Lagged() bool {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	return sub.lagged
+}
+
+// Close ends the subscription, closing twice is a no-op
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub)
+}
+
+// publishes an event to one or more topics
+// subscribers of several of the topics receive the event once, topics idle for the ttl keep no history
+func (h *hub) Publish(topics []string, typ string, data interface{}) (Event, error) {
+
+	if len(topics) == 0 {
+		return Event{}, ErrNoTopics
+	}
+	for _, t := range topics {
+		if !topicPattern.MatchString(t) {
+			return Event{}, ErrInvalidTopic
+		}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	h.expire(now)
+
+	h.seq++
+	event := Event{Id: h.seq, Topics: topics, Type: typ, Data: raw, At: now}
+
+	delivered := make(map[*Subscription]struct{})
+	for _, name := range topics {
+		t, ok := h.topics[name]
+		if !ok {
+			t = h.topic(name)
+			t.idleSince = now
+		}
+		if t.expired(now, h.config.TTL) {
+			t.evicted = event.Id
+			continue
+		}
+
+		t.events = append(t.events, event)
+		if len(t.events) > h.config.History {
+			t.evicted = t.events[0].Id
+			t.events = t.events[1:]
+		}
+
+		for sub := range t.subscribers {
+			if _, ok := delivered[sub]; ok {
+				continue
+			}
+			delivered[sub] = struct{}{}
+
+			select {
+			case sub.ch <- event:
+			default:
+				// back-pressure, the subscriber resumes from its last received event
+				sub.lagged = true
+				h.remove(sub)
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// subscribes to one or more topics
+// with a last event id, later events still in the history are replayed
+func (h *hub) Subscribe(topics []string, lastEventId uint64) (*Subscription, error) {
+
+	if len(topics) == 0 {
+		return nil, ErrNoTopics
+	}
+	for _, t := range topics {
+		if !topicPattern.MatchString(t) {
+			return nil, ErrInvalidTopic
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(h.now())
+
+	if h.config.MaxSubscribers > 0 && h.subscribers >= h.config.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	sub := &Subscription{
+		hub:    h,
+		topics: topics,
+		ch:     make(chan Event, h.config.Buffer),
+	}
+
+	if lastEventId > 0 {
+		// ids of an earlier process or evicted events cannot be replayed
+		if lastEventId > h.seq {
+			sub.Gap = true
+		}
+		seen := make(map[uint64]bool)
+		for _, name := range topics {
+			t, ok := h.topics[name]
+			if !ok {
+				// nothing was published to the topic
+				continue
+			}
+			if t.evicted > lastEventId {
+				sub.Gap = true
+			}
+			for _, e := range t.events {
+				if e.Id > lastEventId && !seen[e.Id] {
+					seen[e.Id] = true
+					sub.Replay = append(sub.Replay, e)
+				}
+			}
+		}
+		sort.Slice(sub.Replay, func(i, j int) bool {
+			return sub.Replay[i].Id < sub.Replay[j].Id
+		})
+	}
+
+	for _, name := range topics {
+		h.topic(name).subscribers[sub] = struct{}{}
+	}
+	h.subscribers++
+
+	return sub, nil
+}
+
+// topic by name, created by the first subscriber, caller holds h.mu
+func (h *hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{subscribers: make(map[*Subscription]struct{})}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// unregisters and closes a subscription, caller holds h.mu
+// a topic without events goes with its last subscriber, otherwise its history is kept for the ttl
+func (h *hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	for _, name := range sub.topics {
+		t, ok := h.topics[name]
+		if !ok || len(t.subscribers) == 0 {
+			continue
+		}
+		delete(t.subscribers, sub)
+		if len(t.subscribers) > 0 {
+			continue
+		}
+		t.idleSince = h.now()
+		if len(t.events) == 0 && t.evicted == 0 {
+			delete(h.topics, name)
+		}
+	}
+	h.subscribers--
+	close(sub.ch)
+}
+
+// drops the history of topics idle for the ttl, caller holds h.mu
+func (h *hub) expire(now time.Time) {
+	for _, t := range h.topics {
+		if n := len(t.events); n > 0 && t.expired(now, h.config.TTL) {
+			t.evicted = t.events[n-1].Id
+			t.events = nil
+		}
+	}
+}
+
+// initialization function to return hub struct
+// this function should is called in main.go
+func NewHub(config HubConfig) Hub {
+	if config.History <= 0 {
+		config.History = 256
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = 64
+	}
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+	return &hub{
+		topics: make(map[string]*topic),
+		config: config,
+		now:    time.Now,
+	}
+}
@@ -0,0 +1,272 @@
+package realtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	collectionId = "0123456789abcdef0123456789abcdef"
+	auctionId    = "fedcba9876543210fedcba9876543210"
+)
+
+// receives the next event or fails after a second
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	select {
+	case e, ok := <-sub.Events():
+		return e, ok
+	case <-time.After(time.Second):
+		t.Fatalf("no event received.")
+		return Event{}, false
+	}
+}
+
+func TestPublishFanOut(t *testing.T) {
+
+	h := NewHub(HubConfig{})
+	collection, auction := CollectionTopic(collectionId), AuctionTopic(auctionId)
+
+	both, err := h.Subscribe([]string{collection, auction}, 0)
+	if err != nil {
+		t.Fatalf("Subscribe failed, error: %v.", err)
+	}
+	other, _ := h.Subscribe([]string{TokenTopic(collectionId, 7)}, 0)
+
+	e, err := h.Publish([]string{auction, collection}, "bid", map[string]string{"amount": "100"})
+	if err != nil {
+		t.Fatalf("Publish failed, error: %v.", err)
+	}
+
+	// subscribed to both topics of the event, received once
+	if got, _ := receive(t, both); got.Id != e.Id || string(got.Data) != `{"amount":"100"}` {
+		t.Errorf("received %+v, expected %+v.", got, e)
+	}
+	select {
+	case got := <-both.Events():
+		t.Errorf("received duplicate %+v.", got)
+	case got := <-other.Events():
+		t.Errorf("received event of another topic %+v.", got)
+	default:
+	}
+
+	if _, err := h.Publish([]string{"collections/x"}, "bid", nil); err != ErrInvalidTopic {
+		t.Errorf("Publish returned %v, expected %v.", err, ErrInvalidTopic)
+	}
+	if _, err := h.Subscribe(nil, 0); err != ErrNoTopics {
+		t.Errorf("Subscribe returned %v, expected %v.", err, ErrNoTopics)
+	}
+
+	both.Close()
+	both.Close()
+	if _, ok := <-both.Events(); ok {
+		t.Errorf("closed subscription still open.")
+	}
+}
+
+func TestResume(t *testing.T) {
+
+	h := NewHub(HubConfig{History: 3})
+	topic := AuctionTopic(auctionId)
+
+	// the topic keeps its history while it has a subscriber
+	holder, _ := h.Subscribe([]string{topic}, 0)
+	defer holder.Close()
+
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		e, _ := h.Publish([]string{topic}, "bid", i)
+		ids = append(ids, e.Id)
+	}
+
+	// the events after the third are still kept
+	sub, _ := h.Subscribe([]string{topic}, ids[2])
+	if sub.Gap || len(sub.Replay) != 2 || sub.Replay[0].Id != ids[3] || sub.Replay[1].Id != ids[4] {
+		t.Errorf("resumed with gap %v and replay %+v, expected events %v.", sub.Gap, sub.Replay, ids[3:])
+	}
+
+	// the second event was evicted
+	sub, _ = h.Subscribe([]string{topic}, ids[0])
+	if !sub.Gap || len(sub.Replay) != 3 {
+		t.Errorf("resumed with gap %v and %d events, expected a gap and 3 events.", sub.Gap, len(sub.Replay))
+	}
+
+	// ids of an earlier process
+	sub, _ = h.Subscribe([]string{topic}, 1000)
+	if !sub.Gap || len(sub.Replay) != 0 {
+		t.Errorf("resumed with gap %v and %d events, expected a gap and no events.", sub.Gap, len(sub.Replay))
+	}
+}
+
+// topics without events go with their last subscriber, history is kept for the ttl after it left
+func TestTopicHistoryExpires(t *testing.T) {
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := NewHub(HubConfig{TTL: time.Minute}).(*hub)
+	h.now = func() time.Time { return now }
+	topic, quiet, other := AuctionTopic(auctionId), CollectionTopic(collectionId), TokenTopic(collectionId, 1)
+
+	sub, _ := h.Subscribe([]string{topic, topic, quiet}, 0)
+	e, _ := h.Publish([]string{topic}, "bid", 1)
+	if got, _ := receive(t, sub); got.Id != e.Id {
+		t.Errorf("received %d, expected %d.", got.Id, e.Id)
+	}
+	sub.Close()
+	if _, ok := h.topics[quiet]; ok || len(h.topics) != 1 {
+		t.Errorf("%d topics left after the last subscriber, expected the one with events.", len(h.topics))
+	}
+
+	// a client reconnecting within the ttl misses nothing, also of events published meanwhile
+	now = now.Add(30 * time.Second)
+	missed, _ := h.Publish([]string{topic}, "bid", 2)
+	resumed, _ := h.Subscribe([]string{topic}, e.Id)
+	if resumed.Gap || len(resumed.Replay) != 1 || resumed.Replay[0].Id != missed.Id {
+		t.Errorf("resumed with gap %v and replay %+v, expected event %d.", resumed.Gap, resumed.Replay, missed.Id)
+	}
+	resumed.Close()
+
+	// gaps are per topic, events of other topics do not force a reload
+	now = now.Add(2 * time.Minute)
+	h.Publish([]string{topic}, "bid", 3)
+	resumed, _ = h.Subscribe([]string{other}, e.Id)
+	if resumed.Gap {
+		t.Errorf("resumed another topic with a gap.")
+	}
+	resumed.Close()
+
+	// after the ttl only the newest id is left, resuming reports the gap
+	if n := len(h.topics[topic].events); n != 0 {
+		t.Errorf("topic kept %d events after the ttl, expected none.", n)
+	}
+	resumed, _ = h.Subscribe([]string{topic}, missed.Id)
+	if !resumed.Gap || len(resumed.Replay) != 0 {
+		t.Errorf("resumed with gap %v and %d events, expected a gap and no events.", resumed.Gap, len(resumed.Replay))
+	}
+	latest, _ := h.Publish([]string{topic}, "bid", 4)
+	resumed, _ = h.Subscribe([]string{topic}, latest.Id)
+	if resumed.Gap {
+		t.Errorf("resumed after the last event with a gap.")
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+
+	h := NewHub(HubConfig{Buffer: 2, MaxSubscribers: 2})
+	topic := AuctionTopic(auctionId)
+
+	slow, _ := h.Subscribe([]string{topic}, 0)
+	fast, _ := h.Subscribe([]string{topic}, 0)
+	if _, err := h.Subscribe([]string{topic}, 0); err != ErrTooManySubscribers {
+		t.Errorf("Subscribe returned %v, expected %v.", err, ErrTooManySubscribers)
+	}
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		e, _ := h.Publish([]string{topic}, "bid", i)
+		if got, _ := receive(t, fast); got.Id != e.Id {
+			t.Errorf("fast subscriber received %d, expected %d.", got.Id, e.Id)
+		}
+		last = e.Id
+	}
+
+	// the two buffered events are still delivered, then the channel is closed
+	for i := 0; i < 2; i++ {
+		if _, ok := receive(t, slow); !ok {
+			t.Fatalf("buffered event lost.")
+		}
+	}
+	if _, ok := receive(t, slow); ok || !slow.Lagged() {
+		t.Fatalf("slow subscriber not dropped.")
+	}
+
+	// the dropped subscriber freed its slot and resumes without losing events
+	resumed, err := h.Subscribe([]string{topic}, last-1)
+	if err != nil || resumed.Gap || len(resumed.Replay) != 1 || resumed.Replay[0].Id != last {
+		t.Errorf("resume failed with %+v, error: %v.", resumed, err)
+	}
+}
+
+func TestServeEvents(t *testing.T) {
+
+	h := NewHub(HubConfig{})
+	router := mux.NewRouter()
+	AttachRoutes(router, h, 20*time.Millisecond, log.NewNopLogger())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	topic := AuctionTopic(auctionId)
+	holder, _ := h.Subscribe([]string{topic}, 0)
+	defer holder.Close()
+	first, _ := h.Publish([]string{topic}, "bid", "first")
+	second, _ := h.Publish([]string{topic}, "extended", "second")
+
+	req, _ := http.NewRequest("GET", server.URL+"/events?topic="+topic, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events failed, error: %v.", err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("content type %s, expected text/event-stream.", res.Header.Get("Content-Type"))
+	}
+
+	// replayed event and a heartbeat
+	lines := bufio.NewScanner(res.Body)
+	var got []string
+	for lines.Scan() && len(got) < 6 {
+		if lines.Text() != "" {
+			got = append(got, lines.Text())
+		}
+	}
+	if got[0] != "retry: 3000" || got[1] != "id: 2" || got[2] != "event: extended" || got[4] != ": heartbeat" {
+		t.Fatalf("stream started with %q.", got)
+	}
+
+	var e Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[3], "data: ")), &e); err != nil || e.Id != second.Id || first.Id != 1 {
+		t.Errorf("decoding event failed, got %+v, error: %v.", e, err)
+	}
+
+	res, _ = http.Get(server.URL + "/events?topic=unknown")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid topic returned status %d, expected %d.", res.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+
+	h := NewHub(HubConfig{})
+	router := mux.NewRouter()
+	AttachRoutes(router, h, time.Second, log.NewNopLogger())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	topic := CollectionTopic(collectionId)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws?topic=" + topic + "&last_event_id=99"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed, error: %v.", err)
+	}
+	defer conn.Close()
+
+	// unknown last event id
+	var e Event
+	if err := conn.ReadJSON(&e); err != nil || e.Type != typeReset {
+		t.Fatalf("first message %+v, expected a reset, error: %v.", e, err)
+	}
+
+	published, _ := h.Publish([]string{topic}, "raffle", []string{"0xabc"})
+	if err := conn.ReadJSON(&e); err != nil || e.Id != published.Id || e.Type != "raffle" {
+		t.Errorf("received %+v, expected %+v, error: %v.", e, published, err)
+	}
+}
@@ -0,0 +1,158 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidNumber),
+		errors.Is(err, ErrInvalidAmount),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrSignerMismatch),
+		errors.Is(err, ErrExpired),
+		errors.Is(err, ErrWouldRevert):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrRelayNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrInvalidNonce),
+		errors.Is(err, ErrRelayPending):
+		return http.StatusConflict
+
+	case errors.Is(err, ErrNoBalance):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrRateLimited),
+		errors.Is(err, ErrBudgetExhausted):
+		return http.StatusTooManyRequests
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decode signed permit from body
+func decodeSubmitPermit(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req SubmitPermitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Permit); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode signed delegation from body
+func decodeSubmitDelegation(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req SubmitDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Delegation); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode relay identifier from route
+func decodeReadRelay(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadRelayRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode signer from query parameters
+func decodeListRelays(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListRelaysRequest{Signer: r.URL.Query().Get("signer")}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching relayer handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	submitPermitHandler := httptransport.NewServer(
+		e.SubmitPermit,
+		decodeSubmitPermit,
+		encodeResponse,
+		options...,
+	)
+
+	submitDelegationHandler := httptransport.NewServer(
+		e.SubmitDelegation,
+		decodeSubmitDelegation,
+		encodeResponse,
+		options...,
+	)
+
+	readRelayHandler := httptransport.NewServer(
+		e.ReadRelay,
+		decodeReadRelay,
+		encodeResponse,
+		options...,
+	)
+
+	listRelaysHandler := httptransport.NewServer(
+		e.ListRelays,
+		decodeListRelays,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/relay/permits", submitPermitHandler).Methods("POST")
+	router.Handle("/relay/delegations", submitDelegationHandler).Methods("POST")
+	router.Handle("/relay/relays", listRelaysHandler).Methods("GET")
+	router.Handle("/relay/relays/{id}", readRelayHandler).Methods("GET")
+
+	return router
+}
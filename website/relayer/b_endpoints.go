@@ -0,0 +1,132 @@
+package relayer
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type SubmitPermitRequest struct {
+	Permit PermitInput
+}
+
+type SubmitDelegationRequest struct {
+	Delegation DelegationInput
+}
+
+type ReadRelayRequest struct {
+	Id string
+}
+
+type ListRelaysRequest struct {
+	Signer string
+}
+
+type RelayResponse struct {
+	Data Relay `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have RelayResponse follow the customError interface defined in a_transport.go
+func (r RelayResponse) error() error { return r.Err }
+
+type RelaysResponse struct {
+	Data []Relay `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have RelaysResponse follow the customError interface defined in a_transport.go
+func (r RelaysResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	SubmitPermit     endpoint.Endpoint
+	SubmitDelegation endpoint.Endpoint
+	ReadRelay        endpoint.Endpoint
+	ListRelays       endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		SubmitPermit:     epSubmitPermit(s),
+		SubmitDelegation: epSubmitDelegation(s),
+		ReadRelay:        epReadRelay(s),
+		ListRelays:       epListRelays(s),
+	}
+}
+
+// relay signed permit endpoint
+func epSubmitPermit(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(SubmitPermitRequest)
+
+		// call service method
+		r, err := s.SubmitPermit(ctx, req.Permit)
+		if err != nil {
+			return RelayResponse{Err: err}, err
+		}
+
+		return RelayResponse{Data: r, Err: nil}, nil
+	}
+}
+
+// relay signed delegation endpoint
+func epSubmitDelegation(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(SubmitDelegationRequest)
+
+		// call service method
+		r, err := s.SubmitDelegation(ctx, req.Delegation)
+		if err != nil {
+			return RelayResponse{Err: err}, err
+		}
+
+		return RelayResponse{Data: r, Err: nil}, nil
+	}
+}
+
+// relay status endpoint
+func epReadRelay(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadRelayRequest)
+
+		// call service method
+		r, err := s.ReadRelay(ctx, req.Id)
+		if err != nil {
+			return RelayResponse{Err: err}, err
+		}
+
+		return RelayResponse{Data: r, Err: nil}, nil
+	}
+}
+
+// relays of a signer endpoint
+func epListRelays(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListRelaysRequest)
+
+		// call service method
+		relays, err := s.ListRelays(ctx, req.Signer)
+		if err != nil {
+			return RelaysResponse{Err: err}, err
+		}
+
+		return RelaysResponse{Data: relays, Err: nil}, nil
+	}
+}
@@ -0,0 +1,140 @@
+package relayer
+
+import (
+	"errors"
+	"math/big"
+
+	"website/signing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ******** Art permit and delegation messages **********
+
+// type strings of the Art contract's PERMIT_TYPEHASH and DELEGATION_TYPEHASH
+const (
+	PermitType     = "Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"
+	DelegationType = "Delegation(address delegatee,uint256 nonce,uint256 expiry)"
+)
+
+var (
+	permitTypeHash     = crypto.Keccak256Hash([]byte(PermitType))
+	delegationTypeHash = crypto.Keccak256Hash([]byte(DelegationType))
+)
+
+var (
+	maxUint96  = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+var ErrInvalidAmount = errors.New("Amount must be at most 2^96-1 or exactly 2^256-1")
+
+// approval of spender by owner, value 2^256-1 approves without limit
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// delegation of the signer's votes to delegatee
+type Delegation struct {
+	Delegatee common.Address
+	Nonce     *big.Int
+	Expiry    *big.Int
+}
+
+// StructHash of a permit like Art.permit, the raw value is hashed
+func (p Permit) StructHash() common.Hash {
+	return crypto.Keccak256Hash(
+		permitTypeHash[:],
+		common.LeftPadBytes(p.Owner[:], 32),
+		common.LeftPadBytes(p.Spender[:], 32),
+		math.U256Bytes(new(big.Int).Set(p.Value)),
+		math.U256Bytes(new(big.Int).Set(p.Nonce)),
+		math.U256Bytes(new(big.Int).Set(p.Deadline)),
+	)
+}
+
+// StructHash of a delegation like Art.delegateBySig
+func (d Delegation) StructHash() common.Hash {
+	return crypto.Keccak256Hash(
+		delegationTypeHash[:],
+		common.LeftPadBytes(d.Delegatee[:], 32),
+		math.U256Bytes(new(big.Int).Set(d.Nonce)),
+		math.U256Bytes(new(big.Int).Set(d.Expiry)),
+	)
+}
+
+// follows Art.permit, values above 96 bits revert unless they are the unlimited allowance
+func checkAmount(value *big.Int) error {
+	if value.Sign() < 0 || (value.Cmp(maxUint96) > 0 && value.Cmp(maxUint256) != 0) {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// parses a uint256 decimal string
+func parseUint256(s string) (*big.Int, bool) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.Cmp(maxUint256) > 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// signature split into the contract's v, r and s arguments
+type vrs struct {
+	V uint8
+	R [32]byte
+	S [32]byte
+}
+
+// parses a 65 byte hex signature, recovery ids 0/1 are converted to the 27/28 ecrecover expects
+func parseSignature(signature string) (vrs, error) {
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return vrs{}, ErrInvalidSignature
+	}
+
+	var out vrs
+	copy(out.R[:], sig[:32])
+	copy(out.S[:], sig[32:64])
+	out.V = sig[64]
+	if out.V < 27 {
+		out.V += 27
+	}
+	if out.V != 27 && out.V != 28 {
+		return vrs{}, ErrInvalidSignature
+	}
+	return out, nil
+}
+
+// recovers the signer like the ecrecover precompile the contract calls
+// high s values are accepted as the contract does, the zero address stands for an invalid signature
+func (sig vrs) recover(domain signing.Domain, structHash common.Hash) common.Address {
+
+	r, s := new(big.Int).SetBytes(sig.R[:]), new(big.Int).SetBytes(sig.S[:])
+	if !crypto.ValidateSignatureValues(sig.V-27, r, s, false) {
+		return common.Address{}
+	}
+
+	raw := make([]byte, crypto.SignatureLength)
+	copy(raw[:32], sig.R[:])
+	copy(raw[32:64], sig.S[:])
+	raw[64] = sig.V - 27
+
+	digest := domain.Digest(structHash)
+	pub, err := crypto.SigToPub(digest[:], raw)
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
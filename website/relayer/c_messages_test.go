@@ -0,0 +1,70 @@
+package relayer
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// values follow Art.permit, 96 bit amounts and the unlimited allowance
+func TestCheckAmount(t *testing.T) {
+
+	cases := []struct {
+		value *big.Int
+		err   error
+	}{
+		{big.NewInt(0), nil},
+		{big.NewInt(1000), nil},
+		{maxUint96, nil},
+		{new(big.Int).Add(maxUint96, big.NewInt(1)), ErrInvalidAmount},
+		{new(big.Int).Sub(maxUint256, big.NewInt(1)), ErrInvalidAmount},
+		{maxUint256, nil},
+		{new(big.Int).Add(maxUint256, big.NewInt(1)), ErrInvalidAmount},
+		{big.NewInt(-1), ErrInvalidAmount},
+	}
+	for _, c := range cases {
+		if err := checkAmount(c.value); err != c.err {
+			t.Errorf("checkAmount of %v returned %v, expected %v.", c.value, err, c.err)
+		}
+	}
+}
+
+// 65 byte signatures, recovery ids 0/1 and 27/28
+func TestParseSignature(t *testing.T) {
+
+	body := "0x" + strings.Repeat("11", 32) + strings.Repeat("22", 32)
+
+	for _, c := range []struct {
+		v        string
+		expected uint8
+	}{{"00", 27}, {"01", 28}, {"1b", 27}, {"1c", 28}} {
+		sig, err := parseSignature(body + c.v)
+		if err != nil {
+			t.Fatalf("parseSignature failed, error: %v.", err)
+		}
+		if sig.V != c.expected || sig.R[0] != 0x11 || sig.R[31] != 0x11 || sig.S[0] != 0x22 || sig.S[31] != 0x22 {
+			t.Errorf("parseSignature of v %s returned %+v, expected v %d.", c.v, sig, c.expected)
+		}
+	}
+
+	for _, signature := range []string{
+		body + "02",
+		body + "1d",
+		body + "ff",
+		body,
+		body + "1b00",
+		strings.TrimPrefix(body, "0x") + "1b",
+		body[:len(body)-1] + "z1b",
+		"",
+	} {
+		if _, err := parseSignature(signature); err != ErrInvalidSignature {
+			t.Errorf("parseSignature of %q returned %v, expected %v.", signature, err, ErrInvalidSignature)
+		}
+	}
+
+	if sig, err := parseSignature(hexutil.Encode(make([]byte, 65))); err != nil || sig.V != 27 {
+		t.Errorf("parseSignature of zeros returned %+v, %v, expected v 27.", sig, err)
+	}
+}
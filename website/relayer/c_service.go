@@ -0,0 +1,407 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"website/chain"
+	"website/signing"
+	"website/txmanager"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidNumber = errors.New("Nonce, deadline and value must be uint256 decimal strings")
+
+var ErrSignerMismatch = errors.New("Permit was not signed by the owner")
+
+var ErrInvalidNonce = errors.New("Nonce does not match the signer's next contract nonce")
+
+var ErrExpired = errors.New("Signature expires before it could be mined")
+
+var ErrRelayPending = errors.New("Signer has a relay waiting to be mined")
+
+var ErrRateLimited = errors.New("Too many relays for this signer")
+
+var ErrBudgetExhausted = errors.New("Relay budget is used up, try again later")
+
+var ErrNoBalance = errors.New("Only Art holders can relay")
+
+var ErrWouldRevert = errors.New("Transaction would revert")
+
+// Config of the relayer
+// signatures must stay valid for min_validity after submission, each signer may relay rate_limit messages per rate_window
+// and all signers together budget messages per rate_window, zero for no budget
+// signers must hold min_balance Art, at least some Art when it is nil
+type Config struct {
+	Domain      signing.Domain
+	MinValidity time.Duration
+	RateLimit   int
+	RateWindow  time.Duration
+	Budget      int
+	MinBalance  *big.Int
+}
+
+// Sender broadcasts and tracks transactions of the hot wallet, implemented by the transaction manager
+type Sender interface {
+	Send(ctx context.Context, req txmanager.Request) (txmanager.Tx, error)
+	ReadTx(ctx context.Context, id string) (txmanager.Tx, error)
+}
+
+// signed Art.permit arguments, numbers are decimal strings, the deadline in unix seconds
+type PermitInput struct {
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Value     string `json:"value"`
+	Nonce     string `json:"nonce"`
+	Deadline  string `json:"deadline"`
+	Signature string `json:"signature"`
+}
+
+// signed Art.delegateBySig arguments, the signer is recovered from the signature
+type DelegationInput struct {
+	Delegatee string `json:"delegatee"`
+	Nonce     string `json:"nonce"`
+	Expiry    string `json:"expiry"`
+	Signature string `json:"signature"`
+}
+
+// service interface defining all required methods
+type Service interface {
+	SubmitPermit(ctx context.Context, in PermitInput) (Relay, error)
+	SubmitDelegation(ctx context.Context, in DelegationInput) (Relay, error)
+	ReadRelay(ctx context.Context, id string) (Relay, error)
+	ListRelays(ctx context.Context, signer string) ([]Relay, error)
+	Track(ctx context.Context) error
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// submissions are serialized, checks of one signer's open relays must not race
+	mtx sync.Mutex
+
+	relayStore RelayStore
+	chain      chain.Client
+	sender     Sender
+	config     Config
+	now        func() time.Time
+	logger     log.Logger
+}
+
+// parses a hex address
+func parseAddress(address string) (common.Address, error) {
+	if !common.IsHexAddress(address) {
+		return common.Address{}, ErrInvalidAddress
+	}
+	return common.HexToAddress(address), nil
+}
+
+func formatAddress(a common.Address) string {
+	return strings.ToLower(a.Hex())
+}
+
+// parses uint256 decimal strings
+func parseNumbers(s ...string) ([]*big.Int, error) {
+	out := make([]*big.Int, len(s))
+	for i := range s {
+		n, ok := parseUint256(strings.TrimSpace(s[i]))
+		if !ok {
+			return nil, ErrInvalidNumber
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// service struct submit permit method
+// checks amount bounds, signature, deadline and nonce like Art.permit before the hot wallet broadcasts it
+func (s *service) SubmitPermit(ctx context.Context, in PermitInput) (Relay, error) {
+
+	owner, err := parseAddress(in.Owner)
+	if err != nil {
+		return Relay{}, err
+	}
+	spender, err := parseAddress(in.Spender)
+	if err != nil {
+		return Relay{}, err
+	}
+	n, err := parseNumbers(in.Value, in.Nonce, in.Deadline)
+	if err != nil {
+		return Relay{}, err
+	}
+	p := Permit{Owner: owner, Spender: spender, Value: n[0], Nonce: n[1], Deadline: n[2]}
+	if err := checkAmount(p.Value); err != nil {
+		return Relay{}, err
+	}
+
+	sig, err := parseSignature(in.Signature)
+	if err != nil {
+		return Relay{}, err
+	}
+	signer := sig.recover(s.config.Domain, p.StructHash())
+	if signer == (common.Address{}) {
+		return Relay{}, ErrInvalidSignature
+	}
+	if signer != owner {
+		return Relay{}, ErrSignerMismatch
+	}
+
+	data, err := s.chain.Art().Pack("permit", p.Owner, p.Spender, p.Value, p.Deadline, sig.V, sig.R, sig.S)
+	if err != nil {
+		return Relay{}, err
+	}
+	return s.submit(ctx, KindPermit, s.config.Domain.Digest(p.StructHash()), owner, p.Nonce, p.Deadline, data)
+}
+
+// service struct submit delegation method
+// the contract delegates the votes of whoever signed, so the signer is recovered first
+func (s *service) SubmitDelegation(ctx context.Context, in DelegationInput) (Relay, error) {
+
+	delegatee, err := parseAddress(in.Delegatee)
+	if err != nil {
+		return Relay{}, err
+	}
+	n, err := parseNumbers(in.Nonce, in.Expiry)
+	if err != nil {
+		return Relay{}, err
+	}
+	d := Delegation{Delegatee: delegatee, Nonce: n[0], Expiry: n[1]}
+
+	sig, err := parseSignature(in.Signature)
+	if err != nil {
+		return Relay{}, err
+	}
+	signer := sig.recover(s.config.Domain, d.StructHash())
+	if signer == (common.Address{}) {
+		return Relay{}, ErrInvalidSignature
+	}
+
+	data, err := s.chain.Art().Pack("delegateBySig", d.Delegatee, d.Nonce, d.Expiry, sig.V, sig.R, sig.S)
+	if err != nil {
+		return Relay{}, err
+	}
+	return s.submit(ctx, KindDelegation, s.config.Domain.Digest(d.StructHash()), signer, d.Nonce, d.Expiry, data)
+}
+
+// checks what depends on stored relays and the chain, then hands the call to the transaction manager
+// the same digest returns the existing relay, so clients can safely retry
+func (s *service) submit(ctx context.Context, kind Kind, digest common.Hash, signer common.Address, nonce *big.Int, deadline *big.Int, data []byte) (Relay, error) {
+
+	// log level
+	logger := log.With(s.logger, "method", "submit")
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	id := digest.Hex()
+	if r, err := s.relayStore.ReadRelay(id); err == nil {
+		return r, nil
+	}
+
+	now := s.now()
+	if deadline.Cmp(big.NewInt(now.Add(s.config.MinValidity).Unix())) < 0 {
+		return Relay{}, ErrExpired
+	}
+
+	address := formatAddress(signer)
+	relays, err := s.relayStore.ReadRelays()
+	if err != nil {
+		level.Error(logger).Log("s.relayStore.ReadRelays:", err)
+		return Relay{}, err
+	}
+	recent, total := 0, 0
+	for _, r := range relays {
+		if now.Sub(r.CreatedAt) < s.config.RateWindow {
+			total++
+		}
+		if r.Signer != address {
+			continue
+		}
+		if r.open() {
+			return Relay{}, ErrRelayPending
+		}
+		if now.Sub(r.CreatedAt) < s.config.RateWindow {
+			recent++
+		}
+	}
+	if recent >= s.config.RateLimit {
+		return Relay{}, ErrRateLimited
+	}
+	if s.config.Budget > 0 && total >= s.config.Budget {
+		return Relay{}, ErrBudgetExhausted
+	}
+
+	// the hot wallet only pays for holders, fresh keys cannot drain it
+	balance, err := s.chain.BalanceOf(ctx, address)
+	if err != nil {
+		level.Error(logger).Log("s.chain.BalanceOf:", err)
+		return Relay{}, err
+	}
+	if balance.Sign() == 0 || (s.config.MinBalance != nil && balance.Cmp(s.config.MinBalance) < 0) {
+		return Relay{}, ErrNoBalance
+	}
+
+	next, err := s.chain.Nonce(ctx, address)
+	if err != nil {
+		level.Error(logger).Log("s.chain.Nonce:", err)
+		return Relay{}, err
+	}
+	if next.Cmp(nonce) != 0 {
+		return Relay{}, ErrInvalidNonce
+	}
+
+	// gas estimation executes the call, anything the checks above missed reverts here
+	tx, err := s.sender.Send(ctx, txmanager.Request{To: s.chain.Art().Address(), Data: data, Ref: string(kind) + ":" + id})
+	if err != nil {
+		if errors.Is(err, txmanager.ErrWouldRevert) {
+			return Relay{}, fmt.Errorf("%w: %v", ErrWouldRevert, err)
+		}
+		level.Error(logger).Log("s.sender.Send:", err)
+		return Relay{}, err
+	}
+
+	r := Relay{
+		Id:        id,
+		Kind:      kind,
+		Signer:    address,
+		Nonce:     nonce.String(),
+		TxId:      tx.Id,
+		TxHash:    tx.Hash,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.relayStore.WriteRelay(r); err != nil {
+		level.Error(logger).Log("s.relayStore.WriteRelay:", err, "tx", r.TxHash)
+		return Relay{}, err
+	}
+	level.Info(logger).Log("msg", "relayed", "kind", kind, "signer", address, "tx", r.TxHash)
+
+	return r, nil
+}
+
+// service struct read relay method
+func (s *service) ReadRelay(ctx context.Context, id string) (Relay, error) {
+	return s.relayStore.ReadRelay(id)
+}
+
+// service struct list relays method
+// returns the relays of a signer, oldest first
+func (s *service) ListRelays(ctx context.Context, signer string) ([]Relay, error) {
+
+	a, err := parseAddress(signer)
+	if err != nil {
+		return nil, err
+	}
+	address := formatAddress(a)
+
+	relays, err := s.relayStore.ReadRelays()
+	if err != nil {
+		return nil, err
+	}
+	selected := []Relay{}
+	for _, r := range relays {
+		if r.Signer == address {
+			selected = append(selected, r)
+		}
+	}
+	return selected, nil
+}
+
+// Track copies the state of the transactions of open relays
+// replacements, reorgs and confirmations are handled by the transaction manager
+func (s *service) Track(ctx context.Context) error {
+
+	// log level
+	logger := log.With(s.logger, "method", "Track")
+
+	relays, err := s.relayStore.ReadRelays()
+	if err != nil {
+		level.Error(logger).Log("s.relayStore.ReadRelays:", err)
+		return err
+	}
+
+	for _, r := range relays {
+		if !r.open() {
+			continue
+		}
+
+		tx, err := s.sender.ReadTx(ctx, r.TxId)
+		if err != nil {
+			level.Error(logger).Log("s.sender.ReadTx:", err, "relay", r.Id)
+			continue
+		}
+		if Status(tx.Status) == r.Status && tx.Block == r.Block && tx.Hash == r.TxHash {
+			continue
+		}
+
+		r.Status, r.Block, r.TxHash, r.Error = Status(tx.Status), tx.Block, tx.Hash, tx.Error
+		r.UpdatedAt = s.now()
+		if err := s.relayStore.WriteRelay(r); err != nil {
+			level.Error(logger).Log("s.relayStore.WriteRelay:", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scheduler periodically tracks open relays
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and tracks relays every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := sc.service.Track(ctx); err != nil {
+				level.Error(logger).Log("sc.service.Track:", err)
+			}
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// initialization function to return service struct
+// sender signs and pays for the relayed transactions
+// this function should is called in main.go
+func NewService(relayStore RelayStore, client chain.Client, sender Sender, config Config, logger log.Logger) Service {
+	return &service{
+		relayStore: relayStore,
+		chain:      client,
+		sender:     sender,
+		config:     config,
+		now:        time.Now,
+		logger:     logger,
+	}
+}
@@ -0,0 +1,356 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/signing"
+	"website/txmanager"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/kit/log"
+)
+
+// signs a struct hash within the domain, recovery id 0/1 like most wallets return
+func sign(t *testing.T, a chaintest.Account, domain signing.Domain, structHash common.Hash) string {
+	digest := domain.Digest(structHash)
+	sig, err := crypto.Sign(digest[:], a.Key)
+	if err != nil {
+		t.Fatalf("Sign failed, error: %v.", err)
+	}
+	return hexutil.Encode(sig)
+}
+
+type testRelayer struct {
+	sim    *chain.SimulatedBackend
+	client chain.Client
+	domain signing.Domain
+	txs    txmanager.Service
+	s      Service
+}
+
+// relayed transactions are sent by a transaction manager of a fresh hot wallet
+func newTestRelayer(t *testing.T, holder chaintest.Account, confirmations uint64, config Config) testRelayer {
+
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	address := client.Art().Address()
+
+	store, err := NewRelayStore(RelayStoreConfig{RelaysPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewRelayStore failed, error: %v.", err)
+	}
+	txStore, err := txmanager.NewTxStore(txmanager.TxStoreConfig{TxsPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewTxStore failed, error: %v.", err)
+	}
+	txs := txmanager.NewService(txStore, sim, chaintest.NewAccount(t).Key, txmanager.Config{ChainId: chaintest.ChainId, Confirmations: confirmations, GasMargin: 20, StuckAfter: time.Minute, BumpPercent: 20}, log.NewNopLogger())
+	config.Domain = signing.Domain{Name: "ArtToken", ChainId: chaintest.ChainId, VerifyingContract: address}
+
+	return testRelayer{
+		sim:    sim,
+		client: client,
+		domain: config.Domain,
+		txs:    txs,
+		s:      NewService(store, client, txs, config, log.NewNopLogger()),
+	}
+}
+
+// tracks the transactions, then the relays
+func (tr testRelayer) track(ctx context.Context) {
+	tr.txs.Track(ctx)
+	tr.s.Track(ctx)
+}
+
+var testConfig = Config{MinValidity: time.Minute, RateLimit: 3, RateWindow: time.Hour, Budget: 10}
+
+func (tr testRelayer) permit(t *testing.T, owner chaintest.Account, spender common.Address, value *big.Int, nonce int64, deadline time.Time) PermitInput {
+	p := Permit{Owner: owner.Addr, Spender: spender, Value: value, Nonce: big.NewInt(nonce), Deadline: big.NewInt(deadline.Unix())}
+	return PermitInput{
+		Owner:     owner.Addr.Hex(),
+		Spender:   spender.Hex(),
+		Value:     value.String(),
+		Nonce:     p.Nonce.String(),
+		Deadline:  p.Deadline.String(),
+		Signature: sign(t, owner, tr.domain, p.StructHash()),
+	}
+}
+
+func TestRelayPermit(t *testing.T) {
+
+	ctx := context.Background()
+	owner, spender := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	tr := newTestRelayer(t, owner, 2, testConfig)
+	deadline := time.Now().Add(time.Hour)
+
+	in := tr.permit(t, owner, spender.Addr, big.NewInt(1000), 0, deadline)
+	r, err := tr.s.SubmitPermit(ctx, in)
+	if err != nil {
+		t.Fatalf("SubmitPermit failed, error: %v.", err)
+	}
+	if r.Status != StatusPending || r.Signer != owner.Hex() || r.Kind != KindPermit || r.TxId == "" {
+		t.Errorf("relay %+v, expected a pending permit of the owner.", r)
+	}
+
+	// retries return the same relay, other messages wait for it
+	if again, err := tr.s.SubmitPermit(ctx, in); err != nil || again.Id != r.Id || again.TxHash != r.TxHash {
+		t.Errorf("resubmission returned %+v, %v, expected relay %s.", again, err, r.Id)
+	}
+	next := tr.permit(t, owner, spender.Addr, big.NewInt(1), 1, deadline)
+	if _, err := tr.s.SubmitPermit(ctx, next); err != ErrRelayPending {
+		t.Errorf("SubmitPermit returned %v, expected %v.", err, ErrRelayPending)
+	}
+
+	tr.sim.Commit()
+	tr.track(ctx)
+	if r, _ = tr.s.ReadRelay(ctx, r.Id); r.Status != StatusMined || r.Block == 0 {
+		t.Errorf("relay %+v, expected mined.", r)
+	}
+	tr.sim.Commit()
+	tr.track(ctx)
+	if r, _ = tr.s.ReadRelay(ctx, r.Id); r.Status != StatusConfirmed {
+		t.Errorf("relay %+v, expected confirmed.", r)
+	}
+
+	allowance, _ := tr.client.Allowance(ctx, owner.Hex(), spender.Hex())
+	if allowance.Int64() != 1000 {
+		t.Errorf("allowance %v, expected 1000.", allowance)
+	}
+
+	// the unlimited allowance is the only value above 96 bits
+	unlimited := tr.permit(t, owner, spender.Addr, maxUint256, 1, deadline)
+	if _, err := tr.s.SubmitPermit(ctx, unlimited); err != nil {
+		t.Errorf("SubmitPermit of the unlimited allowance failed, error: %v.", err)
+	}
+	tr.sim.Commit()
+	if allowance, _ = tr.client.Allowance(ctx, owner.Hex(), spender.Hex()); allowance.Cmp(maxUint96) != 0 {
+		t.Errorf("allowance %v, expected 2^96-1.", allowance)
+	}
+
+	relays, _ := tr.s.ListRelays(ctx, owner.Addr.Hex())
+	if len(relays) != 2 || relays[0].Id != r.Id {
+		t.Errorf("relays %+v, expected both permits oldest first.", relays)
+	}
+}
+
+func TestRejectPermit(t *testing.T) {
+
+	ctx := context.Background()
+	owner, spender, other := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	tr := newTestRelayer(t, owner, 2, testConfig)
+	deadline := time.Now().Add(time.Hour)
+
+	tooLarge := tr.permit(t, owner, spender.Addr, new(big.Int).Add(maxUint96, big.NewInt(1)), 0, deadline)
+	forged := tr.permit(t, other, spender.Addr, big.NewInt(5), 0, deadline)
+	forged.Owner = owner.Addr.Hex()
+	tampered := tr.permit(t, owner, spender.Addr, big.NewInt(5), 0, deadline)
+	tampered.Value = "6"
+	highS := tr.permit(t, owner, spender.Addr, big.NewInt(5), 0, deadline)
+	highS.Signature = highS.Signature[:len(highS.Signature)-2] + "1d"
+
+	cases := []struct {
+		name string
+		in   PermitInput
+		err  error
+	}{
+		{"amount above 96 bits", tooLarge, ErrInvalidAmount},
+		{"signed by another wallet", forged, ErrSignerMismatch},
+		{"tampered value", tampered, ErrSignerMismatch},
+		{"invalid recovery id", highS, ErrInvalidSignature},
+		{"expires too soon", tr.permit(t, owner, spender.Addr, big.NewInt(5), 0, time.Now().Add(30*time.Second)), ErrExpired},
+		{"future nonce", tr.permit(t, owner, spender.Addr, big.NewInt(5), 4, deadline), ErrInvalidNonce},
+		{"negative value", PermitInput{Owner: owner.Hex(), Spender: spender.Hex(), Value: "-1", Nonce: "0", Deadline: "1"}, ErrInvalidNumber},
+		{"invalid spender", PermitInput{Owner: owner.Hex(), Spender: "nobody"}, ErrInvalidAddress},
+	}
+	for _, c := range cases {
+		if _, err := tr.s.SubmitPermit(ctx, c.in); !errors.Is(err, c.err) {
+			t.Errorf("%s: SubmitPermit returned %v, expected %v.", c.name, err, c.err)
+		}
+	}
+
+	// nothing was broadcast
+	tr.sim.Commit()
+	if nonce, _ := tr.client.Nonce(ctx, owner.Hex()); nonce.Sign() != 0 {
+		t.Errorf("owner nonce %v, expected 0.", nonce)
+	}
+}
+
+func TestRelayDelegation(t *testing.T) {
+
+	ctx := context.Background()
+	holder, delegatee := chaintest.NewAccount(t), chaintest.NewAccount(t)
+	tr := newTestRelayer(t, holder, 1, Config{MinValidity: time.Minute, RateLimit: 2, RateWindow: time.Hour})
+	expiry := big.NewInt(time.Now().Add(time.Hour).Unix())
+
+	delegate := func(to common.Address, nonce int64) DelegationInput {
+		d := Delegation{Delegatee: to, Nonce: big.NewInt(nonce), Expiry: expiry}
+		return DelegationInput{Delegatee: to.Hex(), Nonce: d.Nonce.String(), Expiry: expiry.String(), Signature: sign(t, holder, tr.domain, d.StructHash())}
+	}
+
+	for nonce, to := range []common.Address{delegatee.Addr, holder.Addr} {
+		r, err := tr.s.SubmitDelegation(ctx, delegate(to, int64(nonce)))
+		if err != nil {
+			t.Fatalf("SubmitDelegation failed, error: %v.", err)
+		}
+		if r.Signer != holder.Hex() || r.Kind != KindDelegation {
+			t.Errorf("relay %+v, expected a delegation of the holder.", r)
+		}
+		tr.sim.Commit()
+		tr.track(ctx)
+		if r, _ = tr.s.ReadRelay(ctx, r.Id); r.Status != StatusConfirmed {
+			t.Errorf("relay %+v, expected confirmed.", r)
+		}
+		if current, _ := tr.client.Delegates(ctx, holder.Hex()); current != formatAddress(to) {
+			t.Errorf("delegate %s, expected %s.", current, formatAddress(to))
+		}
+	}
+
+	// two relays per window
+	if _, err := tr.s.SubmitDelegation(ctx, delegate(delegatee.Addr, 2)); err != ErrRateLimited {
+		t.Errorf("SubmitDelegation returned %v, expected %v.", err, ErrRateLimited)
+	}
+}
+
+// signers without Art and signers beyond the budget of all signers are not relayed
+func TestRelayBudget(t *testing.T) {
+
+	ctx := context.Background()
+	holder, other, empty, spender := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	tr := newTestRelayer(t, holder, 1, Config{MinValidity: time.Minute, RateLimit: 3, RateWindow: time.Hour, Budget: 1, MinBalance: big.NewInt(10)})
+	deadline := time.Now().Add(time.Hour)
+
+	if _, err := tr.client.Art().Transfer(holder.Opts, other.Addr, big.NewInt(5)); err != nil {
+		t.Fatalf("Transfer failed, error: %v.", err)
+	}
+	tr.sim.Commit()
+
+	if _, err := tr.s.SubmitPermit(ctx, tr.permit(t, empty, spender.Addr, big.NewInt(1), 0, deadline)); err != ErrNoBalance {
+		t.Errorf("SubmitPermit without Art returned %v, expected %v.", err, ErrNoBalance)
+	}
+	if _, err := tr.s.SubmitPermit(ctx, tr.permit(t, other, spender.Addr, big.NewInt(1), 0, deadline)); err != ErrNoBalance {
+		t.Errorf("SubmitPermit below the minimum balance returned %v, expected %v.", err, ErrNoBalance)
+	}
+
+	if _, err := tr.s.SubmitPermit(ctx, tr.permit(t, holder, spender.Addr, big.NewInt(1), 0, deadline)); err != nil {
+		t.Fatalf("SubmitPermit failed, error: %v.", err)
+	}
+	if _, err := tr.client.Art().Transfer(holder.Opts, other.Addr, big.NewInt(5)); err != nil {
+		t.Fatalf("Transfer failed, error: %v.", err)
+	}
+	tr.sim.Commit()
+	if _, err := tr.s.SubmitPermit(ctx, tr.permit(t, other, spender.Addr, big.NewInt(1), 0, deadline)); err != ErrBudgetExhausted {
+		t.Errorf("SubmitPermit beyond the budget returned %v, expected %v.", err, ErrBudgetExhausted)
+	}
+}
+
+// chain of holders with a balance of one and a nonce of zero, the contract is never called
+type stubChain struct {
+	chain.Client
+	art *chain.Art
+}
+
+func (sc stubChain) BalanceOf(ctx context.Context, account string) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (sc stubChain) Nonce(ctx context.Context, account string) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (sc stubChain) Art() *chain.Art {
+	return sc.art
+}
+
+// accepts every transaction and counts them
+type stubSender struct {
+	sent int
+}
+
+func (ss *stubSender) Send(ctx context.Context, req txmanager.Request) (txmanager.Tx, error) {
+	ss.sent++
+	return txmanager.Tx{Id: req.Ref, Hash: common.BigToHash(big.NewInt(int64(ss.sent))).Hex()}, nil
+}
+
+func (ss *stubSender) ReadTx(ctx context.Context, id string) (txmanager.Tx, error) {
+	return txmanager.Tx{}, txmanager.ErrTxNotFound
+}
+
+// relays older than the window no longer count toward the signer's limit or the budget
+func TestRelayWindow(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	alice, bob, carol := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+
+	store, err := NewRelayStore(RelayStoreConfig{RelaysPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewRelayStore failed, error: %v.", err)
+	}
+	sender := &stubSender{}
+	s := NewService(store, stubChain{art: chain.NewArt(common.HexToAddress("0xa7"), nil)}, sender, Config{MinValidity: time.Minute, RateLimit: 2, RateWindow: time.Hour, Budget: 3}, log.NewNopLogger()).(*service)
+	s.now = func() time.Time { return now }
+
+	for i, r := range []struct {
+		signer common.Address
+		age    time.Duration
+	}{{alice, 2 * time.Hour}, {alice, 30 * time.Minute}, {bob, 10 * time.Minute}} {
+		relay := Relay{Id: common.BigToHash(big.NewInt(int64(100 + i))).Hex(), Kind: KindPermit, Signer: formatAddress(r.signer), Status: StatusConfirmed, CreatedAt: now.Add(-r.age)}
+		if err := store.WriteRelay(relay); err != nil {
+			t.Fatalf("WriteRelay failed, error: %v.", err)
+		}
+	}
+
+	digest := 0
+	submit := func(signer common.Address) (Relay, error) {
+		digest++
+		deadline := big.NewInt(now.Add(time.Hour).Unix())
+		return s.submit(ctx, KindPermit, common.BigToHash(big.NewInt(int64(digest))), signer, big.NewInt(0), deadline, nil)
+	}
+	confirm := func(r Relay) {
+		r.Status = StatusConfirmed
+		if err := store.WriteRelay(r); err != nil {
+			t.Fatalf("WriteRelay failed, error: %v.", err)
+		}
+	}
+
+	// one of alice's relays is in the window
+	r, err := submit(alice)
+	if err != nil {
+		t.Fatalf("submit failed, error: %v.", err)
+	}
+	if _, err := submit(alice); err != ErrRelayPending {
+		t.Errorf("submit with a pending relay returned %v, expected %v.", err, ErrRelayPending)
+	}
+	confirm(r)
+	if _, err := submit(alice); err != ErrRateLimited {
+		t.Errorf("submit above the signer limit returned %v, expected %v.", err, ErrRateLimited)
+	}
+	if _, err := submit(carol); err != ErrBudgetExhausted {
+		t.Errorf("submit above the budget returned %v, expected %v.", err, ErrBudgetExhausted)
+	}
+
+	// a relay exactly one window old has left it
+	now = now.Add(time.Hour)
+	if r, err = submit(carol); err != nil {
+		t.Fatalf("submit failed, error: %v.", err)
+	}
+	confirm(r)
+	for i := 0; i < 2; i++ {
+		if r, err = submit(alice); err != nil {
+			t.Fatalf("submit failed, error: %v.", err)
+		}
+		confirm(r)
+	}
+	if _, err := submit(alice); err != ErrRateLimited {
+		t.Errorf("submit above the signer limit returned %v, expected %v.", err, ErrRateLimited)
+	}
+	if sender.sent != 4 {
+		t.Errorf("%d transactions were sent, expected 4.", sender.sent)
+	}
+}
@@ -0,0 +1,182 @@
+package relayer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Relay struct **********
+
+type Kind string
+
+const (
+	KindPermit     Kind = "permit"
+	KindDelegation Kind = "delegation"
+)
+
+type Status string
+
+const (
+
+	// broadcast, no receipt yet or the receipt was dropped by a reorg
+	StatusPending Status = "pending"
+
+	// included, waiting for confirmations
+	StatusMined Status = "mined"
+
+	StatusConfirmed Status = "confirmed"
+
+	// reverted or the transaction's nonce was used by another transaction
+	StatusFailed Status = "failed"
+)
+
+// signed message relayed to the Art contract from the hot wallet
+// statuses follow the transaction manager's transaction, tx_hash is its latest attempt or the mined one
+// the identifier is the signed digest, so submitting the same signature twice returns the same relay
+type Relay struct {
+	Id        string    `json:"id"`
+	Kind      Kind      `json:"kind"`
+	Signer    string    `json:"signer"`
+	Nonce     string    `json:"nonce"`
+	TxId      string    `json:"tx_id"`
+	TxHash    string    `json:"tx_hash"`
+	Status    Status    `json:"status"`
+	Block     uint64    `json:"block,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// pending and mined relays are still tracked
+func (r Relay) open() bool {
+	return r.Status == StatusPending || r.Status == StatusMined
+}
+
+// ******* Relay store interface *********
+
+var ErrRelayNotFound = errors.New("Relay not found")
+
+type RelayStoreConfig struct {
+	RelaysPath string
+}
+
+type RelayStore interface {
+	WriteRelay(r Relay) error
+	ReadRelay(id string) (Relay, error)
+	ReadRelays() ([]Relay, error)
+}
+
+type relayStore struct {
+	mu     sync.Mutex
+	relays map[string]Relay
+	config RelayStoreConfig
+	logger log.Logger
+}
+
+// creates or replaces a relay
+func (rs *relayStore) WriteRelay(r Relay) error {
+
+	// log level
+	logger := log.With(rs.logger, "method", "WriteRelay")
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	previous, existed := rs.relays[r.Id]
+	rs.relays[r.Id] = r
+
+	if err := rs.write(); err != nil {
+		if existed {
+			rs.relays[r.Id] = previous
+		} else {
+			delete(rs.relays, r.Id)
+		}
+		level.Error(logger).Log("rs.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (rs *relayStore) ReadRelay(id string) (Relay, error) {
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.relays[id]
+	if !ok {
+		return Relay{}, ErrRelayNotFound
+	}
+	return r, nil
+}
+
+// all relays, oldest first
+func (rs *relayStore) ReadRelays() ([]Relay, error) {
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	relays := make([]Relay, 0, len(rs.relays))
+	for _, r := range rs.relays {
+		relays = append(relays, r)
+	}
+	sort.Slice(relays, func(i, k int) bool {
+		if relays[i].CreatedAt.Equal(relays[k].CreatedAt) {
+			return relays[i].Id < relays[k].Id
+		}
+		return relays[i].CreatedAt.Before(relays[k].CreatedAt)
+	})
+	return relays, nil
+}
+
+// writes all relays, temporary file first so that a crash never leaves a partial file
+func (rs *relayStore) write() error {
+
+	if err := os.MkdirAll(rs.config.RelaysPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rs.relays)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(rs.config.RelaysPath, "relays.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads the persisted relays, a missing file starts empty
+func NewRelayStore(config RelayStoreConfig, logger log.Logger) (RelayStore, error) {
+
+	rs := &relayStore{
+		relays: make(map[string]Relay),
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.RelaysPath, "relays.json"))
+	if os.IsNotExist(err) {
+		return rs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &rs.relays); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
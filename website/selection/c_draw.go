@@ -0,0 +1,78 @@
+package selection
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+var ErrInvalidSeed = errors.New("Invalid seed")
+
+// deterministic random stream derived from a seed
+// block i of the stream is sha256(seed | i), consumed 8 bytes at a time
+type stream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func newStream(seed string) (*stream, error) {
+	b, err := hex.DecodeString(seed)
+	if err != nil || len(b) != sha256.Size {
+		return nil, ErrInvalidSeed
+	}
+	return &stream{seed: b}, nil
+}
+
+// next 64 bit value of the stream
+func (s *stream) uint64() uint64 {
+
+	if len(s.buf) < 8 {
+		var block [sha256.Size + 8]byte
+		copy(block[:], s.seed)
+		binary.BigEndian.PutUint64(block[sha256.Size:], s.counter)
+		sum := sha256.Sum256(block[:])
+		s.counter++
+		s.buf = sum[:]
+	}
+
+	v := binary.BigEndian.Uint64(s.buf[:8])
+	s.buf = s.buf[8:]
+	return v
+}
+
+// uniform value in [0, n) using rejection sampling to avoid modulo bias
+func (s *stream) intn(n uint64) uint64 {
+
+	// largest multiple of n representable in 64 bits
+	limit := ^uint64(0) - (^uint64(0) % n)
+	for {
+		v := s.uint64()
+		if v < limit {
+			return v % n
+		}
+	}
+}
+
+// Draw picks k distinct entrants from the canonical entrant order with a partial Fisher-Yates shuffle
+// returns the winners in draw order, all entrants win if there are fewer than k
+func Draw(seed string, entrants []Entrant, k int) ([]Entrant, error) {
+
+	s, err := newStream(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := Canonical(entrants)
+	if k > len(pool) {
+		k = len(pool)
+	}
+
+	for i := 0; i < k; i++ {
+		j := i + int(s.intn(uint64(len(pool)-i)))
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+
+	return pool[:k], nil
+}
@@ -0,0 +1,81 @@
+package selection
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testEntrants(n int) []Entrant {
+	entrants := make([]Entrant, n)
+	for i := range entrants {
+		entrants[i] = Entrant{Address: fmt.Sprintf("0x%040x", i), Contribution: fmt.Sprintf("%02x", i%256)}
+	}
+	return entrants
+}
+
+func TestTranscript(t *testing.T) {
+
+	secret, _ := NewSecret()
+	commitment := Commit(secret)
+	entrants := testEntrants(50)
+	now := time.Now()
+
+	tr, err := NewTranscript(secret, commitment, entrants, 0, "", 10, now)
+	if err != nil {
+		t.Fatalf("NewTranscript failed, error: %v.", err)
+	}
+	if err := Verify(tr); err != nil {
+		t.Fatalf("Verify failed, error: %v.", err)
+	}
+
+	// without replacement
+	seen := make(map[string]bool)
+	for _, w := range tr.Winners {
+		if seen[w.Address] {
+			t.Errorf("address %s drawn twice.", w.Address)
+		}
+		seen[w.Address] = true
+	}
+
+	// entrant order does not influence the result
+	reversed := make([]Entrant, len(entrants))
+	for i, e := range entrants {
+		reversed[len(entrants)-1-i] = e
+	}
+	tr2, _ := NewTranscript(secret, commitment, reversed, 0, "", 10, now)
+	if tr2.Seed != tr.Seed || tr2.Winners[0] != tr.Winners[0] {
+		t.Errorf("draw depends on entrant order.")
+	}
+
+	// wrong secret
+	if _, err := NewTranscript("00", commitment, entrants, 0, "", 10, now); err != ErrCommitmentMismatch {
+		t.Errorf("NewTranscript returned %v, expected %v.", err, ErrCommitmentMismatch)
+	}
+
+	// tampered winners
+	tr.Winners[0].Address, tr.Winners[1].Address = tr.Winners[1].Address, tr.Winners[0].Address
+	if err := Verify(tr); err != ErrTranscriptMismatch {
+		t.Errorf("Verify returned %v, expected %v.", err, ErrTranscriptMismatch)
+	}
+}
+
+func TestDrawUniform(t *testing.T) {
+
+	entrants := testEntrants(4)
+	counts := make(map[string]int)
+	rounds := 4000
+	for i := 0; i < rounds; i++ {
+		winners, err := Draw(hashHex(fmt.Sprint(i)), entrants, 1)
+		if err != nil {
+			t.Fatalf("Draw failed, error: %v.", err)
+		}
+		counts[winners[0].Address]++
+	}
+
+	for address, c := range counts {
+		if c < rounds/4-150 || c > rounds/4+150 {
+			t.Errorf("address %s won %d of %d rounds.", address, c, rounds)
+		}
+	}
+}
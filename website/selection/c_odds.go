@@ -0,0 +1,360 @@
+package selection
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// ******** Odds calculation **********
+
+// largest number of entrants with positive weight for which odds are computed exactly
+const exactLimit = 16
+
+const DefaultIterations = 10000
+
+const MaxIterations = 1000000
+
+var ErrInvalidIterations = errors.New("Invalid number of iterations")
+
+var ErrInvalidStake = errors.New("Invalid stake amount")
+
+// probability of each entrant to win at least one of the slots
+// entrants win at most once, so this equals the probability of being drawn
+type OddsResult struct {
+	Exact         bool      `json:"exact"`
+	Iterations    int       `json:"iterations,omitempty"`
+	Probabilities []float64 `json:"probabilities"`
+}
+
+// Odds computes winning probabilities of a draw of k slots without replacement
+// uniform weights, single slots and small entrant lists are computed exactly, others are simulated
+// the simulation is seeded so that repeated requests return the same result
+func Odds(weights []*big.Int, k int, iterations int) (OddsResult, error) {
+
+	w, positive := toFloats(weights)
+	n := len(w)
+	probabilities := make([]float64, n)
+
+	if positive == 0 || k <= 0 {
+		return OddsResult{Exact: true, Probabilities: probabilities}, nil
+	}
+	if k > positive {
+		k = positive
+	}
+
+	// every positive entrant is drawn
+	if k == positive {
+		for i := range w {
+			if w[i] > 0 {
+				probabilities[i] = 1
+			}
+		}
+		return OddsResult{Exact: true, Probabilities: probabilities}, nil
+	}
+
+	// single slot or equal weights
+	if k == 1 || uniform(w) {
+		total := 0.0
+		for _, x := range w {
+			total += x
+		}
+		for i, x := range w {
+			if x > 0 {
+				if k == 1 {
+					probabilities[i] = x / total
+				} else {
+					probabilities[i] = float64(k) / float64(positive)
+				}
+			}
+		}
+		return OddsResult{Exact: true, Probabilities: probabilities}, nil
+	}
+
+	if positive <= exactLimit {
+		return OddsResult{Exact: true, Probabilities: exactOdds(w, k)}, nil
+	}
+
+	if iterations == 0 {
+		iterations = DefaultIterations
+	}
+	if iterations < 0 || iterations > MaxIterations {
+		return OddsResult{}, ErrInvalidIterations
+	}
+
+	return OddsResult{Iterations: iterations, Probabilities: simulateOdds(w, k, iterations)}, nil
+}
+
+// odds of one entrant together with the weight they were computed from
+type EntrantOdds struct {
+	Address     string  `json:"address"`
+	Votes       string  `json:"votes"`
+	Weight      string  `json:"weight"`
+	Probability float64 `json:"probability"`
+}
+
+// odds of all entrants of a raffle with an optional what-if scenario
+type OddsReport struct {
+	Slots      int           `json:"slots"`
+	Exact      bool          `json:"exact"`
+	Iterations int           `json:"iterations,omitempty"`
+	Entrants   []EntrantOdds `json:"entrants"`
+	WhatIf     *WhatIf       `json:"what_if,omitempty"`
+}
+
+// odds of an address after adding hypothetical votes, compared to its current odds
+type WhatIf struct {
+	EntrantOdds
+	Extra   string  `json:"extra"`
+	Current float64 `json:"current"`
+	Exact   bool    `json:"exact"`
+}
+
+// UniformTable returns a weight table giving every entrant the same weight
+func UniformTable(entrants []Entrant) []WeightEntry {
+	canonical := Canonical(entrants)
+	table := make([]WeightEntry, len(canonical))
+	for i, e := range canonical {
+		table[i] = WeightEntry{Address: e.Address, Votes: "0", Weight: "1"}
+	}
+	return table
+}
+
+// TableOdds computes the odds of every entrant of a weight table for a draw of slots winners
+func TableOdds(table []WeightEntry, slots int, iterations int) (OddsReport, error) {
+
+	weights := make([]*big.Int, len(table))
+	for i, entry := range table {
+		w, ok := new(big.Int).SetString(entry.Weight, 10)
+		if !ok || w.Sign() < 0 {
+			return OddsReport{}, ErrInvalidWeighting
+		}
+		weights[i] = w
+	}
+
+	result, err := Odds(weights, slots, iterations)
+	if err != nil {
+		return OddsReport{}, err
+	}
+
+	report := OddsReport{
+		Slots:      slots,
+		Exact:      result.Exact,
+		Iterations: result.Iterations,
+		Entrants:   make([]EntrantOdds, len(table)),
+	}
+	for i, entry := range table {
+		report.Entrants[i] = EntrantOdds{Address: entry.Address, Votes: entry.Votes, Weight: entry.Weight, Probability: result.Probabilities[i]}
+	}
+
+	return report, nil
+}
+
+// AddStake returns a copy of the table with extra votes added to an address
+// addresses not in the table are appended with the extra votes as their only votes
+// a nil config stands for uniform raffles, where votes do not change the weight
+func AddStake(table []WeightEntry, cfg *WeightConfig, address string, extra *big.Int) ([]WeightEntry, int, error) {
+
+	if extra.Sign() < 0 {
+		return nil, 0, ErrInvalidStake
+	}
+
+	updated := make([]WeightEntry, len(table), len(table)+1)
+	copy(updated, table)
+
+	index := -1
+	for i, entry := range updated {
+		if strings.EqualFold(entry.Address, address) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		updated = append(updated, WeightEntry{Address: address, Votes: "0", Weight: "1"})
+		index = len(updated) - 1
+	}
+
+	if cfg == nil {
+		return updated, index, nil
+	}
+
+	votes, ok := new(big.Int).SetString(updated[index].Votes, 10)
+	if !ok {
+		return nil, 0, ErrInvalidWeighting
+	}
+	votes.Add(votes, extra)
+
+	w, err := cfg.Weight(votes)
+	if err != nil {
+		return nil, 0, err
+	}
+	updated[index].Votes = votes.String()
+	updated[index].Weight = w.String()
+
+	return updated, index, nil
+}
+
+// AddWhatIf computes the odds of an address holding extra votes, all other entrants unchanged
+// the report's entrant odds are taken as the current odds
+func (r *OddsReport) AddWhatIf(table []WeightEntry, cfg *WeightConfig, address string, extra *big.Int, iterations int) error {
+
+	updated, index, err := AddStake(table, cfg, address, extra)
+	if err != nil {
+		return err
+	}
+
+	hypothetical, err := TableOdds(updated, r.Slots, iterations)
+	if err != nil {
+		return err
+	}
+
+	r.WhatIf = &WhatIf{
+		EntrantOdds: hypothetical.Entrants[index],
+		Extra:       extra.String(),
+		Exact:       hypothetical.Exact,
+	}
+	if index < len(r.Entrants) {
+		r.WhatIf.Current = r.Entrants[index].Probability
+	}
+
+	return nil
+}
+
+// exact odds by summing over all ordered draw sequences, grouped by the set of drawn entrants
+// f[mask] is the probability that exactly the entrants in mask have been drawn so far
+func exactOdds(w []float64, k int) []float64 {
+
+	// compact positive entrants
+	index := make([]int, 0, len(w))
+	for i, x := range w {
+		if x > 0 {
+			index = append(index, i)
+		}
+	}
+	m := len(index)
+
+	total := 0.0
+	for _, i := range index {
+		total += w[i]
+	}
+
+	f := make([]float64, 1<<uint(m))
+	drawnWeight := make([]float64, 1<<uint(m))
+	f[0] = 1
+	probabilities := make([]float64, len(w))
+
+	for mask := 0; mask < len(f); mask++ {
+		if f[mask] == 0 {
+			continue
+		}
+
+		size := popcount(mask)
+		if size == k {
+			for j := 0; j < m; j++ {
+				if mask&(1<<uint(j)) != 0 {
+					probabilities[index[j]] += f[mask]
+				}
+			}
+			continue
+		}
+
+		remaining := total - drawnWeight[mask]
+		for j := 0; j < m; j++ {
+			if mask&(1<<uint(j)) != 0 {
+				continue
+			}
+			next := mask | 1<<uint(j)
+			f[next] += f[mask] * w[index[j]] / remaining
+			drawnWeight[next] = drawnWeight[mask] + w[index[j]]
+		}
+	}
+
+	return probabilities
+}
+
+// monte carlo estimation of the odds
+func simulateOdds(w []float64, k int, iterations int) []float64 {
+
+	r := rand.New(rand.NewSource(1))
+	wins := make([]int, len(w))
+	taken := make([]bool, len(w))
+
+	total := 0.0
+	for _, x := range w {
+		total += x
+	}
+
+	for it := 0; it < iterations; it++ {
+
+		for i := range taken {
+			taken[i] = false
+		}
+		remaining := total
+
+		for d := 0; d < k; d++ {
+			point := r.Float64() * remaining
+			last := -1
+			for i, x := range w {
+				if taken[i] || x == 0 {
+					continue
+				}
+				last = i
+				if point < x {
+					break
+				}
+				point -= x
+			}
+
+			// rounding may leave point just above the last weight
+			taken[last] = true
+			wins[last]++
+			remaining -= w[last]
+		}
+	}
+
+	probabilities := make([]float64, len(w))
+	for i, c := range wins {
+		probabilities[i] = float64(c) / float64(iterations)
+	}
+
+	return probabilities
+}
+
+// converts weights to floats and counts positive weights
+func toFloats(weights []*big.Int) ([]float64, int) {
+	w := make([]float64, len(weights))
+	positive := 0
+	for i, x := range weights {
+		w[i], _ = new(big.Float).SetInt(x).Float64()
+		if w[i] > 0 {
+			positive++
+		}
+	}
+	return w, positive
+}
+
+// true if all positive weights are equal
+func uniform(w []float64) bool {
+	first := 0.0
+	for _, x := range w {
+		if x == 0 {
+			continue
+		}
+		if first == 0 {
+			first = x
+		} else if x != first {
+			return false
+		}
+	}
+	return true
+}
+
+func popcount(x int) int {
+	c := 0
+	for x != 0 {
+		x &= x - 1
+		c++
+	}
+	return c
+}
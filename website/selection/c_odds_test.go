@@ -0,0 +1,94 @@
+package selection
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func bigInts(values ...int64) []*big.Int {
+	weights := make([]*big.Int, len(values))
+	for i, v := range values {
+		weights[i] = big.NewInt(v)
+	}
+	return weights
+}
+
+func TestOdds(t *testing.T) {
+
+	// single slot follows the weights
+	result, err := Odds(bigInts(1, 3, 0), 1, 0)
+	if err != nil {
+		t.Fatalf("Odds failed, error: %v.", err)
+	}
+	if result.Probabilities[0] != 0.25 || result.Probabilities[1] != 0.75 || result.Probabilities[2] != 0 {
+		t.Errorf("Odds returned %v, expected [0.25 0.75 0].", result.Probabilities)
+	}
+
+	// two of three with weights 1, 1, 2
+	// p(heavy) = 1/2 + 2 * 1/4 * 2/3 = 5/6, remaining 7/6 split between the light entrants
+	result, _ = Odds(bigInts(1, 1, 2), 2, 0)
+	expected := []float64{7.0 / 12, 7.0 / 12, 5.0 / 6}
+	for i := range expected {
+		if math.Abs(result.Probabilities[i]-expected[i]) > 1e-12 {
+			t.Errorf("Odds returned %v, expected %v.", result.Probabilities, expected)
+			break
+		}
+	}
+
+	// simulation converges to the exact odds
+	weights := make([]int64, 20)
+	for i := range weights {
+		weights[i] = int64(i + 1)
+	}
+	w, _ := toFloats(bigInts(weights...))
+	exact := exactOdds(w, 5)
+	result, err = Odds(bigInts(weights...), 5, 50000)
+	if err != nil {
+		t.Fatalf("Odds failed, error: %v.", err)
+	}
+	if result.Exact || result.Iterations != 50000 {
+		t.Errorf("Odds computed exact %v with %d iterations, expected a simulation.", result.Exact, result.Iterations)
+	}
+	for i := range exact {
+		if math.Abs(result.Probabilities[i]-exact[i]) > 0.01 {
+			t.Errorf("simulated odds %f of entrant %d, expected about %f.", result.Probabilities[i], i, exact[i])
+		}
+	}
+
+	if _, err := Odds(bigInts(weights...), 5, MaxIterations+1); err != ErrInvalidIterations {
+		t.Errorf("Odds returned %v, expected %v.", err, ErrInvalidIterations)
+	}
+}
+
+func TestWhatIf(t *testing.T) {
+
+	entrants := testEntrants(4)
+	cfg := WeightConfig{Mode: WeightSqrt, SnapshotBlock: 1}
+	table := make([]WeightEntry, len(entrants))
+	for i, e := range Canonical(entrants) {
+		table[i] = WeightEntry{Address: e.Address, Votes: "100", Weight: "10"}
+	}
+
+	report, err := TableOdds(table, 2, 0)
+	if err != nil {
+		t.Fatalf("TableOdds failed, error: %v.", err)
+	}
+	if !report.Exact || report.Entrants[0].Probability != 0.5 {
+		t.Errorf("TableOdds returned %+v, expected exact odds of 0.5.", report.Entrants[0])
+	}
+
+	// 300 extra votes double the weight
+	if err := report.AddWhatIf(table, &cfg, table[0].Address, big.NewInt(300), 0); err != nil {
+		t.Fatalf("AddWhatIf failed, error: %v.", err)
+	}
+	if report.WhatIf.Weight != "20" || report.WhatIf.Current != 0.5 || report.WhatIf.Probability <= 0.5 {
+		t.Errorf("AddWhatIf returned %+v.", report.WhatIf)
+	}
+
+	// newcomers join with their extra votes only
+	updated, index, _ := AddStake(table, &cfg, "0x00000000000000000000000000000000000000ff", big.NewInt(25))
+	if index != 4 || updated[index].Weight != "5" || len(table) != 4 {
+		t.Errorf("AddStake returned %+v at %d.", updated[index], index)
+	}
+}
@@ -0,0 +1,87 @@
+package selection
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// domain separation of all hashes of the selection protocol
+const protocolVersion = "art-token-selection-v1"
+
+var ErrCommitmentMismatch = errors.New("Server secret does not match commitment")
+
+var ErrInvalidContribution = errors.New("Invalid entrant contribution")
+
+// an entrant of a closed raffle
+// contribution is an optional random hex string chosen by the entrant's browser
+// contributions are submitted while the server secret is still hidden
+type Entrant struct {
+	Address      string `json:"address"`
+	Contribution string `json:"contribution"`
+}
+
+// NewSecret returns a random hex encoded 32 byte server secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Commit returns the public commitment to a server secret, sha256(version | secret)
+func Commit(secret string) string {
+	return hashHex(protocolVersion, secret)
+}
+
+// ValidContribution checks that a contribution is at most 64 hex characters
+func ValidContribution(contribution string) bool {
+	if len(contribution) > 64 {
+		return false
+	}
+	_, err := hex.DecodeString(contribution)
+	return err == nil && len(contribution)%2 == 0
+}
+
+// Canonical returns entrants sorted by lowercased address
+// the draw operates on this order so that storage order does not matter
+func Canonical(entrants []Entrant) []Entrant {
+
+	sorted := make([]Entrant, len(entrants))
+	for i, e := range entrants {
+		sorted[i] = Entrant{Address: strings.ToLower(e.Address), Contribution: strings.ToLower(e.Contribution)}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	return sorted
+}
+
+// EntrantsHash commits to the canonical entrant list and all contributions
+func EntrantsHash(entrants []Entrant) string {
+
+	var b strings.Builder
+	for _, e := range Canonical(entrants) {
+		b.WriteString(e.Address)
+		b.WriteString(":")
+		b.WriteString(e.Contribution)
+		b.WriteString("\n")
+	}
+
+	return hashHex(protocolVersion, b.String())
+}
+
+// Seed mixes the revealed server secret, the entrants hash and an optional block hash
+// sha256(version | secret | entrants hash | block hash)
+func Seed(secret, entrantsHash, blockHash string) string {
+	return hashHex(protocolVersion, secret, entrantsHash, strings.ToLower(blockHash))
+}
+
+// sha256 over "|" separated parts, hex encoded
+func hashHex(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
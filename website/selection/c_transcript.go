@@ -0,0 +1,166 @@
+package selection
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTranscriptMismatch = errors.New("Transcript does not replay to the published result")
+
+// a drawn entrant and the token id it may mint, token ids follow draw order
+type Winner struct {
+	TokenId int    `json:"token_id"`
+	Address string `json:"address"`
+}
+
+// Transcript holds every input and output of a draw
+// publishing it allows anyone to replay the draw with Verify
+// uniform is set when a weighted raffle fell back to a uniform draw because no entrant had weight
+type Transcript struct {
+	Version      string        `json:"version"`
+	Commitment   string        `json:"commitment"`
+	Secret       string        `json:"secret"`
+	Entrants     []Entrant     `json:"entrants"`
+	EntrantsHash string        `json:"entrants_hash"`
+	BlockNumber  uint64        `json:"block_number,omitempty"`
+	BlockHash    string        `json:"block_hash,omitempty"`
+	Seed         string        `json:"seed"`
+	Slots        int           `json:"slots"`
+	Weighting    *WeightConfig `json:"weighting,omitempty"`
+	Weights      []WeightEntry `json:"weights,omitempty"`
+	Uniform      bool          `json:"uniform,omitempty"`
+	Winners      []Winner      `json:"winners"`
+	DrawnAt      time.Time     `json:"drawn_at"`
+}
+
+// NewTranscript reveals the server secret, derives the seed and draws winners for all slots
+// blockHash is optional, if given it must be the hash of a block mined after entries closed
+func NewTranscript(secret, commitment string, entrants []Entrant, blockNumber uint64, blockHash string, slots int, now time.Time) (Transcript, error) {
+	return newTranscript(secret, commitment, entrants, blockNumber, blockHash, slots, nil, nil, now)
+}
+
+// NewWeightedTranscript draws winners proportional to the weights of a weight table
+// the table is published with the transcript so that weights can be checked against the chain
+func NewWeightedTranscript(secret, commitment string, entrants []Entrant, blockNumber uint64, blockHash string, slots int, cfg WeightConfig, table []WeightEntry, now time.Time) (Transcript, error) {
+	return newTranscript(secret, commitment, entrants, blockNumber, blockHash, slots, &cfg, table, now)
+}
+
+func newTranscript(secret, commitment string, entrants []Entrant, blockNumber uint64, blockHash string, slots int, cfg *WeightConfig, table []WeightEntry, now time.Time) (Transcript, error) {
+
+	if Commit(secret) != commitment {
+		return Transcript{}, ErrCommitmentMismatch
+	}
+
+	for _, e := range entrants {
+		if !ValidContribution(e.Contribution) {
+			return Transcript{}, ErrInvalidContribution
+		}
+	}
+
+	t := Transcript{
+		Version:      protocolVersion,
+		Commitment:   commitment,
+		Secret:       secret,
+		Entrants:     Canonical(entrants),
+		EntrantsHash: EntrantsHash(entrants),
+		BlockNumber:  blockNumber,
+		BlockHash:    blockHash,
+		Slots:        slots,
+		Weighting:    cfg,
+		Weights:      table,
+		DrawnAt:      now,
+	}
+	t.Seed = Seed(secret, t.EntrantsHash, blockHash)
+
+	// without any weight a weighted raffle could never be drawn, everybody gets the same chance instead
+	drawn, err := t.draw()
+	if err == ErrNoWeight {
+		t.Uniform = true
+		drawn, err = t.draw()
+	}
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	t.Winners = make([]Winner, len(drawn))
+	for i, e := range drawn {
+		t.Winners[i] = Winner{TokenId: i, Address: e.Address}
+	}
+
+	return t, nil
+}
+
+// Verify replays a published transcript
+// checks the secret against the commitment made before entries opened, recomputes the seed and the draw
+func Verify(t Transcript) error {
+
+	if t.Version != protocolVersion {
+		return ErrTranscriptMismatch
+	}
+
+	if Commit(t.Secret) != t.Commitment {
+		return ErrCommitmentMismatch
+	}
+
+	if EntrantsHash(t.Entrants) != t.EntrantsHash {
+		return ErrTranscriptMismatch
+	}
+
+	if Seed(t.Secret, t.EntrantsHash, t.BlockHash) != t.Seed {
+		return ErrTranscriptMismatch
+	}
+
+	drawn, err := t.draw()
+	if err != nil {
+		return err
+	}
+
+	if len(drawn) != len(t.Winners) {
+		return ErrTranscriptMismatch
+	}
+	for i, e := range drawn {
+		if t.Winners[i].TokenId != i || t.Winners[i].Address != e.Address {
+			return ErrTranscriptMismatch
+		}
+	}
+
+	return nil
+}
+
+// replays the uniform or weighted draw of a transcript
+func (t *Transcript) draw() ([]Entrant, error) {
+
+	if t.Weighting == nil {
+		if t.Uniform {
+			return nil, ErrTranscriptMismatch
+		}
+		return Draw(t.Seed, t.Entrants, t.Slots)
+	}
+
+	// weight table must cover the canonical entrants in order
+	if len(t.Weights) != len(t.Entrants) {
+		return nil, ErrTranscriptMismatch
+	}
+	for i, e := range t.Entrants {
+		if t.Weights[i].Address != e.Address {
+			return nil, ErrTranscriptMismatch
+		}
+	}
+
+	weights, err := checkWeightTable(t.Weights, *t.Weighting)
+	if err != nil {
+		return nil, err
+	}
+
+	// the uniform fallback is only valid if the table has no weight at all
+	if t.Uniform {
+		for _, w := range weights {
+			if w.Sign() > 0 {
+				return nil, ErrTranscriptMismatch
+			}
+		}
+		return Draw(t.Seed, t.Entrants, t.Slots)
+	}
+
+	return DrawWeighted(t.Seed, t.Entrants, weights, t.Slots)
+}
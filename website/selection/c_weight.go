@@ -0,0 +1,254 @@
+package selection
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+// ******** Stake weighting **********
+
+type WeightMode string
+
+const (
+
+	// weight equals the capped votes
+	WeightLinear WeightMode = "linear"
+
+	// weight is the integer square root of the capped votes, limits the advantage of large holders
+	WeightSqrt WeightMode = "sqrt"
+)
+
+// what the weight of an entrant is derived from
+type WeightSource string
+
+const (
+
+	// delegated votes of the entrant, the default
+	SourceVotes WeightSource = "votes"
+
+	// ART the entrant staked toward the collection
+	SourceStake WeightSource = "stake"
+)
+
+var ErrInvalidWeighting = errors.New("Invalid weighting configuration")
+
+var ErrNoWeight = errors.New("No entrant has a positive weight")
+
+var ErrNoVotesSource = errors.New("No votes source configured")
+
+// VotesSource reads delegated votes of an account at a past block
+// implementations must follow the Art contract's getPriorVotes semantics:
+// the block must be finalized and votes are the delegated balance as of the end of that block
+type VotesSource interface {
+	PriorVotes(ctx context.Context, account string, blockNumber uint64) (*big.Int, error)
+}
+
+// WeightConfig defines how entrant weights are derived from votes at the snapshot block
+// amounts are decimal strings in the token's smallest unit
+// source selects votes or stake, votes if empty, both are read at the snapshot block
+// cap limits the votes counted per entrant, base is added to every weight after transformation
+// entrants without votes can only win if base is positive, unless nobody has weight and the draw falls back to uniform
+type WeightConfig struct {
+	Mode          WeightMode   `json:"mode"`
+	Source        WeightSource `json:"source,omitempty"`
+	SnapshotBlock uint64       `json:"snapshot_block"`
+	Cap           string       `json:"cap,omitempty"`
+	Base          string       `json:"base,omitempty"`
+}
+
+// votes and resulting weight of an entrant, published with the transcript
+type WeightEntry struct {
+	Address string `json:"address"`
+	Votes   string `json:"votes"`
+	Weight  string `json:"weight"`
+}
+
+// parses cap and base, nil cap means uncapped
+func (wc *WeightConfig) parse() (cap *big.Int, base *big.Int, err error) {
+
+	if wc.Mode != WeightLinear && wc.Mode != WeightSqrt {
+		return nil, nil, ErrInvalidWeighting
+	}
+	if wc.Source != "" && wc.Source != SourceVotes && wc.Source != SourceStake {
+		return nil, nil, ErrInvalidWeighting
+	}
+
+	base = new(big.Int)
+	if wc.Base != "" {
+		if _, ok := base.SetString(wc.Base, 10); !ok || base.Sign() < 0 {
+			return nil, nil, ErrInvalidWeighting
+		}
+	}
+
+	if wc.Cap != "" {
+		cap = new(big.Int)
+		if _, ok := cap.SetString(wc.Cap, 10); !ok || cap.Sign() <= 0 {
+			return nil, nil, ErrInvalidWeighting
+		}
+	}
+
+	return cap, base, nil
+}
+
+// Validate checks the weighting configuration
+func (wc *WeightConfig) Validate() error {
+	_, _, err := wc.parse()
+	return err
+}
+
+// Weight derives the draw weight from votes
+func (wc *WeightConfig) Weight(votes *big.Int) (*big.Int, error) {
+
+	cap, base, err := wc.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	w := new(big.Int).Set(votes)
+	if w.Sign() < 0 {
+		return nil, ErrInvalidWeighting
+	}
+	if cap != nil && w.Cmp(cap) > 0 {
+		w.Set(cap)
+	}
+	if wc.Mode == WeightSqrt {
+		w.Sqrt(w)
+	}
+
+	return w.Add(w, base), nil
+}
+
+// WeightTable reads votes of all entrants at the snapshot block and derives their weights
+// the table is in canonical entrant order
+func WeightTable(ctx context.Context, src VotesSource, entrants []Entrant, cfg WeightConfig) ([]WeightEntry, error) {
+
+	if src == nil {
+		return nil, ErrNoVotesSource
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	canonical := Canonical(entrants)
+	table := make([]WeightEntry, len(canonical))
+	for i, e := range canonical {
+
+		votes, err := src.PriorVotes(ctx, e.Address, cfg.SnapshotBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		w, err := cfg.Weight(votes)
+		if err != nil {
+			return nil, err
+		}
+
+		table[i] = WeightEntry{Address: e.Address, Votes: votes.String(), Weight: w.String()}
+	}
+
+	return table, nil
+}
+
+// checks that every weight of a published table follows from its votes
+func checkWeightTable(table []WeightEntry, cfg WeightConfig) ([]*big.Int, error) {
+
+	weights := make([]*big.Int, len(table))
+	for i, entry := range table {
+
+		votes, ok := new(big.Int).SetString(entry.Votes, 10)
+		if !ok {
+			return nil, ErrTranscriptMismatch
+		}
+
+		w, err := cfg.Weight(votes)
+		if err != nil {
+			return nil, err
+		}
+		if w.String() != entry.Weight {
+			return nil, ErrTranscriptMismatch
+		}
+
+		weights[i] = w
+	}
+
+	return weights, nil
+}
+
+// uniform big integer in [0, n) using rejection sampling
+func (s *stream) bigIntn(n *big.Int) *big.Int {
+
+	bits := n.BitLen()
+	bytes := make([]byte, (bits+7)/8)
+	mask := byte(0xFF >> (uint(len(bytes)*8 - bits)))
+
+	v := new(big.Int)
+	for {
+		for i := 0; i < len(bytes); i += 8 {
+			var chunk [8]byte
+			x := s.uint64()
+			for b := 0; b < 8; b++ {
+				chunk[b] = byte(x >> (56 - 8*uint(b)))
+			}
+			copy(bytes[i:], chunk[:])
+		}
+		bytes[0] &= mask
+
+		v.SetBytes(bytes)
+		if v.Cmp(n) < 0 {
+			return v
+		}
+	}
+}
+
+// DrawWeighted picks k distinct entrants with probability proportional to their weight
+// each round draws from the remaining entrants, entrants with zero weight are never drawn
+// entrants and weights must be in canonical order
+func DrawWeighted(seed string, entrants []Entrant, weights []*big.Int, k int) ([]Entrant, error) {
+
+	s, err := newStream(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := make([]Entrant, len(entrants))
+	copy(pool, entrants)
+	remaining := make([]*big.Int, len(weights))
+	total := new(big.Int)
+	positive := 0
+	for i, w := range weights {
+		remaining[i] = new(big.Int).Set(w)
+		total.Add(total, w)
+		if w.Sign() > 0 {
+			positive++
+		}
+	}
+
+	if positive == 0 {
+		return nil, ErrNoWeight
+	}
+	if k > positive {
+		k = positive
+	}
+
+	winners := make([]Entrant, 0, k)
+	for len(winners) < k {
+
+		// walk cumulative weights until the random point is covered
+		r := s.bigIntn(total)
+		i := 0
+		for ; i < len(pool); i++ {
+			if r.Cmp(remaining[i]) < 0 {
+				break
+			}
+			r.Sub(r, remaining[i])
+		}
+
+		winners = append(winners, pool[i])
+		total.Sub(total, remaining[i])
+		pool = append(pool[:i], pool[i+1:]...)
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+
+	return winners, nil
+}
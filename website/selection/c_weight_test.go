@@ -0,0 +1,106 @@
+package selection
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// votes source returning fixed votes per address
+type staticVotes map[string]int64
+
+func (sv staticVotes) PriorVotes(_ context.Context, account string, _ uint64) (*big.Int, error) {
+	return big.NewInt(sv[account]), nil
+}
+
+func TestWeight(t *testing.T) {
+
+	cfg := WeightConfig{Mode: WeightSqrt, SnapshotBlock: 1, Cap: "10000", Base: "1"}
+
+	for votes, expected := range map[int64]int64{0: 1, 99: 10, 10000: 101, 1000000: 101} {
+		w, err := cfg.Weight(big.NewInt(votes))
+		if err != nil {
+			t.Fatalf("Weight failed, error: %v.", err)
+		}
+		if w.Int64() != expected {
+			t.Errorf("Weight(%d) returned %d, expected %d.", votes, w.Int64(), expected)
+		}
+	}
+
+	if err := (&WeightConfig{Mode: "quadratic"}).Validate(); err != ErrInvalidWeighting {
+		t.Errorf("Validate returned %v, expected %v.", err, ErrInvalidWeighting)
+	}
+}
+
+func TestWeightedTranscript(t *testing.T) {
+
+	entrants := testEntrants(3)
+	votes := staticVotes{entrants[0].Address: 0, entrants[1].Address: 100, entrants[2].Address: 300}
+	cfg := WeightConfig{Mode: WeightLinear, SnapshotBlock: 1}
+
+	table, err := WeightTable(context.Background(), votes, entrants, cfg)
+	if err != nil {
+		t.Fatalf("WeightTable failed, error: %v.", err)
+	}
+
+	// zero weight entrants are never drawn, remaining slots stay empty
+	secret, _ := NewSecret()
+	tr, err := NewWeightedTranscript(secret, Commit(secret), entrants, 0, "", 3, cfg, table, time.Now())
+	if err != nil {
+		t.Fatalf("NewWeightedTranscript failed, error: %v.", err)
+	}
+	if len(tr.Winners) != 2 {
+		t.Errorf("drew %d winners, expected 2.", len(tr.Winners))
+	}
+	if err := Verify(tr); err != nil {
+		t.Errorf("Verify failed, error: %v.", err)
+	}
+
+	// inflated weight in published table
+	tr.Weights[1].Weight = "1000"
+	if err := Verify(tr); err != ErrTranscriptMismatch {
+		t.Errorf("Verify returned %v, expected %v.", err, ErrTranscriptMismatch)
+	}
+
+	// first pick follows the weights, 1:3
+	counts := make(map[string]int)
+	weights := []*big.Int{big.NewInt(0), big.NewInt(100), big.NewInt(300)}
+	for i := 0; i < 4000; i++ {
+		winners, _ := DrawWeighted(hashHex(fmt.Sprint(i)), Canonical(entrants), weights, 1)
+		counts[winners[0].Address]++
+	}
+	if c := counts[entrants[2].Address]; c < 2850 || c > 3150 {
+		t.Errorf("heaviest entrant won %d of 4000 rounds, expected about 3000.", c)
+	}
+}
+
+// a weighted raffle without any weight falls back to a uniform draw instead of failing forever
+func TestUniformFallback(t *testing.T) {
+
+	entrants := testEntrants(3)
+	cfg := WeightConfig{Mode: WeightLinear, SnapshotBlock: 1}
+	table, err := WeightTable(context.Background(), staticVotes{}, entrants, cfg)
+	if err != nil {
+		t.Fatalf("WeightTable failed, error: %v.", err)
+	}
+
+	secret, _ := NewSecret()
+	tr, err := NewWeightedTranscript(secret, Commit(secret), entrants, 0, "", 2, cfg, table, time.Now())
+	if err != nil {
+		t.Fatalf("NewWeightedTranscript failed, error: %v.", err)
+	}
+	if !tr.Uniform || len(tr.Winners) != 2 {
+		t.Fatalf("drew %d winners with uniform %t, expected 2 with uniform fallback.", len(tr.Winners), tr.Uniform)
+	}
+	if err := Verify(tr); err != nil {
+		t.Errorf("Verify failed, error: %v.", err)
+	}
+
+	// the fallback is not valid once anybody has weight
+	tr.Weights[0].Votes, tr.Weights[0].Weight = "5", "5"
+	if err := Verify(tr); err != ErrTranscriptMismatch {
+		t.Errorf("Verify returned %v, expected %v.", err, ErrTranscriptMismatch)
+	}
+}
@@ -104,11 +104,12 @@ func AttachRoutes(router *mux.Router, sc *securecookie.SecureCookie, ctx context
 		httptransport.ServerErrorEncoder(encodeError),
 	}
 
+	// login creates the session inside the endpoint, the holder carries its identifier to AfterEndpointCall
 	createSessionHandler := httptransport.NewServer(
 		e.CreateSession,
 		decodeCreateSession,
 		encodeResponse,
-		options...,
+		append([]httptransport.ServerOption{httptransport.ServerBefore(newSessionHolder)}, options...)...,
 	)
 
 	deleteSessionHandler := httptransport.NewServer(
@@ -126,6 +127,18 @@ func AttachRoutes(router *mux.Router, sc *securecookie.SecureCookie, ctx context
 	return router
 }
 
+// puts an empty session identifier holder into the login request context
+func newSessionHolder(ctx context.Context, _ *http.Request) context.Context {
+	return context.WithValue(ctx, SessionIdContextKey("new_session_id"), new(string))
+}
+
+// reads the session identifier set by setSessionIdContextMDW
+// other services use it to identify the user of a request
+func SessionIdFromContext(ctx context.Context) (string, bool) {
+	sessionId, ok := ctx.Value(SessionIdContextKey("session_id")).(string)
+	return sessionId, ok && sessionId != ""
+}
+
 // makes sure that login request and responses of session calls are encoded in JSON
 func jsonMDW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -150,11 +163,11 @@ func setSessionIdContextMDW(ctx context.Context, s Service, ss *securecookie.Sec
 				value := make(map[string]string)
 				if err = ss.Decode("session_cookie", cookie.Value, &value); err == nil {
 
-					// extract session identifier from cookie and write to context
+					// extract session identifier from cookie and write to request context
 					var sessionId string
 					sessionId = value["session_id"]
 					key := SessionIdContextKey("session_id")
-					context.WithValue(ctx, key, sessionId)
+					r = r.WithContext(context.WithValue(r.Context(), key, sessionId))
 
 					// update session expiry time if session active
 					// info: applies to all active session requests hitting the page
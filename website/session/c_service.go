@@ -78,10 +78,11 @@ func (s *service) CreateSession(ctx context.Context, email string, password stri
 		return err
 	}
 
-	// if session creation successfull, set session identifier in context
+	// if session creation successfull, set session identifier in the holder of the login context
 	// if not set, service.AfterEndpointCall cannot read session identifier and set cookie
-	key := SessionIdContextKey("session_id")
-	context.WithValue(ctx, key, sessionId)
+	if holder, ok := ctx.Value(SessionIdContextKey("new_session_id")).(*string); ok {
+		*holder = sessionId
+	}
 
 	return nil
 
@@ -156,6 +157,9 @@ func (s *service) AfterEndpointCall(ctx context.Context, w http.ResponseWriter)
 	// make sure session_id context set in createSession if request does not contains a cookie
 	k := SessionIdContextKey("session_id")
 	sessionId, ok := ctx.Value(k).(string)
+	if holder, set := ctx.Value(SessionIdContextKey("new_session_id")).(*string); set && *holder != "" {
+		sessionId, ok = *holder, true
+	}
 
 	// only set activate cookie if session_id allows to search for active session
 	if ok {
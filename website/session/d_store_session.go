@@ -1,6 +1,7 @@
 package session
 
 import (
+	"encoding/hex"
 	"errors"
 	"sync"
 	"time"
@@ -14,6 +15,16 @@ type Session struct {
 	ExpiresAt time.Time
 }
 
+// user identifier of the session owner, the hex encoded md5 hash of the user email
+func (s Session) UserId() string {
+	return hex.EncodeToString([]byte(s.FileHash))
+}
+
+// true if the session has not expired
+func (s Session) Active(now time.Time) bool {
+	return now.Before(s.ExpiresAt)
+}
+
 // ********** Session store interface **********
 
 type SessionStore interface {
@@ -0,0 +1,120 @@
+package signing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// handler not found
+var ErrInternalServer = errors.New("Internal server error")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := ErrorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// ErrorStatusCode maps predefined and signature errors to status codes
+// services verifying signed requests use it for errors returned by Verify methods
+// returns internal server error if error cannot be mapped
+func ErrorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrSignerMismatch),
+		errors.Is(err, ErrExpired):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrWalletNotLinked):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrInvalidNonce):
+		return http.StatusConflict
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// does not decode request
+func decodeReadTypedData(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadTypedDataRequest{}, nil
+}
+
+// decode address from route
+func decodeReadNonce(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadNonceRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching signing handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	readTypedDataHandler := httptransport.NewServer(
+		e.ReadTypedData,
+		decodeReadTypedData,
+		encodeResponse,
+		options...,
+	)
+
+	readNonceHandler := httptransport.NewServer(
+		e.ReadNonce,
+		decodeReadNonce,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/signing/typed-data", readTypedDataHandler).Methods("GET")
+	router.Handle("/signing/nonces/{address}", readNonceHandler).Methods("GET")
+
+	return router
+}
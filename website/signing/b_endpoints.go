@@ -0,0 +1,79 @@
+package signing
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ReadTypedDataRequest struct{}
+
+type ReadNonceRequest struct {
+	Address string
+}
+
+type TypedDataResponse struct {
+	Data TypedData `json:"data"`
+	Err  error     `json:"errors"`
+}
+
+// have TypedDataResponse follow the customError interface defined in a_transport.go
+func (r TypedDataResponse) error() error { return r.Err }
+
+type NonceResponse struct {
+	Data uint64 `json:"data"`
+	Err  error  `json:"errors"`
+}
+
+// have NonceResponse follow the customError interface defined in a_transport.go
+func (r NonceResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	ReadTypedData endpoint.Endpoint
+	ReadNonce     endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		ReadTypedData: epReadTypedData(s),
+		ReadNonce:     epReadNonce(s),
+	}
+}
+
+// typed data definitions endpoint
+func epReadTypedData(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		td, err := s.ReadTypedData(ctx)
+		if err != nil {
+			return TypedDataResponse{Err: err}, err
+		}
+
+		return TypedDataResponse{Data: td, Err: nil}, nil
+	}
+}
+
+// next nonce endpoint
+func epReadNonce(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadNonceRequest)
+
+		// call service method
+		nonce, err := s.ReadNonce(ctx, req.Address)
+		if err != nil {
+			return NonceResponse{Err: err}, err
+		}
+
+		return NonceResponse{Data: nonce, Err: nil}, nil
+	}
+}
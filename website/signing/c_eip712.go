@@ -0,0 +1,167 @@
+package signing
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ******** EIP-712 typed data **********
+
+// type strings, the domain follows the Art contract's DOMAIN_TYPEHASH
+const (
+	DomainType = "EIP712Domain(string name,uint256 chainId,address verifyingContract)"
+	BidType    = "Bid(string auction,address bidder,uint256 amount,uint256 nonce,uint256 deadline)"
+	EntryType  = "Entry(string collection,address entrant,string contribution,uint256 nonce,uint256 deadline)"
+)
+
+var (
+	domainTypeHash = crypto.Keccak256Hash([]byte(DomainType))
+	bidTypeHash    = crypto.Keccak256Hash([]byte(BidType))
+	entryTypeHash  = crypto.Keccak256Hash([]byte(EntryType))
+)
+
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+// signing domain, bound to a chain and the Art contract
+type Domain struct {
+	Name              string         `json:"name"`
+	ChainId           *big.Int       `json:"chainId"`
+	VerifyingContract common.Address `json:"verifyingContract"`
+}
+
+// auction bid as signed by the bidder's wallet
+type Bid struct {
+	Auction  string
+	Bidder   common.Address
+	Amount   *big.Int
+	Nonce    uint64
+	Deadline int64
+}
+
+// raffle entry as signed by the entrant's wallet
+type Entry struct {
+	Collection   string
+	Entrant      common.Address
+	Contribution string
+	Nonce        uint64
+	Deadline     int64
+}
+
+// Separator computes the domain separator like the Art contract
+// keccak256(abi.encode(DOMAIN_TYPEHASH, keccak256(bytes(name)), chainId, address(this)))
+func (d Domain) Separator() common.Hash {
+	return crypto.Keccak256Hash(
+		domainTypeHash[:],
+		crypto.Keccak256([]byte(d.Name)),
+		word(d.ChainId),
+		common.LeftPadBytes(d.VerifyingContract[:], 32),
+	)
+}
+
+// Digest of a struct hash within the domain, the hash wallets sign
+func (d Domain) Digest(structHash common.Hash) common.Hash {
+	sep := d.Separator()
+	return crypto.Keccak256Hash([]byte("\x19\x01"), sep[:], structHash[:])
+}
+
+// StructHash of a bid, strings are hashed, all other members are abi encoded words
+func (b Bid) StructHash() common.Hash {
+	return crypto.Keccak256Hash(
+		bidTypeHash[:],
+		crypto.Keccak256([]byte(b.Auction)),
+		common.LeftPadBytes(b.Bidder[:], 32),
+		word(b.Amount),
+		word(new(big.Int).SetUint64(b.Nonce)),
+		word(big.NewInt(b.Deadline)),
+	)
+}
+
+// StructHash of a raffle entry
+func (e Entry) StructHash() common.Hash {
+	return crypto.Keccak256Hash(
+		entryTypeHash[:],
+		crypto.Keccak256([]byte(e.Collection)),
+		common.LeftPadBytes(e.Entrant[:], 32),
+		crypto.Keccak256([]byte(e.Contribution)),
+		word(new(big.Int).SetUint64(e.Nonce)),
+		word(big.NewInt(e.Deadline)),
+	)
+}
+
+// Recover returns the address that signed a digest
+// accepts 65 byte signatures with a recovery id of 0/1 or 27/28 and rejects malleable high s values
+func Recover(digest common.Hash, signature []byte) (common.Address, error) {
+
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, true) {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	pub, err := crypto.SigToPub(digest[:], sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// ******** typed data for wallets **********
+
+// member of an EIP-712 type
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// typed data skeleton for eth_signTypedData_v4, clients add primaryType and message
+type TypedData struct {
+	Types  map[string][]Field `json:"types"`
+	Domain Domain             `json:"domain"`
+}
+
+// typed data definitions of all signed messages
+func (d Domain) TypedData() TypedData {
+	return TypedData{
+		Types: map[string][]Field{
+			"EIP712Domain": fields(DomainType),
+			"Bid":          fields(BidType),
+			"Entry":        fields(EntryType),
+		},
+		Domain: d,
+	}
+}
+
+// parses the members of a type string
+func fields(typ string) []Field {
+	members := typ[strings.Index(typ, "(")+1 : len(typ)-1]
+	var fs []Field
+	for _, member := range strings.Split(members, ",") {
+		parts := strings.SplitN(member, " ", 2)
+		fs = append(fs, Field{Name: parts[1], Type: parts[0]})
+	}
+	return fs
+}
+
+// 32 byte big endian word of a non-negative integer
+func word(x *big.Int) []byte {
+	if x == nil {
+		x = new(big.Int)
+	}
+	return math.U256Bytes(new(big.Int).Set(x))
+}
@@ -0,0 +1,229 @@
+package signing
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/go-kit/kit/log"
+)
+
+var testDomain = Domain{
+	Name:              "ArtToken",
+	ChainId:           big.NewInt(31337),
+	VerifyingContract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+}
+
+// service with a fixed clock and nonces in a temporary directory
+func testService(t *testing.T, now time.Time) Service {
+	nonceStore, err := NewNonceStore(NonceStoreConfig{NoncesPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewNonceStore failed, error: %v.", err)
+	}
+	s := NewService(testDomain, nonceStore, nil, nil, log.NewNopLogger()).(*service)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+// signs a digest like a wallet, recovery id 27/28
+func sign(t *testing.T, key *ecdsa.PrivateKey, digest common.Hash) string {
+	sig, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		t.Fatalf("crypto.Sign failed, error: %v.", err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+// digests must match go-ethereum's eth_signTypedData_v4 implementation
+func TestDigestMatchesTypedData(t *testing.T) {
+
+	bid := Bid{
+		Auction:  "0123456789abcdef0123456789abcdef",
+		Bidder:   common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		Amount:   big.NewInt(1500000000000000000),
+		Nonce:    3,
+		Deadline: 1700000000,
+	}
+	entry := Entry{
+		Collection:   "fedcba9876543210fedcba9876543210",
+		Entrant:      common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		Contribution: "1000",
+		Nonce:        4,
+		Deadline:     1700000000,
+	}
+
+	typed := testDomain.TypedData()
+	types := apitypes.Types{}
+	for name, fs := range typed.Types {
+		for _, f := range fs {
+			types[name] = append(types[name], apitypes.Type{Name: f.Name, Type: f.Type})
+		}
+	}
+	domain := apitypes.TypedDataDomain{
+		Name:              testDomain.Name,
+		ChainId:           (*math.HexOrDecimal256)(testDomain.ChainId),
+		VerifyingContract: testDomain.VerifyingContract.Hex(),
+	}
+
+	cases := []struct {
+		primary string
+		message apitypes.TypedDataMessage
+		digest  common.Hash
+	}{
+		{"Bid", apitypes.TypedDataMessage{
+			"auction":  bid.Auction,
+			"bidder":   bid.Bidder.Hex(),
+			"amount":   bid.Amount.String(),
+			"nonce":    "3",
+			"deadline": "1700000000",
+		}, testDomain.Digest(bid.StructHash())},
+		{"Entry", apitypes.TypedDataMessage{
+			"collection":   entry.Collection,
+			"entrant":      entry.Entrant.Hex(),
+			"contribution": entry.Contribution,
+			"nonce":        "4",
+			"deadline":     "1700000000",
+		}, testDomain.Digest(entry.StructHash())},
+	}
+
+	for _, c := range cases {
+		want, _, err := apitypes.TypedDataAndHash(apitypes.TypedData{
+			Types:       types,
+			PrimaryType: c.primary,
+			Domain:      domain,
+			Message:     c.message,
+		})
+		if err != nil {
+			t.Fatalf("TypedDataAndHash failed, error: %v.", err)
+		}
+		if common.BytesToHash(want) != c.digest {
+			t.Errorf("%s digest failed, got %s, want %s.", c.primary, c.digest.Hex(), common.BytesToHash(want).Hex())
+		}
+	}
+}
+
+func TestVerifyBid(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	s := testService(t, now)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed, error: %v.", err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed, error: %v.", err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	bid := Bid{Auction: "0123456789abcdef0123456789abcdef", Bidder: address, Amount: big.NewInt(100), Nonce: 0, Deadline: now.Unix() + 60}
+	sig := sign(t, key, testDomain.Digest(bid.StructHash()))
+
+	if err := s.VerifyBid(ctx, bid, sig); err != nil {
+		t.Errorf("VerifyBid failed, error: %v.", err)
+	}
+	if nonce, err := s.ReadNonce(ctx, address.Hex()); err != nil || nonce != 1 {
+		t.Errorf("ReadNonce failed, got %d, error: %v.", nonce, err)
+	}
+
+	// replaying the same signature
+	if err := s.VerifyBid(ctx, bid, sig); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("VerifyBid replay failed, error: %v.", err)
+	}
+
+	// signature by another wallet
+	bid.Nonce = 1
+	if err := s.VerifyBid(ctx, bid, sign(t, other, testDomain.Digest(bid.StructHash()))); !errors.Is(err, ErrSignerMismatch) {
+		t.Errorf("VerifyBid signer mismatch failed, error: %v.", err)
+	}
+
+	// altered amount
+	sig = sign(t, key, testDomain.Digest(bid.StructHash()))
+	altered := bid
+	altered.Amount = big.NewInt(1)
+	if err := s.VerifyBid(ctx, altered, sig); !errors.Is(err, ErrSignerMismatch) {
+		t.Errorf("VerifyBid altered amount failed, error: %v.", err)
+	}
+
+	// a rejected signature does not consume the nonce
+	if err := s.VerifyBid(ctx, bid, sig); err != nil {
+		t.Errorf("VerifyBid failed, error: %v.", err)
+	}
+
+	// expired deadline
+	bid.Nonce = 2
+	bid.Deadline = now.Unix() - 1
+	if err := s.VerifyBid(ctx, bid, sign(t, key, testDomain.Digest(bid.StructHash()))); !errors.Is(err, ErrExpired) {
+		t.Errorf("VerifyBid expired failed, error: %v.", err)
+	}
+
+	// signature for another chain
+	bid.Deadline = now.Unix() + 60
+	foreign := testDomain
+	foreign.ChainId = big.NewInt(1)
+	if err := s.VerifyBid(ctx, bid, sign(t, key, foreign.Digest(bid.StructHash()))); !errors.Is(err, ErrSignerMismatch) {
+		t.Errorf("VerifyBid foreign chain failed, error: %v.", err)
+	}
+
+	if err := s.VerifyBid(ctx, bid, "0x1234"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyBid malformed signature failed, error: %v.", err)
+	}
+}
+
+func TestVerifyEntry(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	s := testService(t, now)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed, error: %v.", err)
+	}
+
+	entry := Entry{Collection: "fedcba9876543210fedcba9876543210", Entrant: crypto.PubkeyToAddress(key.PublicKey), Contribution: "0", Deadline: now.Unix()}
+	if err := s.VerifyEntry(context.Background(), entry, sign(t, key, testDomain.Digest(entry.StructHash()))); err != nil {
+		t.Errorf("VerifyEntry failed, error: %v.", err)
+	}
+
+	// a bid signature with the same nonce is not an entry signature
+	bid := Bid{Auction: entry.Collection, Bidder: entry.Entrant, Amount: big.NewInt(0), Nonce: 1, Deadline: now.Unix()}
+	entry.Nonce = 1
+	if err := s.VerifyEntry(context.Background(), entry, sign(t, key, testDomain.Digest(bid.StructHash()))); !errors.Is(err, ErrSignerMismatch) {
+		t.Errorf("VerifyEntry with bid signature failed, error: %v.", err)
+	}
+}
+
+// nonces survive a restart
+func TestNonceStorePersists(t *testing.T) {
+
+	dir := t.TempDir()
+	ns, err := NewNonceStore(NonceStoreConfig{NoncesPath: dir}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewNonceStore failed, error: %v.", err)
+	}
+	if err := ns.Use("0xabc", 0); err != nil {
+		t.Errorf("Use failed, error: %v.", err)
+	}
+	if err := ns.Use("0xabc", 2); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Use out of order failed, error: %v.", err)
+	}
+
+	ns, err = NewNonceStore(NonceStoreConfig{NoncesPath: dir}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewNonceStore failed, error: %v.", err)
+	}
+	if next, _ := ns.Next("0xabc"); next != 1 {
+		t.Errorf("Next failed, got %d, want 1.", next)
+	}
+}
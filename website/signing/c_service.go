@@ -0,0 +1,185 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"website/session"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrSignerMismatch = errors.New("Message was not signed by the given wallet")
+
+var ErrExpired = errors.New("Signature deadline has passed")
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrWalletNotLinked = errors.New("Wallet is not linked to the user")
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+// hex encoded 20 byte ethereum address
+var addressPattern = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// nonce, deadline in unix seconds and hex encoded signature sent along with a signed request
+type Proof struct {
+	Nonce     uint64 `json:"nonce"`
+	Deadline  int64  `json:"deadline"`
+	Signature string `json:"signature"`
+}
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// Wallets checks whether a wallet address belongs to a user
+type Wallets interface {
+	IsLinked(ctx context.Context, userId string, address string) (bool, error)
+}
+
+// service interface defining all required methods
+type Service interface {
+	ReadTypedData(ctx context.Context) (TypedData, error)
+	ReadNonce(ctx context.Context, address string) (uint64, error)
+	VerifyBid(ctx context.Context, b Bid, signature string) error
+	VerifyEntry(ctx context.Context, e Entry, signature string) error
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+	domain     Domain
+	nonceStore NonceStore
+	sessions   Sessions
+	wallets    Wallets
+	now        func() time.Time
+	logger     log.Logger
+}
+
+// service struct read typed data method
+// returns domain and type definitions for wallets signing bids and entries
+func (s *service) ReadTypedData(ctx context.Context) (TypedData, error) {
+	return s.domain.TypedData(), nil
+}
+
+// service struct read nonce method
+// returns the nonce the next signed message of an address must use
+func (s *service) ReadNonce(ctx context.Context, address string) (uint64, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadNonce")
+
+	if !addressPattern.MatchString(address) {
+		return 0, ErrInvalidAddress
+	}
+
+	nonce, err := s.nonceStore.Next(strings.ToLower(address))
+	if err != nil {
+		level.Error(logger).Log("s.nonceStore.Next:", err)
+		return 0, err
+	}
+
+	return nonce, nil
+}
+
+// service struct verify bid method
+func (s *service) VerifyBid(ctx context.Context, b Bid, signature string) error {
+	return s.verify(ctx, b.StructHash(), b.Bidder, b.Nonce, b.Deadline, signature)
+}
+
+// service struct verify entry method
+func (s *service) VerifyEntry(ctx context.Context, e Entry, signature string) error {
+	return s.verify(ctx, e.StructHash(), e.Entrant, e.Nonce, e.Deadline, signature)
+}
+
+// checks deadline, signer, wallet link of the session's user and finally consumes the nonce
+// the nonce is only consumed by valid signatures, so invalid requests cannot block a wallet
+func (s *service) verify(ctx context.Context, structHash common.Hash, signer common.Address, nonce uint64, deadline int64, signature string) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "verify")
+
+	if s.now().Unix() > deadline {
+		return ErrExpired
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	recovered, err := Recover(s.domain.Digest(structHash), sig)
+	if err != nil {
+		return err
+	}
+	if recovered != signer {
+		return ErrSignerMismatch
+	}
+
+	address := strings.ToLower(signer.Hex())
+	if err := s.checkLinked(ctx, address); err != nil {
+		return err
+	}
+
+	if err := s.nonceStore.Use(address, nonce); err != nil {
+		if !errors.Is(err, ErrInvalidNonce) {
+			level.Error(logger).Log("s.nonceStore.Use:", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// the signing wallet must be linked to the user of the request's session
+// without wallet links only the session is required
+func (s *service) checkLinked(ctx context.Context, address string) error {
+
+	if s.sessions == nil {
+		return nil
+	}
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok {
+		return ErrUnauthorized
+	}
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return ErrUnauthorized
+	}
+
+	if s.wallets == nil {
+		return nil
+	}
+
+	linked, err := s.wallets.IsLinked(ctx, sess.UserId(), address)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return ErrWalletNotLinked
+	}
+
+	return nil
+}
+
+// initialization function to return service struct
+// sessions and wallets are optional, without them only signatures and nonces are checked
+// this function should is called in main.go
+func NewService(domain Domain, nonceStore NonceStore, sessions Sessions, wallets Wallets, logger log.Logger) Service {
+	return &service{
+		domain:     domain,
+		nonceStore: nonceStore,
+		sessions:   sessions,
+		wallets:    wallets,
+		now:        time.Now,
+		logger:     logger,
+	}
+}
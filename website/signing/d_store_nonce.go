@@ -0,0 +1,110 @@
+package signing
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******* Nonce store interface *********
+
+var ErrInvalidNonce = errors.New("Nonce already used or out of order")
+
+type NonceStoreConfig struct {
+	NoncesPath string
+}
+
+// nonces are sequential per signer like the Art contract's nonces
+// a signed message is only accepted with the signer's next nonce
+type NonceStore interface {
+	Next(address string) (uint64, error)
+	Use(address string, nonce uint64) error
+}
+
+type nonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]uint64
+	config NonceStoreConfig
+	logger log.Logger
+}
+
+// next unused nonce of a lowercase address
+func (ns *nonceStore) Next(address string) (uint64, error) {
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	return ns.nonces[address], nil
+}
+
+// consumes the next nonce of a lowercase address, any other nonce is rejected
+func (ns *nonceStore) Use(address string, nonce uint64) error {
+
+	// log level
+	logger := log.With(ns.logger, "method", "Use")
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ns.nonces[address] != nonce {
+		return ErrInvalidNonce
+	}
+
+	ns.nonces[address] = nonce + 1
+	if err := ns.write(); err != nil {
+		ns.nonces[address] = nonce
+		level.Error(logger).Log("ns.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// writes all nonces, temporary file first so that a crash never loses used nonces
+func (ns *nonceStore) write() error {
+
+	if err := os.MkdirAll(ns.config.NoncesPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ns.nonces)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ns.config.NoncesPath, "nonces.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads persisted nonces, a missing file starts all signers at nonce 0
+func NewNonceStore(config NonceStoreConfig, logger log.Logger) (NonceStore, error) {
+
+	ns := &nonceStore{
+		nonces: make(map[string]uint64),
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.NoncesPath, "nonces.json"))
+	if os.IsNotExist(err) {
+		return ns, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ns.nonces); err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
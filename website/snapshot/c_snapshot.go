@@ -0,0 +1,375 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"website/indexer"
+	"website/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrInvalidRules = errors.New("Invalid snapshot rules")
+
+var ErrNotIndexed = errors.New("Block is not indexed yet")
+
+var ErrNotConfirmed = errors.New("Block is not confirmed yet")
+
+var ErrNoBackend = errors.New("Excluding contracts requires a node")
+
+var ErrNoRecipients = errors.New("No holder matches the rules")
+
+var ErrBlockMismatch = errors.New("Index and node disagree on the block hash")
+
+var ErrSnapshotMismatch = errors.New("Snapshot differs from the recorded one with the same block and rules")
+
+var ErrRootMismatch = errors.New("Rows do not match the snapshot root")
+
+// ******** Snapshot structs **********
+
+// inclusion rules, amounts are decimal strings in the token's smallest unit
+// holders below min_balance and excluded addresses get nothing, cap limits the amount of every recipient
+// exclude_contracts drops every holder with code at the snapshot block, e.g. pools and multisigs
+type Rules struct {
+	MinBalance       string   `json:"min_balance"`
+	Cap              string   `json:"cap,omitempty"`
+	Exclude          []string `json:"exclude,omitempty"`
+	ExcludeContracts bool     `json:"exclude_contracts"`
+}
+
+// recipient of the drop, amount is the balance limited by the cap
+type Row struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Amount  string `json:"amount"`
+}
+
+type Reason string
+
+const (
+	ReasonListed   Reason = "listed"
+	ReasonContract Reason = "contract"
+)
+
+// holder above the minimum balance left out by the rules
+type Exclusion struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Reason  Reason `json:"reason"`
+}
+
+// Snapshot of the holders at a block with the rules applied
+// the id is derived from block and rules, events_hash pins the transfers the balances were replayed from,
+// so taking the same snapshot again must reproduce the same root
+// root is the Merkle root of the rows as merkle.Leaf{address, amount}
+type Snapshot struct {
+	Id         string      `json:"id"`
+	Block      uint64      `json:"block"`
+	BlockHash  string      `json:"block_hash,omitempty"`
+	Rules      Rules       `json:"rules"`
+	EventsHash string      `json:"events_hash"`
+	Holders    int         `json:"holders"`
+	Total      string      `json:"total"`
+	Root       string      `json:"root"`
+	Rows       []Row       `json:"rows"`
+	Excluded   []Exclusion `json:"excluded"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Backend is what a snapshot needs from a node, *ethclient.Client and *chain.SimulatedBackend implement it
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+}
+
+// ******** Rules **********
+
+func parseAmount(s string) (*big.Int, bool) {
+	if s == "" {
+		return new(big.Int), true
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// validates the rules and brings them into canonical form, excluded addresses lowercase, sorted and unique
+func (r Rules) normalize() (Rules, error) {
+
+	if _, ok := parseAmount(r.MinBalance); !ok {
+		return Rules{}, ErrInvalidRules
+	}
+	if c, ok := parseAmount(r.Cap); !ok || (r.Cap != "" && c.Sign() == 0) {
+		return Rules{}, ErrInvalidRules
+	}
+	if r.MinBalance == "" {
+		r.MinBalance = "0"
+	}
+
+	seen := make(map[string]bool)
+	exclude := []string{}
+	for _, a := range r.Exclude {
+		if !common.IsHexAddress(a) {
+			return Rules{}, ErrInvalidRules
+		}
+		a = strings.ToLower(common.HexToAddress(a).Hex())
+		if !seen[a] {
+			seen[a] = true
+			exclude = append(exclude, a)
+		}
+	}
+	sort.Strings(exclude)
+	r.Exclude = exclude
+
+	return r, nil
+}
+
+// identifier of a snapshot, keccak256 of block and canonical rules
+func snapshotId(block uint64, r Rules) string {
+	data, _ := json.Marshal(struct {
+		Block uint64 `json:"block"`
+		Rules Rules  `json:"rules"`
+	}{block, r})
+	return crypto.Keccak256Hash(data).Hex()[2:18]
+}
+
+// ******** Taking snapshots **********
+
+// transfers up to and including block, with the balances they leave
+func replay(events []indexer.Event, block uint64) ([]indexer.Event, map[string]*big.Int) {
+
+	zero := strings.ToLower(common.Address{}.Hex())
+	transfers := []indexer.Event{}
+	balances := make(map[string]*big.Int)
+	for _, e := range events {
+		if e.Block > block || e.Type != indexer.EventTransfer {
+			continue
+		}
+		transfers = append(transfers, e)
+		value, _ := parseAmount(e.Amount)
+		if value == nil {
+			value = new(big.Int)
+		}
+		if e.From != zero {
+			balances[e.From] = new(big.Int).Sub(balance(balances, e.From), value)
+		}
+		if e.To != zero {
+			balances[e.To] = new(big.Int).Add(balance(balances, e.To), value)
+		}
+	}
+	return transfers, balances
+}
+
+func balance(balances map[string]*big.Int, address string) *big.Int {
+	if b, ok := balances[address]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+// Take computes the snapshot of block from indexed events
+// the block must be indexed and confirmed, cursor.Recent holds the blocks that may still be reorganised
+// backend may be nil unless contracts are excluded, it also supplies the block hash
+func Take(ctx context.Context, events []indexer.Event, cursor indexer.Cursor, block uint64, rules Rules, backend Backend) (Snapshot, error) {
+
+	rules, err := rules.normalize()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if block >= cursor.Next {
+		return Snapshot{}, ErrNotIndexed
+	}
+	for _, b := range cursor.Recent {
+		if b.Number <= block {
+			return Snapshot{}, ErrNotConfirmed
+		}
+	}
+	if rules.ExcludeContracts && backend == nil {
+		return Snapshot{}, ErrNoBackend
+	}
+
+	transfers, balances := replay(events, block)
+	data, err := json.Marshal(transfers)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	s := Snapshot{
+		Id:         snapshotId(block, rules),
+		Block:      block,
+		Rules:      rules,
+		EventsHash: crypto.Keccak256Hash(data).Hex(),
+		Rows:       []Row{},
+		Excluded:   []Exclusion{},
+		CreatedAt:  time.Now(),
+	}
+
+	number := new(big.Int).SetUint64(block)
+	if backend != nil {
+		header, err := backend.HeaderByNumber(ctx, number)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		s.BlockHash = header.Hash().Hex()
+	}
+	for _, e := range transfers {
+		if e.Block != block {
+			continue
+		}
+		if s.BlockHash != "" && e.BlockHash != s.BlockHash {
+			return Snapshot{}, ErrBlockMismatch
+		}
+		s.BlockHash = e.BlockHash
+	}
+
+	minBalance, _ := parseAmount(rules.MinBalance)
+	limit, _ := parseAmount(rules.Cap)
+	listed := make(map[string]bool, len(rules.Exclude))
+	for _, a := range rules.Exclude {
+		listed[a] = true
+	}
+
+	addresses := make([]string, 0, len(balances))
+	for a, b := range balances {
+		if b.Sign() > 0 {
+			addresses = append(addresses, a)
+		}
+	}
+	sort.Strings(addresses)
+
+	total := new(big.Int)
+	for _, a := range addresses {
+		b := balances[a]
+		s.Holders++
+		if b.Cmp(minBalance) < 0 {
+			continue
+		}
+		if listed[a] {
+			s.Excluded = append(s.Excluded, Exclusion{Address: a, Balance: b.String(), Reason: ReasonListed})
+			continue
+		}
+		if rules.ExcludeContracts {
+			code, err := backend.CodeAt(ctx, common.HexToAddress(a), number)
+			if err != nil {
+				return Snapshot{}, err
+			}
+			if len(code) > 0 {
+				s.Excluded = append(s.Excluded, Exclusion{Address: a, Balance: b.String(), Reason: ReasonContract})
+				continue
+			}
+		}
+		amount := b
+		if limit.Sign() > 0 && amount.Cmp(limit) > 0 {
+			amount = limit
+		}
+		total.Add(total, amount)
+		s.Rows = append(s.Rows, Row{Address: a, Balance: b.String(), Amount: amount.String()})
+	}
+	if len(s.Rows) == 0 {
+		return Snapshot{}, ErrNoRecipients
+	}
+
+	// largest balances first, ties by address
+	sort.SliceStable(s.Rows, func(i, k int) bool {
+		return balance(balances, s.Rows[i].Address).Cmp(balance(balances, s.Rows[k].Address)) > 0
+	})
+
+	tree, err := merkle.NewTree(leaves(s.Rows))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s.Root = tree.Root().Hex()
+	s.Total = total.String()
+
+	return s, nil
+}
+
+func leaves(rows []Row) []merkle.Leaf {
+	leaves := make([]merkle.Leaf, len(rows))
+	for i, r := range rows {
+		amount, _ := parseAmount(r.Amount)
+		leaves[i] = merkle.Leaf{Address: common.HexToAddress(r.Address), Value: amount}
+	}
+	return leaves
+}
+
+// Record stores a new snapshot, a snapshot taken before with the same block and rules is returned as recorded
+// when the index reproduces it, otherwise ErrSnapshotMismatch tells that the balances changed since
+func Record(store SnapshotStore, s Snapshot) (Snapshot, error) {
+
+	recorded, err := store.ReadSnapshot(s.Id)
+	if err == ErrSnapshotNotFound {
+		return s, store.WriteSnapshot(s)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if recorded.Root != s.Root || recorded.EventsHash != s.EventsHash {
+		return recorded, ErrSnapshotMismatch
+	}
+	// the block hash is only known when a node or an event of the block supplied it
+	if recorded.BlockHash != "" && s.BlockHash != "" && recorded.BlockHash != s.BlockHash {
+		return recorded, ErrSnapshotMismatch
+	}
+	return recorded, nil
+}
+
+// WriteCSV writes the rows with an address,balance,amount header
+func WriteCSV(w io.Writer, s Snapshot) error {
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"address", "balance", "amount"}); err != nil {
+		return err
+	}
+	for _, r := range s.Rows {
+		if err := cw.Write([]string{r.Address, r.Balance, r.Amount}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ******** Claims **********
+
+// claim of one recipient, a contract verifies it with
+// MerkleProof.verify(proof, root, keccak256(bytes.concat(keccak256(abi.encode(msg.sender, amount)))))
+type Claim struct {
+	Address string        `json:"address"`
+	Amount  string        `json:"amount"`
+	Proof   []common.Hash `json:"proof"`
+}
+
+// Claims returns the claim of every recipient of the snapshot, in row order
+func Claims(s Snapshot) ([]Claim, error) {
+
+	tree, err := merkle.NewTree(leaves(s.Rows))
+	if err != nil {
+		return nil, err
+	}
+	if tree.Root().Hex() != s.Root {
+		return nil, ErrRootMismatch
+	}
+
+	claims := make([]Claim, len(s.Rows))
+	for i, l := range leaves(s.Rows) {
+		proof, err := tree.Proof(l)
+		if err != nil {
+			return nil, err
+		}
+		claims[i] = Claim{Address: s.Rows[i].Address, Amount: s.Rows[i].Amount, Proof: proof}
+	}
+	return claims, nil
+}
@@ -0,0 +1,248 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"website/chain/chaintest"
+	"website/indexer"
+	"website/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+)
+
+// holders at a block, with rules, recording and claims
+// the Art contract itself holds tokens and stands in for a contract holder
+func TestSnapshot(t *testing.T) {
+
+	ctx := context.Background()
+	holder, alice, bob, carol, treasury := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	art := client.Art()
+	address := art.Address()
+
+	for _, tr := range []struct {
+		to     common.Address
+		amount int64
+	}{{alice.Addr, 5000}, {bob.Addr, 50}, {treasury.Addr, 100000}, {address, 3000}} {
+		if _, err := art.Transfer(holder.Opts, tr.to, big.NewInt(tr.amount)); err != nil {
+			t.Fatalf("Transfer failed, error: %v.", err)
+		}
+	}
+	sim.Commit()
+	block, _ := client.BlockNumber(ctx)
+
+	// after the snapshot block, must not change it
+	if _, err := art.Transfer(alice.Opts, carol.Addr, big.NewInt(4000)); err != nil {
+		t.Fatalf("Transfer failed, error: %v.", err)
+	}
+	sim.Commit()
+
+	index, indexStore := chaintest.NewIndex(t, t.TempDir(), client, indexer.Config{Confirmations: 2, BatchSize: 16})
+	sync := func() ([]indexer.Event, indexer.Cursor) {
+		chaintest.Sync(t, index)
+		events, _ := indexStore.ReadEvents()
+		cursor, _ := indexStore.ReadCursor()
+		return events, cursor
+	}
+
+	events, cursor := sync()
+	rules := Rules{MinBalance: "100", Cap: "4000", Exclude: []string{treasury.Addr.Hex()}, ExcludeContracts: true}
+	if _, err := Take(ctx, events, cursor, block, Rules{Exclude: []string{"0x123"}}, sim); err != ErrInvalidRules {
+		t.Fatalf("Take with an invalid address returned %v, expected ErrInvalidRules.", err)
+	}
+	if _, err := Take(ctx, events, cursor, block, rules, sim); err != ErrNotConfirmed {
+		t.Fatalf("Take of an unconfirmed block returned %v, expected ErrNotConfirmed.", err)
+	}
+	if _, err := Take(ctx, events, cursor, block+10, rules, sim); err != ErrNotIndexed {
+		t.Fatalf("Take of a future block returned %v, expected ErrNotIndexed.", err)
+	}
+
+	sim.Commit()
+	sim.Commit()
+	events, cursor = sync()
+	if _, err := Take(ctx, events, cursor, block, rules, nil); err != ErrNoBackend {
+		t.Fatalf("Take without a node returned %v, expected ErrNoBackend.", err)
+	}
+
+	s, err := Take(ctx, events, cursor, block, rules, sim)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	header, _ := sim.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if s.BlockHash != header.Hash().Hex() {
+		t.Errorf("block hash is %s, expected %s.", s.BlockHash, header.Hash().Hex())
+	}
+
+	// holder keeps the rest of the supply, bob is below the minimum, alice is capped
+	supply, _ := client.TotalSupply(ctx)
+	rest := new(big.Int).Sub(supply, big.NewInt(5000+50+100000+3000))
+	expected := []Row{
+		{Address: holder.Hex(), Balance: rest.String(), Amount: "4000"},
+		{Address: alice.Hex(), Balance: "5000", Amount: "4000"},
+	}
+	if len(s.Rows) != len(expected) {
+		t.Fatalf("snapshot has rows %+v, expected %+v.", s.Rows, expected)
+	}
+	for i := range expected {
+		if s.Rows[i] != expected[i] {
+			t.Errorf("row %d is %+v, expected %+v.", i, s.Rows[i], expected[i])
+		}
+	}
+	if s.Holders != 5 || s.Total != "8000" || len(s.Excluded) != 2 {
+		t.Errorf("snapshot has %d holders, total %s and exclusions %+v.", s.Holders, s.Total, s.Excluded)
+	}
+	for _, e := range s.Excluded {
+		if (e.Address == treasury.Hex()) != (e.Reason == ReasonListed) || (e.Address == strings.ToLower(address.Hex())) != (e.Reason == ReasonContract) {
+			t.Errorf("exclusion %+v has the wrong reason.", e)
+		}
+	}
+
+	var csv bytes.Buffer
+	if err := WriteCSV(&csv, s); err != nil {
+		t.Fatalf("WriteCSV failed, error: %v.", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(csv.String()), "\n"); len(lines) != 3 || lines[2] != alice.Hex()+",5000,4000" {
+		t.Errorf("CSV is %q.", csv.String())
+	}
+
+	claims, err := Claims(s)
+	if err != nil {
+		t.Fatalf("Claims failed, error: %v.", err)
+	}
+	for _, c := range claims {
+		leaf := merkle.Leaf{Address: common.HexToAddress(c.Address), Value: big.NewInt(4000)}
+		if !merkle.Verify(c.Proof, common.HexToHash(s.Root), leaf.Hash()) {
+			t.Errorf("claim of %s does not verify.", c.Address)
+		}
+	}
+
+	// taking it again reproduces the recorded snapshot, the same rules written differently map to the same id
+	store := NewSnapshotStore(SnapshotStoreConfig{SnapshotsPath: t.TempDir()}, log.NewNopLogger())
+	recorded, err := Record(store, s)
+	if err != nil {
+		t.Fatalf("Record failed, error: %v.", err)
+	}
+	rules.Exclude = []string{treasury.Hex(), treasury.Hex()}
+	again, err := Take(ctx, events, cursor, block, rules, sim)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	if again.Id != s.Id {
+		t.Fatalf("snapshot id is %s, expected %s.", again.Id, s.Id)
+	}
+	again, err = Record(store, again)
+	if err != nil || !again.CreatedAt.Equal(recorded.CreatedAt) {
+		t.Fatalf("Record of the same snapshot returned %+v, error %v.", again, err)
+	}
+
+	// an index that lost an event no longer reproduces it
+	tampered, err := Take(ctx, events[1:], cursor, block, rules, sim)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	if _, err := Record(store, tampered); err != ErrSnapshotMismatch {
+		t.Fatalf("Record of a different snapshot returned %v, expected ErrSnapshotMismatch.", err)
+	}
+
+	snapshots, err := store.ReadSnapshots()
+	if err != nil || len(snapshots) != 1 || snapshots[0].Root != s.Root {
+		t.Fatalf("ReadSnapshots returned %+v, error %v.", snapshots, err)
+	}
+}
+
+// rules and recording over plain indexed events, without a node
+func TestTakeEvents(t *testing.T) {
+
+	ctx := context.Background()
+	zero := strings.ToLower(common.Address{}.Hex())
+	a, b, c, d := "0x000000000000000000000000000000000000000a", "0x000000000000000000000000000000000000000b", "0x000000000000000000000000000000000000000c", "0x000000000000000000000000000000000000000d"
+	treasury := "0x00000000000000000000000000000000000000ff"
+	transfer := func(block uint64, from, to, amount string) indexer.Event {
+		return indexer.Event{Type: indexer.EventTransfer, Block: block, BlockHash: fmt.Sprintf("0x%02d", block), From: from, To: to, Amount: amount}
+	}
+	events := []indexer.Event{
+		transfer(1, zero, a, "5000"),
+		transfer(1, zero, b, "50"),
+		transfer(1, zero, treasury, "100000"),
+		transfer(1, zero, c, "200"),
+		{Type: indexer.EventApproval, Block: 2, Owner: a, Spender: c, Amount: "1000"},
+		transfer(2, a, c, "100"),
+		transfer(3, a, d, "4000"),
+	}
+	cursor := indexer.Cursor{Next: 4, Recent: []indexer.BlockRef{{Number: 3, Hash: "0x03"}}}
+	rules := Rules{MinBalance: "100", Cap: "4000", Exclude: []string{"0x00000000000000000000000000000000000000FF"}}
+
+	for _, tc := range []struct {
+		block uint64
+		rules Rules
+		err   error
+	}{
+		{3, rules, ErrNotConfirmed},
+		{4, rules, ErrNotIndexed},
+		{2, Rules{MinBalance: "100", ExcludeContracts: true}, ErrNoBackend},
+		{2, Rules{MinBalance: "1000000"}, ErrNoRecipients},
+		{2, Rules{MinBalance: "-1"}, ErrInvalidRules},
+		{2, Rules{Cap: "0"}, ErrInvalidRules},
+	} {
+		if _, err := Take(ctx, events, cursor, tc.block, tc.rules, nil); err != tc.err {
+			t.Errorf("Take of block %d with rules %+v returned %v, expected %v.", tc.block, tc.rules, err, tc.err)
+		}
+	}
+
+	s, err := Take(ctx, events, cursor, 2, rules, nil)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	expected := []Row{{Address: a, Balance: "4900", Amount: "4000"}, {Address: c, Balance: "300", Amount: "300"}}
+	if len(s.Rows) != len(expected) {
+		t.Fatalf("rows %+v, expected %+v.", s.Rows, expected)
+	}
+	for i := range expected {
+		if s.Rows[i] != expected[i] {
+			t.Errorf("row %d is %+v, expected %+v.", i, s.Rows[i], expected[i])
+		}
+	}
+	if len(s.Excluded) != 1 || s.Excluded[0] != (Exclusion{Address: treasury, Balance: "100000", Reason: ReasonListed}) {
+		t.Errorf("excluded %+v, expected the listed treasury.", s.Excluded)
+	}
+	if s.Holders != 4 || s.Total != "4300" || s.BlockHash != "0x02" {
+		t.Errorf("snapshot has %d holders, total %s and block hash %s, expected 4, 4300 and 0x02.", s.Holders, s.Total, s.BlockHash)
+	}
+
+	// the same block and rules in another form reproduce the snapshot
+	again, err := Take(ctx, events, cursor, 2, Rules{MinBalance: "100", Cap: "4000", Exclude: []string{treasury, "0x00000000000000000000000000000000000000Ff"}}, nil)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	if again.Id != s.Id || again.Root != s.Root || again.EventsHash != s.EventsHash {
+		t.Errorf("snapshot %s with root %s, expected %s with root %s.", again.Id, again.Root, s.Id, s.Root)
+	}
+
+	store := NewSnapshotStore(SnapshotStoreConfig{SnapshotsPath: t.TempDir()}, log.NewNopLogger())
+	recorded, err := Record(store, s)
+	if err != nil {
+		t.Fatalf("Record failed, error: %v.", err)
+	}
+	if again, err = Record(store, again); err != nil || !again.CreatedAt.Equal(recorded.CreatedAt) {
+		t.Errorf("Record of the same snapshot returned %+v, %v, expected the recorded one.", again, err)
+	}
+
+	// a transfer at the block that was not indexed before changes the balances
+	changed, err := Take(ctx, append(events, transfer(2, b, d, "50")), cursor, 2, rules, nil)
+	if err != nil {
+		t.Fatalf("Take failed, error: %v.", err)
+	}
+	if changed.Id != s.Id || changed.Root != s.Root {
+		t.Errorf("snapshot %s with root %s, expected the rows of %s to stay.", changed.Id, changed.Root, s.Id)
+	}
+	if _, err := Record(store, changed); err != ErrSnapshotMismatch {
+		t.Errorf("Record of changed events returned %v, expected %v.", err, ErrSnapshotMismatch)
+	}
+}
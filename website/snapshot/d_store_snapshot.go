@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******* Snapshot store interface *********
+
+var ErrSnapshotNotFound = errors.New("Snapshot not found")
+
+var ErrSnapshotExists = errors.New("Snapshot already recorded")
+
+type SnapshotStoreConfig struct {
+	SnapshotsPath string
+}
+
+// SnapshotStore keeps every recorded snapshot, recorded snapshots are never replaced
+type SnapshotStore interface {
+	WriteSnapshot(s Snapshot) error
+	ReadSnapshot(id string) (Snapshot, error)
+	ReadSnapshots() ([]Snapshot, error)
+}
+
+// one file per snapshot, {id}.json
+type snapshotStore struct {
+	mu     sync.Mutex
+	config SnapshotStoreConfig
+	logger log.Logger
+}
+
+func (ss *snapshotStore) path(id string) string {
+	return filepath.Join(ss.config.SnapshotsPath, id+".json")
+}
+
+// records a new snapshot, temporary file first so that a crash never leaves a partial file
+func (ss *snapshotStore) WriteSnapshot(s Snapshot) error {
+
+	// log level
+	logger := log.With(ss.logger, "method", "WriteSnapshot")
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	path := ss.path(s.Id)
+	if _, err := os.Stat(path); err == nil {
+		return ErrSnapshotExists
+	}
+
+	if err := os.MkdirAll(ss.config.SnapshotsPath, 0755); err != nil {
+		level.Error(logger).Log("os.MkdirAll:", err)
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		level.Error(logger).Log("os.WriteFile:", err)
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		level.Error(logger).Log("os.Rename:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (ss *snapshotStore) ReadSnapshot(id string) (Snapshot, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	return ss.read(ss.path(id))
+}
+
+func (ss *snapshotStore) read(path string) (Snapshot, error) {
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, err
+	}
+	return s, nil
+}
+
+// all recorded snapshots, oldest first
+func (ss *snapshotStore) ReadSnapshots() ([]Snapshot, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	entries, err := os.ReadDir(ss.config.SnapshotsPath)
+	if os.IsNotExist(err) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		s, err := ss.read(filepath.Join(ss.config.SnapshotsPath, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, k int) bool {
+		if snapshots[i].CreatedAt.Equal(snapshots[k].CreatedAt) {
+			return snapshots[i].Id < snapshots[k].Id
+		}
+		return snapshots[i].CreatedAt.Before(snapshots[k].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func NewSnapshotStore(config SnapshotStoreConfig, logger log.Logger) SnapshotStore {
+	return &snapshotStore{
+		config: config,
+		logger: logger,
+	}
+}
@@ -0,0 +1,204 @@
+package staking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"website/chain"
+	"website/collection"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidAmount),
+		errors.Is(err, ErrBelowMinimum),
+		errors.Is(err, ErrInvalidLock):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrWalletNotLinked):
+		return http.StatusForbidden
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrPositionNotFound),
+		errors.Is(err, collection.ErrCollectionNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrNotStakeable),
+		errors.Is(err, ErrInsufficientBacking),
+		errors.Is(err, ErrLocked),
+		errors.Is(err, ErrNotActive),
+		errors.Is(err, chain.ErrNotDetermined):
+		return http.StatusConflict
+
+	case errors.Is(err, ErrNotSynced),
+		errors.Is(err, ErrIndexChanged),
+		errors.Is(err, ErrWithdrawalsDisabled):
+		return http.StatusServiceUnavailable
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func decodeReadOverview(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadOverviewRequest{}, nil
+}
+
+// decode address from route
+func decodeReadAccount(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadAccountRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// decode collection identifier from route
+func decodeReadCollection(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadCollectionRequest{CollectionId: mux.Vars(r)["id"]}, nil
+}
+
+// decode collection identifier from route
+func decodeListEpochs(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListEpochsRequest{CollectionId: mux.Vars(r)["id"]}, nil
+}
+
+// decode stake from body
+func decodeStake(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req StakeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.Stake); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode position identifier from route
+func decodeUnstake(_ context.Context, r *http.Request) (interface{}, error) {
+	return UnstakeRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode address and amount from body
+func decodeWithdraw(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req WithdrawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach after session routes, stakes need the session identifier set by the session middleware
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching staking handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	readOverviewHandler := httptransport.NewServer(
+		e.ReadOverview,
+		decodeReadOverview,
+		encodeResponse,
+		options...,
+	)
+
+	readAccountHandler := httptransport.NewServer(
+		e.ReadAccount,
+		decodeReadAccount,
+		encodeResponse,
+		options...,
+	)
+
+	readCollectionHandler := httptransport.NewServer(
+		e.ReadCollection,
+		decodeReadCollection,
+		encodeResponse,
+		options...,
+	)
+
+	listEpochsHandler := httptransport.NewServer(
+		e.ListEpochs,
+		decodeListEpochs,
+		encodeResponse,
+		options...,
+	)
+
+	stakeHandler := httptransport.NewServer(
+		e.Stake,
+		decodeStake,
+		encodeResponse,
+		options...,
+	)
+
+	unstakeHandler := httptransport.NewServer(
+		e.Unstake,
+		decodeUnstake,
+		encodeResponse,
+		options...,
+	)
+
+	withdrawHandler := httptransport.NewServer(
+		e.Withdraw,
+		decodeWithdraw,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/staking", readOverviewHandler).Methods("GET")
+	router.Handle("/staking/accounts/{address}", readAccountHandler).Methods("GET")
+	router.Handle("/staking/collections/{id}", readCollectionHandler).Methods("GET")
+	router.Handle("/staking/collections/{id}/epochs", listEpochsHandler).Methods("GET")
+	router.Handle("/staking/positions", stakeHandler).Methods("POST")
+	router.Handle("/staking/positions/{id}/unstake", unstakeHandler).Methods("POST")
+	router.Handle("/staking/withdrawals", withdrawHandler).Methods("POST")
+
+	return router
+}
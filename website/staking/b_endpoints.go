@@ -0,0 +1,232 @@
+package staking
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ReadOverviewRequest struct{}
+
+type ReadAccountRequest struct {
+	Address string
+}
+
+type ReadCollectionRequest struct {
+	CollectionId string
+}
+
+type ListEpochsRequest struct {
+	CollectionId string
+}
+
+type StakeRequest struct {
+	Stake StakeInput
+}
+
+type UnstakeRequest struct {
+	Id string
+}
+
+type WithdrawRequest struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+type OverviewResponse struct {
+	Data Overview `json:"data"`
+	Err  error    `json:"errors"`
+}
+
+// have OverviewResponse follow the customError interface defined in a_transport.go
+func (r OverviewResponse) error() error { return r.Err }
+
+type AccountResponse struct {
+	Data Account `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have AccountResponse follow the customError interface defined in a_transport.go
+func (r AccountResponse) error() error { return r.Err }
+
+type CollectionResponse struct {
+	Data CollectionStakes `json:"data"`
+	Err  error            `json:"errors"`
+}
+
+// have CollectionResponse follow the customError interface defined in a_transport.go
+func (r CollectionResponse) error() error { return r.Err }
+
+type EpochsResponse struct {
+	Data []Epoch `json:"data"`
+	Err  error   `json:"errors"`
+}
+
+// have EpochsResponse follow the customError interface defined in a_transport.go
+func (r EpochsResponse) error() error { return r.Err }
+
+type PositionResponse struct {
+	Data Position `json:"data"`
+	Err  error    `json:"errors"`
+}
+
+// have PositionResponse follow the customError interface defined in a_transport.go
+func (r PositionResponse) error() error { return r.Err }
+
+type WithdrawalResponse struct {
+	Data Withdrawal `json:"data"`
+	Err  error      `json:"errors"`
+}
+
+// have WithdrawalResponse follow the customError interface defined in a_transport.go
+func (r WithdrawalResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	ReadOverview   endpoint.Endpoint
+	ReadAccount    endpoint.Endpoint
+	ReadCollection endpoint.Endpoint
+	ListEpochs     endpoint.Endpoint
+	Stake          endpoint.Endpoint
+	Unstake        endpoint.Endpoint
+	Withdraw       endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		ReadOverview:   epReadOverview(s),
+		ReadAccount:    epReadAccount(s),
+		ReadCollection: epReadCollection(s),
+		ListEpochs:     epListEpochs(s),
+		Stake:          epStake(s),
+		Unstake:        epUnstake(s),
+		Withdraw:       epWithdraw(s),
+	}
+}
+
+// read overview endpoint
+func epReadOverview(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		o, err := s.ReadOverview(ctx)
+		if err != nil {
+			return OverviewResponse{Err: err}, err
+		}
+
+		return OverviewResponse{Data: o, Err: nil}, nil
+	}
+}
+
+// read account endpoint
+func epReadAccount(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadAccountRequest)
+
+		// call service method
+		a, err := s.ReadAccount(ctx, req.Address)
+		if err != nil {
+			return AccountResponse{Err: err}, err
+		}
+
+		return AccountResponse{Data: a, Err: nil}, nil
+	}
+}
+
+// read collection stakes endpoint
+func epReadCollection(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadCollectionRequest)
+
+		// call service method
+		c, err := s.ReadCollection(ctx, req.CollectionId)
+		if err != nil {
+			return CollectionResponse{Err: err}, err
+		}
+
+		return CollectionResponse{Data: c, Err: nil}, nil
+	}
+}
+
+// list epochs endpoint
+func epListEpochs(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListEpochsRequest)
+
+		// call service method
+		epochs, err := s.ListEpochs(ctx, req.CollectionId)
+		if err != nil {
+			return EpochsResponse{Err: err}, err
+		}
+
+		return EpochsResponse{Data: epochs, Err: nil}, nil
+	}
+}
+
+// stake endpoint
+func epStake(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(StakeRequest)
+
+		// call service method
+		p, err := s.Stake(ctx, req.Stake)
+		if err != nil {
+			return PositionResponse{Err: err}, err
+		}
+
+		return PositionResponse{Data: p, Err: nil}, nil
+	}
+}
+
+// unstake endpoint
+func epUnstake(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(UnstakeRequest)
+
+		// call service method
+		p, err := s.Unstake(ctx, req.Id)
+		if err != nil {
+			return PositionResponse{Err: err}, err
+		}
+
+		return PositionResponse{Data: p, Err: nil}, nil
+	}
+}
+
+// withdraw endpoint
+func epWithdraw(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(WithdrawRequest)
+
+		// call service method
+		w, err := s.Withdraw(ctx, req.Address, req.Amount)
+		if err != nil {
+			return WithdrawalResponse{Err: err}, err
+		}
+
+		return WithdrawalResponse{Data: w, Err: nil}, nil
+	}
+}
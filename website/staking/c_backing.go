@@ -0,0 +1,112 @@
+package staking
+
+import (
+	"math/big"
+
+	"website/indexer"
+)
+
+// ******** Backing replayed from events **********
+
+// tokens every address has put behind its stakes, replayed from confirmed events
+// custody is what the address transferred to the staking address minus what it got back,
+// the wallet part is the whole balance while votes are delegated to the staking address,
+// otherwise the balance up to the allowance of the staking address
+type backing struct {
+	address    string
+	custody    map[string]*big.Int
+	balances   map[string]*big.Int
+	delegates  map[string]string
+	allowances map[string]*big.Int
+
+	// hashes of confirmed transfers out of the staking address
+	paid map[string]bool
+}
+
+func newBacking(address string) *backing {
+	return &backing{
+		address:    address,
+		custody:    make(map[string]*big.Int),
+		balances:   make(map[string]*big.Int),
+		delegates:  make(map[string]string),
+		allowances: make(map[string]*big.Int),
+		paid:       make(map[string]bool),
+	}
+}
+
+func amount(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
+func get(m map[string]*big.Int, address string) *big.Int {
+	if v, ok := m[address]; ok {
+		return v
+	}
+	return new(big.Int)
+}
+
+func set(m map[string]*big.Int, address string, v *big.Int) {
+	if v.Sign() <= 0 {
+		delete(m, address)
+		return
+	}
+	m[address] = v
+}
+
+// applies an event and returns the addresses whose backing may have changed
+func (b *backing) apply(e indexer.Event) []string {
+
+	switch e.Type {
+
+	case indexer.EventTransfer:
+		value := amount(e.Amount)
+		if e.From == b.address {
+			b.paid[e.TxHash] = true
+		}
+		set(b.balances, e.From, new(big.Int).Sub(get(b.balances, e.From), value))
+		set(b.balances, e.To, new(big.Int).Add(get(b.balances, e.To), value))
+		switch {
+		case e.To == b.address && e.From != b.address:
+			set(b.custody, e.From, new(big.Int).Add(get(b.custody, e.From), value))
+		case e.From == b.address && e.To != b.address:
+			// payouts beyond custody, e.g. from the treasury, leave custody at zero
+			set(b.custody, e.To, new(big.Int).Sub(get(b.custody, e.To), value))
+		}
+		return []string{e.From, e.To}
+
+	case indexer.EventApproval:
+		if e.Spender != b.address {
+			return nil
+		}
+		set(b.allowances, e.Owner, amount(e.Amount))
+		return []string{e.Owner}
+
+	case indexer.EventDelegateChanged:
+		b.delegates[e.Delegator] = e.ToDelegate
+		return []string{e.Delegator}
+	}
+
+	return nil
+}
+
+// tokens held by the address that back its stakes
+func (b *backing) wallet(address string) *big.Int {
+
+	balance := get(b.balances, address)
+	if b.delegates[address] == b.address {
+		return new(big.Int).Set(balance)
+	}
+	allowance := get(b.allowances, address)
+	if allowance.Cmp(balance) < 0 {
+		return new(big.Int).Set(allowance)
+	}
+	return new(big.Int).Set(balance)
+}
+
+func (b *backing) total(address string) *big.Int {
+	return new(big.Int).Add(get(b.custody, address), b.wallet(address))
+}
@@ -0,0 +1,65 @@
+package staking
+
+import (
+	"testing"
+
+	"website/indexer"
+)
+
+const (
+	stakingAddress = "0x00000000000000000000000000000000000000aa"
+	aliceAddress   = "0x0000000000000000000000000000000000000001"
+	bobAddress     = "0x0000000000000000000000000000000000000002"
+	zeroAddress    = "0x0000000000000000000000000000000000000000"
+)
+
+// custody, delegation to the staking address and allowances replayed event by event
+func TestBackingApply(t *testing.T) {
+
+	b := newBacking(stakingAddress)
+	check := func(step string, custody, wallet int64) {
+		if c := get(b.custody, aliceAddress); c.Int64() != custody {
+			t.Errorf("%s: custody %v, expected %d.", step, c, custody)
+		}
+		if w := b.wallet(aliceAddress); w.Int64() != wallet {
+			t.Errorf("%s: wallet %v, expected %d.", step, w, wallet)
+		}
+		if total := b.total(aliceAddress); total.Int64() != custody+wallet {
+			t.Errorf("%s: total %v, expected %d.", step, total, custody+wallet)
+		}
+	}
+
+	if changed := b.apply(indexer.Event{Type: indexer.EventTransfer, From: zeroAddress, To: aliceAddress, Amount: "100"}); len(changed) != 2 || changed[1] != aliceAddress {
+		t.Errorf("apply of a mint returned %v, expected the zero address and alice.", changed)
+	}
+	check("mint", 0, 0)
+
+	b.apply(indexer.Event{Type: indexer.EventApproval, Owner: aliceAddress, Spender: stakingAddress, Amount: "30"})
+	check("approval", 0, 30)
+	if changed := b.apply(indexer.Event{Type: indexer.EventApproval, Owner: aliceAddress, Spender: bobAddress, Amount: "100"}); changed != nil {
+		t.Errorf("apply of an approval of another spender returned %v, expected nil.", changed)
+	}
+	check("approval of another spender", 0, 30)
+
+	b.apply(indexer.Event{Type: indexer.EventDelegateChanged, Delegator: aliceAddress, ToDelegate: stakingAddress})
+	check("delegation", 0, 100)
+
+	b.apply(indexer.Event{Type: indexer.EventTransfer, From: aliceAddress, To: stakingAddress, Amount: "40"})
+	check("deposit", 40, 60)
+
+	// payouts beyond custody leave it at zero
+	b.apply(indexer.Event{Type: indexer.EventTransfer, TxHash: "0x01", From: stakingAddress, To: aliceAddress, Amount: "50"})
+	check("payout", 0, 110)
+	if !b.paid["0x01"] {
+		t.Errorf("payout was not recorded as paid.")
+	}
+
+	b.apply(indexer.Event{Type: indexer.EventDelegateChanged, Delegator: aliceAddress, ToDelegate: bobAddress})
+	check("delegation elsewhere", 0, 30)
+
+	b.apply(indexer.Event{Type: indexer.EventTransfer, From: aliceAddress, To: bobAddress, Amount: "90"})
+	check("transfer below the allowance", 0, 20)
+	if _, ok := b.balances[stakingAddress]; ok {
+		t.Errorf("staking address kept a negative balance %v.", b.balances[stakingAddress])
+	}
+}
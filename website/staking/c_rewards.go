@@ -0,0 +1,71 @@
+package staking
+
+import (
+	"math/big"
+	"time"
+)
+
+// ******** Epochs and rewards **********
+
+// boost of a position without lock, in basis points
+const baseBoost = 10000
+
+// number of the epoch containing t, times before the first epoch belong to epoch 0
+func (c Config) epoch(t time.Time) uint64 {
+	if t.Before(c.EpochStart) {
+		return 0
+	}
+	return uint64(t.Sub(c.EpochStart) / c.EpochLength)
+}
+
+func (c Config) epochStart(n uint64) time.Time {
+	return c.EpochStart.Add(time.Duration(n) * c.EpochLength)
+}
+
+// Boost grows linearly with the lock period, from 1x without lock to 1x + max_boost at max_lock
+func (c Config) Boost(lock time.Duration) int64 {
+	if c.MaxLock <= 0 {
+		return baseBoost
+	}
+	if lock > c.MaxLock {
+		lock = c.MaxLock
+	}
+	return baseBoost + c.MaxBoost*int64(lock/time.Second)/int64(c.MaxLock/time.Second)
+}
+
+// amount times boost, the share of the epoch pool is proportional to it
+func weight(p Position) *big.Int {
+	w := new(big.Int).Mul(amount(p.Amount), big.NewInt(p.Boost))
+	return w.Div(w, big.NewInt(baseBoost))
+}
+
+// positions earn for an epoch if they were staked when it started and counted until it ended
+func earns(p Position, start, end time.Time) bool {
+	return !p.StakedAt.After(start) && (p.EndedAt.IsZero() || !p.EndedAt.Before(end))
+}
+
+// splits the pool of an epoch by weight among the positions that earn for it
+// every share is rounded down, the remainder stays with the staking operator
+func distribute(pool *big.Int, positions []Position, start, end time.Time) (map[string]*big.Int, *big.Int) {
+
+	total := new(big.Int)
+	weights := make(map[string]*big.Int)
+	for _, p := range positions {
+		if !earns(p, start, end) {
+			continue
+		}
+		w := weight(p)
+		weights[p.Id] = w
+		total.Add(total, w)
+	}
+
+	rewards := make(map[string]*big.Int, len(weights))
+	if total.Sign() == 0 {
+		return rewards, total
+	}
+	for id, w := range weights {
+		r := new(big.Int).Mul(pool, w)
+		rewards[id] = r.Div(r, total)
+	}
+	return rewards, total
+}
@@ -0,0 +1,39 @@
+package staking
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+// the pool is split by boosted amount among positions staked for the whole epoch
+func TestDistribute(t *testing.T) {
+
+	start := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * day)
+	positions := []Position{
+		{Id: "a", Amount: "100", Boost: 10000, StakedAt: start.Add(-day)},
+		{Id: "b", Amount: "100", Boost: 20000, StakedAt: start},
+		{Id: "late", Amount: "100", Boost: 10000, StakedAt: start.Add(time.Second)},
+		{Id: "ended", Amount: "100", Boost: 10000, StakedAt: start, EndedAt: end.Add(-time.Second)},
+		{Id: "kept", Amount: "50", Boost: 10000, StakedAt: start, EndedAt: end},
+	}
+
+	rewards, total := distribute(big.NewInt(1000), positions, start, end)
+	if total.Int64() != 350 {
+		t.Errorf("total weight %v, expected 350.", total)
+	}
+	expected := map[string]int64{"a": 285, "b": 571, "kept": 142}
+	if len(rewards) != len(expected) {
+		t.Errorf("rewards %v, expected %v.", rewards, expected)
+	}
+	for id, r := range expected {
+		if rewards[id] == nil || rewards[id].Int64() != r {
+			t.Errorf("reward of %s %v, expected %d.", id, rewards[id], r)
+		}
+	}
+
+	if rewards, total := distribute(big.NewInt(1000), positions[2:3], start, end); len(rewards) != 0 || total.Sign() != 0 {
+		t.Errorf("distribute without earning positions returned %v, %v, expected nothing.", rewards, total)
+	}
+}
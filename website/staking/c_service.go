@@ -0,0 +1,1063 @@
+package staking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"website/chain"
+	"website/collection"
+	"website/indexer"
+	"website/session"
+	"website/txmanager"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrWalletNotLinked = errors.New("Wallet is not linked to the user")
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidAmount = errors.New("Amount must be a positive decimal integer")
+
+var ErrBelowMinimum = errors.New("Amount is below the minimum stake")
+
+var ErrInvalidLock = errors.New("Lock period is outside the allowed range")
+
+var ErrNotStakeable = errors.New("Collection does not accept stakes")
+
+var ErrInsufficientBacking = errors.New("Not enough unallocated ART behind the address")
+
+var ErrLocked = errors.New("Position is still locked")
+
+var ErrNotActive = errors.New("Position is not active")
+
+var ErrNotSynced = errors.New("Token index is not synced yet")
+
+var ErrWithdrawalsDisabled = errors.New("Withdrawals are not enabled")
+
+var ErrIndexChanged = errors.New("Index changed while reading events")
+
+var ErrInvalidConfig = errors.New("Invalid staking configuration")
+
+// Index reads indexed events, implemented by the indexer service
+type Index interface {
+	ReadStatus(ctx context.Context) (indexer.Status, error)
+	ListEvents(ctx context.Context, from uint64, to uint64) ([]indexer.Event, error)
+}
+
+// Collections reads collections, implemented by the collection store
+type Collections interface {
+	ReadCollection(id string) (collection.Collection, error)
+}
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// Wallets checks whether a wallet address belongs to a user
+type Wallets interface {
+	IsLinked(ctx context.Context, userId string, address string) (bool, error)
+}
+
+// Sender broadcasts and tracks transactions of the hot wallet, implemented by the transaction manager
+type Sender interface {
+	Address() string
+	Send(ctx context.Context, req txmanager.Request) (txmanager.Tx, error)
+	ReadTx(ctx context.Context, id string) (txmanager.Tx, error)
+}
+
+// Config of staking
+// address receives custodial stakes and is the delegatee or spender of non-custodial ones
+// epochs of epoch_length start at epoch_start, every collection with stakes gets epoch_reward per epoch
+// max_boost is added to the weight of positions locked for max_lock, in basis points
+// amounts are in the token's smallest unit
+type Config struct {
+	Address     common.Address
+	EpochStart  time.Time
+	EpochLength time.Duration
+	MinLock     time.Duration
+	MaxLock     time.Duration
+	MaxBoost    int64
+	Cooldown    time.Duration
+	MinStake    *big.Int
+	EpochReward *big.Int
+}
+
+func (c Config) validate() error {
+	if c.Address == (common.Address{}) || c.EpochLength <= 0 || c.MinLock < 0 || c.MaxLock < c.MinLock || c.MaxBoost < 0 || c.Cooldown < 0 {
+		return ErrInvalidConfig
+	}
+	if c.MinStake == nil || c.MinStake.Sign() <= 0 || c.EpochReward == nil || c.EpochReward.Sign() < 0 {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+// request to lock ART toward a collection, the amount is a decimal string
+type StakeInput struct {
+	CollectionId string `json:"collection_id"`
+	Address      string `json:"address"`
+	Amount       string `json:"amount"`
+	LockDays     int    `json:"lock_days"`
+}
+
+// epoch with its time range
+type EpochRange struct {
+	Number uint64    `json:"number"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// staking parameters and the current epoch, lock periods and cooldown in seconds
+// block is the last confirmed block the backing was replayed to
+type Overview struct {
+	Address     string     `json:"address"`
+	Epoch       EpochRange `json:"epoch"`
+	MinLock     int64      `json:"min_lock"`
+	MaxLock     int64      `json:"max_lock"`
+	MaxBoost    int64      `json:"max_boost"`
+	Cooldown    int64      `json:"cooldown"`
+	MinStake    string     `json:"min_stake"`
+	EpochReward string     `json:"epoch_reward"`
+	Withdrawals bool       `json:"withdrawals"`
+	Block       uint64     `json:"block"`
+}
+
+// backing and stakes of an address
+// available can be staked, withdrawable can be transferred back out of custody
+type Account struct {
+	Address      string       `json:"address"`
+	Custody      string       `json:"custody"`
+	Wallet       string       `json:"wallet"`
+	Backing      string       `json:"backing"`
+	Allocated    string       `json:"allocated"`
+	Pending      string       `json:"pending"`
+	Available    string       `json:"available"`
+	Withdrawable string       `json:"withdrawable"`
+	Rewards      string       `json:"rewards"`
+	Positions    []Position   `json:"positions"`
+	Withdrawals  []Withdrawal `json:"withdrawals"`
+	Block        uint64       `json:"block"`
+}
+
+// active stake of one address toward a collection
+type Staker struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+	Weight  string `json:"weight"`
+}
+
+// active stakes toward a collection, stakers ordered by amount, largest first
+type CollectionStakes struct {
+	CollectionId string     `json:"collection_id"`
+	Total        string     `json:"total"`
+	Weight       string     `json:"weight"`
+	Stakers      []Staker   `json:"stakers"`
+	Epoch        EpochRange `json:"epoch"`
+}
+
+// service interface defining all required methods
+// PriorStake makes the service usable as collection.StakeSource
+type Service interface {
+	ReadOverview(ctx context.Context) (Overview, error)
+	ReadAccount(ctx context.Context, address string) (Account, error)
+	ReadCollection(ctx context.Context, collectionId string) (CollectionStakes, error)
+	ListEpochs(ctx context.Context, collectionId string) ([]Epoch, error)
+	Stake(ctx context.Context, in StakeInput) (Position, error)
+	Unstake(ctx context.Context, id string) (Position, error)
+	Withdraw(ctx context.Context, address string, amount string) (Withdrawal, error)
+	PriorStake(ctx context.Context, collectionId string, account string, blockNumber uint64) (*big.Int, error)
+	Settle(ctx context.Context) error
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// guards the backing and serializes changes of positions and withdrawals
+	// next is the first block not yet replayed
+	mtx     sync.Mutex
+	backing *backing
+	next    uint64
+	reorgs  int
+	status  indexer.Status
+
+	stakeStore  StakeStore
+	index       Index
+	collections Collections
+	sessions    Sessions
+	wallets     Wallets
+	chain       chain.Client
+	sender      Sender
+	config      Config
+	now         func() time.Time
+	logger      log.Logger
+}
+
+// parses an address into the lowercase form events are indexed with
+func parseAddress(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(common.HexToAddress(address).Hex()), nil
+}
+
+// parses a positive decimal amount
+func parseAmount(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok || n.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return n, nil
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// last block the backing is replayed to, confirmed blocks only
+func target(st indexer.Status) uint64 {
+	if st.Confirmed < st.Indexed {
+		return st.Confirmed
+	}
+	return st.Indexed
+}
+
+// replays confirmed events indexed since the last call and forfeits positions that lost their backing
+// a reorg in the index drops the backing and replays all events
+func (s *service) refresh(ctx context.Context) error {
+
+	// log level
+	logger := log.With(s.logger, "method", "refresh")
+
+	st, err := s.index.ReadStatus(ctx)
+	if err != nil {
+		level.Error(logger).Log("s.index.ReadStatus:", err)
+		return err
+	}
+	last := target(st)
+	if st.Reorgs != s.reorgs || last+1 < s.next {
+		s.backing, s.next = newBacking(strings.ToLower(s.config.Address.Hex())), 0
+	}
+	s.reorgs = st.Reorgs
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadPositions:", err)
+		return err
+	}
+
+	// blocks before since were checked by earlier calls, lastEvent holds the block of the newest event of an address
+	since := s.next
+	lastEvent := make(map[string]uint64)
+	if last >= s.next && st.Head > 0 {
+		events, err := s.index.ListEvents(ctx, s.next, last)
+		if err != nil {
+			level.Error(logger).Log("s.index.ListEvents:", err)
+			return err
+		}
+
+		// a rollback between the two reads leaves the events incomplete
+		after, err := s.index.ReadStatus(ctx)
+		if err != nil {
+			level.Error(logger).Log("s.index.ReadStatus:", err)
+			return err
+		}
+		if after.Reorgs != st.Reorgs || after.Indexed < st.Indexed {
+			return ErrIndexChanged
+		}
+
+		// addresses are checked once all events of their block are applied
+		changed := make(map[string]bool)
+		for i, e := range events {
+			for _, a := range s.backing.apply(e) {
+				changed[a] = true
+				lastEvent[a] = e.Block
+			}
+			if i+1 < len(events) && events[i+1].Block == e.Block {
+				continue
+			}
+			for a := range changed {
+				if err := s.forfeit(positions, a, e.Block, e.Block); err != nil {
+					return err
+				}
+			}
+			changed = make(map[string]bool)
+		}
+		s.next = last + 1
+	}
+	s.status = st
+
+	// positions that became effective without an event of their address since
+	// the backing of an address is unchanged after its newest event, a shortfall starts no earlier
+	addresses := make(map[string]bool)
+	for _, p := range positions {
+		addresses[p.Address] = true
+	}
+	for a := range addresses {
+		from := since
+		if lastEvent[a] > from {
+			from = lastEvent[a]
+		}
+		if err := s.forfeit(positions, a, last, from); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ends the newest positions of an address effective at block until its backing covers the rest
+// the backing is the same from since to block, the ended positions stop counting at since or,
+// if they became effective later, at their first block, and earn nothing for the running epoch
+func (s *service) forfeit(positions []Position, address string, block uint64, since uint64) error {
+
+	// log level
+	logger := log.With(s.logger, "method", "forfeit")
+
+	now := s.now()
+	allocated := new(big.Int)
+	var effective []int
+	for i, p := range positions {
+		if p.Address == address && p.allocated(now) && p.FromBlock <= block {
+			allocated.Add(allocated, amount(p.Amount))
+			effective = append(effective, i)
+		}
+	}
+
+	backing := s.backing.total(address)
+	for k := len(effective) - 1; k >= 0 && allocated.Cmp(backing) > 0; k-- {
+		p := &positions[effective[k]]
+		allocated.Sub(allocated, amount(p.Amount))
+
+		end := since
+		if end < p.FromBlock {
+			end = p.FromBlock
+		}
+		if end > block {
+			end = block
+		}
+		if p.ToBlock == 0 || p.ToBlock > end {
+			p.ToBlock = end
+		}
+		if p.EndedAt.IsZero() {
+			p.EndedAt = now
+		}
+		p.Status = StatusForfeited
+		if err := s.stakeStore.WritePosition(*p); err != nil {
+			level.Error(logger).Log("s.stakeStore.WritePosition:", err)
+			return err
+		}
+		level.Info(logger).Log("msg", "position forfeited", "id", p.Id, "address", address, "block", block)
+	}
+
+	return nil
+}
+
+// allocated and pending withdrawal amounts of an address
+// withdrawals count as pending until their transfer is replayed
+func (s *service) allocation(address string, now time.Time) (allocated *big.Int, pending *big.Int, err error) {
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		return nil, nil, err
+	}
+	allocated = new(big.Int)
+	for _, p := range positions {
+		if p.Address == address && p.allocated(now) {
+			allocated.Add(allocated, amount(p.Amount))
+		}
+	}
+
+	withdrawals, err := s.stakeStore.ReadWithdrawals()
+	if err != nil {
+		return nil, nil, err
+	}
+	pending = new(big.Int)
+	for _, w := range withdrawals {
+		if w.Address == address && w.Status == WithdrawalPending && !s.backing.paid[w.TxHash] {
+			pending.Add(pending, amount(w.Amount))
+		}
+	}
+
+	return allocated, pending, nil
+}
+
+// custody not needed by the allocation, the wallet part backs stakes first
+func (s *service) withdrawable(address string, allocated, pending *big.Int) *big.Int {
+
+	needed := new(big.Int).Sub(allocated, s.backing.wallet(address))
+	if needed.Sign() < 0 {
+		needed.SetInt64(0)
+	}
+	w := new(big.Int).Sub(get(s.backing.custody, address), pending)
+	w.Sub(w, needed)
+	if w.Sign() < 0 {
+		w.SetInt64(0)
+	}
+	return w
+}
+
+// user identifier of the active session attached to the request context
+func (s *service) user(ctx context.Context) (string, error) {
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok || s.sessions == nil {
+		return "", ErrUnauthorized
+	}
+
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	return sess.UserId(), nil
+}
+
+// checks that the session user linked the wallet
+func (s *service) authorize(ctx context.Context, address string) error {
+
+	userId, err := s.user(ctx)
+	if err != nil {
+		return err
+	}
+	if s.wallets == nil {
+		return ErrWalletNotLinked
+	}
+	linked, err := s.wallets.IsLinked(ctx, userId, address)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return ErrWalletNotLinked
+	}
+	return nil
+}
+
+func (s *service) epochRange(n uint64) EpochRange {
+	return EpochRange{Number: n, Start: s.config.epochStart(n), End: s.config.epochStart(n + 1)}
+}
+
+// service struct read overview method
+func (s *service) ReadOverview(ctx context.Context) (Overview, error) {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return Overview{}, err
+	}
+
+	c := s.config
+	return Overview{
+		Address:     strings.ToLower(c.Address.Hex()),
+		Epoch:       s.epochRange(c.epoch(s.now())),
+		MinLock:     int64(c.MinLock / time.Second),
+		MaxLock:     int64(c.MaxLock / time.Second),
+		MaxBoost:    c.MaxBoost,
+		Cooldown:    int64(c.Cooldown / time.Second),
+		MinStake:    c.MinStake.String(),
+		EpochReward: c.EpochReward.String(),
+		Withdrawals: s.sender != nil,
+		Block:       target(s.status),
+	}, nil
+}
+
+// service struct read account method
+// values follow the last confirmed block, stakes made since count as allocated
+func (s *service) ReadAccount(ctx context.Context, address string) (Account, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadAccount")
+
+	address, err := parseAddress(address)
+	if err != nil {
+		return Account{}, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return Account{}, err
+	}
+
+	now := s.now()
+	allocated, pending, err := s.allocation(address, now)
+	if err != nil {
+		level.Error(logger).Log("s.allocation:", err)
+		return Account{}, err
+	}
+	backing := s.backing.total(address)
+	available := new(big.Int).Sub(backing, allocated)
+	available.Sub(available, pending)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadPositions:", err)
+		return Account{}, err
+	}
+	withdrawals, err := s.stakeStore.ReadWithdrawals()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadWithdrawals:", err)
+		return Account{}, err
+	}
+
+	a := Account{
+		Address:      address,
+		Custody:      get(s.backing.custody, address).String(),
+		Wallet:       s.backing.wallet(address).String(),
+		Backing:      backing.String(),
+		Allocated:    allocated.String(),
+		Pending:      pending.String(),
+		Available:    available.String(),
+		Withdrawable: s.withdrawable(address, allocated, pending).String(),
+		Positions:    []Position{},
+		Withdrawals:  []Withdrawal{},
+		Block:        target(s.status),
+	}
+	rewards := new(big.Int)
+	for _, p := range positions {
+		if p.Address == address {
+			a.Positions = append(a.Positions, p)
+			rewards.Add(rewards, amount(p.Rewards))
+		}
+	}
+	for _, w := range withdrawals {
+		if w.Address == address {
+			a.Withdrawals = append(a.Withdrawals, w)
+		}
+	}
+	a.Rewards = rewards.String()
+
+	return a, nil
+}
+
+// service struct read collection method
+func (s *service) ReadCollection(ctx context.Context, collectionId string) (CollectionStakes, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "ReadCollection")
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadPositions:", err)
+		return CollectionStakes{}, err
+	}
+
+	amounts := make(map[string]*big.Int)
+	weights := make(map[string]*big.Int)
+	total, totalWeight := new(big.Int), new(big.Int)
+	for _, p := range positions {
+		if p.CollectionId != collectionId || p.Status != StatusActive {
+			continue
+		}
+		if amounts[p.Address] == nil {
+			amounts[p.Address], weights[p.Address] = new(big.Int), new(big.Int)
+		}
+		amounts[p.Address].Add(amounts[p.Address], amount(p.Amount))
+		weights[p.Address].Add(weights[p.Address], weight(p))
+		total.Add(total, amount(p.Amount))
+		totalWeight.Add(totalWeight, weight(p))
+	}
+
+	stakers := make([]Staker, 0, len(amounts))
+	for a := range amounts {
+		stakers = append(stakers, Staker{Address: a, Amount: amounts[a].String(), Weight: weights[a].String()})
+	}
+	sort.Slice(stakers, func(i, k int) bool {
+		if c := amounts[stakers[i].Address].Cmp(amounts[stakers[k].Address]); c != 0 {
+			return c > 0
+		}
+		return stakers[i].Address < stakers[k].Address
+	})
+
+	return CollectionStakes{
+		CollectionId: collectionId,
+		Total:        total.String(),
+		Weight:       totalWeight.String(),
+		Stakers:      stakers,
+		Epoch:        s.epochRange(s.config.epoch(s.now())),
+	}, nil
+}
+
+// service struct list epochs method
+// settled epochs of a collection, oldest first
+func (s *service) ListEpochs(ctx context.Context, collectionId string) ([]Epoch, error) {
+	return s.stakeStore.ReadEpochs(collectionId)
+}
+
+// service struct stake method
+// the address must be linked to the session user and back the amount with unallocated ART as of the last confirmed block
+// the stake counts for weights from the block after the current head
+func (s *service) Stake(ctx context.Context, in StakeInput) (Position, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Stake")
+
+	address, err := parseAddress(in.Address)
+	if err != nil {
+		return Position{}, err
+	}
+	if address == strings.ToLower(s.config.Address.Hex()) {
+		return Position{}, ErrInvalidAddress
+	}
+	value, err := parseAmount(in.Amount)
+	if err != nil {
+		return Position{}, err
+	}
+	if value.Cmp(s.config.MinStake) < 0 {
+		return Position{}, ErrBelowMinimum
+	}
+	lock := time.Duration(in.LockDays) * 24 * time.Hour
+	if in.LockDays < 0 || lock < s.config.MinLock || lock > s.config.MaxLock {
+		return Position{}, ErrInvalidLock
+	}
+	if err := s.authorize(ctx, address); err != nil {
+		return Position{}, err
+	}
+
+	c, err := s.collections.ReadCollection(in.CollectionId)
+	if err != nil {
+		return Position{}, err
+	}
+	if c.State == collection.StateDraft {
+		return Position{}, ErrNotStakeable
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return Position{}, err
+	}
+	if s.status.Head == 0 {
+		return Position{}, ErrNotSynced
+	}
+
+	now := s.now()
+	allocated, pending, err := s.allocation(address, now)
+	if err != nil {
+		level.Error(logger).Log("s.allocation:", err)
+		return Position{}, err
+	}
+	available := new(big.Int).Sub(s.backing.total(address), allocated)
+	if available.Sub(available, pending).Cmp(value) < 0 {
+		return Position{}, ErrInsufficientBacking
+	}
+
+	id, err := newId()
+	if err != nil {
+		level.Error(logger).Log("newId:", err)
+		return Position{}, err
+	}
+	p := Position{
+		Id:           id,
+		CollectionId: c.Id,
+		Address:      address,
+		Amount:       value.String(),
+		Boost:        s.config.Boost(lock),
+		FromBlock:    s.status.Head + 1,
+		Status:       StatusActive,
+		Rewards:      "0",
+		StakedAt:     now,
+		LockedUntil:  now.Add(lock),
+	}
+	if err := s.stakeStore.WritePosition(p); err != nil {
+		level.Error(logger).Log("s.stakeStore.WritePosition:", err)
+		return Position{}, err
+	}
+	level.Info(logger).Log("msg", "staked", "id", p.Id, "collection", p.CollectionId, "address", address, "amount", p.Amount)
+
+	return p, nil
+}
+
+// service struct unstake method
+// ends an active position once its lock expired, the tokens stay allocated for the cooldown
+func (s *service) Unstake(ctx context.Context, id string) (Position, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Unstake")
+
+	p, err := s.stakeStore.ReadPosition(id)
+	if err != nil {
+		return Position{}, err
+	}
+	if err := s.authorize(ctx, p.Address); err != nil {
+		return Position{}, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return Position{}, err
+	}
+
+	// re-read, the refresh may have forfeited the position
+	if p, err = s.stakeStore.ReadPosition(id); err != nil {
+		return Position{}, err
+	}
+	if p.Status != StatusActive {
+		return Position{}, ErrNotActive
+	}
+	now := s.now()
+	if now.Before(p.LockedUntil) {
+		return Position{}, ErrLocked
+	}
+
+	p.Status = StatusCooling
+	p.ToBlock = s.status.Head + 1
+	if p.ToBlock < p.FromBlock {
+		p.ToBlock = p.FromBlock
+	}
+	p.EndedAt = now
+	p.ReleaseAt = now.Add(s.config.Cooldown)
+	if err := s.stakeStore.WritePosition(p); err != nil {
+		level.Error(logger).Log("s.stakeStore.WritePosition:", err)
+		return Position{}, err
+	}
+
+	return p, nil
+}
+
+// service struct withdraw method
+// transfers custody that no position needs back to the address from the hot wallet
+func (s *service) Withdraw(ctx context.Context, address string, amountIn string) (Withdrawal, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Withdraw")
+
+	if s.sender == nil {
+		return Withdrawal{}, ErrWithdrawalsDisabled
+	}
+	address, err := parseAddress(address)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	value, err := parseAmount(amountIn)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	if err := s.authorize(ctx, address); err != nil {
+		return Withdrawal{}, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return Withdrawal{}, err
+	}
+
+	now := s.now()
+	allocated, pending, err := s.allocation(address, now)
+	if err != nil {
+		level.Error(logger).Log("s.allocation:", err)
+		return Withdrawal{}, err
+	}
+	if s.withdrawable(address, allocated, pending).Cmp(value) < 0 {
+		return Withdrawal{}, ErrInsufficientBacking
+	}
+
+	id, err := newId()
+	if err != nil {
+		level.Error(logger).Log("newId:", err)
+		return Withdrawal{}, err
+	}
+	art := s.chain.Art()
+	data, err := art.Pack("transfer", common.HexToAddress(address), value)
+	if err != nil {
+		level.Error(logger).Log("art.Pack:", err)
+		return Withdrawal{}, err
+	}
+	tx, err := s.sender.Send(ctx, txmanager.Request{To: art.Address(), Data: data, Ref: "withdrawal:" + id})
+	if err != nil {
+		level.Error(logger).Log("s.sender.Send:", err)
+		return Withdrawal{}, err
+	}
+
+	w := Withdrawal{
+		Id:        id,
+		Address:   address,
+		Amount:    value.String(),
+		TxId:      tx.Id,
+		TxHash:    tx.Hash,
+		Status:    WithdrawalPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.stakeStore.WriteWithdrawal(w); err != nil {
+		level.Error(logger).Log("s.stakeStore.WriteWithdrawal:", err, "tx", w.TxHash)
+		return Withdrawal{}, err
+	}
+	level.Info(logger).Log("msg", "withdrawal sent", "address", address, "amount", w.Amount, "tx", w.TxHash)
+
+	return w, nil
+}
+
+// PriorStake sums the positions of an account toward a collection counting at a block
+// like checkpoints it only answers confirmed blocks below the head, others return chain.ErrNotDetermined
+func (s *service) PriorStake(ctx context.Context, collectionId string, account string, blockNumber uint64) (*big.Int, error) {
+
+	account, err := parseAddress(account)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	st := s.status
+	if st.Head == 0 || blockNumber >= st.Head || blockNumber > target(st) {
+		return nil, chain.ErrNotDetermined
+	}
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		return nil, err
+	}
+	stake := new(big.Int)
+	for _, p := range positions {
+		if p.CollectionId == collectionId && p.Address == account && p.FromBlock <= blockNumber && (p.ToBlock == 0 || blockNumber < p.ToBlock) {
+			stake.Add(stake, amount(p.Amount))
+		}
+	}
+	return stake, nil
+}
+
+// service struct settle method
+// tracks withdrawals, releases cooled down positions and settles every ended epoch
+// epochs are only settled while the index is synced, so forfeitures up to the last confirmed block are known
+func (s *service) Settle(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "Settle")
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return err
+	}
+	now := s.now()
+
+	if err := s.track(ctx); err != nil {
+		return err
+	}
+
+	positions, err := s.stakeStore.ReadPositions()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadPositions:", err)
+		return err
+	}
+	byCollection := make(map[string][]Position)
+	var ids []string
+	for _, p := range positions {
+		if p.Status == StatusCooling && !now.Before(p.ReleaseAt) {
+			p.Status = StatusReleased
+			if err := s.stakeStore.WritePosition(p); err != nil {
+				level.Error(logger).Log("s.stakeStore.WritePosition:", err)
+				return err
+			}
+		}
+		if byCollection[p.CollectionId] == nil {
+			ids = append(ids, p.CollectionId)
+		}
+		byCollection[p.CollectionId] = append(byCollection[p.CollectionId], p)
+	}
+
+	if !s.status.Synced {
+		return nil
+	}
+	for _, id := range ids {
+		if err := s.settleCollection(id, byCollection[id], now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settles the ended epochs of a collection, from the epoch of its first stake on
+func (s *service) settleCollection(collectionId string, positions []Position, now time.Time) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "settleCollection")
+
+	epochs, err := s.stakeStore.ReadEpochs(collectionId)
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadEpochs:", err)
+		return err
+	}
+	next := s.config.epoch(positions[0].StakedAt)
+	if n := len(epochs); n > 0 {
+		next = epochs[n-1].Number + 1
+	}
+
+	index := make(map[string]int, len(positions))
+	for i, p := range positions {
+		index[p.Id] = i
+	}
+
+	for ; !now.Before(s.config.epochStart(next + 1)); next++ {
+		start, end := s.config.epochStart(next), s.config.epochStart(next+1)
+		shares, total := distribute(s.config.EpochReward, positions, start, end)
+
+		e := Epoch{
+			CollectionId: collectionId,
+			Number:       next,
+			Start:        start,
+			End:          end,
+			Pool:         s.config.EpochReward.String(),
+			Weight:       total.String(),
+			Rewards:      make(map[string]string, len(shares)),
+			SettledAt:    now,
+		}
+		rewarded := make([]Position, 0, len(shares))
+		for id, r := range shares {
+			e.Rewards[id] = r.String()
+			p := &positions[index[id]]
+			p.Rewards = new(big.Int).Add(amount(p.Rewards), r).String()
+			rewarded = append(rewarded, *p)
+		}
+		if err := s.stakeStore.WriteSettlement(e, rewarded); err != nil {
+			level.Error(logger).Log("s.stakeStore.WriteSettlement:", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copies the transactions of pending withdrawals, they are paid once their transfer is replayed
+func (s *service) track(ctx context.Context) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "track")
+
+	if s.sender == nil {
+		return nil
+	}
+	withdrawals, err := s.stakeStore.ReadWithdrawals()
+	if err != nil {
+		level.Error(logger).Log("s.stakeStore.ReadWithdrawals:", err)
+		return err
+	}
+
+	for _, w := range withdrawals {
+		if w.Status != WithdrawalPending {
+			continue
+		}
+
+		tx, err := s.sender.ReadTx(ctx, w.TxId)
+		if err != nil {
+			level.Error(logger).Log("s.sender.ReadTx:", err, "withdrawal", w.Id)
+			continue
+		}
+		status := w.Status
+		switch {
+		case tx.Status == txmanager.StatusFailed:
+			status = WithdrawalFailed
+		case s.backing.paid[tx.Hash]:
+			status = WithdrawalPaid
+		}
+		if status == w.Status && tx.Hash == w.TxHash {
+			continue
+		}
+
+		w.Status, w.TxHash, w.Error, w.UpdatedAt = status, tx.Hash, tx.Error, s.now()
+		if err := s.stakeStore.WriteWithdrawal(w); err != nil {
+			level.Error(logger).Log("s.stakeStore.WriteWithdrawal:", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scheduler periodically settles staking
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and settles every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := sc.service.Settle(ctx); err != nil {
+				level.Error(logger).Log("sc.service.Settle:", err)
+			}
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// initialization function to return service struct
+// the backing is replayed from the index on the first query
+// without a sender withdrawals are disabled, with one it must send from the staking address
+// this function should is called in main.go
+func NewService(stakeStore StakeStore, index Index, collections Collections, sessions Sessions, wallets Wallets, client chain.Client, sender Sender, config Config, logger log.Logger) (Service, error) {
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	address := strings.ToLower(config.Address.Hex())
+	if sender != nil && sender.Address() != address {
+		return nil, ErrInvalidConfig
+	}
+
+	return &service{
+		backing:     newBacking(address),
+		stakeStore:  stakeStore,
+		index:       index,
+		collections: collections,
+		sessions:    sessions,
+		wallets:     wallets,
+		chain:       client,
+		sender:      sender,
+		config:      config,
+		now:         time.Now,
+		logger:      logger,
+	}, nil
+}
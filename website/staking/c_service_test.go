@@ -0,0 +1,469 @@
+package staking
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+	"website/collection"
+	"website/indexer"
+	"website/session"
+	"website/txmanager"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/kit/log"
+)
+
+var _ collection.StakeSource = Service(nil)
+
+const day = 24 * time.Hour
+
+// sessions of fixed users, the session identifier is the file hash
+type staticSessions map[string]time.Time
+
+func (ss staticSessions) ReadSession(sessionId string) (session.Session, error) {
+	return session.Session{FileHash: sessionId, ExpiresAt: ss[sessionId]}, nil
+}
+
+// wallet of each session
+type staticWallets map[string]string
+
+func (sw staticWallets) IsLinked(ctx context.Context, userId string, address string) (bool, error) {
+	for sessionId, a := range sw {
+		if hex.EncodeToString([]byte(sessionId)) == userId && a == address {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type staticCollections map[string]collection.State
+
+func (sc staticCollections) ReadCollection(id string) (collection.Collection, error) {
+	state, ok := sc[id]
+	if !ok {
+		return collection.Collection{}, collection.ErrCollectionNotFound
+	}
+	return collection.Collection{Id: id, State: state}, nil
+}
+
+func withSession(sessionId string) context.Context {
+	return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), sessionId)
+}
+
+// chain, index, transaction manager and staking with the manager's account as staking address
+type testStaking struct {
+	t     *testing.T
+	sim   *chain.SimulatedBackend
+	art   *chain.Art
+	index indexer.Service
+	tm    txmanager.Service
+	s     *service
+	now   time.Time
+}
+
+func newTestStaking(t *testing.T, holder chaintest.Account, wallets staticWallets) *testStaking {
+
+	sim, client := chaintest.DeployArt(t, holder, holder.Addr)
+	index, _ := chaintest.NewIndex(t, t.TempDir(), client, indexer.Config{Confirmations: 1, BatchSize: 16})
+
+	manager := chaintest.NewAccount(t)
+	txStore, err := txmanager.NewTxStore(txmanager.TxStoreConfig{TxsPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewTxStore failed, error: %v.", err)
+	}
+	tm := txmanager.NewService(txStore, sim, manager.Key, txmanager.Config{ChainId: chaintest.ChainId, Confirmations: 1, GasMargin: 20, StuckAfter: time.Hour, BumpPercent: 20}, log.NewNopLogger())
+
+	stakeStore, err := NewStakeStore(StakeStoreConfig{StakesPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewStakeStore failed, error: %v.", err)
+	}
+	ts := &testStaking{t: t, sim: sim, art: client.Art(), index: index, tm: tm, now: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)}
+	config := Config{
+		Address:     manager.Addr,
+		EpochStart:  ts.now,
+		EpochLength: 7 * day,
+		MaxLock:     28 * day,
+		MaxBoost:    10000,
+		Cooldown:    2 * day,
+		MinStake:    big.NewInt(10),
+		EpochReward: big.NewInt(1000),
+	}
+	collections := staticCollections{"c1": collection.StateOpen, "draft": collection.StateDraft}
+	sessions := staticSessions{"alice": ts.now.Add(365 * day), "bob": ts.now.Add(365 * day), "carol": ts.now.Add(365 * day)}
+	s, err := NewService(stakeStore, index, collections, sessions, wallets, client, tm, config, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewService failed, error: %v.", err)
+	}
+	ts.s = s.(*service)
+	ts.s.now = func() time.Time { return ts.now }
+	return ts
+}
+
+// mines the pending transactions and enough blocks to confirm them, then indexes everything
+func (ts *testStaking) mine() uint64 {
+
+	ctx := context.Background()
+	ts.sim.Commit()
+	mined, _ := ts.sim.BlockNumber(ctx)
+	ts.sim.Commit()
+	ts.sim.Commit()
+	if err := ts.tm.Track(ctx); err != nil {
+		ts.t.Fatalf("Track failed, error: %v.", err)
+	}
+	chaintest.Sync(ts.t, ts.index)
+	return mined
+}
+
+func (ts *testStaking) account(a chaintest.Account) Account {
+	account, err := ts.s.ReadAccount(context.Background(), a.Hex())
+	if err != nil {
+		ts.t.Fatalf("ReadAccount failed, error: %v.", err)
+	}
+	return account
+}
+
+func (ts *testStaking) stake(sessionId string, a chaintest.Account, value string, lockDays int) (Position, error) {
+	return ts.s.Stake(withSession(sessionId), StakeInput{CollectionId: "c1", Address: a.Hex(), Amount: value, LockDays: lockDays})
+}
+
+func TestStakeLifecycle(t *testing.T) {
+
+	ctx := context.Background()
+	holder, alice, bob, carol := chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t), chaintest.NewAccount(t)
+	ts := newTestStaking(t, holder, staticWallets{"alice": alice.Hex(), "bob": bob.Hex(), "carol": carol.Hex()})
+	staking := ts.s.config.Address
+
+	// custody, delegation and approval
+	for _, a := range []chaintest.Account{alice, bob, carol} {
+		if _, err := ts.art.Transfer(holder.Opts, a.Addr, big.NewInt(1000)); err != nil {
+			t.Fatalf("Transfer failed, error: %v.", err)
+		}
+	}
+	ts.sim.Commit()
+	ts.art.Transfer(alice.Opts, staking, big.NewInt(600))
+	ts.art.Delegate(bob.Opts, staking)
+	ts.art.Approve(carol.Opts, staking, big.NewInt(300))
+	ts.mine()
+
+	for _, c := range []struct {
+		account chaintest.Account
+		backing string
+	}{{alice, "600"}, {bob, "1000"}, {carol, "300"}} {
+		if a := ts.account(c.account); a.Backing != c.backing || a.Available != c.backing {
+			t.Errorf("account %+v, expected a backing of %s.", a, c.backing)
+		}
+	}
+
+	cases := []struct {
+		sessionId string
+		account   chaintest.Account
+		amount    string
+		lockDays  int
+		err       error
+	}{
+		{"", alice, "100", 0, ErrUnauthorized},
+		{"alice", bob, "100", 0, ErrWalletNotLinked},
+		{"alice", alice, "5", 0, ErrBelowMinimum},
+		{"alice", alice, "100", 29, ErrInvalidLock},
+		{"carol", carol, "301", 0, ErrInsufficientBacking},
+	}
+	for _, c := range cases {
+		if _, err := ts.stake(c.sessionId, c.account, c.amount, c.lockDays); err != c.err {
+			t.Errorf("Stake(%s, %s) returned %v, expected %v.", c.sessionId, c.amount, err, c.err)
+		}
+	}
+	if _, err := ts.s.Stake(withSession("alice"), StakeInput{CollectionId: "draft", Address: alice.Hex(), Amount: "100"}); err != ErrNotStakeable {
+		t.Errorf("Stake returned %v, expected %v.", err, ErrNotStakeable)
+	}
+
+	a1, err := ts.stake("alice", alice, "500", 28)
+	if err != nil {
+		t.Fatalf("Stake failed, error: %v.", err)
+	}
+	if a1.Boost != 20000 {
+		t.Errorf("boost %d, expected 20000 for the longest lock.", a1.Boost)
+	}
+	if _, err := ts.stake("alice", alice, "101", 0); err != ErrInsufficientBacking {
+		t.Errorf("Stake returned %v, expected %v.", err, ErrInsufficientBacking)
+	}
+	b1, err := ts.stake("bob", bob, "1000", 0)
+	if err != nil {
+		t.Fatalf("Stake failed, error: %v.", err)
+	}
+
+	// stakes count from the block after the head
+	ts.mine()
+	for block, expected := range map[uint64]int64{a1.FromBlock - 1: 0, a1.FromBlock: 500} {
+		if stake, err := ts.s.PriorStake(ctx, "c1", alice.Hex(), block); err != nil || stake.Int64() != expected {
+			t.Errorf("PriorStake at %d returned %v, %v, expected %d.", block, stake, err, expected)
+		}
+	}
+	head, _ := ts.sim.BlockNumber(ctx)
+	if _, err := ts.s.PriorStake(ctx, "c1", alice.Hex(), head); err != chain.ErrNotDetermined {
+		t.Errorf("PriorStake at the head returned %v, expected %v.", err, chain.ErrNotDetermined)
+	}
+
+	// alice weighs 500 at twice the boost, bob 1000
+	ts.now = ts.now.Add(7 * day)
+	if err := ts.s.Settle(ctx); err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	if epochs, _ := ts.s.ListEpochs(ctx, "c1"); len(epochs) != 1 || epochs[0].Rewards[a1.Id] != "500" || epochs[0].Rewards[b1.Id] != "500" {
+		t.Errorf("epochs %+v, expected an even split.", epochs)
+	}
+
+	// bob moves tokens, the delegated backing no longer covers his stake
+	ts.art.Transfer(bob.Opts, holder.Addr, big.NewInt(1))
+	moved := ts.mine()
+	ts.account(bob)
+	b, _ := ts.s.stakeStore.ReadPosition(b1.Id)
+	if b.Status != StatusForfeited || b.ToBlock != moved {
+		t.Errorf("position %+v, expected forfeited at block %d.", b, moved)
+	}
+	if stake, _ := ts.s.PriorStake(ctx, "c1", bob.Hex(), moved-1); stake.Int64() != 1000 {
+		t.Errorf("stake before forfeiture %v, expected 1000.", stake)
+	}
+	if stake, _ := ts.s.PriorStake(ctx, "c1", bob.Hex(), moved); stake.Sign() != 0 {
+		t.Errorf("stake after forfeiture %v, expected 0.", stake)
+	}
+
+	// locked for four epochs, then cooling down
+	if _, err := ts.s.Unstake(withSession("alice"), a1.Id); err != ErrLocked {
+		t.Errorf("Unstake returned %v, expected %v.", err, ErrLocked)
+	}
+	ts.now = ts.now.Add(21*day + time.Hour)
+	if err := ts.s.Settle(ctx); err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	if _, err := ts.s.Unstake(withSession("bob"), a1.Id); err != ErrWalletNotLinked {
+		t.Errorf("Unstake returned %v, expected %v.", err, ErrWalletNotLinked)
+	}
+	a1, err = ts.s.Unstake(withSession("alice"), a1.Id)
+	if err != nil || a1.Status != StatusCooling {
+		t.Fatalf("Unstake returned %+v, %v, expected cooling.", a1, err)
+	}
+
+	// the first epoch is shared, the next three are alice's alone
+	if a := ts.account(alice); a.Rewards != "3500" || a.Withdrawable != "100" {
+		t.Errorf("account %+v, expected 3500 rewards and 100 withdrawable.", a)
+	}
+	if a := ts.account(bob); a.Rewards != "500" {
+		t.Errorf("account %+v, expected 500 rewards.", a)
+	}
+
+	// custody beyond the allocation goes back through the transaction manager
+	if _, err := ts.s.Withdraw(withSession("alice"), alice.Hex(), "101"); err != ErrInsufficientBacking {
+		t.Errorf("Withdraw returned %v, expected %v.", err, ErrInsufficientBacking)
+	}
+	w, err := ts.s.Withdraw(withSession("alice"), alice.Hex(), "100")
+	if err != nil {
+		t.Fatalf("Withdraw failed, error: %v.", err)
+	}
+	if a := ts.account(alice); a.Pending != "100" || a.Withdrawable != "0" {
+		t.Errorf("account %+v, expected the withdrawal pending.", a)
+	}
+	ts.mine()
+	ts.now = ts.now.Add(2 * day)
+	if err := ts.s.Settle(ctx); err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	a := ts.account(alice)
+	if len(a.Withdrawals) != 1 || a.Withdrawals[0].Id != w.Id || a.Withdrawals[0].Status != WithdrawalPaid {
+		t.Errorf("withdrawals %+v, expected paid.", a.Withdrawals)
+	}
+	if a.Custody != "500" || a.Allocated != "0" || a.Withdrawable != "500" || a.Positions[0].Status != StatusReleased {
+		t.Errorf("account %+v, expected the released custody withdrawable.", a)
+	}
+	if balance, _ := ts.art.BalanceOf(&bind.CallOpts{}, alice.Addr); balance.Int64() != 500 {
+		t.Errorf("balance %v, expected 500.", balance)
+	}
+}
+
+func TestBoost(t *testing.T) {
+
+	c := Config{MaxLock: 28 * day, MaxBoost: 5000}
+	for lock, expected := range map[time.Duration]int64{0: 10000, 7 * day: 11250, 28 * day: 15000, 56 * day: 15000} {
+		if boost := c.Boost(lock); boost != expected {
+			t.Errorf("boost of %v is %d, expected %d.", lock, boost, expected)
+		}
+	}
+}
+
+// index of fixed events, no chain behind it
+type fakeIndex struct {
+	status indexer.Status
+	events []indexer.Event
+}
+
+func (fi *fakeIndex) ReadStatus(ctx context.Context) (indexer.Status, error) {
+	return fi.status, nil
+}
+
+func (fi *fakeIndex) ListEvents(ctx context.Context, from uint64, to uint64) ([]indexer.Event, error) {
+	var events []indexer.Event
+	for _, e := range fi.events {
+		if e.Block >= from && e.Block <= to {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// staking over a fake index, positions are written to the store directly
+func newFakeStaking(t *testing.T, index *fakeIndex, now *time.Time) *service {
+
+	stakeStore, err := NewStakeStore(StakeStoreConfig{StakesPath: t.TempDir()}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewStakeStore failed, error: %v.", err)
+	}
+	config := Config{
+		Address:     common.HexToAddress(stakingAddress),
+		EpochStart:  time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+		EpochLength: 7 * day,
+		MaxLock:     28 * day,
+		MaxBoost:    10000,
+		MinStake:    big.NewInt(10),
+		EpochReward: big.NewInt(1000),
+	}
+	s, err := NewService(stakeStore, index, nil, nil, nil, nil, nil, config, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewService failed, error: %v.", err)
+	}
+	fs := s.(*service)
+	fs.now = func() time.Time { return *now }
+	return fs
+}
+
+func (s *service) writePositions(t *testing.T, positions ...Position) {
+	for _, p := range positions {
+		if err := s.stakeStore.WritePosition(p); err != nil {
+			t.Fatalf("WritePosition failed, error: %v.", err)
+		}
+	}
+}
+
+// a transfer that leaves the backing short forfeits the newest positions from its block on
+func TestForfeitPriorStake(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC)
+	index := &fakeIndex{
+		status: indexer.Status{Indexed: 5, Head: 6, Confirmed: 5, Synced: true},
+		events: []indexer.Event{
+			{Type: indexer.EventTransfer, Block: 1, From: zeroAddress, To: aliceAddress, Amount: "100"},
+			{Type: indexer.EventDelegateChanged, Block: 1, Delegator: aliceAddress, ToDelegate: stakingAddress},
+			{Type: indexer.EventTransfer, Block: 4, From: aliceAddress, To: bobAddress, Amount: "60"},
+			{Type: indexer.EventTransfer, Block: 4, From: bobAddress, To: aliceAddress, Amount: "10"},
+		},
+	}
+	s := newFakeStaking(t, index, &now)
+	s.writePositions(t,
+		Position{Id: "old", CollectionId: "c1", Address: aliceAddress, Amount: "40", Boost: 10000, FromBlock: 2, Status: StatusActive, StakedAt: now.Add(-2 * time.Hour)},
+		Position{Id: "new", CollectionId: "c1", Address: aliceAddress, Amount: "30", Boost: 10000, FromBlock: 3, Status: StatusActive, StakedAt: now.Add(-time.Hour)},
+		Position{Id: "other", CollectionId: "c2", Address: aliceAddress, Amount: "20", Boost: 10000, FromBlock: 6, Status: StatusActive, StakedAt: now},
+	)
+
+	cases := []struct {
+		collectionId string
+		block        uint64
+		expected     int64
+	}{{"c1", 1, 0}, {"c1", 2, 40}, {"c1", 3, 70}, {"c1", 4, 40}, {"c1", 5, 40}, {"c2", 5, 0}}
+	for _, c := range cases {
+		stake, err := s.PriorStake(ctx, c.collectionId, aliceAddress, c.block)
+		if err != nil || stake.Int64() != c.expected {
+			t.Errorf("PriorStake of %s at %d returned %v, %v, expected %d.", c.collectionId, c.block, stake, err, c.expected)
+		}
+	}
+	for _, block := range []uint64{6, 7} {
+		if _, err := s.PriorStake(ctx, "c1", aliceAddress, block); err != chain.ErrNotDetermined {
+			t.Errorf("PriorStake at %d returned %v, expected %v.", block, err, chain.ErrNotDetermined)
+		}
+	}
+	if _, err := s.PriorStake(ctx, "c1", "alice", 2); err != ErrInvalidAddress {
+		t.Errorf("PriorStake of an invalid address returned %v, expected %v.", err, ErrInvalidAddress)
+	}
+
+	// the newest position went at block 4, the rest is backed by the 50 left
+	p, _ := s.stakeStore.ReadPosition("new")
+	if p.Status != StatusForfeited || p.ToBlock != 4 || !p.EndedAt.Equal(now) {
+		t.Errorf("position %+v, expected forfeited at block 4.", p)
+	}
+	p, _ = s.stakeStore.ReadPosition("old")
+	if p.Status != StatusActive || p.ToBlock != 0 {
+		t.Errorf("position %+v, expected active.", p)
+	}
+
+	// the later position is short from its first block on, without an event after it
+	index.status = indexer.Status{Indexed: 7, Head: 8, Confirmed: 7, Synced: true}
+	for _, block := range []uint64{6, 7} {
+		if stake, err := s.PriorStake(ctx, "c2", aliceAddress, block); err != nil || stake.Sign() != 0 {
+			t.Errorf("PriorStake of c2 at %d returned %v, %v, expected 0.", block, stake, err)
+		}
+	}
+	p, _ = s.stakeStore.ReadPosition("other")
+	if p.Status != StatusForfeited || p.ToBlock != 6 {
+		t.Errorf("position %+v, expected forfeited at block 6.", p)
+	}
+}
+
+// every ended epoch is settled once, only while the index is synced
+func TestSettleEpochs(t *testing.T) {
+
+	ctx := context.Background()
+	start := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	now := start.Add(15 * day)
+	index := &fakeIndex{
+		status: indexer.Status{Indexed: 2, Head: 3, Confirmed: 2},
+		events: []indexer.Event{
+			{Type: indexer.EventTransfer, Block: 1, From: zeroAddress, To: aliceAddress, Amount: "100"},
+			{Type: indexer.EventTransfer, Block: 1, From: zeroAddress, To: bobAddress, Amount: "100"},
+			{Type: indexer.EventDelegateChanged, Block: 1, Delegator: aliceAddress, ToDelegate: stakingAddress},
+			{Type: indexer.EventDelegateChanged, Block: 1, Delegator: bobAddress, ToDelegate: stakingAddress},
+		},
+	}
+	s := newFakeStaking(t, index, &now)
+	s.writePositions(t,
+		Position{Id: "a", CollectionId: "c1", Address: aliceAddress, Amount: "100", Boost: 10000, FromBlock: 1, Status: StatusActive, StakedAt: start},
+		Position{Id: "b", CollectionId: "c1", Address: bobAddress, Amount: "100", Boost: 20000, FromBlock: 1, Status: StatusActive, StakedAt: start},
+	)
+
+	if err := s.Settle(ctx); err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	if epochs, _ := s.stakeStore.ReadEpochs("c1"); len(epochs) != 0 {
+		t.Errorf("%d epochs settled before the index is synced, expected none.", len(epochs))
+	}
+
+	index.status.Synced = true
+	for i := 0; i < 2; i++ {
+		if err := s.Settle(ctx); err != nil {
+			t.Fatalf("Settle failed, error: %v.", err)
+		}
+	}
+	epochs, _ := s.stakeStore.ReadEpochs("c1")
+	if len(epochs) != 2 || epochs[0].Number != 0 || epochs[1].Number != 1 || epochs[1].Weight != "300" {
+		t.Fatalf("epochs %+v, expected epochs 0 and 1 with weight 300.", epochs)
+	}
+	if epochs[1].Rewards["a"] != "333" || epochs[1].Rewards["b"] != "666" {
+		t.Errorf("rewards %v, expected 333 and 666.", epochs[1].Rewards)
+	}
+	for id, expected := range map[string]string{"a": "666", "b": "1332"} {
+		if p, _ := s.stakeStore.ReadPosition(id); p.Rewards != expected {
+			t.Errorf("rewards of %s %s, expected %s.", id, p.Rewards, expected)
+		}
+	}
+
+	now = now.Add(7 * day)
+	if err := s.Settle(ctx); err != nil {
+		t.Fatalf("Settle failed, error: %v.", err)
+	}
+	if epochs, _ = s.stakeStore.ReadEpochs("c1"); len(epochs) != 3 || epochs[2].Number != 2 {
+		t.Errorf("epochs %+v, expected epoch 2 settled.", epochs)
+	}
+}
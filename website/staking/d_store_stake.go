@@ -0,0 +1,331 @@
+package staking
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Position struct **********
+
+type Status string
+
+const (
+
+	// locked toward the collection, counts for weights and rewards
+	StatusActive Status = "active"
+
+	// unstake requested, no longer counts but stays allocated until release_at
+	StatusCooling Status = "cooling"
+
+	// cooldown over, the tokens can be staked again or withdrawn
+	StatusReleased Status = "released"
+
+	// the tokens behind the position left, see forfeit
+	StatusForfeited Status = "forfeited"
+)
+
+// stake of one address toward one collection
+// amounts are decimal strings in the token's smallest unit, boost is in basis points
+// the stake counts for weights at blocks from from_block up to, but excluding, to_block
+// ended_at is set when the position stops counting for rewards
+type Position struct {
+	Id           string    `json:"id"`
+	CollectionId string    `json:"collection_id"`
+	Address      string    `json:"address"`
+	Amount       string    `json:"amount"`
+	Boost        int64     `json:"boost"`
+	FromBlock    uint64    `json:"from_block"`
+	ToBlock      uint64    `json:"to_block,omitempty"`
+	Status       Status    `json:"status"`
+	Rewards      string    `json:"rewards"`
+	StakedAt     time.Time `json:"staked_at"`
+	LockedUntil  time.Time `json:"locked_until"`
+	EndedAt      time.Time `json:"ended_at,omitempty"`
+	ReleaseAt    time.Time `json:"release_at,omitempty"`
+}
+
+// active and cooling positions hold tokens
+func (p Position) allocated(now time.Time) bool {
+	return p.Status == StatusActive || (p.Status == StatusCooling && now.Before(p.ReleaseAt))
+}
+
+// ******** Epoch struct **********
+
+// settled epoch of a collection, the pool is split by weight among positions staked for the whole epoch
+// rewards maps position identifiers to their share, integer division leaves the remainder undistributed
+type Epoch struct {
+	CollectionId string            `json:"collection_id"`
+	Number       uint64            `json:"number"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Pool         string            `json:"pool"`
+	Weight       string            `json:"weight"`
+	Rewards      map[string]string `json:"rewards"`
+	SettledAt    time.Time         `json:"settled_at"`
+}
+
+// ******** Withdrawal struct **********
+
+type WithdrawalStatus string
+
+const (
+
+	// sent by the transaction manager, the transfer is not yet indexed as confirmed
+	WithdrawalPending WithdrawalStatus = "pending"
+
+	// the transfer out of the staking address is confirmed
+	WithdrawalPaid WithdrawalStatus = "paid"
+
+	WithdrawalFailed WithdrawalStatus = "failed"
+)
+
+// transfer of released tokens from the staking address back to their owner
+type Withdrawal struct {
+	Id        string           `json:"id"`
+	Address   string           `json:"address"`
+	Amount    string           `json:"amount"`
+	TxId      string           `json:"tx_id"`
+	TxHash    string           `json:"tx_hash"`
+	Status    WithdrawalStatus `json:"status"`
+	Error     string           `json:"error,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// ******* Stake store interface *********
+
+var ErrPositionNotFound = errors.New("Position not found")
+
+type StakeStoreConfig struct {
+	StakesPath string
+}
+
+// StakeStore persists positions, settled epochs and withdrawals
+// WriteSettlement writes an epoch together with the rewards it added to positions
+type StakeStore interface {
+	WritePosition(p Position) error
+	ReadPosition(id string) (Position, error)
+	ReadPositions() ([]Position, error)
+	WriteSettlement(e Epoch, positions []Position) error
+	ReadEpochs(collectionId string) ([]Epoch, error)
+	WriteWithdrawal(w Withdrawal) error
+	ReadWithdrawals() ([]Withdrawal, error)
+}
+
+// persisted content of stakes.json, epochs are keyed by collection
+type stakeFile struct {
+	Positions   map[string]Position   `json:"positions"`
+	Epochs      map[string][]Epoch    `json:"epochs"`
+	Withdrawals map[string]Withdrawal `json:"withdrawals"`
+}
+
+type stakeStore struct {
+	mu     sync.Mutex
+	file   stakeFile
+	config StakeStoreConfig
+	logger log.Logger
+}
+
+// creates or replaces a position
+func (ss *stakeStore) WritePosition(p Position) error {
+
+	// log level
+	logger := log.With(ss.logger, "method", "WritePosition")
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	previous, existed := ss.file.Positions[p.Id]
+	ss.file.Positions[p.Id] = p
+
+	if err := ss.write(); err != nil {
+		if existed {
+			ss.file.Positions[p.Id] = previous
+		} else {
+			delete(ss.file.Positions, p.Id)
+		}
+		level.Error(logger).Log("ss.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (ss *stakeStore) ReadPosition(id string) (Position, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	p, ok := ss.file.Positions[id]
+	if !ok {
+		return Position{}, ErrPositionNotFound
+	}
+	return p, nil
+}
+
+// all positions, oldest first
+func (ss *stakeStore) ReadPositions() ([]Position, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	positions := make([]Position, 0, len(ss.file.Positions))
+	for _, p := range ss.file.Positions {
+		positions = append(positions, p)
+	}
+	sort.Slice(positions, func(i, k int) bool {
+		if positions[i].StakedAt.Equal(positions[k].StakedAt) {
+			return positions[i].Id < positions[k].Id
+		}
+		return positions[i].StakedAt.Before(positions[k].StakedAt)
+	})
+	return positions, nil
+}
+
+// appends an epoch and replaces the rewarded positions in one write
+func (ss *stakeStore) WriteSettlement(e Epoch, positions []Position) error {
+
+	// log level
+	logger := log.With(ss.logger, "method", "WriteSettlement")
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	epochs := ss.file.Epochs[e.CollectionId]
+	previous := make(map[string]Position, len(positions))
+	for _, p := range positions {
+		previous[p.Id] = ss.file.Positions[p.Id]
+		ss.file.Positions[p.Id] = p
+	}
+	ss.file.Epochs[e.CollectionId] = append(epochs, e)
+
+	if err := ss.write(); err != nil {
+		for id, p := range previous {
+			ss.file.Positions[id] = p
+		}
+		ss.file.Epochs[e.CollectionId] = epochs
+		level.Error(logger).Log("ss.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// settled epochs of a collection, oldest first
+func (ss *stakeStore) ReadEpochs(collectionId string) ([]Epoch, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	epochs := ss.file.Epochs[collectionId]
+	return append(make([]Epoch, 0, len(epochs)), epochs...), nil
+}
+
+// creates or replaces a withdrawal
+func (ss *stakeStore) WriteWithdrawal(w Withdrawal) error {
+
+	// log level
+	logger := log.With(ss.logger, "method", "WriteWithdrawal")
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	previous, existed := ss.file.Withdrawals[w.Id]
+	ss.file.Withdrawals[w.Id] = w
+
+	if err := ss.write(); err != nil {
+		if existed {
+			ss.file.Withdrawals[w.Id] = previous
+		} else {
+			delete(ss.file.Withdrawals, w.Id)
+		}
+		level.Error(logger).Log("ss.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// all withdrawals, oldest first
+func (ss *stakeStore) ReadWithdrawals() ([]Withdrawal, error) {
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	withdrawals := make([]Withdrawal, 0, len(ss.file.Withdrawals))
+	for _, w := range ss.file.Withdrawals {
+		withdrawals = append(withdrawals, w)
+	}
+	sort.Slice(withdrawals, func(i, k int) bool {
+		if withdrawals[i].CreatedAt.Equal(withdrawals[k].CreatedAt) {
+			return withdrawals[i].Id < withdrawals[k].Id
+		}
+		return withdrawals[i].CreatedAt.Before(withdrawals[k].CreatedAt)
+	})
+	return withdrawals, nil
+}
+
+// writes the whole file, temporary file first so that a crash never leaves a partial file
+func (ss *stakeStore) write() error {
+
+	if err := os.MkdirAll(ss.config.StakesPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ss.file)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ss.config.StakesPath, "stakes.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads the persisted stakes, a missing file starts empty
+func NewStakeStore(config StakeStoreConfig, logger log.Logger) (StakeStore, error) {
+
+	ss := &stakeStore{
+		file: stakeFile{
+			Positions:   make(map[string]Position),
+			Epochs:      make(map[string][]Epoch),
+			Withdrawals: make(map[string]Withdrawal),
+		},
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.StakesPath, "stakes.json"))
+	if os.IsNotExist(err) {
+		return ss, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ss.file); err != nil {
+		return nil, err
+	}
+	if ss.file.Positions == nil {
+		ss.file.Positions = make(map[string]Position)
+	}
+	if ss.file.Epochs == nil {
+		ss.file.Epochs = make(map[string][]Epoch)
+	}
+	if ss.file.Withdrawals == nil {
+		ss.file.Withdrawals = make(map[string]Withdrawal)
+	}
+
+	return ss, nil
+}
@@ -3,10 +3,12 @@ package storage
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -29,7 +31,7 @@ func (c customTime) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf("\"%s\"", c.Format(time.RFC1123))), nil
 }
 
-func (c customTime) UnmarshalJSON(v []byte) error {
+func (c *customTime) UnmarshalJSON(v []byte) error {
 	var err error
 	c.Time, err = time.Parse(time.RFC1123, strings.ReplaceAll(string(v), "\"", ""))
 	if err != nil {
@@ -38,6 +40,19 @@ func (c customTime) UnmarshalJSON(v []byte) error {
 	return nil
 }
 
+// wallet types, contract wallets sign through EIP-1271
+const (
+	WalletEOA      = "eoa"
+	WalletContract = "contract"
+)
+
+// ethereum address linked to a user by a signed challenge, lowercase hex
+type Wallet struct {
+	Address  string    `json:"address"`
+	Type     string    `json:"type"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
 // important: userId is the md5 hash of User.Email
 type User struct {
 	UUID      string     `json:"uuid"`
@@ -45,6 +60,7 @@ type User struct {
 	LastName  string     `json:"lastname"`
 	Email     string     `json:"email"`
 	Password  string     `json:"password"`
+	Wallets   []Wallet   `json:"wallets,omitempty"`
 	CreatedAt customTime `json:"created_at"`
 }
 
@@ -53,9 +69,16 @@ type UserReturn struct {
 	FirstName string     `json:"firstname"`
 	LastName  string     `json:"lastname"`
 	Email     string     `json:"email"`
+	Wallets   []Wallet   `json:"wallets,omitempty"`
 	CreatedAt customTime `json:"created_at"`
 }
 
+// user identifier, the hex encoded md5 hash of the email like session.Session.UserId
+func (u User) UserId() string {
+	hash := md5.Sum([]byte(u.Email))
+	return hex.EncodeToString(hash[:])
+}
+
 // Validate user input
 func (u *User) Validate(action string) error {
 
@@ -118,6 +141,8 @@ func (u *User) Prepare() error {
 
 var UserStoreError = errors.New("User store error")
 
+var ErrUserNotFound = errors.New("User not found")
+
 type UserStoreConfig struct {
 	UsersPath string
 }
@@ -125,6 +150,8 @@ type UserStoreConfig struct {
 type UserStore interface {
 	WriteUser(user User) error
 	ReadUser(email string) (User, error)
+	ReadUserById(userId string) (User, error)
+	ReadUsers() ([]User, error)
 	// UpdateUser(ctx context.Context, user User) error
 	DeleteUser(email string) error
 }
@@ -142,7 +169,7 @@ func (us *userStore) WriteUser(user User) error {
 
 	// load user storage file
 	fileLocation := getUserPath(user.Email, us.config.UsersPath, ".json")
-	f, err := os.OpenFile(fileLocation, os.O_WRONLY|os.O_CREATE, 0755)
+	f, err := os.OpenFile(fileLocation, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		level.Error(logger).Log("OpenFile", err)
 		return err
@@ -176,7 +203,7 @@ func (us *userStore) ReadUser(email string) (user User, err error) {
 	defer f.Close()
 
 	// read json and decode into user struct
-	if err := json.NewDecoder(f).Decode(user); err != nil {
+	if err := json.NewDecoder(f).Decode(&user); err != nil {
 		level.Error(logger).Log("json.NewReader(f).Decode(user):", err)
 		return user, err
 	}
@@ -184,6 +211,69 @@ func (us *userStore) ReadUser(email string) (user User, err error) {
 	return user, nil
 }
 
+// reads a user by the hex encoded md5 hash of the email
+func (us *userStore) ReadUserById(userId string) (user User, err error) {
+
+	// log level
+	logger := log.With(us.logger, "method", "ReadUserById")
+
+	hash, err := hex.DecodeString(userId)
+	if err != nil || len(hash) != md5.Size {
+		return user, ErrUserNotFound
+	}
+
+	// user files are named by the raw hash, see getUserPath
+	f, err := os.Open(us.config.UsersPath + string(hash) + ".json")
+	if os.IsNotExist(err) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		level.Error(logger).Log("os.Open:", err)
+		return user, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&user); err != nil {
+		level.Error(logger).Log("json.NewReader(f).Decode(user):", err)
+		return user, err
+	}
+
+	return user, nil
+}
+
+// reads all users
+func (us *userStore) ReadUsers() ([]User, error) {
+
+	// log level
+	logger := log.With(us.logger, "method", "ReadUsers")
+
+	entries, err := os.ReadDir(us.config.UsersPath)
+	if err != nil {
+		level.Error(logger).Log("os.ReadDir:", err)
+		return nil, err
+	}
+
+	users := []User{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(us.config.UsersPath, entry.Name()))
+		if err != nil {
+			level.Error(logger).Log("os.ReadFile:", err)
+			return nil, err
+		}
+		var user User
+		if err := json.Unmarshal(data, &user); err != nil {
+			level.Error(logger).Log("json.Unmarshal:", err, "file", entry.Name())
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 // func (us *userStore) UpdateUser(user User) error {
 // 	// same as WriteUser
 // }
@@ -0,0 +1,105 @@
+package txmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidStatus):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrTxNotFound):
+		return http.StatusNotFound
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decode transaction identifier from route
+func decodeReadTx(_ context.Context, r *http.Request) (interface{}, error) {
+	return ReadTxRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decode status from query parameters
+func decodeListTxs(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListTxsRequest{Status: Status(r.URL.Query().Get("status"))}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach before spa routes, spa handler catches all remaining paths
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching txmanager handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	readTxHandler := httptransport.NewServer(
+		e.ReadTx,
+		decodeReadTx,
+		encodeResponse,
+		options...,
+	)
+
+	listTxsHandler := httptransport.NewServer(
+		e.ListTxs,
+		decodeListTxs,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/txs", listTxsHandler).Methods("GET")
+	router.Handle("/txs/{id}", readTxHandler).Methods("GET")
+
+	return router
+}
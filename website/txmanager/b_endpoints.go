@@ -0,0 +1,84 @@
+package txmanager
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type ReadTxRequest struct {
+	Id string
+}
+
+type ListTxsRequest struct {
+	Status Status
+}
+
+type TxResponse struct {
+	Data Tx    `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have TxResponse follow the customError interface defined in a_transport.go
+func (r TxResponse) error() error { return r.Err }
+
+type TxsResponse struct {
+	Data []Tx  `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have TxsResponse follow the customError interface defined in a_transport.go
+func (r TxsResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	ReadTx  endpoint.Endpoint
+	ListTxs endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		ReadTx:  epReadTx(s),
+		ListTxs: epListTxs(s),
+	}
+}
+
+// read transaction endpoint
+func epReadTx(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ReadTxRequest)
+
+		// call service method
+		tx, err := s.ReadTx(ctx, req.Id)
+		if err != nil {
+			return TxResponse{Err: err}, err
+		}
+
+		return TxResponse{Data: tx, Err: nil}, nil
+	}
+}
+
+// list transactions endpoint
+func epListTxs(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(ListTxsRequest)
+
+		// call service method
+		txs, err := s.ListTxs(ctx, req.Status)
+		if err != nil {
+			return TxsResponse{Err: err}, err
+		}
+
+		return TxsResponse{Data: txs, Err: nil}, nil
+	}
+}
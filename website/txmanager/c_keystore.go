@@ -0,0 +1,38 @@
+package txmanager
+
+import (
+	"crypto/ecdsa"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ******** Keystore **********
+
+// LoadKey decrypts a Web3 Secret Storage v3 key file
+// a wrong passphrase returns keystore.ErrDecrypt
+func LoadKey(path string, passphrase string) (*ecdsa.PrivateKey, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// CreateKey generates a key and stores it encrypted in dir
+// scrypt parameters are keystore.StandardScryptN/P in production, the light ones in tests
+// returns the path of the key file and the address of the key
+func CreateKey(dir string, passphrase string, scryptN int, scryptP int) (string, common.Address, error) {
+
+	account, err := keystore.StoreKey(dir, passphrase, scryptN, scryptP)
+	if err != nil {
+		return "", common.Address{}, err
+	}
+	return account.URL.Path, account.Address, nil
+}
@@ -0,0 +1,508 @@
+package txmanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"website/chain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrWouldRevert = errors.New("Transaction would revert")
+
+var ErrFeeTooHigh = errors.New("Network fees exceed the maximum fee cap")
+
+var ErrInvalidStatus = errors.New("Invalid transaction status")
+
+// Config of the transaction manager
+// gas_margin is added to gas estimates in percent, pending transactions are replaced after stuck_after with fees raised by bump_percent
+// max_fee_cap limits what the hot wallet pays per gas in wei, nil is unlimited
+type Config struct {
+	ChainId       *big.Int
+	Confirmations uint64
+	GasMargin     int64
+	StuckAfter    time.Duration
+	BumpPercent   int64
+	MaxFeeCap     *big.Int
+}
+
+// transaction to send, ref is a free label of the caller such as a relay identifier
+type Request struct {
+	To    common.Address
+	Data  []byte
+	Value *big.Int
+	Ref   string
+}
+
+// service interface defining all required methods
+type Service interface {
+	Address() string
+	Send(ctx context.Context, req Request) (Tx, error)
+	ReadTx(ctx context.Context, id string) (Tx, error)
+	ListTxs(ctx context.Context, status Status) ([]Tx, error)
+	Track(ctx context.Context) error
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// sends and replacements are serialized, nonces are allocated one at a time
+	mtx sync.Mutex
+
+	txStore TxStore
+	backend chain.Backend
+	key     *ecdsa.PrivateKey
+	from    common.Address
+	signer  types.Signer
+	config  Config
+	now     func() time.Time
+	logger  log.Logger
+}
+
+func formatAddress(a common.Address) string {
+	return strings.ToLower(a.Hex())
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// service struct address method
+// the hot wallet's address, lowercase
+func (s *service) Address() string {
+	return formatAddress(s.from)
+}
+
+// EIP-1559 fees for a new transaction
+// the fee cap allows the base fee to double before the transaction stops being includable
+func (s *service) fees(ctx context.Context) (*big.Int, *big.Int, error) {
+
+	tip, err := s.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := s.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, nil, errors.New("chain does not support EIP-1559")
+	}
+
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+	if s.config.MaxFeeCap != nil {
+		if new(big.Int).Add(head.BaseFee, tip).Cmp(s.config.MaxFeeCap) > 0 {
+			return nil, nil, ErrFeeTooHigh
+		}
+		if feeCap.Cmp(s.config.MaxFeeCap) > 0 {
+			feeCap.Set(s.config.MaxFeeCap)
+		}
+	}
+	return tip, feeCap, nil
+}
+
+// raises a fee by the bump percentage, at least by one wei
+func (s *service) bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+s.config.BumpPercent))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
+
+// signs the stored transaction with the given fees
+// signatures are deterministic, the same fees give the same hash
+func (s *service) sign(tx Tx, tip *big.Int, feeCap *big.Int) (*types.Transaction, error) {
+
+	data, err := hexutil.Decode(tx.Data)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := new(big.Int).SetString(tx.Value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid value %q", tx.Value)
+	}
+	to := common.HexToAddress(tx.To)
+
+	return types.SignNewTx(s.key, s.signer, &types.DynamicFeeTx{
+		ChainID:   s.config.ChainId,
+		Nonce:     tx.Nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       tx.Gas,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
+}
+
+// next nonce of the hot wallet
+// the stored nonce covers transactions the node has not seen yet, the pending nonce transactions sent elsewhere
+func (s *service) nextNonce(ctx context.Context) (uint64, error) {
+
+	pending, err := s.backend.PendingNonceAt(ctx, s.from)
+	if err != nil {
+		return 0, err
+	}
+	if stored := s.txStore.ReadNonce(s.Address()); stored > pending {
+		return stored, nil
+	}
+	return pending, nil
+}
+
+// service struct send method
+// estimates gas and fees, allocates the nonce and broadcasts
+// the transaction is stored before the broadcast, so a crash never loses a sent transaction
+// only a definite rejection fails it and releases the nonce, after other broadcast errors it stays pending
+func (s *service) Send(ctx context.Context, req Request) (Tx, error) {
+
+	// log level
+	logger := log.With(s.logger, "method", "Send")
+
+	value := req.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	gas, err := s.backend.EstimateGas(ctx, ethereum.CallMsg{From: s.from, To: &req.To, Value: value, Data: req.Data})
+	if err != nil {
+		if strings.Contains(err.Error(), "execution reverted") {
+			return Tx{}, fmt.Errorf("%w: %v", ErrWouldRevert, err)
+		}
+		level.Error(logger).Log("s.backend.EstimateGas:", err)
+		return Tx{}, err
+	}
+	gas += gas * uint64(s.config.GasMargin) / 100
+
+	tip, feeCap, err := s.fees(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrFeeTooHigh) {
+			level.Error(logger).Log("s.fees:", err)
+		}
+		return Tx{}, err
+	}
+
+	nonce, err := s.nextNonce(ctx)
+	if err != nil {
+		level.Error(logger).Log("s.nextNonce:", err)
+		return Tx{}, err
+	}
+
+	id, err := newId()
+	if err != nil {
+		level.Error(logger).Log("newId:", err)
+		return Tx{}, err
+	}
+	now := s.now()
+	tx := Tx{
+		Id:        id,
+		Ref:       req.Ref,
+		From:      s.Address(),
+		To:        formatAddress(req.To),
+		Data:      hexutil.Encode(req.Data),
+		Value:     value.String(),
+		Nonce:     nonce,
+		Gas:       gas,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	signed, err := s.sign(tx, tip, feeCap)
+	if err != nil {
+		level.Error(logger).Log("s.sign:", err)
+		return Tx{}, err
+	}
+	tx.Hash = signed.Hash().Hex()
+	tx.Attempts = []Attempt{{Hash: tx.Hash, GasTipCap: tip.String(), GasFeeCap: feeCap.String(), SentAt: now}}
+
+	if err := s.txStore.WriteNonce(tx.From, nonce+1); err != nil {
+		return Tx{}, err
+	}
+	if err := s.txStore.WriteTx(tx); err != nil {
+		s.txStore.WriteNonce(tx.From, nonce)
+		return Tx{}, err
+	}
+
+	if err := s.backend.SendTransaction(ctx, signed); err != nil {
+
+		// the node may have received it anyway, the transaction stays pending and Track broadcasts it again
+		level.Error(logger).Log("s.backend.SendTransaction:", err, "tx", tx.Id)
+		if !rejected(err) {
+			return tx, nil
+		}
+
+		// nothing else was allocated meanwhile, the nonce is released for the next transaction
+		tx.Status, tx.Error, tx.UpdatedAt = StatusFailed, err.Error(), s.now()
+		if err := s.txStore.WriteTx(tx); err != nil {
+			return Tx{}, err
+		}
+		if err := s.txStore.WriteNonce(tx.From, nonce); err != nil {
+			return Tx{}, err
+		}
+		return Tx{}, err
+	}
+	level.Info(logger).Log("msg", "sent", "tx", tx.Id, "hash", tx.Hash, "nonce", nonce, "ref", tx.Ref)
+
+	return tx, nil
+}
+
+// errors of nodes that definitely refused a transaction, it can never be mined
+func rejected(err error) bool {
+	for _, reason := range []string{"nonce too low", "insufficient funds", "intrinsic gas"} {
+		if strings.Contains(err.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// service struct read tx method
+func (s *service) ReadTx(ctx context.Context, id string) (Tx, error) {
+	return s.txStore.ReadTx(id)
+}
+
+// service struct list txs method
+// returns the transactions with a status, all of them for an empty status, oldest first
+func (s *service) ListTxs(ctx context.Context, status Status) ([]Tx, error) {
+
+	switch status {
+	case "", StatusPending, StatusMined, StatusConfirmed, StatusFailed:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	txs, err := s.txStore.ReadTxs()
+	if err != nil {
+		return nil, err
+	}
+	selected := []Tx{}
+	for _, tx := range txs {
+		if status == "" || tx.Status == status {
+			selected = append(selected, tx)
+		}
+	}
+	return selected, nil
+}
+
+// receipt of any attempt, the latest first
+func (s *service) receipt(ctx context.Context, tx Tx) (*types.Receipt, error) {
+	for i := len(tx.Attempts) - 1; i >= 0; i-- {
+		receipt, err := s.backend.TransactionReceipt(ctx, common.HexToHash(tx.Attempts[i].Hash))
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+// replaces a stuck transaction with the same nonce and raised fees
+// fees never drop below what a new transaction would pay, at the maximum fee cap the latest attempt is broadcast again
+func (s *service) replace(ctx context.Context, tx *Tx) error {
+
+	last := tx.Attempts[len(tx.Attempts)-1]
+	lastTip, _ := new(big.Int).SetString(last.GasTipCap, 10)
+	lastFeeCap, _ := new(big.Int).SetString(last.GasFeeCap, 10)
+	if lastTip == nil || lastFeeCap == nil {
+		return fmt.Errorf("invalid fees of attempt %s", last.Hash)
+	}
+
+	tip, feeCap := s.bumpFee(lastTip), s.bumpFee(lastFeeCap)
+	if currentTip, currentFeeCap, err := s.fees(ctx); err == nil {
+		if currentTip.Cmp(tip) > 0 {
+			tip = currentTip
+		}
+		if currentFeeCap.Cmp(feeCap) > 0 {
+			feeCap = currentFeeCap
+		}
+	}
+	if s.config.MaxFeeCap != nil && feeCap.Cmp(s.config.MaxFeeCap) > 0 {
+		tip, feeCap = lastTip, lastFeeCap
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	signed, err := s.sign(*tx, tip, feeCap)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.SendTransaction(ctx, signed); err != nil && !strings.Contains(err.Error(), "already known") {
+		return err
+	}
+
+	now := s.now()
+	if signed.Hash().Hex() == last.Hash {
+		tx.Attempts[len(tx.Attempts)-1].SentAt = now
+		return nil
+	}
+	tx.Attempts = append(tx.Attempts, Attempt{Hash: signed.Hash().Hex(), GasTipCap: tip.String(), GasFeeCap: feeCap.String(), SentAt: now})
+	tx.Hash = signed.Hash().Hex()
+	return nil
+}
+
+// Track follows open transactions until they are confirmed or failed
+// a receipt dropped by a reorg moves the transaction back to pending
+// pending transactions older than stuck_after are replaced with higher fees
+func (s *service) Track(ctx context.Context) error {
+
+	// log level
+	logger := log.With(s.logger, "method", "Track")
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	txs, err := s.txStore.ReadTxs()
+	if err != nil {
+		level.Error(logger).Log("s.txStore.ReadTxs:", err)
+		return err
+	}
+	head, err := s.backend.BlockNumber(ctx)
+	if err != nil {
+		level.Error(logger).Log("s.backend.BlockNumber:", err)
+		return err
+	}
+
+	for _, tx := range txs {
+		if !tx.open() {
+			continue
+		}
+		before := tx.clone()
+		attempts := len(tx.Attempts)
+
+		receipt, err := s.receipt(ctx, tx)
+		switch {
+
+		case err != nil && !errors.Is(err, ethereum.NotFound):
+			level.Error(logger).Log("s.receipt:", err, "tx", tx.Id)
+			continue
+
+		case err != nil:
+			tx.Status, tx.Block = StatusPending, 0
+			confirmed, err := s.backend.NonceAt(ctx, s.from, nil)
+			if err != nil {
+				level.Error(logger).Log("s.backend.NonceAt:", err)
+				continue
+			}
+			if confirmed > tx.Nonce {
+
+				// mined since the receipt lookup, picked up on the next run
+				if _, err := s.receipt(ctx, tx); err == nil {
+					continue
+				}
+				tx.Status, tx.Error = StatusFailed, "nonce used by another transaction"
+				break
+			}
+			if s.now().Sub(tx.Attempts[attempts-1].SentAt) < s.config.StuckAfter {
+				break
+			}
+			if err := s.replace(ctx, &tx); err != nil {
+				level.Error(logger).Log("s.replace:", err, "tx", tx.Id)
+				continue
+			}
+			if len(tx.Attempts) > attempts {
+				level.Info(logger).Log("msg", "replaced", "tx", tx.Id, "hash", tx.Hash, "fee_cap", tx.Attempts[len(tx.Attempts)-1].GasFeeCap)
+			}
+
+		case receipt.Status != types.ReceiptStatusSuccessful:
+			tx.Hash = receipt.TxHash.Hex()
+			tx.Status, tx.Block, tx.Error = StatusFailed, receipt.BlockNumber.Uint64(), "transaction reverted"
+
+		default:
+			tx.Hash = receipt.TxHash.Hex()
+			tx.Block = receipt.BlockNumber.Uint64()
+			tx.Status = StatusMined
+			if head+1 >= tx.Block+s.config.Confirmations {
+				tx.Status = StatusConfirmed
+			}
+		}
+
+		if tx.Status == before.Status && tx.Block == before.Block && tx.Hash == before.Hash && len(tx.Attempts) == attempts && tx.Attempts[attempts-1].SentAt.Equal(before.Attempts[attempts-1].SentAt) {
+			continue
+		}
+		tx.UpdatedAt = s.now()
+		if err := s.txStore.WriteTx(tx); err != nil {
+			level.Error(logger).Log("s.txStore.WriteTx:", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scheduler periodically tracks open transactions
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+	logger   log.Logger
+}
+
+// Run blocks and tracks transactions every interval until ctx is cancelled
+func (sc *Scheduler) Run(ctx context.Context) error {
+
+	// log level
+	logger := log.With(sc.logger, "method", "Run")
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := sc.service.Track(ctx); err != nil {
+				level.Error(logger).Log("sc.service.Track:", err)
+			}
+		}
+	}
+}
+
+func NewScheduler(s Service, interval time.Duration, logger log.Logger) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// initialization function to return service struct
+// key signs and pays for all transactions, load it with LoadKey
+// this function should is called in main.go
+func NewService(txStore TxStore, backend chain.Backend, key *ecdsa.PrivateKey, config Config, logger log.Logger) Service {
+	return &service{
+		txStore: txStore,
+		backend: backend,
+		key:     key,
+		from:    crypto.PubkeyToAddress(key.PublicKey),
+		signer:  types.LatestSignerForChainID(config.ChainId),
+		config:  config,
+		now:     time.Now,
+		logger:  log.With(logger, "from", formatAddress(crypto.PubkeyToAddress(key.PublicKey))),
+	}
+}
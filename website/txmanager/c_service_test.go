@@ -0,0 +1,278 @@
+package txmanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"website/chain"
+	"website/chain/chaintest"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/kit/log"
+)
+
+var gwei = big.NewInt(1000000000)
+
+type testManager struct {
+	sim   *chain.SimulatedBackend
+	key   *ecdsa.PrivateKey
+	path  string
+	store TxStore
+	s     Service
+}
+
+func newTestManager(t *testing.T, config Config) testManager {
+
+	key := chaintest.NewAccount(t).Key
+	path := t.TempDir()
+	store, err := NewTxStore(TxStoreConfig{TxsPath: path}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewTxStore failed, error: %v.", err)
+	}
+	tm := testManager{sim: chaintest.NewBackend(t), key: key, path: path, store: store}
+	tm.s = tm.restart(config)
+	return tm
+}
+
+func (tm testManager) restart(config Config) Service {
+	config.ChainId = chaintest.ChainId
+	return NewService(tm.store, tm.sim, tm.key, config, log.NewNopLogger())
+}
+
+// moves the service's clock forward
+func (tm testManager) advance(d time.Duration) {
+	s := tm.s.(*service)
+	now := s.now()
+	s.now = func() time.Time { return now.Add(d) }
+}
+
+func transfer(to common.Address) Request {
+	return Request{To: to, Value: big.NewInt(1), Ref: "test"}
+}
+
+var testConfig = Config{Confirmations: 2, GasMargin: 20, StuckAfter: time.Minute, BumpPercent: 50}
+
+func TestSendAndConfirm(t *testing.T) {
+
+	ctx := context.Background()
+	tm := newTestManager(t, testConfig)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	var sent []Tx
+	for i := 0; i < 2; i++ {
+		tx, err := tm.s.Send(ctx, transfer(to))
+		if err != nil {
+			t.Fatalf("Send failed, error: %v.", err)
+		}
+		if tx.Nonce != uint64(i) || tx.Status != StatusPending || tx.Gas != 25200 || len(tx.Attempts) != 1 {
+			t.Errorf("tx %+v, expected pending with nonce %d and a gas margin.", tx, i)
+		}
+		sent = append(sent, tx)
+	}
+
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	if tx, _ := tm.s.ReadTx(ctx, sent[0].Id); tx.Status != StatusMined || tx.Block != 1 || tx.Hash != sent[0].Hash {
+		t.Errorf("tx %+v, expected mined in block 1.", tx)
+	}
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	if confirmed, _ := tm.s.ListTxs(ctx, StatusConfirmed); len(confirmed) != 2 || confirmed[0].Id != sent[0].Id {
+		t.Errorf("confirmed %+v, expected both transactions oldest first.", confirmed)
+	}
+	if _, err := tm.s.ListTxs(ctx, "lost"); err != ErrInvalidStatus {
+		t.Errorf("ListTxs returned %v, expected %v.", err, ErrInvalidStatus)
+	}
+
+	// allocated nonces survive a restart
+	tx, err := tm.restart(testConfig).Send(ctx, transfer(to))
+	if err != nil || tx.Nonce != 2 {
+		t.Errorf("Send after restart returned %+v, %v, expected nonce 2.", tx, err)
+	}
+	if tm.store.ReadNonce(tm.s.Address()) != 3 {
+		t.Errorf("stored nonce %d, expected 3.", tm.store.ReadNonce(tm.s.Address()))
+	}
+}
+
+func TestReplaceStuck(t *testing.T) {
+
+	ctx := context.Background()
+	tm := newTestManager(t, testConfig)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	// the suggested tip of one gwei is not enough
+	tm.sim.SetMinTip(new(big.Int).Mul(big.NewInt(3), new(big.Int).Div(gwei, big.NewInt(2))))
+	stuck, err := tm.s.Send(ctx, transfer(to))
+	if err != nil {
+		t.Fatalf("Send failed, error: %v.", err)
+	}
+	next, err := tm.s.Send(ctx, transfer(to))
+	if err != nil || next.Nonce != 1 {
+		t.Fatalf("Send returned %+v, %v, expected nonce 1 behind the stuck transaction.", next, err)
+	}
+
+	// not yet stuck
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	if tx, _ := tm.s.ReadTx(ctx, stuck.Id); len(tx.Attempts) != 1 || tx.Status != StatusPending {
+		t.Errorf("tx %+v, expected one pending attempt.", tx)
+	}
+
+	tm.advance(2 * time.Minute)
+	tm.s.Track(ctx)
+	tx, _ := tm.s.ReadTx(ctx, stuck.Id)
+	if len(tx.Attempts) != 2 || tx.Attempts[1].GasTipCap != "1500000000" || tx.Hash != tx.Attempts[1].Hash {
+		t.Fatalf("tx %+v, expected a replacement with a 50%% higher tip.", tx)
+	}
+
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	for _, id := range []string{stuck.Id, next.Id} {
+		if tx, _ := tm.s.ReadTx(ctx, id); tx.Status != StatusConfirmed {
+			t.Errorf("tx %+v, expected confirmed.", tx)
+		}
+	}
+	if tx, _ = tm.s.ReadTx(ctx, stuck.Id); tx.Hash != tx.Attempts[1].Hash {
+		t.Errorf("tx hash %s, expected the replacement %s.", tx.Hash, tx.Attempts[1].Hash)
+	}
+}
+
+// at the maximum fee cap the stuck transaction is broadcast again, the new broadcast time must reach the file
+func TestRebroadcastSurvivesRestart(t *testing.T) {
+
+	ctx := context.Background()
+	config := testConfig
+	config.MaxFeeCap = new(big.Int).Mul(big.NewInt(3), gwei)
+	tm := newTestManager(t, config)
+
+	tm.sim.SetMinTip(new(big.Int).Mul(big.NewInt(2), gwei))
+	sent, err := tm.s.Send(ctx, transfer(common.Address{}))
+	if err != nil {
+		t.Fatalf("Send failed, error: %v.", err)
+	}
+	tm.advance(2 * time.Minute)
+	tm.s.Track(ctx)
+	tx, _ := tm.s.ReadTx(ctx, sent.Id)
+	if len(tx.Attempts) != 1 || !tx.Attempts[0].SentAt.After(sent.Attempts[0].SentAt) {
+		t.Fatalf("tx %+v, expected the first attempt broadcast again.", tx)
+	}
+
+	store, err := NewTxStore(TxStoreConfig{TxsPath: tm.path}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewTxStore failed, error: %v.", err)
+	}
+	stored, err := store.ReadTx(sent.Id)
+	if err != nil || !stored.Attempts[0].SentAt.Equal(tx.Attempts[0].SentAt) {
+		t.Errorf("stored tx %+v, error %v, expected the attempt sent at %v.", stored, err, tx.Attempts[0].SentAt)
+	}
+}
+
+// node that fails every broadcast with the same error
+type failingBackend struct {
+	chain.Backend
+	err error
+}
+
+func (b failingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.err
+}
+
+// a definite rejection fails the transaction and releases its nonce, any other error leaves it pending
+func TestSendErrors(t *testing.T) {
+
+	ctx := context.Background()
+	tm := newTestManager(t, testConfig)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	rejecting := NewService(tm.store, failingBackend{tm.sim, errors.New("insufficient funds for gas * price + value")}, tm.key, Config{ChainId: chaintest.ChainId, Confirmations: 2, GasMargin: 20, StuckAfter: time.Minute, BumpPercent: 50}, log.NewNopLogger())
+	if _, err := rejecting.Send(ctx, transfer(to)); err == nil {
+		t.Fatalf("Send of a rejected transaction succeeded.")
+	}
+	if failed, _ := tm.s.ListTxs(ctx, StatusFailed); len(failed) != 1 || tm.store.ReadNonce(tm.s.Address()) != 0 {
+		t.Errorf("failed %+v and nonce %d, expected one failed transaction and the nonce released.", failed, tm.store.ReadNonce(tm.s.Address()))
+	}
+
+	timeout := NewService(tm.store, failingBackend{tm.sim, errors.New("context deadline exceeded")}, tm.key, Config{ChainId: chaintest.ChainId, Confirmations: 2, GasMargin: 20, StuckAfter: time.Minute, BumpPercent: 50}, log.NewNopLogger())
+	tx, err := timeout.Send(ctx, transfer(to))
+	if err != nil || tx.Status != StatusPending || tm.store.ReadNonce(tm.s.Address()) != 1 {
+		t.Fatalf("Send after a timeout returned %+v, %v, expected a pending transaction keeping nonce 0.", tx, err)
+	}
+
+	// the node never received it, tracking broadcasts it again once it is stuck
+	tm.advance(2 * time.Minute)
+	tm.s.Track(ctx)
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	if tx, _ = tm.s.ReadTx(ctx, tx.Id); tx.Status != StatusMined {
+		t.Errorf("tx %+v, expected mined after the broadcast by Track.", tx)
+	}
+}
+
+func TestNonceUsedElsewhere(t *testing.T) {
+
+	ctx := context.Background()
+	tm := newTestManager(t, testConfig)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	tm.sim.SetMinTip(new(big.Int).Mul(big.NewInt(2), gwei))
+	tx, err := tm.s.Send(ctx, transfer(to))
+	if err != nil {
+		t.Fatalf("Send failed, error: %v.", err)
+	}
+
+	// the same key replaces the transaction outside of the manager
+	other, _ := types.SignNewTx(tm.key, types.LatestSignerForChainID(chaintest.ChainId), &types.DynamicFeeTx{
+		ChainID: chaintest.ChainId, Nonce: 0, GasTipCap: new(big.Int).Mul(big.NewInt(3), gwei), GasFeeCap: new(big.Int).Mul(big.NewInt(10), gwei), Gas: 21000, To: &to,
+	})
+	if err := tm.sim.SendTransaction(ctx, other); err != nil {
+		t.Fatalf("SendTransaction failed, error: %v.", err)
+	}
+	tm.sim.Commit()
+	tm.s.Track(ctx)
+	if tx, _ = tm.s.ReadTx(ctx, tx.Id); tx.Status != StatusFailed || tx.Error != "nonce used by another transaction" {
+		t.Errorf("tx %+v, expected failed.", tx)
+	}
+}
+
+// fees above the cap are not paid
+func TestMaxFeeCap(t *testing.T) {
+
+	ctx := context.Background()
+	config := testConfig
+	config.MaxFeeCap = gwei
+	tm := newTestManager(t, config)
+
+	if _, err := tm.s.Send(ctx, transfer(common.Address{})); err != ErrFeeTooHigh {
+		t.Errorf("Send returned %v, expected %v.", err, ErrFeeTooHigh)
+	}
+	if tm.store.ReadNonce(tm.s.Address()) != 0 {
+		t.Errorf("a nonce was allocated for a transaction that was not sent.")
+	}
+}
+
+func TestKeystore(t *testing.T) {
+
+	path, address, err := CreateKey(t.TempDir(), "secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("CreateKey failed, error: %v.", err)
+	}
+	key, err := LoadKey(path, "secret")
+	if err != nil {
+		t.Fatalf("LoadKey failed, error: %v.", err)
+	}
+	if crypto.PubkeyToAddress(key.PublicKey) != address {
+		t.Errorf("loaded key of %s, expected %s.", crypto.PubkeyToAddress(key.PublicKey).Hex(), address.Hex())
+	}
+	if _, err := LoadKey(path, "wrong"); err != keystore.ErrDecrypt {
+		t.Errorf("LoadKey returned %v, expected %v.", err, keystore.ErrDecrypt)
+	}
+}
@@ -0,0 +1,241 @@
+package txmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ******** Tx struct **********
+
+type Status string
+
+const (
+
+	// broadcast, no receipt yet or the receipt was dropped by a reorg
+	StatusPending Status = "pending"
+
+	// included, waiting for confirmations
+	StatusMined Status = "mined"
+
+	StatusConfirmed Status = "confirmed"
+
+	// reverted, never broadcast or the nonce was used by another transaction
+	StatusFailed Status = "failed"
+)
+
+// one signed version of a transaction, replacements share the nonce and raise the fees
+// fees are decimal wei strings
+type Attempt struct {
+	Hash      string    `json:"hash"`
+	GasTipCap string    `json:"gas_tip_cap"`
+	GasFeeCap string    `json:"gas_fee_cap"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// transaction sent from the hot wallet
+// hash is the attempt that was mined, or the latest attempt while pending
+type Tx struct {
+	Id        string    `json:"id"`
+	Ref       string    `json:"ref,omitempty"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Data      string    `json:"data"`
+	Value     string    `json:"value"`
+	Nonce     uint64    `json:"nonce"`
+	Gas       uint64    `json:"gas"`
+	Attempts  []Attempt `json:"attempts"`
+	Hash      string    `json:"hash"`
+	Status    Status    `json:"status"`
+	Block     uint64    `json:"block,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// pending and mined transactions are still tracked
+func (tx Tx) open() bool {
+	return tx.Status == StatusPending || tx.Status == StatusMined
+}
+
+// copy that shares no attempts with tx, the store hands out and keeps only copies
+func (tx Tx) clone() Tx {
+	tx.Attempts = append([]Attempt(nil), tx.Attempts...)
+	return tx
+}
+
+// ******* Tx store interface *********
+
+var ErrTxNotFound = errors.New("Transaction not found")
+
+type TxStoreConfig struct {
+	TxsPath string
+}
+
+// TxStore persists transactions and the next nonce of each sending account
+type TxStore interface {
+	WriteTx(tx Tx) error
+	ReadTx(id string) (Tx, error)
+	ReadTxs() ([]Tx, error)
+	ReadNonce(from string) uint64
+	WriteNonce(from string, next uint64) error
+}
+
+// persisted content of txs.json
+type txFile struct {
+	Nonces map[string]uint64 `json:"nonces"`
+	Txs    map[string]Tx     `json:"txs"`
+}
+
+type txStore struct {
+	mu     sync.Mutex
+	file   txFile
+	config TxStoreConfig
+	logger log.Logger
+}
+
+// creates or replaces a transaction
+func (ts *txStore) WriteTx(tx Tx) error {
+
+	// log level
+	logger := log.With(ts.logger, "method", "WriteTx")
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	previous, existed := ts.file.Txs[tx.Id]
+	ts.file.Txs[tx.Id] = tx.clone()
+
+	if err := ts.write(); err != nil {
+		if existed {
+			ts.file.Txs[tx.Id] = previous
+		} else {
+			delete(ts.file.Txs, tx.Id)
+		}
+		level.Error(logger).Log("ts.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (ts *txStore) ReadTx(id string) (Tx, error) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tx, ok := ts.file.Txs[id]
+	if !ok {
+		return Tx{}, ErrTxNotFound
+	}
+	return tx.clone(), nil
+}
+
+// all transactions, oldest first
+func (ts *txStore) ReadTxs() ([]Tx, error) {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	txs := make([]Tx, 0, len(ts.file.Txs))
+	for _, tx := range ts.file.Txs {
+		txs = append(txs, tx.clone())
+	}
+	sort.Slice(txs, func(i, k int) bool {
+		if txs[i].CreatedAt.Equal(txs[k].CreatedAt) {
+			return txs[i].Id < txs[k].Id
+		}
+		return txs[i].CreatedAt.Before(txs[k].CreatedAt)
+	})
+	return txs, nil
+}
+
+// next nonce allocated to an account, zero if none was allocated yet
+func (ts *txStore) ReadNonce(from string) uint64 {
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.file.Nonces[from]
+}
+
+func (ts *txStore) WriteNonce(from string, next uint64) error {
+
+	// log level
+	logger := log.With(ts.logger, "method", "WriteNonce")
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	previous, existed := ts.file.Nonces[from]
+	ts.file.Nonces[from] = next
+
+	if err := ts.write(); err != nil {
+		if existed {
+			ts.file.Nonces[from] = previous
+		} else {
+			delete(ts.file.Nonces, from)
+		}
+		level.Error(logger).Log("ts.write:", err)
+		return err
+	}
+
+	return nil
+}
+
+// writes nonces and transactions, temporary file first so that a crash never leaves a partial file
+func (ts *txStore) write() error {
+
+	if err := os.MkdirAll(ts.config.TxsPath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ts.file)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ts.config.TxsPath, "txs.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// loads the persisted transactions, a missing file starts empty
+func NewTxStore(config TxStoreConfig, logger log.Logger) (TxStore, error) {
+
+	ts := &txStore{
+		file:   txFile{Nonces: make(map[string]uint64), Txs: make(map[string]Tx)},
+		config: config,
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.TxsPath, "txs.json"))
+	if os.IsNotExist(err) {
+		return ts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ts.file); err != nil {
+		return nil, err
+	}
+	if ts.file.Nonces == nil {
+		ts.file.Nonces = make(map[string]uint64)
+	}
+	if ts.file.Txs == nil {
+		ts.file.Txs = make(map[string]Tx)
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"website/storage"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// wrong input data
+var ErrBadRequest = errors.New("Bad request")
+
+// handler not found
+var ErrNotFound = errors.New("Resource not found")
+
+// custom error interface which allows to pass service logic errors back to the client
+type customError interface {
+	error() error
+}
+
+// encodes customError to JSON and writes error to responseWriter
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+
+	errorType := errorStatusCode(err)
+	w.WriteHeader(errorType)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+
+}
+
+// get error status code for predefined and service error types
+// returns internal server error if error cannot be mapped
+func errorStatusCode(err error) int {
+
+	switch {
+
+	case errors.Is(err, ErrBadRequest),
+		errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrSignerMismatch),
+		errors.Is(err, ErrNoChallenge),
+		errors.Is(err, ErrTooManyWallets):
+		return http.StatusBadRequest
+
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrWalletNotLinked),
+		errors.Is(err, storage.ErrUserNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, ErrWalletTaken):
+		return http.StatusConflict
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decode address from body
+func decodeCreateChallenge(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req CreateChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode address and signature from body
+func decodeLinkWallet(_ context.Context, r *http.Request) (interface{}, error) {
+
+	var req LinkWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, ErrBadRequest
+	}
+	return req, nil
+}
+
+// decode address from route
+func decodeUnlinkWallet(_ context.Context, r *http.Request) (interface{}, error) {
+	return UnlinkWalletRequest{Address: mux.Vars(r)["address"]}, nil
+}
+
+// does not decode request
+func decodeListWallets(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListWalletsRequest{}, nil
+}
+
+// encodes response after endpoint handlers are done with writing to responseWriter
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+
+	if err, ok := response.(customError); ok && err.error() != nil {
+		encodeError(ctx, err.error(), w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// defines multiplexer routes
+// maps endpoint handlers to routes
+// info: attach after session routes, all handlers require the session of the request
+func AttachRoutes(router *mux.Router, s Service, logger log.Logger) http.Handler {
+
+	level.Info(logger).Log("msg", "attaching wallet handlers")
+
+	e := MakeEndpoints(s)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+	}
+
+	createChallengeHandler := httptransport.NewServer(
+		e.CreateChallenge,
+		decodeCreateChallenge,
+		encodeResponse,
+		options...,
+	)
+
+	linkWalletHandler := httptransport.NewServer(
+		e.LinkWallet,
+		decodeLinkWallet,
+		encodeResponse,
+		options...,
+	)
+
+	unlinkWalletHandler := httptransport.NewServer(
+		e.UnlinkWallet,
+		decodeUnlinkWallet,
+		encodeResponse,
+		options...,
+	)
+
+	listWalletsHandler := httptransport.NewServer(
+		e.ListWallets,
+		decodeListWallets,
+		encodeResponse,
+		options...,
+	)
+
+	router.Handle("/wallets", listWalletsHandler).Methods("GET")
+	router.Handle("/wallets", linkWalletHandler).Methods("POST")
+	router.Handle("/wallets/challenge", createChallengeHandler).Methods("POST")
+	router.Handle("/wallets/{address}", unlinkWalletHandler).Methods("DELETE")
+
+	return router
+}
@@ -0,0 +1,145 @@
+package wallet
+
+import (
+	"context"
+
+	"website/storage"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type CreateChallengeRequest struct {
+	Address string `json:"address"`
+}
+
+type LinkWalletRequest struct {
+	Address   string `json:"address"`
+	Signature string `json:"signature"`
+}
+
+type UnlinkWalletRequest struct {
+	Address string
+}
+
+type ListWalletsRequest struct{}
+
+type ChallengeResponse struct {
+	Data Challenge `json:"data"`
+	Err  error     `json:"errors"`
+}
+
+// have ChallengeResponse follow the customError interface defined in a_transport.go
+func (r ChallengeResponse) error() error { return r.Err }
+
+type WalletResponse struct {
+	Data storage.Wallet `json:"data"`
+	Err  error          `json:"errors"`
+}
+
+// have WalletResponse follow the customError interface defined in a_transport.go
+func (r WalletResponse) error() error { return r.Err }
+
+type WalletsResponse struct {
+	Data []storage.Wallet `json:"data"`
+	Err  error            `json:"errors"`
+}
+
+// have WalletsResponse follow the customError interface defined in a_transport.go
+func (r WalletsResponse) error() error { return r.Err }
+
+type BoolResponse struct {
+	Data bool  `json:"data"`
+	Err  error `json:"errors"`
+}
+
+// have BoolResponse follow the customError interface defined in a_transport.go
+func (r BoolResponse) error() error { return r.Err }
+
+// Endpoints struct groups all endpoint handlers and could be used to store further attributes such as logger, entpoint state etc.
+// endpoint handlers call and manage service logic
+type Endpoints struct {
+	CreateChallenge endpoint.Endpoint
+	LinkWallet      endpoint.Endpoint
+	UnlinkWallet    endpoint.Endpoint
+	ListWallets     endpoint.Endpoint
+}
+
+// init Endpoints struct and maps functions to endpoint handlers
+// this function is called in a_transport.go
+func MakeEndpoints(s Service) Endpoints {
+
+	return Endpoints{
+		CreateChallenge: epCreateChallenge(s),
+		LinkWallet:      epLinkWallet(s),
+		UnlinkWallet:    epUnlinkWallet(s),
+		ListWallets:     epListWallets(s),
+	}
+}
+
+// link challenge endpoint
+func epCreateChallenge(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(CreateChallengeRequest)
+
+		// call service method
+		c, err := s.CreateChallenge(ctx, req.Address)
+		if err != nil {
+			return ChallengeResponse{Err: err}, err
+		}
+
+		return ChallengeResponse{Data: c, Err: nil}, nil
+	}
+}
+
+// link wallet endpoint
+func epLinkWallet(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(LinkWalletRequest)
+
+		// call service method
+		w, err := s.LinkWallet(ctx, req.Address, req.Signature)
+		if err != nil {
+			return WalletResponse{Err: err}, err
+		}
+
+		return WalletResponse{Data: w, Err: nil}, nil
+	}
+}
+
+// unlink wallet endpoint
+func epUnlinkWallet(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// convert request interface
+		req := request.(UnlinkWalletRequest)
+
+		// call service method
+		if err := s.UnlinkWallet(ctx, req.Address); err != nil {
+			return BoolResponse{Data: false, Err: err}, err
+		}
+
+		return BoolResponse{Data: true, Err: nil}, nil
+	}
+}
+
+// wallets of the session user endpoint
+func epListWallets(s Service) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		// call service method
+		wallets, err := s.ListWallets(ctx)
+		if err != nil {
+			return WalletsResponse{Err: err}, err
+		}
+
+		return WalletsResponse{Data: wallets, Err: nil}, nil
+	}
+}
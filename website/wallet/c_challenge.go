@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ******** Link challenges **********
+
+// message a wallet signs with personal_sign to prove it belongs to the user
+// challenges are single use and bound to one user, address and chain
+type Challenge struct {
+	Address   string    `json:"address"`
+	Message   string    `json:"message"`
+	Nonce     string    `json:"nonce"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	userId string
+}
+
+func newChallenge(name string, chainId *big.Int, userId string, address string, now time.Time, ttl time.Duration) (Challenge, error) {
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Challenge{}, err
+	}
+
+	c := Challenge{
+		Address:   address,
+		Nonce:     hex.EncodeToString(b),
+		IssuedAt:  now.UTC().Truncate(time.Second),
+		ExpiresAt: now.UTC().Truncate(time.Second).Add(ttl),
+		userId:    userId,
+	}
+	c.Message = fmt.Sprintf("Link wallet %s to your %s account.\n\nChain ID: %s\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		common.HexToAddress(address).Hex(), name, chainId, c.Nonce, c.IssuedAt.Format(time.RFC3339), c.ExpiresAt.Format(time.RFC3339))
+
+	return c, nil
+}
+
+// EIP-191 hash of the message, what personal_sign signs and contract wallets are asked about
+func (c Challenge) Hash() common.Hash {
+	return common.BytesToHash(accounts.TextHash([]byte(c.Message)))
+}
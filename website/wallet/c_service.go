@@ -0,0 +1,367 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"website/session"
+	"website/signing"
+	"website/storage"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+var ErrUnauthorized = errors.New("Active session required")
+
+var ErrInvalidAddress = errors.New("Invalid address")
+
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+var ErrSignerMismatch = errors.New("Challenge was not signed by the wallet")
+
+var ErrNoChallenge = errors.New("No open challenge for this wallet")
+
+var ErrWalletTaken = errors.New("Wallet is linked to another account")
+
+var ErrWalletNotLinked = errors.New("Wallet is not linked to the user")
+
+var ErrTooManyWallets = errors.New("Too many wallets linked to the account")
+
+// hex encoded 20 byte ethereum address
+var addressPattern = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// Config of wallet linking, name appears in the signed message
+type Config struct {
+	Name         string
+	ChainId      *big.Int
+	ChallengeTTL time.Duration
+	MaxWallets   int
+}
+
+// Sessions reads sessions by identifier, implemented by the session service
+type Sessions interface {
+	ReadSession(sessionId string) (session.Session, error)
+}
+
+// Validator checks EIP-1271 signatures of contract wallets, implemented by the chain client
+type Validator interface {
+	IsValidSignature(ctx context.Context, account string, hash common.Hash, signature []byte) (bool, error)
+}
+
+// service interface defining all required methods
+// IsLinked makes the service usable as auction.Wallets and signing.Wallets
+type Service interface {
+	CreateChallenge(ctx context.Context, address string) (Challenge, error)
+	LinkWallet(ctx context.Context, address string, signature string) (storage.Wallet, error)
+	UnlinkWallet(ctx context.Context, address string) error
+	ListWallets(ctx context.Context) ([]storage.Wallet, error)
+	IsLinked(ctx context.Context, userId string, address string) (bool, error)
+}
+
+// service struct implementing service interface with attributes
+type service struct {
+
+	// guards owners and challenges, links of one address are serialized
+	mtx        sync.Mutex
+	owners     map[string]string
+	challenges map[string]Challenge
+
+	userStore storage.UserStore
+	sessions  Sessions
+	validator Validator
+	config    Config
+	now       func() time.Time
+	logger    log.Logger
+}
+
+// user identifier of the request's active session
+func (s *service) userId(ctx context.Context) (string, error) {
+
+	sessionId, ok := session.SessionIdFromContext(ctx)
+	if !ok {
+		return "", ErrUnauthorized
+	}
+	sess, err := s.sessions.ReadSession(sessionId)
+	if err != nil || !sess.Active(s.now()) {
+		return "", ErrUnauthorized
+	}
+
+	return sess.UserId(), nil
+}
+
+// validates and lowercases an ethereum address
+func normalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !addressPattern.MatchString(address) {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(address), nil
+}
+
+// challenges are keyed by user and address
+func challengeKey(userId, address string) string {
+	return userId + ":" + address
+}
+
+// service struct create challenge method
+// replaces an open challenge of the same user and wallet
+func (s *service) CreateChallenge(ctx context.Context, address string) (Challenge, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "CreateChallenge")
+
+	userId, err := s.userId(ctx)
+	if err != nil {
+		return Challenge{}, err
+	}
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if owner, ok := s.owners[address]; ok && owner != userId {
+		return Challenge{}, ErrWalletTaken
+	}
+
+	now := s.now()
+	for key, c := range s.challenges {
+		if !now.Before(c.ExpiresAt) {
+			delete(s.challenges, key)
+		}
+	}
+
+	c, err := newChallenge(s.config.Name, s.config.ChainId, userId, address, now, s.config.ChallengeTTL)
+	if err != nil {
+		level.Error(logger).Log("newChallenge:", err)
+		return Challenge{}, err
+	}
+	s.challenges[challengeKey(userId, address)] = c
+
+	return c, nil
+}
+
+// checks the signature of a challenge
+// an ecrecover match is an externally owned account, otherwise the address is asked as contract wallet
+func (s *service) verify(ctx context.Context, c Challenge, signature string) (string, error) {
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) == 0 {
+		return "", ErrInvalidSignature
+	}
+
+	if recovered, err := signing.Recover(c.Hash(), sig); err == nil && strings.ToLower(recovered.Hex()) == c.Address {
+		return storage.WalletEOA, nil
+	}
+
+	if s.validator != nil {
+		valid, err := s.validator.IsValidSignature(ctx, c.Address, c.Hash(), sig)
+		if err != nil {
+			return "", err
+		}
+		if valid {
+			return storage.WalletContract, nil
+		}
+	}
+
+	return "", ErrSignerMismatch
+}
+
+// service struct link wallet method
+// consumes the open challenge and stores the wallet on the user
+func (s *service) LinkWallet(ctx context.Context, address string, signature string) (storage.Wallet, error) {
+
+	// logger level
+	logger := log.With(s.logger, "method", "LinkWallet")
+
+	userId, err := s.userId(ctx)
+	if err != nil {
+		return storage.Wallet{}, err
+	}
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return storage.Wallet{}, err
+	}
+
+	// the signature check may call the wallet contract, so it runs without holding the lock
+	key := challengeKey(userId, address)
+	s.mtx.Lock()
+	c, err := s.pending(key, userId, address)
+	s.mtx.Unlock()
+	if err != nil {
+		return storage.Wallet{}, err
+	}
+
+	kind, err := s.verify(ctx, c, signature)
+	if err != nil {
+		if !errors.Is(err, ErrSignerMismatch) && !errors.Is(err, ErrInvalidSignature) {
+			level.Error(logger).Log("s.verify:", err)
+		}
+		return storage.Wallet{}, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// the challenge may have been used or replaced, or the wallet linked by someone else meanwhile
+	current, err := s.pending(key, userId, address)
+	if err != nil {
+		return storage.Wallet{}, err
+	}
+	if current.Nonce != c.Nonce {
+		return storage.Wallet{}, ErrNoChallenge
+	}
+	delete(s.challenges, key)
+
+	user, err := s.userStore.ReadUserById(userId)
+	if err != nil {
+		level.Error(logger).Log("s.userStore.ReadUserById:", err)
+		return storage.Wallet{}, err
+	}
+	for _, w := range user.Wallets {
+		if w.Address == address {
+			return w, nil
+		}
+	}
+	if s.config.MaxWallets > 0 && len(user.Wallets) >= s.config.MaxWallets {
+		return storage.Wallet{}, ErrTooManyWallets
+	}
+
+	w := storage.Wallet{Address: address, Type: kind, LinkedAt: s.now().UTC()}
+	user.Wallets = append(user.Wallets, w)
+	if err := s.userStore.WriteUser(user); err != nil {
+		level.Error(logger).Log("s.userStore.WriteUser:", err)
+		return storage.Wallet{}, err
+	}
+	s.owners[address] = userId
+
+	return w, nil
+}
+
+// open challenge of the user for the address, the caller holds the lock
+func (s *service) pending(key string, userId string, address string) (Challenge, error) {
+	c, ok := s.challenges[key]
+	if !ok || !s.now().Before(c.ExpiresAt) {
+		return Challenge{}, ErrNoChallenge
+	}
+	if owner, ok := s.owners[address]; ok && owner != userId {
+		return Challenge{}, ErrWalletTaken
+	}
+	return c, nil
+}
+
+// service struct unlink wallet method
+func (s *service) UnlinkWallet(ctx context.Context, address string) error {
+
+	// logger level
+	logger := log.With(s.logger, "method", "UnlinkWallet")
+
+	userId, err := s.userId(ctx)
+	if err != nil {
+		return err
+	}
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.owners[address] != userId {
+		return ErrWalletNotLinked
+	}
+
+	user, err := s.userStore.ReadUserById(userId)
+	if err != nil {
+		level.Error(logger).Log("s.userStore.ReadUserById:", err)
+		return err
+	}
+	wallets := make([]storage.Wallet, 0, len(user.Wallets))
+	for _, w := range user.Wallets {
+		if w.Address != address {
+			wallets = append(wallets, w)
+		}
+	}
+	user.Wallets = wallets
+	if err := s.userStore.WriteUser(user); err != nil {
+		level.Error(logger).Log("s.userStore.WriteUser:", err)
+		return err
+	}
+	delete(s.owners, address)
+
+	return nil
+}
+
+// service struct list wallets method
+// returns the wallets of the session's user, oldest link first
+func (s *service) ListWallets(ctx context.Context) ([]storage.Wallet, error) {
+
+	userId, err := s.userId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userStore.ReadUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Wallets == nil {
+		return []storage.Wallet{}, nil
+	}
+	return user.Wallets, nil
+}
+
+// service struct is linked method
+func (s *service) IsLinked(ctx context.Context, userId string, address string) (bool, error) {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	owner, ok := s.owners[strings.ToLower(address)]
+	return ok && owner == userId, nil
+}
+
+// initialization function to return service struct
+// builds the address index from the wallets stored on users, validator is optional and enables contract wallets
+// this function should is called in main.go
+func NewService(userStore storage.UserStore, sessions Sessions, validator Validator, config Config, logger log.Logger) (Service, error) {
+
+	s := &service{
+		owners:     make(map[string]string),
+		challenges: make(map[string]Challenge),
+		userStore:  userStore,
+		sessions:   sessions,
+		validator:  validator,
+		config:     config,
+		now:        time.Now,
+		logger:     logger,
+	}
+
+	users, err := userStore.ReadUsers()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, u := range users {
+		for _, w := range u.Wallets {
+			if owner, ok := s.owners[w.Address]; ok && owner != u.UserId() {
+				level.Error(logger).Log("msg", "wallet linked to two accounts", "address", w.Address)
+				continue
+			}
+			s.owners[w.Address] = u.UserId()
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,293 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/md5"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"website/session"
+	"website/storage"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/kit/log"
+)
+
+// sessions of fixed users, keyed by session identifier
+type stubSessions map[string]session.Session
+
+func (ss stubSessions) ReadSession(sessionId string) (session.Session, error) {
+	sess, ok := ss[sessionId]
+	if !ok {
+		return session.Session{}, errors.New("session not found")
+	}
+	return sess, nil
+}
+
+// contract wallets accepting any signature
+type stubValidator map[string]bool
+
+func (sv stubValidator) IsValidSignature(_ context.Context, account string, _ common.Hash, _ []byte) (bool, error) {
+	return sv[account], nil
+}
+
+type testWallet struct {
+	key     *ecdsa.PrivateKey
+	address string
+}
+
+func newTestWallet() testWallet {
+	key, _ := crypto.GenerateKey()
+	return testWallet{key: key, address: strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())}
+}
+
+// personal_sign of the challenge message, recovery id 27/28 like wallets return it
+func (w testWallet) sign(t *testing.T, c Challenge) string {
+	sig, err := crypto.Sign(accounts.TextHash([]byte(c.Message)), w.key)
+	if err != nil {
+		t.Fatalf("Sign failed, error: %v.", err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+type testEnv struct {
+	userStore storage.UserStore
+	sessions  stubSessions
+	validator stubValidator
+	s         Service
+}
+
+// stores users and opens a session for each, the session identifier is the email
+func newTestEnv(t *testing.T, emails ...string) testEnv {
+
+	env := testEnv{
+		userStore: storage.NewUserStore(storage.UserStoreConfig{UsersPath: t.TempDir() + "/"}, log.NewNopLogger()),
+		sessions:  stubSessions{},
+		validator: stubValidator{},
+	}
+	for _, email := range emails {
+		if err := env.userStore.WriteUser(storage.User{UUID: email, FirstName: "first", LastName: "last", Email: email}); err != nil {
+			t.Fatalf("WriteUser failed, error: %v.", err)
+		}
+		hash := md5.Sum([]byte(email))
+		env.sessions[email] = session.Session{FileHash: string(hash[:]), ExpiresAt: time.Now().Add(time.Hour)}
+	}
+	env.s = env.restart(t)
+	return env
+}
+
+func (env testEnv) restart(t *testing.T) Service {
+	s, err := NewService(env.userStore, env.sessions, env.validator, Config{Name: "Art", ChainId: big.NewInt(1), ChallengeTTL: time.Minute, MaxWallets: 2}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewService failed, error: %v.", err)
+	}
+	return s
+}
+
+func as(email string) context.Context {
+	return context.WithValue(context.Background(), session.SessionIdContextKey("session_id"), email)
+}
+
+func userId(email string) string {
+	return storage.User{Email: email}.UserId()
+}
+
+// challenges and links a wallet in one go
+func link(t *testing.T, s Service, email string, w testWallet) (storage.Wallet, error) {
+	c, err := s.CreateChallenge(as(email), w.address)
+	if err != nil {
+		t.Fatalf("CreateChallenge failed, error: %v.", err)
+	}
+	return s.LinkWallet(as(email), w.address, w.sign(t, c))
+}
+
+func TestLinkWallet(t *testing.T) {
+
+	env := newTestEnv(t, "alice@test.com")
+	ctx := as("alice@test.com")
+	w := newTestWallet()
+
+	c, err := env.s.CreateChallenge(ctx, w.address)
+	if err != nil {
+		t.Fatalf("CreateChallenge failed, error: %v.", err)
+	}
+	if !strings.Contains(c.Message, common.HexToAddress(w.address).Hex()) || !strings.Contains(c.Message, "Nonce: "+c.Nonce) {
+		t.Errorf("challenge message %q does not name the wallet and nonce.", c.Message)
+	}
+
+	linked, err := env.s.LinkWallet(ctx, w.address, w.sign(t, c))
+	if err != nil {
+		t.Fatalf("LinkWallet failed, error: %v.", err)
+	}
+	if linked.Address != w.address || linked.Type != storage.WalletEOA {
+		t.Errorf("linked %+v, expected an externally owned wallet.", linked)
+	}
+
+	// the challenge is single use
+	if _, err := env.s.LinkWallet(ctx, w.address, w.sign(t, c)); err != ErrNoChallenge {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrNoChallenge)
+	}
+
+	// the link is stored on the user and survives a restart
+	user, _ := env.userStore.ReadUser("alice@test.com")
+	if len(user.Wallets) != 1 || user.Wallets[0].Address != w.address || user.FirstName != "first" {
+		t.Errorf("stored user %+v, expected the linked wallet.", user)
+	}
+	for _, s := range []Service{env.s, env.restart(t)} {
+		if ok, _ := s.IsLinked(ctx, userId("alice@test.com"), common.HexToAddress(w.address).Hex()); !ok {
+			t.Errorf("wallet is not linked to alice.")
+		}
+	}
+	if ok, _ := env.s.IsLinked(ctx, "", newTestWallet().address); ok {
+		t.Errorf("unknown wallet is linked to an empty user.")
+	}
+
+	if err := env.s.UnlinkWallet(ctx, w.address); err != nil {
+		t.Fatalf("UnlinkWallet failed, error: %v.", err)
+	}
+	if wallets, _ := env.s.ListWallets(ctx); len(wallets) != 0 {
+		t.Errorf("wallets %+v after unlinking, expected none.", wallets)
+	}
+	if ok, _ := env.restart(t).IsLinked(ctx, userId("alice@test.com"), w.address); ok {
+		t.Errorf("unlinked wallet is still linked after a restart.")
+	}
+}
+
+func TestWalletBelongsToOneAccount(t *testing.T) {
+
+	env := newTestEnv(t, "alice@test.com", "bob@test.com")
+	w := newTestWallet()
+
+	if _, err := link(t, env.s, "alice@test.com", w); err != nil {
+		t.Fatalf("LinkWallet failed, error: %v.", err)
+	}
+	if _, err := env.s.CreateChallenge(as("bob@test.com"), w.address); err != ErrWalletTaken {
+		t.Errorf("CreateChallenge returned %v, expected %v.", err, ErrWalletTaken)
+	}
+	if err := env.s.UnlinkWallet(as("bob@test.com"), w.address); err != ErrWalletNotLinked {
+		t.Errorf("UnlinkWallet returned %v, expected %v.", err, ErrWalletNotLinked)
+	}
+
+	// a challenge issued before the wallet was taken can no longer be used
+	other := newTestWallet()
+	c, _ := env.s.CreateChallenge(as("bob@test.com"), other.address)
+	if _, err := link(t, env.s, "alice@test.com", other); err != nil {
+		t.Fatalf("LinkWallet failed, error: %v.", err)
+	}
+	if _, err := env.s.LinkWallet(as("bob@test.com"), other.address, other.sign(t, c)); err != ErrWalletTaken {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrWalletTaken)
+	}
+
+	// at most two wallets per account
+	if _, err := link(t, env.s, "alice@test.com", newTestWallet()); err != ErrTooManyWallets {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrTooManyWallets)
+	}
+
+	// once released the wallet can move to another account
+	if err := env.s.UnlinkWallet(as("alice@test.com"), w.address); err != nil {
+		t.Fatalf("UnlinkWallet failed, error: %v.", err)
+	}
+	if _, err := link(t, env.s, "bob@test.com", w); err != nil {
+		t.Errorf("LinkWallet failed after unlinking, error: %v.", err)
+	}
+}
+
+func TestRejectLink(t *testing.T) {
+
+	env := newTestEnv(t, "alice@test.com")
+	ctx := as("alice@test.com")
+	w, other := newTestWallet(), newTestWallet()
+
+	if _, err := env.s.CreateChallenge(context.Background(), w.address); err != ErrUnauthorized {
+		t.Errorf("CreateChallenge returned %v, expected %v.", err, ErrUnauthorized)
+	}
+	if _, err := env.s.CreateChallenge(ctx, "0x1234"); err != ErrInvalidAddress {
+		t.Errorf("CreateChallenge returned %v, expected %v.", err, ErrInvalidAddress)
+	}
+	if _, err := env.s.LinkWallet(ctx, w.address, "0x00"); err != ErrNoChallenge {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrNoChallenge)
+	}
+
+	c, _ := env.s.CreateChallenge(ctx, w.address)
+	if _, err := env.s.LinkWallet(ctx, w.address, other.sign(t, c)); err != ErrSignerMismatch {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrSignerMismatch)
+	}
+	if _, err := env.s.LinkWallet(ctx, w.address, "signature"); err != ErrInvalidSignature {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrInvalidSignature)
+	}
+
+	// expired challenges are rejected
+	env.s.(*service).now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := env.s.LinkWallet(ctx, w.address, w.sign(t, c)); err != ErrNoChallenge {
+		t.Errorf("LinkWallet returned %v, expected %v.", err, ErrNoChallenge)
+	}
+}
+
+// contract wallets are verified through EIP-1271
+func TestLinkContractWallet(t *testing.T) {
+
+	env := newTestEnv(t, "alice@test.com")
+	ctx := as("alice@test.com")
+	safe, owner := newTestWallet().address, newTestWallet()
+	env.validator[safe] = true
+
+	c, _ := env.s.CreateChallenge(ctx, safe)
+	linked, err := env.s.LinkWallet(ctx, safe, owner.sign(t, c))
+	if err != nil {
+		t.Fatalf("LinkWallet failed, error: %v.", err)
+	}
+	if linked.Type != storage.WalletContract {
+		t.Errorf("linked %+v, expected a contract wallet.", linked)
+	}
+}
+
+// contract wallet whose first signature check waits for the test
+type slowValidator struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (sv slowValidator) IsValidSignature(_ context.Context, _ string, _ common.Hash, _ []byte) (bool, error) {
+	select {
+	case sv.entered <- struct{}{}:
+		<-sv.release
+	default:
+	}
+	return true, nil
+}
+
+// the signature check of a contract wallet does not block other links, the link is checked again after it
+func TestLinkWhileVerifying(t *testing.T) {
+
+	env := newTestEnv(t, "alice@test.com", "bob@test.com")
+	validator := slowValidator{entered: make(chan struct{}), release: make(chan struct{})}
+	s, err := NewService(env.userStore, env.sessions, validator, Config{Name: "Art", ChainId: big.NewInt(1), ChallengeTTL: time.Minute, MaxWallets: 2}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("NewService failed, error: %v.", err)
+	}
+	safe, owner := newTestWallet().address, newTestWallet()
+
+	c, _ := s.CreateChallenge(as("alice@test.com"), safe)
+	done := make(chan error)
+	go func() {
+		_, err := s.LinkWallet(as("alice@test.com"), safe, owner.sign(t, c))
+		done <- err
+	}()
+	<-validator.entered
+
+	if _, err := link(t, s, "bob@test.com", testWallet{key: owner.key, address: safe}); err != nil {
+		t.Fatalf("LinkWallet while another check runs failed, error: %v.", err)
+	}
+	close(validator.release)
+	if err := <-done; err != ErrWalletTaken {
+		t.Fatalf("LinkWallet of a wallet linked meanwhile returned %v, expected %v.", err, ErrWalletTaken)
+	}
+}